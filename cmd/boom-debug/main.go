@@ -50,6 +50,7 @@ func main() {
 			"./artifacts",
 			helpers.PruneHome(*orbconfig),
 			ensure, query,
+			nil,
 		)
 
 		for {
@@ -60,6 +60,8 @@ func main() {
 	packableFiles := executables.PackableFiles(toChan([]string{
 		filepath.Join(cmdPath, "../internal/operator/orbiter/kinds/clusters/kubernetes/networks/calico.yaml"),
 		filepath.Join(cmdPath, "../internal/operator/orbiter/kinds/clusters/kubernetes/networks/cilium.yaml"),
+		filepath.Join(cmdPath, "../internal/operator/orbiter/kinds/clusters/kubernetes/networks/flannel.yaml"),
+		filepath.Join(cmdPath, "../internal/operator/orbiter/kinds/clusters/kubernetes/networks/hubble.yaml"),
 		filepath.Join(cmdPath, "../internal/operator/orbiter/kinds/providers/gce/kubernetes_gce.yaml"),
 	}))
 
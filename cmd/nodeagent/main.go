@@ -33,6 +33,7 @@ func main() {
 		r := recover()
 		if r != nil {
 			os.Stderr.Write([]byte(fmt.Sprintf("\x1b[0;31m%v\x1b[0m\n", r)))
+			mntr.Flush()
 			os.Exit(1)
 		}
 	}()
@@ -53,6 +54,15 @@ func main() {
 	if *nodeAgentID == "" {
 		panic("flag --id is required")
 	}
+
+	logCfg, err := mntr.LogConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	if err := mntr.ConfigureLogging(logCfg); err != nil {
+		panic(err)
+	}
+	mntr.ConfigureTracingFromEnv()
 	monitor := mntr.Monitor{
 		OnInfo:   mntr.LogMessage,
 		OnChange: mntr.LogMessage,
@@ -102,8 +112,8 @@ func main() {
 		gitClient,
 		gitCommit,
 		*nodeAgentID,
-		firewall.Ensurer(monitor, os.OperatingSystem, portsSlice),
-		networking.Ensurer(monitor, os.OperatingSystem),
+		firewall.Ensurer(monitor, os, portsSlice),
+		networking.Ensurer(monitor, os),
 		conv,
 		conv.Init())
 
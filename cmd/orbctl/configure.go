@@ -19,6 +19,7 @@ func ConfigCommand(getRv GetRootValues) *cobra.Command {
 	var (
 		newMasterKey string
 		newRepoURL   string
+		storeToken   string
 		cmd          = &cobra.Command{
 			Use:     "configure",
 			Short:   "Configures and reconfigures an orb",
@@ -30,6 +31,7 @@ func ConfigCommand(getRv GetRootValues) *cobra.Command {
 	flags := cmd.Flags()
 	flags.StringVar(&newMasterKey, "masterkey", "", "Reencrypts all secrets")
 	flags.StringVar(&newRepoURL, "repourl", "", "Configures the repository URL")
+	flags.StringVar(&storeToken, "store-token", "", "Access token for the GitHub, GitLab or Gitea API. Defaults to the environment variables GITHUB_TOKEN, GITLAB_TOKEN and GITEA_TOKEN")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
 
@@ -42,7 +44,7 @@ func ConfigCommand(getRv GetRootValues) *cobra.Command {
 			return errors.New("configure command is only supported with the --gitops flag")
 		}
 
-		if err := orb.Reconfigure(rv.Ctx, rv.Monitor, rv.OrbConfig, newRepoURL, newMasterKey, rv.GitClient, githubClientID, githubClientSecret); err != nil {
+		if err := orb.Reconfigure(rv.Ctx, rv.Monitor, rv.OrbConfig, newRepoURL, newMasterKey, rv.GitClient, githubClientID, githubClientSecret, storeToken); err != nil {
 			return err
 		}
 
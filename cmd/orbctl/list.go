@@ -17,8 +17,8 @@ import (
 
 func ListCommand(getRv GetRootValues) *cobra.Command {
 	var (
-		column, context string
-		cmd             = &cobra.Command{
+		column, context, output string
+		cmd                     = &cobra.Command{
 			Use:   "list",
 			Short: "List available machines",
 			Long:  "List available machines",
@@ -29,6 +29,7 @@ func ListCommand(getRv GetRootValues) *cobra.Command {
 	flags := cmd.Flags()
 	flags.StringVar(&column, "column", "", "Print this column only")
 	flags.StringVar(&context, "context", "", "Print machines from this context only")
+	addOutputFlag(cmd, &output, outputJSON, outputYAML, outputWide)
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
 		rv, err := getRv()
@@ -47,8 +48,36 @@ func ListCommand(getRv GetRootValues) *cobra.Command {
 			return errors.New("list command is only supported with the --gitops flag and a committed orbiter.yml")
 		}
 
+		if err := validateOutput(output, outputJSON, outputYAML, outputWide); err != nil {
+			return err
+		}
+
+		if output != "" && column != "" {
+			return errors.New("flags --column and --output are mutually exclusive")
+		}
+
 		return machines(monitor, gitClient, orbConfig, func(machineIDs []string, machines map[string]infra.Machine, _ *tree.Tree) error {
 
+			if output != "" {
+				all, err := readNodes(gitClient, machines)
+				if err != nil {
+					return err
+				}
+
+				var nodes []*Node
+				for _, node := range all {
+					if context == "" || context == node.Context {
+						nodes = append(nodes, node)
+					}
+				}
+
+				if structured, err := printStructured(output, nodes); structured {
+					return err
+				}
+				printTable(nodesTable(nodes))
+				return nil
+			}
+
 			printer := tableprinter.New(os.Stdout)
 			printer.BorderTop, printer.BorderBottom = true, true
 			printer.HeaderFgColor = tablewriter.FgYellowColor
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/internal/secret/operators"
+	"github.com/caos/orbos/pkg/kubernetes/cli"
+	"github.com/caos/orbos/pkg/secret"
+)
+
+func ListSecretsCommand(getRv GetRootValues) *cobra.Command {
+
+	var (
+		output   string
+		validate bool
+		cmd      = &cobra.Command{
+			Use:   "listsecrets",
+			Short: "List all secret paths and where their values are resolved from",
+			Long: `List all secret paths and where their values are resolved from.
+Values are encrypted with the master key, encrypted to recipient groups, resolved from an external backend or read from an existing kubernetes secret in the caos-system namespace.
+With --validate, the existing kubernetes secrets are checked for the referenced keys.`,
+			Example: `orbctl --gitops listsecrets
+orbctl --gitops listsecrets --validate --output yaml`,
+			Args: cobra.NoArgs,
+		}
+	)
+	cmd.Flags().BoolVar(&validate, "validate", false, "Check that the referenced existing kubernetes secrets contain the referenced keys")
+	addOutputFlag(cmd, &output, outputJSON, outputYAML)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+
+		rv, err := getRv()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = rv.ErrFunc(err)
+		}()
+
+		monitor := rv.Monitor
+		orbConfig := rv.OrbConfig
+		gitClient := rv.GitClient
+
+		if err := validateOutput(output, outputJSON, outputYAML); err != nil {
+			return err
+		}
+
+		k8sClient, err := cli.Client(monitor, orbConfig, gitClient, rv.Kubeconfig, rv.Gitops, true)
+		if err != nil && (validate || !rv.Gitops) {
+			return err
+		}
+		err = nil
+
+		secrets, existing, _, err := operators.GetAllSecretsFunc(monitor, false, rv.Gitops, gitClient, k8sClient, orbConfig)()
+		if err != nil {
+			return err
+		}
+
+		var statuses map[string]*secret.ExistingStatus
+		if validate {
+			statuses = secret.ValidateExisting(k8sClient, secrets, existing)
+		}
+
+		sources := secret.Sources(secrets, existing, statuses)
+		if structured, err := printStructured(output, sources); structured {
+			return err
+		}
+
+		headers := []string{"path", "source", "from"}
+		if validate {
+			headers = append(headers, "status", "last changed")
+		}
+		rows := make([][]string, 0, len(sources))
+		for _, source := range sources {
+			row := []string{source.Path, source.Source, source.From}
+			if validate {
+				row = append(row, statusColumns(source.Status)...)
+			}
+			rows = append(rows, row)
+		}
+		printTable(headers, rows)
+		return nil
+	}
+	return cmd
+}
+
+func statusColumns(status *secret.ExistingStatus) []string {
+	if status == nil {
+		return []string{"", ""}
+	}
+	lastChanged := ""
+	if status.LastChanged != nil {
+		lastChanged = status.LastChanged.Format(time.RFC3339)
+	}
+	if status.Error != "" {
+		return []string{status.Status + ": " + status.Error, lastChanged}
+	}
+	return []string{status.Status, lastChanged}
+}
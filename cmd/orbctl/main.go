@@ -43,18 +43,34 @@ func main() {
 		ListCommand(getRootValues),
 	)
 
+	recipients := RecipientsCommand()
+	recipients.AddCommand(
+		ListRecipientsCommand(getRootValues),
+		AddRecipientsCommand(getRootValues),
+		RemoveRecipientsCommand(getRootValues),
+		ReencryptRecipientsCommand(getRootValues),
+		OperatorsRecipientCommand(getRootValues),
+	)
+
 	rootCmd.AddCommand(
 		ReadSecretCommand(getRootValues),
+		ListSecretsCommand(getRootValues),
 		WriteSecretCommand(getRootValues),
+		RestoreCommand(getRootValues),
 		EditCommand(getRootValues),
 		TeardownCommand(getRootValues),
 		ConfigCommand(getRootValues),
+		RotateMasterkeyCommand(getRootValues),
 		APICommand(getRootValues),
+		ProvidersCommand(),
 		takeoff,
 		nodes,
+		recipients,
 	)
 
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	mntr.Flush()
+	if err != nil {
 		os.Exit(1)
 	}
 }
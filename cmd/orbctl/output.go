@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kataras/tablewriter"
+	"github.com/landoop/tableprinter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent/state"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/pkg/git"
+)
+
+const (
+	outputJSON = "json"
+	outputYAML = "yaml"
+	outputWide = "wide"
+)
+
+func addOutputFlag(cmd *cobra.Command, output *string, formats ...string) {
+	cmd.Flags().StringVarP(output, "output", "o", "", fmt.Sprintf("Output format, one of %s", strings.Join(formats, "|")))
+}
+
+func validateOutput(output string, formats ...string) error {
+	if output == "" {
+		return nil
+	}
+	for _, format := range formats {
+		if output == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %s, expected one of %s", output, strings.Join(formats, "|"))
+}
+
+// printStructured prints the value as JSON or YAML.
+// It returns false if the output format is not a structured one
+func printStructured(output string, value interface{}) (bool, error) {
+	var (
+		out []byte
+		err error
+	)
+	switch output {
+	case outputJSON:
+		out, err = json.MarshalIndent(value, "", "  ")
+		out = append(out, '\n')
+	case outputYAML:
+		out, err = yaml.Marshal(value)
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	_, err = os.Stdout.Write(out)
+	return true, err
+}
+
+func printTable(headers []string, rows [][]string) {
+	printer := tableprinter.New(os.Stdout)
+	printer.BorderTop, printer.BorderBottom = true, true
+	printer.HeaderFgColor = tablewriter.FgYellowColor
+	printer.Render(headers, rows, nil, false)
+}
+
+// Node is the stable machine-readable representation of a machine and its node agent
+type Node struct {
+	Context             string `json:"context" yaml:"context"`
+	ID                  string `json:"id" yaml:"id"`
+	IP                  string `json:"ip" yaml:"ip"`
+	Provider            string `json:"provider" yaml:"provider"`
+	Pool                string `json:"pool" yaml:"pool"`
+	RebootRequired      bool   `json:"rebootRequired" yaml:"rebootRequired"`
+	ReplacementRequired bool   `json:"replacementRequired" yaml:"replacementRequired"`
+	NodeAgentReady      bool   `json:"nodeAgentReady" yaml:"nodeAgentReady"`
+	NodeAgentCommit     string `json:"nodeAgentCommit" yaml:"nodeAgentCommit"`
+}
+
+// TakeoffStatus is the stable machine-readable representation of the orb after a takeoff
+type TakeoffStatus struct {
+	Orbiter    bool    `json:"orbiter" yaml:"orbiter"`
+	Deployed   bool    `json:"deployed" yaml:"deployed"`
+	Nodes      []*Node `json:"nodes" yaml:"nodes"`
+	NodesReady int     `json:"nodesReady" yaml:"nodesReady"`
+}
+
+// readNodes lists the machines together with the node agents current states committed to the orbs repository
+func readNodes(gitClient *git.Client, machines map[string]infra.Machine) ([]*Node, error) {
+	currentNodeAgents := common.NodeAgentsCurrentKind{}
+	if err := yaml.Unmarshal(gitClient.Read(state.CurrentPath), &currentNodeAgents); err != nil {
+		return nil, err
+	}
+	return listNodes(machines, &currentNodeAgents.Current), nil
+}
+
+// listNodes maps the machines, which are keyed by provider.pool.id, to nodes sorted by context and ID
+func listNodes(machines map[string]infra.Machine, current *common.CurrentNodeAgents) []*Node {
+	nodes := make([]*Node, 0, len(machines))
+	for path, machine := range machines {
+		ctx := path[:strings.LastIndex(path, ".")]
+		node := &Node{
+			Context: ctx,
+			ID:      machine.ID(),
+			IP:      machine.IP(),
+		}
+		if sep := strings.Index(ctx, "."); sep >= 0 {
+			node.Provider, node.Pool = ctx[:sep], ctx[sep+1:]
+		}
+		node.RebootRequired, _, _ = machine.RebootRequired()
+		node.ReplacementRequired, _, _ = machine.ReplacementRequired()
+		if na, ok := current.NA[node.ID]; ok && na != nil {
+			node.NodeAgentReady = na.NodeIsReady
+			node.NodeAgentCommit = na.Commit
+		}
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Context != nodes[j].Context {
+			return nodes[i].Context < nodes[j].Context
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes
+}
+
+func nodesTable(nodes []*Node) ([]string, [][]string) {
+	headers := []string{"context", "id", "ip", "provider", "pool", "reboot required", "replacement required", "node agent ready", "node agent commit"}
+	rows := make([][]string, len(nodes))
+	for idx, node := range nodes {
+		rows[idx] = []string{
+			node.Context,
+			node.ID,
+			node.IP,
+			node.Provider,
+			node.Pool,
+			strconv.FormatBool(node.RebootRequired),
+			strconv.FormatBool(node.ReplacementRequired),
+			strconv.FormatBool(node.NodeAgentReady),
+			node.NodeAgentCommit,
+		}
+	}
+	return headers, rows
+}
+
+func takeoffStatusTable(status *TakeoffStatus) ([]string, [][]string) {
+	headers, rows := nodesTable(status.Nodes)
+	headers = append([]string{"orbiter", "deployed"}, headers...)
+	for idx := range rows {
+		rows[idx] = append([]string{strconv.FormatBool(status.Orbiter), strconv.FormatBool(status.Deployed)}, rows[idx]...)
+	}
+	return headers, rows
+}
+
+func planTable(plan *orbiter.Plan) ([]string, [][]string) {
+	headers := []string{"affects", "action", "id", "path", "from", "to"}
+	var rows [][]string
+	for _, affected := range []struct {
+		name    string
+		changes []*orbiter.PlannedChange
+	}{
+		{"machines", plan.Machines},
+		{"node agents", plan.NodeAgents},
+		{"load balancing", plan.LoadBalancing},
+		{"kubernetes", plan.Kubernetes},
+	} {
+		for _, change := range affected.changes {
+			rows = append(rows, []string{affected.name, change.Action, change.ID, change.Path, change.From, change.To})
+		}
+	}
+	return headers, rows
+}
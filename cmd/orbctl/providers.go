@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers"
+)
+
+func ProvidersCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "providers",
+		Short:   "List supported provider kinds",
+		Long:    "Lists all provider kinds which can be used in the orbiter.yml",
+		Aliases: []string{"provider"},
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, kind := range providers.Kinds() {
+				fmt.Println(kind)
+			}
+		},
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 
 	"github.com/caos/orbos/pkg/kubernetes/cli"
@@ -14,14 +15,17 @@ import (
 
 func ReadSecretCommand(getRv GetRootValues) *cobra.Command {
 
-	return &cobra.Command{
+	var output string
+
+	cmd := &cobra.Command{
 		Use:   "readsecret [path]",
 		Short: "Print a secrets decrypted value to stdout",
 		Long:  "Print a secrets decrypted value to stdout.\nIf no path is provided, a secret can interactively be chosen from a list of all possible secrets",
 		Args:  cobra.MaximumNArgs(1),
 		Example: `orbctl readsecret
 orbctl readsecret orbiter.k8s.kubeconfig.encrypted
-orbctl readsecret orbiter.k8s.kubeconfig.encrypted > ~/.kube/config`,
+orbctl readsecret orbiter.k8s.kubeconfig.encrypted > ~/.kube/config
+orbctl readsecret orbiter.k8s.kubeconfig.encrypted --output json`,
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
 
 			rv, err := getRv()
@@ -36,11 +40,19 @@ orbctl readsecret orbiter.k8s.kubeconfig.encrypted > ~/.kube/config`,
 			orbConfig := rv.OrbConfig
 			gitClient := rv.GitClient
 
+			if err := validateOutput(output, outputJSON, outputYAML); err != nil {
+				return err
+			}
+
 			path := ""
 			if len(args) > 0 {
 				path = args[0]
 			}
 
+			if output != "" && path == "" {
+				return errors.New("a path is required when the flag --output is passed")
+			}
+
 			k8sClient, err := cli.Client(monitor, orbConfig, gitClient, rv.Kubeconfig, rv.Gitops, true)
 			if err != nil && !rv.Gitops {
 				return err
@@ -55,10 +67,23 @@ orbctl readsecret orbiter.k8s.kubeconfig.encrypted > ~/.kube/config`,
 			if err != nil {
 				return err
 			}
+
+			if structured, err := printStructured(output, &Secret{Path: path, Value: value}); structured {
+				return err
+			}
+
 			if _, err := os.Stdout.Write([]byte(value)); err != nil {
 				panic(err)
 			}
 			return nil
 		},
 	}
+	addOutputFlag(cmd, &output, outputJSON, outputYAML)
+	return cmd
+}
+
+// Secret is the stable machine-readable representation of a decrypted secret
+type Secret struct {
+	Path  string `json:"path" yaml:"path"`
+	Value string `json:"value" yaml:"value"`
 }
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/pkg/cfg"
+	"github.com/caos/orbos/pkg/git"
+	"github.com/caos/orbos/pkg/kubernetes/cli"
+	"github.com/caos/orbos/pkg/orb"
+	"github.com/caos/orbos/pkg/secret"
+)
+
+// operatorsGroup is the recipient group the operators identity is a member of
+const operatorsGroup = "operators"
+
+var recipientsFiles = []git.DesiredFile{git.OrbiterFile, git.BoomFile, git.NetworkingFile}
+
+func RecipientsCommand() *cobra.Command {
+
+	return &cobra.Command{
+		Use:   "recipients command",
+		Short: "Manage who can decrypt recipient encrypted secrets",
+		Long: `Manage the recipient groups in recipients.yml.
+Secrets written with orbctl writesecret --groups are encrypted to the age or SSH public keys of these groups instead of the master key.
+Decryption uses the age identity or the SSH private key configured in the orbconfig by the properties ageidentity and sshidentity.`,
+		Example: `orbctl recipients <list|add|remove|reencrypt|operators>`,
+		Args:    cobra.MinimumNArgs(1),
+	}
+}
+
+func ListRecipientsCommand(getRv GetRootValues) *cobra.Command {
+
+	var output string
+	cmd := &cobra.Command{
+		Use:   "list [group]",
+		Short: "List the members of all or a specific recipient group",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	addOutputFlag(cmd, &output, outputJSON, outputYAML)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+
+		rv, err := getRv()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = rv.ErrFunc(err)
+		}()
+
+		if err := validateOutput(output, outputJSON, outputYAML); err != nil {
+			return err
+		}
+
+		recipients, err := cloneRecipients(rv)
+		if err != nil {
+			return err
+		}
+
+		if len(args) > 0 {
+			members, ok := recipients.Groups[args[0]]
+			if !ok {
+				return fmt.Errorf("recipient group %s is not declared", args[0])
+			}
+			recipients = &secret.Recipients{Groups: map[string][]string{args[0]: members}}
+		}
+
+		if structured, err := printStructured(output, recipients); structured {
+			return err
+		}
+
+		for _, group := range recipients.List() {
+			for _, member := range recipients.Groups[group] {
+				fmt.Printf("%s\t%s\n", group, member)
+			}
+		}
+		return nil
+	}
+	return cmd
+}
+
+func AddRecipientsCommand(getRv GetRootValues) *cobra.Command {
+
+	var always bool
+	cmd := &cobra.Command{
+		Use:   "add group recipient...",
+		Short: "Add age or SSH public keys to a recipient group and reencrypt the groups secrets",
+		Long: `Add age or SSH public keys to a recipient group and reencrypt the groups secrets.
+You need to be able to decrypt all secrets of the group yourself.`,
+		Example: `orbctl --gitops recipients add monitoring age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+orbctl --gitops recipients add infra "$(cat ~/.ssh/id_ed25519.pub)"
+orbctl --gitops recipients add admins "$(cat ~/.ssh/id_ed25519.pub)" --always`,
+		Args: cobra.MinimumNArgs(2),
+	}
+	cmd.Flags().BoolVar(&always, "always", false, "Encrypt every recipient encrypted secret to this group")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+
+		rv, err := getRv()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = rv.ErrFunc(err)
+		}()
+
+		recipients, err := cloneRecipients(rv)
+		if err != nil {
+			return err
+		}
+
+		group := args[0]
+		added, err := recipients.Add(group, args[1:]...)
+		if err != nil {
+			return err
+		}
+
+		if always && !recipients.Includes(nil, group) {
+			recipients.Always = append(recipients.Always, group)
+		} else if added == 0 {
+			rv.Monitor.Info("Nothing changed")
+			return nil
+		}
+
+		return updateRecipients(rv, recipients, group, always, fmt.Sprintf("Added %d recipients to group %s", added, group))
+	}
+	return cmd
+}
+
+func RemoveRecipientsCommand(getRv GetRootValues) *cobra.Command {
+
+	return &cobra.Command{
+		Use:   "remove group recipient...",
+		Short: "Remove age or SSH public keys from a recipient group and reencrypt the groups secrets",
+		Long: `Remove age or SSH public keys from a recipient group and reencrypt the groups secrets.
+You need to be able to decrypt all secrets of the group yourself.
+The removed recipients can still decrypt the secrets from the repositories history, so consider changing the secrets values.`,
+		Example: `orbctl --gitops recipients remove monitoring age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p`,
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+
+			rv, err := getRv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = rv.ErrFunc(err)
+			}()
+
+			recipients, err := cloneRecipients(rv)
+			if err != nil {
+				return err
+			}
+
+			group := args[0]
+			always := recipients.Includes(nil, group)
+			removed, err := recipients.Remove(group, args[1:]...)
+			if err != nil {
+				return err
+			}
+
+			if removed == 0 {
+				rv.Monitor.Info("Nothing changed")
+				return nil
+			}
+
+			return updateRecipients(rv, recipients, group, always, fmt.Sprintf("Removed %d recipients from group %s", removed, group))
+		},
+	}
+}
+
+func ReencryptRecipientsCommand(getRv GetRootValues) *cobra.Command {
+
+	return &cobra.Command{
+		Use:   "reencrypt [group]",
+		Short: "Reencrypt all or a groups recipient encrypted secrets to the current members of their groups",
+		Long: `Reencrypt all or a groups recipient encrypted secrets to the current members of their groups.
+Use this command after editing recipients.yml manually.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+
+			rv, err := getRv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = rv.ErrFunc(err)
+			}()
+
+			recipients, err := cloneRecipients(rv)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				return pushRecipients(rv, recipients, func([]string) bool { return true }, "Reencrypted all recipient encrypted secrets")
+			}
+
+			group := args[0]
+			return updateRecipients(rv, recipients, group, recipients.Includes(nil, group), fmt.Sprintf("Reencrypted secrets of group %s", group))
+		},
+	}
+}
+
+func OperatorsRecipientCommand(getRv GetRootValues) *cobra.Command {
+
+	return &cobra.Command{
+		Use:   "operators",
+		Short: "Generate a new identity for the operators and encrypt every recipient encrypted secret to it",
+		Long: `Generate a new identity for the operators and encrypt every recipient encrypted secret to it.
+The identity is only written to the orbconfig kubernetes secret, so it never leaves the cluster.
+Its public key replaces the members of the recipient group operators, which every secret is encrypted to.
+You need to be able to decrypt all recipient encrypted secrets yourself.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+
+			rv, err := getRv()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				err = rv.ErrFunc(err)
+			}()
+
+			recipients, err := cloneRecipients(rv)
+			if err != nil {
+				return err
+			}
+
+			k8sClient, err := cli.Client(rv.Monitor, rv.OrbConfig, rv.GitClient, rv.Kubeconfig, rv.Gitops, false)
+			if err != nil {
+				return err
+			}
+
+			identity, err := age.GenerateX25519Identity()
+			if err != nil {
+				return err
+			}
+
+			recipients.Groups[operatorsGroup] = []string{identity.Recipient().String()}
+			if !recipients.Includes(nil, operatorsGroup) {
+				recipients.Always = append(recipients.Always, operatorsGroup)
+			}
+
+			oldIdentity, err := cfg.CurrentOperatorIdentity(k8sClient)
+			if err != nil {
+				return err
+			}
+
+			// Until the secrets encrypted to the new identity are pushed, the operators need to decrypt with both
+			if err := cfg.ApplyOperatorIdentity(rv.OrbConfig, strings.TrimSpace(oldIdentity+"\n"+identity.String()), k8sClient, rv.Monitor); err != nil {
+				return err
+			}
+
+			if err := pushRecipients(rv, recipients, func([]string) bool { return true }, "Encrypted all recipient encrypted secrets to a new operators identity"); err != nil {
+				if restoreErr := cfg.ApplyOperatorIdentity(rv.OrbConfig, oldIdentity, k8sClient, rv.Monitor); restoreErr != nil {
+					return fmt.Errorf("restoring the old operators identity failed: %w: %s", restoreErr, err.Error())
+				}
+				return err
+			}
+
+			return cfg.ApplyOperatorIdentity(rv.OrbConfig, identity.String(), k8sClient, rv.Monitor)
+		},
+	}
+}
+
+func cloneRecipients(rv *RootValues) (*secret.Recipients, error) {
+
+	if !rv.Gitops {
+		return nil, errors.New("recipients commands are only supported with the --gitops flag")
+	}
+
+	if err := orb.IsComplete(rv.OrbConfig); err != nil {
+		return nil, err
+	}
+
+	if err := rv.GitClient.Configure(rv.OrbConfig.URL, []byte(rv.OrbConfig.Repokey)); err != nil {
+		return nil, err
+	}
+
+	if err := rv.GitClient.Clone(); err != nil {
+		return nil, err
+	}
+
+	return secret.ReadRecipients(rv.GitClient)
+}
+
+// updateRecipients reencrypts the secrets of the group or, if the group is encrypted to always, all recipient encrypted secrets
+func updateRecipients(rv *RootValues, recipients *secret.Recipients, group string, always bool, msg string) error {
+	affected := func(groups []string) bool {
+		return always || recipients.Includes(groups, group)
+	}
+	return pushRecipients(rv, recipients, affected, msg)
+}
+
+func pushRecipients(rv *RootValues, recipients *secret.Recipients, affected func([]string) bool, msg string) error {
+	updated, err := secret.UpdateRecipients(rv.GitClient, recipients, msg, affected, recipientsFiles...)
+	if err != nil {
+		return err
+	}
+	rv.Monitor.WithField("secrets", updated).Info(fmt.Sprintf("Pushed %s with reencrypted secrets", secret.RecipientsFile))
+	return nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes/etcd"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/orb"
+	"github.com/caos/orbos/pkg/git"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+func RestoreCommand(getRv GetRootValues) *cobra.Command {
+	var (
+		clusterID, machineID string
+		list                 bool
+		cmd                  = &cobra.Command{
+			Use:   "restore [snapshot]",
+			Short: "Rebuild a control plane from an etcd snapshot",
+			Long: `Restore an etcd snapshot taken by ORBITER on a control plane machine.
+If no snapshot is passed, the latest one is restored.
+Etcd and the API server are stopped on all other control plane machines first.
+The etcd member on the restoring machine becomes a single member cluster, so all other control plane machines are replaced.`,
+			Args: cobra.MaximumNArgs(1),
+			Example: `orbctl --gitops restore --list
+orbctl --gitops restore
+orbctl --gitops restore etcd-k8s-20240101T000000Z.db.enc --machine k8s-controlplane-1`,
+		}
+	)
+
+	flags := cmd.Flags()
+	flags.StringVar(&clusterID, "cluster", "", "ID of the cluster to restore, required if the orb has multiple clusters")
+	flags.StringVar(&machineID, "machine", "", "ID of the control plane machine to restore the snapshot on. Defaults to the first control plane machine")
+	flags.BoolVar(&list, "list", false, "Only list the available snapshots, the latest first")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+
+		rv, err := getRv()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = rv.ErrFunc(err)
+		}()
+
+		monitor := rv.Monitor
+		orbConfig := rv.OrbConfig
+		gitClient := rv.GitClient
+
+		if !rv.Gitops {
+			return errors.New("restore command is only supported with the --gitops flag and a committed orbiter.yml")
+		}
+
+		return machines(monitor, gitClient, orbConfig, func(_ []string, machines map[string]infra.Machine, desired *tree.Tree) error {
+
+			clusterID, cluster, err := restoringCluster(desired, clusterID)
+			if err != nil {
+				return err
+			}
+			if cluster.Spec.Backup == nil {
+				return fmt.Errorf("cluster %s has no backup configured", clusterID)
+			}
+
+			machinesByID := make(map[string]infra.Machine, len(machines))
+			var controlplane []infra.Machine
+			controlplanePrefix := cluster.Spec.ControlPlane.Provider + "." + cluster.Spec.ControlPlane.Pool + "."
+			for path, machine := range machines {
+				machinesByID[machine.ID()] = machine
+				if strings.HasPrefix(path, controlplanePrefix) {
+					controlplane = append(controlplane, machine)
+				}
+			}
+			sort.Slice(controlplane, func(i, j int) bool { return controlplane[i].ID() < controlplane[j].ID() })
+			if len(controlplane) == 0 {
+				return fmt.Errorf("cluster %s has no control plane machines", clusterID)
+			}
+
+			restoring := controlplane[0]
+			if machineID != "" {
+				restoring = nil
+				for _, machine := range controlplane {
+					if machine.ID() == machineID {
+						restoring = machine
+					}
+				}
+				if restoring == nil {
+					return fmt.Errorf("machine %s is not a control plane machine of cluster %s", machineID, clusterID)
+				}
+			}
+
+			key, err := cluster.Spec.Backup.SnapshotKey()
+			if err != nil {
+				return err
+			}
+
+			target, err := kubernetes.BackupTarget(cluster.Spec.Backup, restoring, machinesByID)
+			if err != nil {
+				return err
+			}
+
+			snapshots, err := etcd.Snapshots(target, clusterID)
+			if err != nil {
+				return err
+			}
+
+			if list {
+				for _, snapshot := range snapshots {
+					fmt.Println(snapshot)
+				}
+				return nil
+			}
+
+			if len(snapshots) == 0 {
+				return fmt.Errorf("no snapshots of cluster %s found", clusterID)
+			}
+
+			snapshot := snapshots[0]
+			if len(args) > 0 {
+				snapshot = args[0]
+			}
+
+			data, err := target.Get(snapshot)
+			if err != nil {
+				return fmt.Errorf("reading snapshot %s failed: %w", snapshot, err)
+			}
+
+			// The other members would keep their data, so they are stopped until they are replaced
+			for _, machine := range controlplane {
+				if machine.ID() == restoring.ID() {
+					continue
+				}
+				if err := etcd.Stop(machine); err != nil {
+					return err
+				}
+				monitor.WithField("machine", machine.ID()).Info("Etcd member stopped")
+			}
+
+			monitor.WithFields(map[string]interface{}{
+				"snapshot": snapshot,
+				"machine":  restoring.ID(),
+			}).Info("Restoring etcd snapshot")
+
+			if err := etcd.Restore(restoring, key, data); err != nil {
+				return err
+			}
+
+			var push bool
+			for _, machine := range controlplane {
+				if machine.ID() == restoring.ID() {
+					continue
+				}
+				if required, require, _ := machine.ReplacementRequired(); !required {
+					require()
+					push = true
+				}
+			}
+
+			monitor.Info("Etcd snapshot restored")
+			if !push {
+				return nil
+			}
+			return gitClient.PushDesiredFunc(git.OrbiterFile, desired)(monitor)
+		})
+	}
+	return cmd
+}
+
+func restoringCluster(desired *tree.Tree, clusterID string) (string, *kubernetes.DesiredV0, error) {
+	orbDesired, ok := desired.Parsed.(*orb.DesiredV0)
+	if !ok {
+		return "", nil, errors.New("parsing orbiter.yml failed")
+	}
+
+	if clusterID == "" {
+		if len(orbDesired.Clusters) != 1 {
+			return "", nil, errors.New("the orb has multiple clusters, please pass the flag --cluster")
+		}
+		for id := range orbDesired.Clusters {
+			clusterID = id
+		}
+	}
+
+	clusterTree, ok := orbDesired.Clusters[clusterID]
+	if !ok {
+		return "", nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+
+	cluster, ok := clusterTree.Parsed.(*kubernetes.DesiredV0)
+	if !ok {
+		return "", nil, fmt.Errorf("cluster %s is not of kind orbiter.caos.ch/KubernetesCluster", clusterID)
+	}
+	return clusterID, cluster, nil
+}
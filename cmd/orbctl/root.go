@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
 
 	"github.com/caos/orbos/internal/helpers"
 
@@ -44,8 +47,16 @@ func RootCommand() (*cobra.Command, GetRootValues) {
 	var (
 		orbConfigPath string
 		verbose       bool
+		logFormat     = os.Getenv(mntr.EnvLogFormat)
+		logLevel      = os.Getenv(mntr.EnvLogLevel)
+		logSinks      []string
+		tracesURL     = os.Getenv(mntr.EnvTracesEndpoint)
 	)
 
+	if sinks := os.Getenv(mntr.EnvLogSinks); sinks != "" {
+		logSinks = strings.Split(sinks, ",")
+	}
+
 	cmd := &cobra.Command{
 		Use:   "orbctl [flags]",
 		Short: "Interact with your orbs",
@@ -74,23 +85,62 @@ $ orbctl --gitops -f ~/.orb/myorb [command]
 	flags.StringVarP(&rv.Kubeconfig, "kubeconfig", "k", "~/.kube/config", "Path to the kubeconfig file to the cluster orbctl should target")
 	flags.BoolVar(&rv.Gitops, "gitops", false, "Run orbctl in gitops mode. Not specifying this flag is only supported for BOOM and Networking Operator")
 	flags.BoolVar(&verbose, "verbose", false, "Print debug levelled logs")
+	flags.StringVar(&logFormat, "log-format", logFormat, fmt.Sprintf("Format of the log records, either text or json. Defaults to $%s", mntr.EnvLogFormat))
+	flags.StringVar(&logLevel, "log-level", logLevel, fmt.Sprintf("Minimum level of the written log records, either debug, info or error. Defaults to $%s", mntr.EnvLogLevel))
+	flags.StringSliceVar(&logSinks, "log-sink", logSinks, fmt.Sprintf("Where to write log records to: stdout, stderr, syslog, file:<path> or the http(s) URL of an OTLP logs endpoint. Defaults to $%s or stdout", mntr.EnvLogSinks))
+	flags.StringVar(&tracesURL, "traces-endpoint", tracesURL, fmt.Sprintf("The http(s) URL of an OTLP traces endpoint the adapt, query and ensure phases are traced to. Defaults to $%s", mntr.EnvTracesEndpoint))
+
+	// Structured output is written to stdout, so log records are written to stderr instead
+	var structuredOutput bool
+	cmd.PersistentPreRun = func(cmd *cobra.Command, _ []string) {
+		if output := cmd.Flags().Lookup("output"); output != nil {
+			structuredOutput = output.Value.String() == outputJSON || output.Value.String() == outputYAML
+		}
+	}
 
 	return cmd, func() (*RootValues, error) {
 
-		if verbose {
+		logCfg := mntr.LogConfig{Sinks: logSinks}
+		if structuredOutput {
+			logCfg.Sinks = []string{}
+			for _, sink := range logSinks {
+				if sink == "stdout" {
+					sink = "stderr"
+				}
+				logCfg.Sinks = append(logCfg.Sinks, sink)
+			}
+			if len(logCfg.Sinks) == 0 {
+				logCfg.Sinks = append(logCfg.Sinks, "stderr")
+			}
+		}
+		var err error
+		if logCfg.Format, err = mntr.ParseFormat(logFormat); err != nil {
+			return nil, err
+		}
+		if logCfg.Level, err = mntr.ParseLevel(logLevel); err != nil {
+			return nil, err
+		}
+		if err := mntr.ConfigureLogging(logCfg); err != nil {
+			return nil, err
+		}
+		mntr.ConfigureTracing(tracesURL)
+
+		if verbose || strings.ToLower(logLevel) == "debug" {
 			monitor = monitor.Verbose()
 		}
 		rv.Monitor = monitor
 		rv.Kubeconfig = helpers.PruneHome(rv.Kubeconfig)
 		rv.GitClient = git.New(ctx, monitor, "orbos", "orbos@caos.ch")
 
-		var err error
 		if rv.Gitops {
 			prunedPath := helpers.PruneHome(orbConfigPath)
 			rv.OrbConfig, err = orb.ParseOrbConfig(prunedPath)
 			if rv.OrbConfig == nil {
 				rv.OrbConfig = &orb.Orb{Path: prunedPath}
 			}
+			if err == nil {
+				err = rv.OrbConfig.ConfigureSecretBackends(rv.GitClient, nil)
+			}
 		}
 
 		return rv, err
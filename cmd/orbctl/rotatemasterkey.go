@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/caos/orbos/pkg/cfg"
+	"github.com/caos/orbos/pkg/git"
+	"github.com/caos/orbos/pkg/kubernetes"
+	"github.com/caos/orbos/pkg/kubernetes/cli"
+	"github.com/caos/orbos/pkg/orb"
+	"github.com/caos/orbos/pkg/secret"
+)
+
+func RotateMasterkeyCommand(getRv GetRootValues) *cobra.Command {
+
+	var (
+		newMasterKey string
+		storeToken   string
+		skipCluster  bool
+		cmd          = &cobra.Command{
+			Use:   "rotatemasterkey",
+			Short: "Reencrypts all secrets with a new master key",
+			Long: `Reencrypts all secrets in orbiter.yml, boom.yml and networking.yml with a new master key and pushes them in a single commit.
+Before, the new master key is written to the local orbconfig and to the orbconfig kubernetes secret. If this or pushing fails, the old master key is restored.
+The cluster must be reachable before anything is changed, unless the flag --skip-cluster is passed for orbs without a running cluster.
+Secrets encrypted by former orbctl versions are upgraded to the authenticated encryption format on the fly.
+The etcd snapshot key is reencrypted like any other secret, so existing etcd snapshots stay restorable.`,
+			Example: `orbctl --gitops rotatemasterkey --masterkey "$(openssl rand -base64 21)"`,
+		}
+	)
+
+	flags := cmd.Flags()
+	flags.StringVar(&newMasterKey, "masterkey", "", "The new master key")
+	flags.StringVar(&storeToken, "store-token", "", "Access token for the GitHub, GitLab or Gitea API. Defaults to the environment variables GITHUB_TOKEN, GITLAB_TOKEN and GITEA_TOKEN")
+	flags.BoolVar(&skipCluster, "skip-cluster", false, "Don't write the new master key to the orbconfig kubernetes secret, for orbs without a running cluster")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
+
+		rv, err := getRv()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err = rv.ErrFunc(err)
+		}()
+
+		if !rv.Gitops {
+			return errors.New("rotatemasterkey command is only supported with the --gitops flag")
+		}
+
+		if newMasterKey == "" {
+			return errors.New("the new master key must be passed by the flag masterkey")
+		}
+
+		monitor := rv.Monitor
+		orbConfig := rv.OrbConfig
+		gitClient := rv.GitClient
+
+		if err := orb.IsComplete(orbConfig); err != nil {
+			return err
+		}
+
+		if newMasterKey == orbConfig.Masterkey {
+			return errors.New("the new master key equals the current master key")
+		}
+
+		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
+			return err
+		}
+
+		if err := gitClient.Clone(); err != nil {
+			return err
+		}
+
+		// The operators can't decrypt the rotated secrets until they read the new master key,
+		// so the cluster must be reachable before anything is changed
+		var k8sClient *kubernetes.Client
+		if !skipCluster {
+			if k8sClient, err = cli.Client(monitor, orbConfig, gitClient, rv.Kubeconfig, rv.Gitops, false); err != nil {
+				return fmt.Errorf("connecting to the cluster failed, no secrets were changed. Pass the flag --skip-cluster if the orb has no running cluster: %w", err)
+			}
+		}
+
+		oldMasterKey := orbConfig.Masterkey
+		rotatedFiles, rotated, err := secret.RotateMasterkey(gitClient, oldMasterKey, newMasterKey, git.OrbiterFile, git.BoomFile, git.NetworkingFile)
+		if err != nil {
+			return err
+		}
+
+		// Pushed secrets must always be decryptable by the master key the operators read,
+		// so the new master key is distributed before the secrets are pushed
+		distribute := func(masterKey string) error {
+			if err := orb.Reconfigure(rv.Ctx, monitor, orbConfig, "", masterKey, gitClient, githubClientID, githubClientSecret, storeToken); err != nil {
+				return err
+			}
+			secret.Masterkey = masterKey
+			if skipCluster {
+				return nil
+			}
+			return cfg.ApplyOrbconfigSecret(orbConfig, k8sClient, monitor)
+		}
+
+		rollback := func(cause error) error {
+			if err := distribute(oldMasterKey); err != nil {
+				return fmt.Errorf("restoring the old master key failed, so the local orbconfig or the orbconfig kubernetes secret might contain the new master key: %w: %s", err, cause.Error())
+			}
+			return fmt.Errorf("no secrets were changed and the old master key was restored: %w", cause)
+		}
+
+		if err := distribute(newMasterKey); err != nil {
+			return rollback(fmt.Errorf("distributing the new master key failed: %w", err))
+		}
+		if skipCluster {
+			monitor.Info("Writing the new master key to the orbconfig kubernetes secret skipped")
+		}
+
+		if len(rotatedFiles) > 0 {
+			if err := gitClient.UpdateRemote(fmt.Sprintf("Rotated master key of %d secrets", rotated), rotatedFiles...); err != nil {
+				return rollback(fmt.Errorf("pushing the reencrypted secrets failed: %w", err))
+			}
+		}
+		monitor.WithField("secrets", rotated).Info("Secrets reencrypted and pushed")
+		return nil
+	}
+	return cmd
+}
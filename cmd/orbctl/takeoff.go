@@ -1,11 +1,19 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/git"
 	"github.com/caos/orbos/pkg/kubernetes"
+	"github.com/caos/orbos/pkg/tree"
 
 	orbcfg "github.com/caos/orbos/pkg/orb"
 
@@ -26,6 +34,7 @@ func TakeoffCommand(getRv GetRootValues) *cobra.Command {
 		ingestionAddress string
 		gitOpsBoom       bool
 		gitOpsNetworking bool
+		output           string
 		cmd              = &cobra.Command{
 			Use:   "takeoff",
 			Short: "Launch an orbiter",
@@ -39,12 +48,21 @@ func TakeoffCommand(getRv GetRootValues) *cobra.Command {
 	flags.BoolVar(&gitOpsBoom, "gitops-boom", false, "Ensure Boom runs in gitops mode")
 	flags.BoolVar(&gitOpsNetworking, "gitops-networking", false, "Ensure Networking-operator runs in gitops mode")
 	flags.StringVar(&ingestionAddress, "ingestion", "", "Ingestion API address")
+	addOutputFlag(cmd, &output, outputJSON, outputYAML, outputWide)
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
 		if recur && destroy {
 			return errors.New("flags --recur and --destroy are mutually exclusive, please provide either one or none")
 		}
 
+		if output != "" && (recur || destroy) {
+			return errors.New("flag --output is not supported together with the flags --recur or --destroy")
+		}
+
+		if err := validateOutput(output, outputJSON, outputYAML, outputWide); err != nil {
+			return err
+		}
+
 		rv, err := getRv()
 		if err != nil {
 			return err
@@ -57,7 +75,7 @@ func TakeoffCommand(getRv GetRootValues) *cobra.Command {
 		gitClient := rv.GitClient
 		ctx := rv.Ctx
 
-		return cmds.Takeoff(
+		if err := cmds.Takeoff(
 			monitor,
 			ctx,
 			orbConfig,
@@ -72,11 +90,46 @@ func TakeoffCommand(getRv GetRootValues) *cobra.Command {
 			rv.Kubeconfig,
 			rv.Gitops || gitOpsBoom,
 			rv.Gitops || gitOpsNetworking,
-		)
+		); err != nil || output == "" {
+			return err
+		}
+
+		return printTakeoffStatus(monitor, gitClient, orbConfig, deploy, output)
 	}
 	return cmd
 }
 
+// printTakeoffStatus prints the nodes and their node agents after the takeoff in the given output format
+func printTakeoffStatus(monitor mntr.Monitor, gitClient *git.Client, orbConfig *orbcfg.Orb, deployed bool, output string) error {
+	status := &TakeoffStatus{
+		Orbiter:  gitClient.Exists(git.OrbiterFile),
+		Deployed: deployed,
+		Nodes:    make([]*Node, 0),
+	}
+
+	if status.Orbiter {
+		if err := machines(monitor, gitClient, orbConfig, func(_ []string, machines map[string]infra.Machine, _ *tree.Tree) error {
+			nodes, err := readNodes(gitClient, machines)
+			status.Nodes = nodes
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range status.Nodes {
+		if node.NodeAgentReady {
+			status.NodesReady++
+		}
+	}
+
+	if structured, err := printStructured(output, status); structured {
+		return err
+	}
+	printTable(takeoffStatusTable(status))
+	return nil
+}
+
 func StartOrbiter(getRv GetRootValues) *cobra.Command {
 	var (
 		verbose          bool
@@ -85,6 +138,8 @@ func StartOrbiter(getRv GetRootValues) *cobra.Command {
 		deploy           bool
 		ingestionAddress string
 		pprof            bool
+		plan             bool
+		output           string
 		cmd              = &cobra.Command{
 			Use:   "orbiter",
 			Short: "Launch an orbiter",
@@ -96,6 +151,8 @@ func StartOrbiter(getRv GetRootValues) *cobra.Command {
 	flags.BoolVar(&recur, "recur", true, "Ensure the desired state continously")
 	flags.BoolVar(&deploy, "deploy", true, "Ensure Orbiter deployment continously")
 	flags.BoolVar(&pprof, "pprof", false, "Start pprof to analyse memory usage")
+	flags.BoolVar(&plan, "plan", false, "Print the changes Orbiter would apply without applying them")
+	addOutputFlag(cmd, &output, outputJSON, outputYAML, outputWide)
 	flags.StringVar(&ingestionAddress, "ingestion", "", "Ingestion API address")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
@@ -103,6 +160,18 @@ func StartOrbiter(getRv GetRootValues) *cobra.Command {
 			return errors.New("flags --recur and --destroy are mutually exclusive, please provide eighter one or none")
 		}
 
+		if plan && destroy {
+			return errors.New("flags --plan and --destroy are mutually exclusive, please provide eighter one or none")
+		}
+
+		if output != "" && !plan {
+			return errors.New("flag --output is only supported together with the flag --plan")
+		}
+
+		if err := validateOutput(output, outputJSON, outputYAML, outputWide); err != nil {
+			return err
+		}
+
 		rv, err := getRv()
 		if err != nil {
 			return err
@@ -135,6 +204,30 @@ func StartOrbiter(getRv GetRootValues) *cobra.Command {
 			IngestionAddress: ingestionAddress,
 		}
 
+		if plan {
+			planned, err := ctrlgitops.OrbiterPlan(monitor, orbiterConfig, gitClient)
+			if err != nil {
+				return err
+			}
+			if structured, err := printStructured(output, planned); structured {
+				return err
+			}
+			if planned.Empty() {
+				fmt.Println("No changes. The infrastructure matches the desired state.")
+				return nil
+			}
+			if output == outputWide {
+				printTable(planTable(planned))
+				return nil
+			}
+			out, err := yaml.Marshal(planned)
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(out)
+			return err
+		}
+
 		if pprof {
 			go func() {
 				log.Println(http.ListenAndServe("localhost:6060", nil))
@@ -175,7 +268,11 @@ func StartBoom(getRv GetRootValues) *cobra.Command {
 		monitor.Info("Takeoff Boom")
 
 		if rv.Gitops {
-			return ctrlgitops.Boom(monitor, orbConfig.Path, version)
+			k8sClient, err := kubernetes.NewK8sClientWithPath(monitor, rv.Kubeconfig)
+			if err != nil {
+				return err
+			}
+			return ctrlgitops.Boom(monitor, orbConfig.Path, k8sClient, version)
 		} else {
 			return ctrlcrd.Start(monitor, version, "/boom", metricsAddr, "", ctrlcrd.Boom)
 		}
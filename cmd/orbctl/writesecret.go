@@ -17,10 +17,11 @@ import (
 func WriteSecretCommand(getRv GetRootValues) *cobra.Command {
 
 	var (
-		value string
-		file  string
-		stdin bool
-		cmd   = &cobra.Command{
+		value  string
+		file   string
+		stdin  bool
+		groups []string
+		cmd    = &cobra.Command{
 			Use:   "writesecret [path]",
 			Short: "Encrypt a secret and push it to the repository",
 			Long:  "Encrypt a secret and push it to the repository.\nIf no path is provided, a secret can interactively be chosen from a list of all possible secrets",
@@ -29,7 +30,8 @@ func WriteSecretCommand(getRv GetRootValues) *cobra.Command {
 orbctl writesecret --value $(cat ~/.ssh/my-orb-bootstrap)
 orbctl writesecret mystaticprovider.bootstrapkey.encrypted --file ~/.ssh/my-orb-bootstrap
 orbctl writesecret mystaticprovider.bootstrapkey_pub.encrypted --file ~/.ssh/my-orb-bootstrap.pub
-orbctl writesecret mygceprovider.google_application_credentials_value.encrypted --value "$(cat $GOOGLE_APPLICATION_CREDENTIALS)" `,
+orbctl writesecret mygceprovider.google_application_credentials_value.encrypted --value "$(cat $GOOGLE_APPLICATION_CREDENTIALS)"
+orbctl writesecret boom.monitoring.admin.password.encrypted --value "$(openssl rand -base64 21)" --groups monitoring`,
 		}
 	)
 
@@ -37,6 +39,7 @@ orbctl writesecret mygceprovider.google_application_credentials_value.encrypted
 	flags.StringVar(&value, "value", "", "Secret value to encrypt")
 	flags.StringVarP(&file, "file", "s", "", "File containing the value to encrypt")
 	flags.BoolVar(&stdin, "stdin", false, "Value to encrypt is read from standard input")
+	flags.StringSliceVar(&groups, "groups", nil, "Encrypt the value to the members of these recipient groups instead of the master key")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
 
@@ -73,6 +76,7 @@ orbctl writesecret mygceprovider.google_application_credentials_value.encrypted
 			k8sClient,
 			path,
 			s,
+			groups,
 			"orbctl",
 			version,
 			operators.GetAllSecretsFunc(monitor, true, rv.Gitops, gitClient, k8sClient, orbConfig),
@@ -6,9 +6,10 @@ import (
 
 	"github.com/caos/orbos/internal/operator/boom"
 	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/kubernetes"
 )
 
-func Boom(monitor mntr.Monitor, orbConfigPath string, version string) error {
+func Boom(monitor mntr.Monitor, orbConfigPath string, k8sClient kubernetes.ClientInt, version string) error {
 
 	ensureClient := gitClient(monitor, "ensure")
 	queryClient := gitClient(monitor, "query")
@@ -25,42 +26,49 @@ func Boom(monitor mntr.Monitor, orbConfigPath string, version string) error {
 
 	for range takeoffChan {
 
-		ensureChan := make(chan struct{})
-		queryChan := make(chan struct{})
+		ensureChan := make(chan error)
+		queryChan := make(chan error)
 
+		iterationMonitor, endSpan := monitor.StartSpan("BOOM iteration")
 		ensure, query := boom.Takeoff(
-			monitor,
+			iterationMonitor,
 			"/boom",
 			orbConfigPath,
 			ensureClient,
 			queryClient,
+			k8sClient,
 		)
 		go func() {
 			started := time.Now()
-			query()
+			err := query()
 
 			monitor.WithFields(map[string]interface{}{
 				"took": time.Since(started),
 			}).Info("Iteration done")
 			debug.FreeOSMemory()
 
-			queryChan <- struct{}{}
+			queryChan <- err
 		}()
 		go func() {
 			started := time.Now()
-			ensure()
+			err := ensure()
 
 			monitor.WithFields(map[string]interface{}{
 				"took": time.Since(started),
 			}).Info("Iteration done")
 			debug.FreeOSMemory()
 
-			ensureChan <- struct{}{}
+			ensureChan <- err
 		}()
 
 		go func() {
-			<-queryChan
-			<-ensureChan
+			queryErr := <-queryChan
+			ensureErr := <-ensureChan
+			if ensureErr != nil {
+				endSpan(ensureErr)
+			} else {
+				endSpan(queryErr)
+			}
 
 			time.Sleep(time.Second * 30)
 			takeoffChan <- struct{}{}
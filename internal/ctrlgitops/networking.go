@@ -14,6 +14,9 @@ import (
 )
 
 func Networking(monitor mntr.Monitor, orbConfigPath string, k8sClient *kubernetes2.Client, binaryVersion *string) error {
+
+	networking.Metrics(monitor)
+
 	takeoffChan := make(chan struct{})
 	go func() {
 		takeoffChan <- struct{}{}
@@ -28,6 +31,11 @@ func Networking(monitor mntr.Monitor, orbConfigPath string, k8sClient *kubernete
 			return err
 		}
 
+		if err := orbConfig.ConfigureSecretBackends(gitClient, k8sClient); err != nil {
+			monitor.Error(err)
+			return err
+		}
+
 		if err := gitClient.Configure(orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
 			monitor.Error(err)
 			return err
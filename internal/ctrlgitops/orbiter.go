@@ -11,6 +11,7 @@ import (
 
 	"github.com/caos/orbos/internal/executables"
 	"github.com/caos/orbos/internal/ingestion"
+	"github.com/caos/orbos/internal/operator/nodeagent/state"
 	"github.com/caos/orbos/internal/operator/orbiter"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/orb"
 	"github.com/caos/orbos/mntr"
@@ -39,7 +40,14 @@ func Orbiter(ctx context.Context, monitor mntr.Monitor, conf *OrbiterConfig, orb
 	takeoffChan := make(chan struct{})
 	healthyChan := make(chan bool)
 
+	var nodeAgentStates *state.Server
 	if conf.Recur {
+		nodeAgentStates = state.NewServer(monitor)
+		go func() {
+			if err := nodeAgentStates.ListenAndServe(state.Addr); err != nil {
+				monitor.Error(err)
+			}
+		}()
 		go orbiter.Instrument(monitor, healthyChan)
 	} else {
 		go func() {
@@ -57,7 +65,7 @@ loop:
 		case <-finishedChan:
 			break loop
 		case <-takeoffChan:
-			iterate(conf, orbctlGit, !initialized, ctx, monitor, finishedChan, healthyChan, func(iterated bool) {
+			iterate(conf, orbctlGit, nodeAgentStates, !initialized, ctx, monitor, finishedChan, healthyChan, func(iterated bool) {
 				if iterated {
 					initialized = true
 				}
@@ -70,7 +78,7 @@ loop:
 	return nil
 }
 
-func iterate(conf *OrbiterConfig, gitClient *git.Client, firstIteration bool, ctx context.Context, monitor mntr.Monitor, finishedChan chan struct{}, healthyChan chan bool, done func(iterated bool)) {
+func iterate(conf *OrbiterConfig, gitClient *git.Client, nodeAgentStates *state.Server, firstIteration bool, ctx context.Context, monitor mntr.Monitor, finishedChan chan struct{}, healthyChan chan bool, done func(iterated bool)) {
 
 	var err error
 	defer func() {
@@ -89,6 +97,12 @@ func iterate(conf *OrbiterConfig, gitClient *git.Client, firstIteration bool, ct
 		return
 	}
 
+	if err := orbFile.ConfigureSecretBackends(gitClient, nil); err != nil {
+		monitor.Error(err)
+		done(false)
+		return
+	}
+
 	if err := gitClient.Configure(orbFile.URL, []byte(orbFile.Repokey)); err != nil {
 		monitor.Error(err)
 		done(false)
@@ -171,12 +185,13 @@ func iterate(conf *OrbiterConfig, gitClient *git.Client, firstIteration bool, ct
 	)
 
 	takeoffConf := &orbiter.Config{
-		OrbiterCommit: conf.GitCommit,
-		GitClient:     gitClient,
-		Adapt:         adaptFunc,
-		FinishedChan:  finishedChan,
-		PushEvents:    pushEvents,
-		OrbConfig:     *orbFile,
+		OrbiterCommit:   conf.GitCommit,
+		GitClient:       gitClient,
+		Adapt:           adaptFunc,
+		FinishedChan:    finishedChan,
+		PushEvents:      pushEvents,
+		OrbConfig:       *orbFile,
+		NodeAgentStates: nodeAgentStates,
 	}
 
 	takeoff := orbiter.Takeoff(monitor, takeoffConf, healthyChan)
@@ -192,3 +207,39 @@ func iterate(conf *OrbiterConfig, gitClient *git.Client, firstIteration bool, ct
 		done(true)
 	}()
 }
+
+// OrbiterPlan returns the changes the next ORBITER iteration would apply without applying them
+func OrbiterPlan(monitor mntr.Monitor, conf *OrbiterConfig, gitClient *git.Client) (*orbiter.Plan, error) {
+
+	orbFile, err := orbcfg.ParseOrbConfig(conf.OrbConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := orbFile.ConfigureSecretBackends(gitClient, nil); err != nil {
+		return nil, err
+	}
+
+	if err := gitClient.Configure(orbFile.URL, []byte(orbFile.Repokey)); err != nil {
+		return nil, err
+	}
+
+	if err := gitClient.Clone(); err != nil {
+		return nil, err
+	}
+
+	return orbiter.PlanTakeoff(monitor, &orbiter.Config{
+		OrbiterCommit: conf.GitCommit,
+		GitClient:     gitClient,
+		Adapt: orb.AdaptFunc(
+			labels.MustForOperator("ORBOS", "orbiter.caos.ch", conf.Version),
+			orbFile,
+			conf.GitCommit,
+			true,
+			false,
+			gitClient,
+		),
+		FinishedChan: make(chan struct{}, 1),
+		OrbConfig:    *orbFile,
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,7 +18,7 @@ import (
 
 func Check(protocol string, ip string, port uint16, path string, status int, proxyProdocol bool) (string, error) {
 
-	ipPort := fmt.Sprintf("%s:%d", ip, port)
+	ipPort := net.JoinHostPort(ip, strconv.Itoa(int(port)))
 	if protocol == "tcp" {
 		return check(checks.NewPingCheck("tcp", checks.NewDialPinger("tcp", ipPort), 2*time.Second))
 	}
@@ -46,12 +47,17 @@ func Check(protocol string, ip string, port uint16, path string, status int, pro
 				return nil, err
 			}
 
+			transportProtocol, sourceIP := proxyproto.TCPv4, "10.1.1.1"
+			if target.IP.To4() == nil {
+				transportProtocol, sourceIP = proxyproto.TCPv6, "fd00::1"
+			}
+
 			header := &proxyproto.Header{
 				Version:           1,
 				Command:           proxyproto.PROXY,
-				TransportProtocol: proxyproto.TCPv4,
+				TransportProtocol: transportProtocol,
 				SourceAddr: &net.TCPAddr{
-					IP:   net.ParseIP("10.1.1.1"),
+					IP:   net.ParseIP(sourceIP),
 					Port: 1000,
 				},
 				DestinationAddr: target,
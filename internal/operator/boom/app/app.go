@@ -14,6 +14,8 @@ import (
 	gitcrdconfig "github.com/caos/orbos/internal/operator/boom/gitcrd/config"
 	"github.com/caos/orbos/internal/operator/boom/metrics"
 	"github.com/caos/orbos/internal/utils/clientgo"
+	"github.com/caos/orbos/pkg/kubernetes"
+	"github.com/caos/orbos/pkg/secret"
 
 	"github.com/caos/orbos/internal/operator/boom/bundle/bundles"
 	"github.com/caos/orbos/internal/operator/boom/templator/helm"
@@ -116,7 +118,7 @@ func (a *App) Reconcile() error {
 	return nil
 }
 
-func (a *App) WriteBackCurrentState() error {
+func (a *App) WriteBackCurrentState(k8sClient kubernetes.ClientInt) error {
 
 	monitor := a.monitor.WithFields(map[string]interface{}{
 		"action": "current",
@@ -125,12 +127,18 @@ func (a *App) WriteBackCurrentState() error {
 
 	a.GitCrd.SetBackStatus()
 
+	secrets := a.GitCrd.ValidateExisting(k8sClient)
+	if err := a.GitCrd.GetStatus(); err != nil {
+		return err
+	}
+	secret.ReportExisting(monitor, "boom", secrets)
+
 	currentResourceList, err := a.getCurrent(monitor)
 	if err != nil {
 		return err
 	}
 
-	a.GitCrd.WriteBackCurrentState(currentResourceList)
+	a.GitCrd.WriteBackCurrentState(currentResourceList, secrets)
 	if err := a.GitCrd.GetStatus(); err != nil {
 		metrics.FailedWritingCurrentState(a.GitCrd.GetRepoURL())
 		return err
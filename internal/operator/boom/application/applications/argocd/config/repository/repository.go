@@ -38,15 +38,19 @@ func getSecretKey(ty string) string {
 	return ty
 }
 
-func GetSecrets(spec *reconciling.Reconciling) []interface{} {
+func GetSecrets(spec *reconciling.Reconciling) ([]interface{}, error) {
 	secrets := make([]interface{}, 0)
 	namespace := "caos-system"
 
 	for _, v := range spec.Repositories {
 		if read.IsCrdSecret(v.Username, v.ExistingUsernameSecret) {
 
+			value, err := v.Username.Resolve()
+			if err != nil {
+				return nil, err
+			}
 			data := map[string]string{
-				getSecretKey(user): v.Username.Value,
+				getSecretKey(user): value,
 			}
 
 			conf := &resources.SecretConfig{
@@ -60,8 +64,12 @@ func GetSecrets(spec *reconciling.Reconciling) []interface{} {
 		}
 		if read.IsCrdSecret(v.Password, v.ExistingPasswordSecret) {
 
+			value, err := v.Password.Resolve()
+			if err != nil {
+				return nil, err
+			}
 			data := map[string]string{
-				getSecretKey(pw): v.Password.Value,
+				getSecretKey(pw): value,
 			}
 
 			conf := &resources.SecretConfig{
@@ -74,8 +82,12 @@ func GetSecrets(spec *reconciling.Reconciling) []interface{} {
 			secrets = append(secrets, secretRes)
 		}
 		if read.IsCrdSecret(v.Certificate, v.ExistingCertificateSecret) {
+			value, err := v.Certificate.Resolve()
+			if err != nil {
+				return nil, err
+			}
 			data := map[string]string{
-				getSecretKey(cert): v.Certificate.Value,
+				getSecretKey(cert): value,
 			}
 
 			conf := &resources.SecretConfig{
@@ -89,7 +101,7 @@ func GetSecrets(spec *reconciling.Reconciling) []interface{} {
 		}
 	}
 
-	return secrets
+	return secrets, nil
 }
 
 func GetFromSpec(monitor mntr.Monitor, spec *reconciling.Reconciling) []*Repository {
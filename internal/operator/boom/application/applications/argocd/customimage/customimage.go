@@ -63,19 +63,23 @@ func getInternalName(store string, ty string) string {
 	return strings.Join([]string{"store", store, ty}, "-")
 }
 
-func GetSecrets(spec *reconciling.Reconciling) []interface{} {
+func GetSecrets(spec *reconciling.Reconciling) ([]interface{}, error) {
 	namespace := "caos-system"
 	secrets := make([]interface{}, 0)
 
 	if spec.CustomImage == nil || spec.CustomImage.GopassStores == nil {
-		return secrets
+		return secrets, nil
 	}
 
 	for _, store := range spec.CustomImage.GopassStores {
 		if read.IsCrdSecret(store.GPGKey, store.ExistingGPGKeySecret) {
 			ty := "gpg"
+			value, err := store.GPGKey.Resolve()
+			if err != nil {
+				return nil, err
+			}
 			data := map[string]string{
-				getSecretKey(store.StoreName, ty): store.GPGKey.Value,
+				getSecretKey(store.StoreName, ty): value,
 			}
 
 			conf := &resources.SecretConfig{
@@ -90,8 +94,12 @@ func GetSecrets(spec *reconciling.Reconciling) []interface{} {
 
 		if read.IsCrdSecret(store.SSHKey, store.ExistingSSHKeySecret) {
 			ty := "ssh"
+			value, err := store.SSHKey.Resolve()
+			if err != nil {
+				return nil, err
+			}
 			data := map[string]string{
-				getSecretKey(store.StoreName, ty): store.SSHKey.Value,
+				getSecretKey(store.StoreName, ty): value,
 			}
 
 			conf := &resources.SecretConfig{
@@ -105,7 +113,7 @@ func GetSecrets(spec *reconciling.Reconciling) []interface{} {
 		}
 	}
 
-	return secrets
+	return secrets, nil
 }
 
 func FromSpec(spec *reconciling.Reconciling, imageTags map[string]string) *CustomImage {
@@ -20,9 +20,18 @@ func (a *Argocd) HelmPreApplySteps(monitor mntr.Monitor, toolsetCRDSpec *toolset
 	if toolsetCRDSpec.Reconciling == nil {
 		return secrets, nil
 	}
-	customimagesecrets := customimage.GetSecrets(toolsetCRDSpec.Reconciling)
-	repoSecrets := repository.GetSecrets(toolsetCRDSpec.Reconciling)
-	credSecrets := credential.GetSecrets(toolsetCRDSpec.Reconciling)
+	customimagesecrets, err := customimage.GetSecrets(toolsetCRDSpec.Reconciling)
+	if err != nil {
+		return nil, err
+	}
+	repoSecrets, err := repository.GetSecrets(toolsetCRDSpec.Reconciling)
+	if err != nil {
+		return nil, err
+	}
+	credSecrets, err := credential.GetSecrets(toolsetCRDSpec.Reconciling)
+	if err != nil {
+		return nil, err
+	}
 
 	secrets = append(secrets, customimagesecrets...)
 	secrets = append(secrets, repoSecrets...)
@@ -140,11 +140,14 @@ func (b *Bundle) ReconcileApplication(currentResourceList []*clientgo.Resource,
 		"application": appName,
 		"action":      "reconciling",
 	}
-	monitor := b.monitor.WithFields(logFields)
+	monitor, endSpan := b.monitor.WithFields(logFields).StartSpan("reconcile application " + appName.String())
+
+	var err error
+	defer func() { endSpan(err) }()
 
 	app, found := b.Applications[appName]
 	if !found {
-		err := errors.New("Application not found")
+		err = errors.New("Application not found")
 		monitor.Error(err)
 		errChan <- err
 		return
@@ -170,7 +173,7 @@ func (b *Bundle) ReconcileApplication(currentResourceList []*clientgo.Resource,
 	_, usedHelm := app.(application.HelmApplication)
 	if usedHelm {
 		templatorName := helm.GetName()
-		err := b.HelmTemplator.Template(app, spec, resultFunc)
+		err = b.HelmTemplator.Template(app, spec, resultFunc)
 		if err != nil {
 			metrics.FailureReconcilingApplication(appName.String(), templatorName.String(), deploy)
 			errChan <- err
@@ -181,7 +184,7 @@ func (b *Bundle) ReconcileApplication(currentResourceList []*clientgo.Resource,
 	_, usedYaml := app.(application.YAMLApplication)
 	if usedYaml {
 		templatorName := yaml.GetName()
-		err := b.YamlTemplator.Template(app, spec, resultFunc)
+		err = b.YamlTemplator.Template(app, spec, resultFunc)
 		if err != nil {
 			metrics.FailureReconcilingApplication(appName.String(), templatorName.String(), deploy)
 			errChan <- err
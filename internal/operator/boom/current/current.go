@@ -7,12 +7,15 @@ import (
 	"github.com/caos/orbos/internal/operator/boom/name"
 	"github.com/caos/orbos/internal/utils/clientgo"
 	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/secret"
 )
 
 type Current struct {
 	APIVersion string `yaml:"apiVersion"`
 	Kind       string `yaml:"kind"`
 	Resources  []*clientgo.Resource
+	// Secrets contains the validation results of the references to existing kubernetes secrets
+	Secrets map[string]*secret.ExistingStatus `yaml:"secrets,omitempty"`
 }
 
 func ResourcesToYaml(resources []*clientgo.Resource) *Current {
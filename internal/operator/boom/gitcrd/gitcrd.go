@@ -20,6 +20,8 @@ import (
 	"github.com/caos/orbos/internal/utils/kustomize"
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/git"
+	"github.com/caos/orbos/pkg/kubernetes"
+	"github.com/caos/orbos/pkg/secret"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
@@ -192,14 +194,24 @@ func (c *GitCrd) getCrdContent() (*toolsetslatest.Toolset, error) {
 	return desiredKind, nil
 }
 
-func (c *GitCrd) WriteBackCurrentState(currentResourceList []*clientgo.Resource) {
+// ValidateExisting validates the toolsets references to existing kubernetes secrets
+func (c *GitCrd) ValidateExisting(k8sClient kubernetes.ClientInt) map[string]*secret.ExistingStatus {
 	if c.status != nil {
-		return
+		return nil
 	}
 
-	content, err := yaml.Marshal(current.ResourcesToYaml(currentResourceList))
+	toolsetCRD, err := c.getCrdContent()
 	if err != nil {
 		c.status = err
+		return nil
+	}
+
+	secrets, existing := toolsetslatest.GetSecretsMap(toolsetCRD)
+	return secret.ValidateExisting(k8sClient, secrets, existing)
+}
+
+func (c *GitCrd) WriteBackCurrentState(currentResourceList []*clientgo.Resource, secrets map[string]*secret.ExistingStatus) {
+	if c.status != nil {
 		return
 	}
 
@@ -209,6 +221,15 @@ func (c *GitCrd) WriteBackCurrentState(currentResourceList []*clientgo.Resource)
 		return
 	}
 
+	currentState := current.ResourcesToYaml(currentResourceList)
+	currentState.Secrets = secrets
+
+	content, err := yaml.Marshal(currentState)
+	if err != nil {
+		c.status = err
+		return
+	}
+
 	currentFolder := toolsetCRD.Spec.CurrentStateFolder
 	if currentFolder == "" {
 		currentFolder = filepath.Join("caos-internal", "boom")
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/caos/orbos/pkg/git"
+	"github.com/caos/orbos/pkg/kubernetes"
 	"github.com/caos/orbos/pkg/orb"
 
 	"github.com/caos/orbos/internal/operator/boom/app"
@@ -34,26 +35,33 @@ func Metrics(monitor mntr.Monitor) {
 	}()
 }
 
-func Takeoff(monitor mntr.Monitor, toolsDirectoryPath string, orbpath string, ensureClient, queryClient *git.Client) (func(), func()) {
+func Takeoff(monitor mntr.Monitor, toolsDirectoryPath string, orbpath string, ensureClient, queryClient *git.Client, k8sClient kubernetes.ClientInt) (func() error, func() error) {
 	gitcrdMonitor := monitor.WithField("type", "gitcrd")
 
 	gconfig.DashboardsDirectoryPath = filepath.Join(toolsDirectoryPath, "dashboards")
 
-	appStruct := app.New(monitor, toolsDirectoryPath)
-	currentStruct := app.New(monitor, toolsDirectoryPath)
+	ensureMonitor, endEnsure := monitor.StartSpan("ensure toolsets")
+	queryMonitor, endQuery := monitor.StartSpan("query toolsets")
+
+	appStruct := app.New(ensureMonitor, toolsDirectoryPath)
+	currentStruct := app.New(queryMonitor, toolsDirectoryPath)
 
 	return task(
-			monitor,
+			ensureMonitor,
 			orbpath,
+			k8sClient,
 			gitConf(gitcrdMonitor.WithField("task", "ensure"), ensureClient, toolsDirectoryPath),
 			appStruct.ReadSpecs,
-			appStruct.Reconcile),
+			appStruct.Reconcile,
+			endEnsure),
 		task(
-			monitor,
+			queryMonitor,
 			orbpath,
+			k8sClient,
 			gitConf(gitcrdMonitor.WithField("task", "query"), queryClient, toolsDirectoryPath),
 			currentStruct.ReadSpecs,
-			currentStruct.WriteBackCurrentState)
+			func() error { return currentStruct.WriteBackCurrentState(k8sClient) },
+			endQuery)
 }
 
 func gitConf(monitor mntr.Monitor, client *git.Client, toolsDirectoryPath string) gitcrdconfig.Config {
@@ -64,15 +72,23 @@ func gitConf(monitor mntr.Monitor, client *git.Client, toolsDirectoryPath string
 	}
 }
 
-func task(monitor mntr.Monitor, orbpath string, gitcrdConf gitcrdconfig.Config, readSpecs func(gitCrdConf *gitcrdconfig.Config, repoURL string, repoKey []byte) error, do func() error) func() {
-	return func() {
+func task(monitor mntr.Monitor, orbpath string, k8sClient kubernetes.ClientInt, gitcrdConf gitcrdconfig.Config, readSpecs func(gitCrdConf *gitcrdconfig.Config, repoURL string, repoKey []byte) error, do func() error, endSpan func(error)) func() error {
+	return func() error {
 		// TODO: use a function scoped error variable
 		started := time.Now()
 
+		var goErr error
+		defer func() { endSpan(goErr) }()
+
 		orbConfig, goErr := orb.ParseOrbConfig(orbpath)
 		if goErr != nil {
 			monitor.Error(goErr)
-			return
+			return goErr
+		}
+
+		if goErr = orbConfig.ConfigureSecretBackends(gitcrdConf.Git, k8sClient); goErr != nil {
+			monitor.Error(goErr)
+			return goErr
 		}
 
 		if err := readSpecs(&gitcrdConf, orbConfig.URL, []byte(orbConfig.Repokey)); err != nil {
@@ -85,5 +101,6 @@ func task(monitor mntr.Monitor, orbpath string, gitcrdConf gitcrdconfig.Config,
 		})
 		recMonitor.Error(goErr)
 		recMonitor.Info("Reconciling iteration done")
+		return goErr
 	}
 }
@@ -199,4 +199,6 @@ const (
 	NonLocalBind          KernelModule = "net.ipv4.ip_nonlocal_bind"
 	BridgeNfCallIptables  KernelModule = "net.bridge.bridge-nf-call-iptables"
 	BridgeNfCallIp6tables KernelModule = "net.bridge.bridge-nf-call-ip6tables"
+	Ipv6Forward           KernelModule = "net.ipv6.conf.all.forwarding"
+	Ipv6NonLocalBind      KernelModule = "net.ipv6.ip_nonlocal_bind"
 )
@@ -30,6 +30,54 @@ type DestroyFunc func(k8sClient kubernetes.ClientInt) error
 
 type QueryFunc func(k8sClient kubernetes.ClientInt, queried map[string]interface{}) (EnsureFunc, error)
 
+// TraceAdaptFunc traces the kind using a mntr.KindTrace
+func TraceAdaptFunc(id string, adapt AdaptFunc) AdaptFunc {
+	return func(monitor mntr.Monitor, desired *tree.Tree, current *tree.Tree) (QueryFunc, DestroyFunc, map[string]*secret.Secret, map[string]*secret.Existing, bool, error) {
+
+		var kind string
+		if desired != nil && desired.Common != nil {
+			kind = desired.Common.Kind
+		}
+
+		trace := monitor.TraceKind(kind, id)
+		endAdapt := trace.Phase("adapt")
+		query, destroy, secrets, existing, migrate, err := adapt(trace.Monitor(), desired, current)
+		endAdapt(err)
+		if err != nil || query == nil {
+			trace.End(err)
+			return query, destroy, secrets, existing, migrate, err
+		}
+
+		if destroy != nil {
+			destroyKind := destroy
+			destroy = func(k8sClient kubernetes.ClientInt) error {
+				endDestroy := trace.Phase("destroy")
+				err := destroyKind(k8sClient)
+				endDestroy(err)
+				trace.End(err)
+				return err
+			}
+		}
+
+		return func(k8sClient kubernetes.ClientInt, queried map[string]interface{}) (EnsureFunc, error) {
+			endQuery := trace.Phase("query")
+			ensure, err := query(k8sClient, queried)
+			endQuery(err)
+			trace.End(err)
+			if err != nil || ensure == nil {
+				return ensure, err
+			}
+
+			return func(k8sClient kubernetes.ClientInt) error {
+				endEnsure := trace.Phase("ensure")
+				err := ensure(k8sClient)
+				endEnsure(err)
+				return err
+			}, nil
+		}, destroy, secrets, existing, migrate, nil
+	}
+}
+
 func Parse(gitClient *git.Client, file string) (*tree.Tree, error) {
 	if err := gitClient.Clone(); err != nil {
 		return nil, err
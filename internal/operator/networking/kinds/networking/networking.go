@@ -26,7 +26,7 @@ func GetQueryAndDestroyFuncs(
 ) {
 	switch desiredTree.Common.Kind {
 	case "networking.caos.ch/LegacyCloudflare":
-		return legacycf.AdaptFunc(namespace, operatorLabels)(monitor, desiredTree, currentTree)
+		return core.TraceAdaptFunc("", legacycf.AdaptFunc(namespace, operatorLabels))(monitor, desiredTree, currentTree)
 	default:
 		return nil, nil, nil, nil, false, errors.Errorf("unknown networking kind %s", desiredTree.Common.Kind)
 	}
@@ -40,7 +40,9 @@ func AdaptFunc(binaryVersion *string, gitops bool) core.AdaptFunc {
 			return nil, nil, nil, nil, false, errors.Wrap(err, "parsing desired state failed")
 		}
 		desiredTree.Parsed = desiredKind
-		currentTree = &tree.Tree{}
+		if currentTree == nil {
+			currentTree = &tree.Tree{}
+		}
 
 		if desiredKind.Spec.Verbose && !orbMonitor.IsVerbose() {
 			orbMonitor = orbMonitor.Verbose()
@@ -62,15 +64,19 @@ func AdaptFunc(binaryVersion *string, gitops bool) core.AdaptFunc {
 			destroyNW,
 		}
 
-		currentTree.Parsed = &DesiredV0{
+		current := &Current{
 			Common: &tree.Common{
 				Kind:    "networking.caos.ch/Orb",
 				Version: "v0",
 			},
 			Networking: networkingCurrent,
 		}
+		currentTree.Parsed = current
 
 		return func(k8sClient kubernetes.ClientInt, _ map[string]interface{}) (core.EnsureFunc, error) {
+				current.Secrets = secret.ValidateExisting(k8sClient, secrets, existing)
+				secret.ReportExisting(orbMonitor, "networking", current.Secrets)
+
 				queried := map[string]interface{}{}
 				monitor.WithField("queriers", len(queriers)).Info("Querying")
 				return core.QueriersToEnsureFunc(monitor, true, queriers, k8sClient, queried)
@@ -0,0 +1,13 @@
+package orb
+
+import (
+	"github.com/caos/orbos/pkg/secret"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+type Current struct {
+	Common     *tree.Common `yaml:",inline"`
+	Networking *tree.Tree   `yaml:"-"`
+	// Secrets contains the validation results of the references to existing kubernetes secrets
+	Secrets map[string]*secret.ExistingStatus `yaml:",omitempty"`
+}
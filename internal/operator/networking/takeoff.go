@@ -2,7 +2,13 @@ package networking
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/caos/orbos/internal/operator/common"
 	"github.com/caos/orbos/internal/operator/core"
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/git"
@@ -10,9 +16,31 @@ import (
 	"github.com/caos/orbos/pkg/tree"
 )
 
+// CurrentFile is the path of the networking operators current state in the orb repository
+const CurrentFile = "caos-internal/networking/current.yml"
+
+func Metrics(monitor mntr.Monitor) {
+	metricsport := "2112"
+
+	http.Handle("/metrics", promhttp.Handler())
+	address := strings.Join([]string{":", metricsport}, "")
+	go func() {
+		if err := http.ListenAndServe(address, nil); err != nil {
+			monitor.Error(fmt.Errorf("error while serving metrics endpoint: %w", err))
+		}
+	}()
+	monitor.WithFields(map[string]interface{}{
+		"port":     metricsport,
+		"endpoint": "/metrics",
+	}).Info("Started metrics")
+}
+
 func Takeoff(monitor mntr.Monitor, gitClient *git.Client, adapt core.AdaptFunc, k8sClient *kubernetes2.Client) func() {
 	return func() {
-		internalMonitor := monitor.WithField("operator", "networking")
+		var err error
+		internalMonitor, endSpan := monitor.WithField("operator", "networking").StartSpan("networking iteration")
+		defer func() { endSpan(err) }()
+
 		internalMonitor.Info("Takeoff")
 		treeDesired, err := core.Parse(gitClient, "networking.yml")
 		if err != nil {
@@ -22,11 +50,12 @@ func Takeoff(monitor mntr.Monitor, gitClient *git.Client, adapt core.AdaptFunc,
 		treeCurrent := &tree.Tree{}
 
 		if k8sClient == nil {
-			internalMonitor.Error(errors.New("kubeclient is not available"))
+			err = errors.New("kubeclient is not available")
+			internalMonitor.Error(err)
 			return
 		}
 
-		query, _, _, _, _, err := adapt(internalMonitor, treeDesired, treeCurrent)
+		query, _, _, _, _, err := core.TraceAdaptFunc("", adapt)(internalMonitor, treeDesired, treeCurrent)
 		if err != nil {
 			internalMonitor.Error(err)
 			return
@@ -38,7 +67,15 @@ func Takeoff(monitor mntr.Monitor, gitClient *git.Client, adapt core.AdaptFunc,
 			return
 		}
 
-		if err := ensure(k8sClient); err != nil {
+		if err = gitClient.UpdateRemote("Current state changed", git.File{
+			Path:    CurrentFile,
+			Content: common.MarshalYAML(treeCurrent),
+		}); err != nil {
+			internalMonitor.Error(err)
+			return
+		}
+
+		if err = ensure(k8sClient); err != nil {
 			internalMonitor.Error(err)
 			return
 		}
@@ -47,7 +47,7 @@ func New(monitor mntr.Monitor, os dep.OperatingSystemMajor, cipher string) Conve
 func (d *dependencies) Init() func() error {
 
 	d.sysd = dep.NewSystemD(d.monitor)
-	d.pm = dep.NewPackageManager(d.monitor, d.os.OperatingSystem, d.sysd)
+	d.pm = dep.NewPackageManager(d.monitor, d.os, d.sysd)
 
 	return func() error {
 		if err := d.pm.Init(); err != nil {
@@ -0,0 +1,119 @@
+package cri
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent/dep"
+)
+
+const (
+	containerdConfigPath = "/etc/containerd/config.toml"
+	containerdSocket     = "unix:///run/containerd/containerd.sock"
+	kubeletFlagsPath     = "/var/lib/kubelet/kubeadm-flags.env"
+)
+
+func (c *criDep) currentContainerd() (pkg common.Package, err error) {
+
+	for _, installedPkg := range c.manager.CurrentVersions(containerdPackage) {
+		pkg.Version = containerdRuntime + " v" + c.dockerVersionPrunerRegexp.FindString(installedPkg.Version)
+	}
+
+	config, err := ioutil.ReadFile(containerdConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		return pkg, err
+	}
+	pkg.Config = map[string]string{"config.toml": string(config)}
+	return pkg, nil
+}
+
+func (c *criDep) ensureContainerd(version string, config map[string]string) error {
+
+	if config == nil {
+		return errors.New("Containerd config is nil")
+	}
+
+	if err := os.MkdirAll("/etc/containerd", 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(containerdConfigPath, []byte(config["config.toml"]), 0644); err != nil {
+		return err
+	}
+
+	var err error
+	switch c.os.OperatingSystem.Packages {
+	case dep.DebianBased:
+		err = c.ensureDebianBased(containerdPackage, version)
+	case dep.REMBased:
+		err = c.ensureREMBased(containerdPackage, version)
+	default:
+		err = errors.Errorf("Operating %s system is not supported", c.os)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Nodes which ran on docker are migrated to the standalone containerd
+	if c.systemd.Active("docker") {
+		if err := c.systemd.Disable("docker"); err != nil {
+			return err
+		}
+	}
+
+	return c.migrateKubelet()
+}
+
+// migrateKubelet makes an already joined kubelet use the containerd socket
+func (c *criDep) migrateKubelet() error {
+	flags, err := ioutil.ReadFile(kubeletFlagsPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	migrated := useContainerdSocket(string(flags))
+	if migrated == string(flags) {
+		return nil
+	}
+
+	if err := ioutil.WriteFile(kubeletFlagsPath, []byte(migrated), 0644); err != nil {
+		return err
+	}
+	c.monitor.WithField("path", kubeletFlagsPath).Info("Kubelet migrated to containerd")
+
+	if !c.systemd.Active("kubelet") {
+		return nil
+	}
+	return c.systemd.Start("kubelet")
+}
+
+// useContainerdSocket replaces all container runtime related flags in the kubelets kubeadm flags file
+// with the containerd socket. The network plugin flag is removed as it is not supported with remote runtimes
+func useContainerdSocket(flagsFile string) string {
+	lines := strings.Split(flagsFile, "\n")
+	for idx, line := range lines {
+		if !strings.HasPrefix(line, "KUBELET_KUBEADM_ARGS=") {
+			continue
+		}
+		args := strings.Trim(strings.TrimPrefix(line, "KUBELET_KUBEADM_ARGS="), `"`)
+		var keep []string
+		for _, arg := range strings.Fields(args) {
+			if strings.HasPrefix(arg, "--container-runtime=") ||
+				strings.HasPrefix(arg, "--container-runtime-endpoint=") ||
+				strings.HasPrefix(arg, "--network-plugin=") {
+				continue
+			}
+			keep = append(keep, arg)
+		}
+		keep = append(keep, "--container-runtime-endpoint="+containerdSocket)
+		lines[idx] = `KUBELET_KUBEADM_ARGS="` + strings.Join(keep, " ") + `"`
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,30 @@
+package cri
+
+import "testing"
+
+func TestUseContainerdSocket(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags string
+		want  string
+	}{{
+		name:  "It should replace dockershim flags",
+		flags: `KUBELET_KUBEADM_ARGS="--cgroup-driver=systemd --network-plugin=cni --pod-infra-container-image=k8s.gcr.io/pause:3.2"` + "\n",
+		want:  `KUBELET_KUBEADM_ARGS="--cgroup-driver=systemd --pod-infra-container-image=k8s.gcr.io/pause:3.2 --container-runtime-endpoint=unix:///run/containerd/containerd.sock"` + "\n",
+	}, {
+		name:  "It should replace other remote runtimes",
+		flags: `KUBELET_KUBEADM_ARGS="--container-runtime=remote --container-runtime-endpoint=unix:///var/run/crio/crio.sock"`,
+		want:  `KUBELET_KUBEADM_ARGS="--container-runtime-endpoint=unix:///run/containerd/containerd.sock"`,
+	}, {
+		name:  "It should not change migrated flags",
+		flags: `KUBELET_KUBEADM_ARGS="--node-ip=10.0.0.1 --container-runtime-endpoint=unix:///run/containerd/containerd.sock"`,
+		want:  `KUBELET_KUBEADM_ARGS="--node-ip=10.0.0.1 --container-runtime-endpoint=unix:///run/containerd/containerd.sock"`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := useContainerdSocket(tt.flags); got != tt.want {
+				t.Errorf("useContainerdSocket() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,14 +17,19 @@ import (
 	"github.com/caos/orbos/mntr"
 )
 
-const containerdVersion = "1.4.3"
+const (
+	containerdVersion = "1.4.3"
+	dockerRuntime     = "docker-ce"
+	containerdRuntime = "containerd"
+	containerdPackage = "containerd.io"
+)
 
 type Installer interface {
 	isCRI()
 	nodeagent.Installer
 }
 
-// TODO: Add support for containerd, cri-o, ...
+// TODO: Add support for cri-o, ...
 type criDep struct {
 	monitor                   mntr.Monitor
 	os                        dep.OperatingSystemMajor
@@ -53,9 +58,17 @@ func (s *criDep) Equals(other nodeagent.Installer) bool {
 }
 
 func (c *criDep) Current() (pkg common.Package, err error) {
-	if !c.systemd.Active("docker") {
-		return pkg, err
+	// As docker runs containerd too, docker is checked first
+	if c.systemd.Active("docker") {
+		return c.currentDocker()
+	}
+	if c.systemd.Active("containerd") {
+		return c.currentContainerd()
 	}
+	return pkg, nil
+}
+
+func (c *criDep) currentDocker() (pkg common.Package, err error) {
 
 	var (
 		dockerVersion     string
@@ -104,34 +117,41 @@ func (c *criDep) Current() (pkg common.Package, err error) {
 
 func (c *criDep) Ensure(_ common.Package, install common.Package) error {
 
-	if install.Config == nil {
-		return errors.New("Docker config is nil")
+	fields := strings.Fields(install.Version)
+	if len(fields) != 2 {
+		return errors.Errorf("Container runtime must have the form [runtime] [version], but got %s", install)
 	}
 
-	if err := os.MkdirAll("/etc/docker", 600); err != nil {
-		return err
-	}
+	version := strings.TrimLeft(fields[1], "v")
 
-	if err := ioutil.WriteFile("/etc/docker/daemon.json", []byte(install.Config["daemon.json"]), 600); err != nil {
-		return err
+	switch fields[0] {
+	case dockerRuntime:
+		return c.ensureDocker(version, install.Config)
+	case containerdRuntime:
+		return c.ensureContainerd(version, install.Config)
 	}
+	return errors.Errorf("Container runtime %s is not supported", fields[0])
+}
 
-	fields := strings.Fields(install.Version)
-	if len(fields) != 2 {
-		return errors.Errorf("Container runtime must have the form [runtime] [version], but got %s", install)
+func (c *criDep) ensureDocker(version string, config map[string]string) error {
+
+	if config == nil {
+		return errors.New("Docker config is nil")
 	}
 
-	if fields[0] != "docker-ce" {
-		return errors.New("Only docker-ce is supported yet")
+	if err := os.MkdirAll("/etc/docker", 600); err != nil {
+		return err
 	}
 
-	version := strings.TrimLeft(fields[1], "v")
+	if err := ioutil.WriteFile("/etc/docker/daemon.json", []byte(config["daemon.json"]), 600); err != nil {
+		return err
+	}
 
-	switch c.os.OperatingSystem {
-	case dep.Ubuntu:
-		return c.ensureUbuntu(fields[0], version)
-	case dep.CentOS:
-		return c.ensureCentOS(fields[0], version)
+	switch c.os.OperatingSystem.Packages {
+	case dep.DebianBased:
+		return c.ensureDebianBased(dockerRuntime, version)
+	case dep.REMBased:
+		return c.ensureREMBased(dockerRuntime, version)
 	}
 	return errors.Errorf("Operating %s system is not supported", c.os)
 }
@@ -10,17 +10,22 @@ import (
 	"github.com/caos/orbos/internal/operator/nodeagent/dep"
 )
 
-func (c *criDep) ensureCentOS(runtime string, version string) error {
+func (c *criDep) ensureREMBased(runtime string, version string) error {
 	errBuf := new(bytes.Buffer)
 	defer errBuf.Reset()
-	cmd := exec.Command("yum", "--assumeyes", "remove", "docker",
+	conflicting := []string{"docker",
 		"docker-client",
 		"docker-client-latest",
 		"docker-common",
 		"docker-latest",
 		"docker-latest-logrotate",
 		"docker-logrotate",
-		"docker-engine")
+		"docker-engine"}
+	// https://docs.docker.com/engine/install/rhel/#uninstall-old-versions
+	if c.os.UsesDNF() {
+		conflicting = append(conflicting, "podman", "buildah", "runc")
+	}
+	cmd := exec.Command("yum", append([]string{"--assumeyes", "remove"}, conflicting...)...)
 	cmd.Stderr = errBuf
 	if c.monitor.IsVerbose() {
 		fmt.Println(strings.Join(cmd.Args, " "))
@@ -39,7 +44,7 @@ func (c *criDep) ensureCentOS(runtime string, version string) error {
 	return c.run(runtime, version, "https://download.docker.com/linux/centos/docker-ce.repo", "", "")
 }
 
-func (c *criDep) ensureUbuntu(runtime string, version string) error {
+func (c *criDep) ensureDebianBased(runtime string, version string) error {
 
 	errBuf := new(bytes.Buffer)
 	defer errBuf.Reset()
@@ -78,23 +83,30 @@ func (c *criDep) ensureUbuntu(runtime string, version string) error {
 	return c.run(
 		runtime,
 		strings.TrimSpace(strings.Split(versionLine, "|")[1]),
-		fmt.Sprintf("deb [arch=amd64] https://download.docker.com/linux/ubuntu %s stable", c.os.Version),
-		"https://download.docker.com/linux/ubuntu/gpg",
+		fmt.Sprintf("deb [arch=amd64] https://download.docker.com/linux/%s %s stable", c.os.OperatingSystem.ID, c.os.Version),
+		fmt.Sprintf("https://download.docker.com/linux/%s/gpg", c.os.OperatingSystem.ID),
 		"0EBFCD88",
 	)
 }
 
 func (c *criDep) run(runtime, version, repoURL, keyURL, keyFingerprint string) error {
 
+	service := "docker"
+	if runtime == containerdPackage {
+		service = "containerd"
+	}
+
 	try := func() error {
 		// Obviously, docker doesn't care about the exact containerd version, so neighter should ORBITER
 		// https://docs.docker.com/engine/install/centos/
 		// https://docs.docker.com/engine/install/ubuntu/
-		if err := c.manager.Install(&dep.Software{
-			Package: "containerd.io",
-			Version: containerdVersion,
-		}); err != nil {
-			return err
+		if runtime != containerdPackage {
+			if err := c.manager.Install(&dep.Software{
+				Package: containerdPackage,
+				Version: containerdVersion,
+			}); err != nil {
+				return err
+			}
 		}
 
 		err := c.manager.Install(&dep.Software{
@@ -105,7 +117,7 @@ func (c *criDep) run(runtime, version, repoURL, keyURL, keyFingerprint string) e
 	}
 
 	if err := try(); err != nil {
-		swmonitor := c.monitor.WithField("software", "docker")
+		swmonitor := c.monitor.WithField("software", service)
 		swmonitor.Error(fmt.Errorf("installing software from existing repo failed, trying again after adding repo: %w", err))
 
 		if err := c.manager.Add(&dep.Repository{
@@ -123,8 +135,8 @@ func (c *criDep) run(runtime, version, repoURL, keyURL, keyFingerprint string) e
 		}
 	}
 
-	if err := c.systemd.Enable("docker"); err != nil {
+	if err := c.systemd.Enable(service); err != nil {
 		return err
 	}
-	return c.systemd.Start("docker")
+	return c.systemd.Start(service)
 }
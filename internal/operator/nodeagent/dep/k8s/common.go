@@ -1,9 +1,12 @@
 package k8s
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -40,23 +43,34 @@ func (c *Common) Ensure(remove common.Package, install common.Package) (err erro
 		}
 	}()
 
-	pkgVersion := strings.TrimLeft(install.Version, "v") + "-0"
-	if c.os == dep.Ubuntu {
+	version := strings.TrimLeft(install.Version, "v")
+	minor, err := minorVersion(version)
+	if err != nil {
+		return err
+	}
+
+	// Since v1.24, packages are only published in the community owned, per minor repositories
+	if minor >= 24 {
+		return c.ensureCommunityPackage(version, minor)
+	}
+
+	pkgVersion := version + "-0"
+	if c.os.Packages == dep.DebianBased {
 		pkgVersion += "0"
 	}
 	err = c.manager.Install(&dep.Software{Package: c.pkg, Version: pkgVersion})
 	if err == nil {
 		return nil
 	}
-	switch c.os {
-	case dep.Ubuntu:
+	switch c.os.Packages {
+	case dep.DebianBased:
 		return c.manager.Add(&dep.Repository{
 			KeyURL:         "https://packages.cloud.google.com/apt/doc/apt-key.gpg",
 			KeyFingerprint: "",
 			Repository:     "deb https://apt.kubernetes.io/ kubernetes-xenial main",
 		})
-	case dep.CentOS:
-		return ioutil.WriteFile("/etc/yum.repos.d/kubernetes.repo", []byte(`[kubernetes]
+	case dep.REMBased:
+		return ioutil.WriteFile(rpmRepoFile, []byte(`[kubernetes]
 name=Kubernetes
 baseurl=https://packages.cloud.google.com/yum/repos/kubernetes-el7-x86_64
 enabled=1
@@ -80,3 +94,64 @@ gpgkey=https://packages.cloud.google.com/yum/doc/yum-key.gpg https://packages.cl
 	}
 	return errors.New("unknown OS")
 }
+
+func (c *Common) ensureCommunityPackage(version string, minor int) error {
+
+	// The community repositories debian packages have the revision 1.1, whereas the rpm release depends on the build target,
+	// for example 150500.1.1. So the rpm package is pinned without release and locked by the package manager
+	pkgVersion := version
+	if c.os.Packages == dep.DebianBased {
+		pkgVersion += "-1.1"
+	}
+
+	installErr := c.manager.Install(&dep.Software{Package: c.pkg, Version: pkgVersion})
+	if installErr == nil {
+		return nil
+	}
+
+	repoURL := fmt.Sprintf("https://pkgs.k8s.io/core:/stable:/v1.%d", minor)
+	switch c.os.Packages {
+	case dep.DebianBased:
+		if debianRepositoryConfigured(repoURL) {
+			return installErr
+		}
+		return c.manager.Add(&dep.Repository{
+			KeyURL:     repoURL + "/deb/Release.key",
+			Repository: fmt.Sprintf("deb %s/deb/ /", repoURL),
+		})
+	case dep.REMBased:
+		repo := []byte(fmt.Sprintf(`[kubernetes]
+name=Kubernetes
+baseurl=%s/rpm/
+enabled=1
+gpgcheck=1
+gpgkey=%s/rpm/repodata/repomd.xml.key`, repoURL, repoURL))
+		if current, err := ioutil.ReadFile(rpmRepoFile); err == nil && bytes.Equal(current, repo) {
+			return installErr
+		}
+		return ioutil.WriteFile(rpmRepoFile, repo, 0600)
+	}
+	return errors.New("unknown OS")
+}
+
+const rpmRepoFile = "/etc/yum.repos.d/kubernetes.repo"
+
+// debianRepositoryConfigured returns true if an apt source list already contains the repository
+func debianRepositoryConfigured(repoURL string) bool {
+	lists, _ := filepath.Glob("/etc/apt/sources.list.d/*.list")
+	for _, list := range append([]string{"/etc/apt/sources.list"}, lists...) {
+		content, err := ioutil.ReadFile(list)
+		if err == nil && strings.Contains(string(content), repoURL+"/deb/") {
+			return true
+		}
+	}
+	return false
+}
+
+func minorVersion(version string) (int, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return 0, errors.Errorf("version %s is not semantic", version)
+	}
+	return strconv.Atoi(parts[1])
+}
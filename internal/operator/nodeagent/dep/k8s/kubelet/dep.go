@@ -62,7 +62,7 @@ func (k *kubeletDep) Ensure(remove common.Package, install common.Package) error
 		return err
 	}
 
-	if k.os != dep.CentOS {
+	if k.os.Packages != dep.REMBased {
 		return k.ensurePackage(remove, install)
 	}
 
@@ -19,6 +19,9 @@ func (p *PackageManager) rembasedAdd(repo *Repository) error {
 	defer errBuf.Reset()
 
 	cmd := exec.Command("yum-config-manager", "--add-repo", repo.Repository)
+	if p.os.UsesDNF() {
+		cmd = exec.Command("dnf", "config-manager", "--add-repo", repo.Repository)
+	}
 	cmd.Stderr = errBuf
 	cmd.Stdout = outBuf
 	err := cmd.Run()
@@ -30,7 +33,7 @@ func (p *PackageManager) rembasedAdd(repo *Repository) error {
 	}
 
 	if err != nil && !strings.Contains(out, fmt.Sprintf("Cannot add repo from %s as is a duplicate of an existing repo", repo.Repository)) {
-		return errors.Wrapf(err, "adding yum repository %s failed with stderr %s", repo.Repository, out)
+		return errors.Wrapf(err, "adding %s repository %s failed with stderr %s", p.rembasedBinary(), repo.Repository, out)
 	}
 
 	return nil
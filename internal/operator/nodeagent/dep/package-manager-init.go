@@ -23,19 +23,29 @@ func (p *PackageManager) debSpecificUpdatePackages() error {
 	return nil
 }
 func (p *PackageManager) debSpecificInit() error {
-	return p.debbasedInstall(
-		&Software{Package: "apt-transport-https"},
-		&Software{Package: "gnupg2"},
-		&Software{Package: "software-properties-common"})
+	pkgs := []*Software{
+		{Package: "gnupg2"},
+		{Package: "software-properties-common"},
+	}
+	// Bionic is still managed without a firewall
+	if p.os != Bionic {
+		pkgs = append(pkgs, &Software{Package: "nftables"})
+	}
+	return p.debbasedInstall(&Software{Package: "apt-transport-https"}, pkgs...)
 }
 
 func (p *PackageManager) remSpecificInit() error {
 
-	if err := p.rembasedInstall(
-		&Software{Package: "yum-utils"},
-		&Software{Package: "yum-plugin-versionlock"},
-		&Software{Package: "firewalld"},
-	); err != nil {
+	pkgs := []*Software{
+		{Package: "yum-plugin-versionlock"},
+		{Package: "firewalld"},
+	}
+	if p.os.UsesDNF() {
+		pkgs[0] = &Software{Package: "python3-dnf-plugin-versionlock"}
+		pkgs = append(pkgs, &Software{Package: "dnf-plugins-core"})
+	}
+
+	if err := p.rembasedInstall(&Software{Package: "yum-utils"}, pkgs...); err != nil {
 		return err
 	}
 
@@ -63,6 +73,9 @@ func (p *PackageManager) remSpecificUpdatePackages() error {
 	}
 
 	cmd := exec.Command("/usr/bin/yum", "--assumeyes", "--errorlevel", "0", "--debuglevel", "3", "update")
+	if p.os.UsesDNF() {
+		cmd = exec.Command("/usr/bin/dnf", "--assumeyes", "--quiet", "update")
+	}
 	errBuf := new(bytes.Buffer)
 	defer errBuf.Reset()
 	cmd.Stderr = errBuf
@@ -71,7 +84,7 @@ func (p *PackageManager) remSpecificUpdatePackages() error {
 		cmd.Stdout = os.Stdout
 	}
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("updating yum packages failed with stderr %s: %w", errBuf.String(), err)
+		return fmt.Errorf("updating %s packages failed with stderr %s: %w", p.rembasedBinary(), errBuf.String(), err)
 	}
 	return nil
 }
@@ -32,7 +32,7 @@ func (p *PackageManager) rembasedInstall(installVersion *Software, more ...*Soft
 
 		installPkg := fmt.Sprintf("%s-%s", sw.Package, sw.Version)
 		installPkgs = append(installPkgs, installPkg)
-		cmd := exec.Command("yum", "versionlock", "delete", sw.Package)
+		cmd := exec.Command(p.rembasedBinary(), "versionlock", "delete", sw.Package)
 		cmd.Stderr = errBuf
 		if p.monitor.IsVerbose() {
 			fmt.Println(strings.Join(cmd.Args, " "))
@@ -40,12 +40,12 @@ func (p *PackageManager) rembasedInstall(installVersion *Software, more ...*Soft
 		}
 		err := cmd.Run()
 		stderr := errBuf.String()
-		if err != nil && !strings.Contains(stderr, "versionlock delete: no matches") {
+		if err != nil && !strings.Contains(stderr, "versionlock delete: no matches") && !strings.Contains(stderr, "No package found") {
 			return errors.Wrapf(err, "unlocking package %s failed with stderr %s", sw.Package, stderr)
 		}
 		errBuf.Reset()
 
-		cmd = exec.Command("yum", "versionlock", "add", "-y", installPkg)
+		cmd = exec.Command(p.rembasedBinary(), "versionlock", "add", "-y", installPkg)
 		cmd.Stderr = errBuf
 		if p.monitor.IsVerbose() {
 			fmt.Println(strings.Join(cmd.Args, " "))
@@ -58,19 +58,19 @@ func (p *PackageManager) rembasedInstall(installVersion *Software, more ...*Soft
 	}
 
 	for _, pkg := range installPkgs {
-		if err := rembasedInstallPkg(p.monitor, pkg); err != nil {
+		if err := rembasedInstallPkg(p.monitor, p.rembasedBinary(), pkg); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func rembasedInstallPkg(monitor mntr.Monitor, pkg string) error {
+func rembasedInstallPkg(monitor mntr.Monitor, binary, pkg string) error {
 	errBuf := new(bytes.Buffer)
 	defer errBuf.Reset()
 	outBuf := new(bytes.Buffer)
 	defer outBuf.Reset()
-	cmd := exec.Command("yum", "install", "-y", pkg)
+	cmd := exec.Command(binary, "install", "-y", pkg)
 	cmd.Stderr = errBuf
 	cmd.Stdout = outBuf
 	err := cmd.Run()
@@ -79,14 +79,14 @@ func rembasedInstallPkg(monitor mntr.Monitor, pkg string) error {
 	monitor.WithFields(map[string]interface{}{
 		"stdout": outStr,
 		"stderr": errStr,
-	}).Debug("Executed yum install")
+	}).Debug("Executed " + binary + " install")
 	if err != nil {
 		if strings.Contains(errStr+outStr, "is already installed") {
 			err = nil
 		}
 	}
 
-	return errors.Wrapf(err, "installing yum package %s failed with stderr %s", pkg, errStr)
+	return errors.Wrapf(err, "installing %s package %s failed with stderr %s", binary, pkg, errStr)
 }
 
 // TODO: Use lower level apt instead of apt-get?
@@ -25,14 +25,14 @@ type Repository struct {
 
 type PackageManager struct {
 	monitor   mntr.Monitor
-	os        OperatingSystem
+	os        OperatingSystemMajor
 	installed map[string]string
 	systemd   *SystemD
 }
 
 func (p *PackageManager) RefreshInstalled() error {
 	var err error
-	switch p.os.Packages {
+	switch p.os.OperatingSystem.Packages {
 	case DebianBased:
 		err = p.debbasedInstalled()
 	case REMBased:
@@ -50,7 +50,7 @@ func (p *PackageManager) Init() error {
 
 	p.monitor.Debug("Initializing package manager")
 	var err error
-	switch p.os.Packages {
+	switch p.os.OperatingSystem.Packages {
 	case DebianBased:
 		err = p.debSpecificInit()
 	case REMBased:
@@ -58,7 +58,7 @@ func (p *PackageManager) Init() error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("initializing packages %s failed: %w", p.os.Packages, err)
+		return fmt.Errorf("initializing packages %s failed: %w", p.os.OperatingSystem.Packages, err)
 	}
 
 	p.monitor.Debug("Package manager initialized")
@@ -68,7 +68,7 @@ func (p *PackageManager) Init() error {
 func (p *PackageManager) Update() error {
 	p.monitor.Debug("Updating packages")
 	var err error
-	switch p.os.Packages {
+	switch p.os.OperatingSystem.Packages {
 	case DebianBased:
 		err = p.debSpecificUpdatePackages()
 	case REMBased:
@@ -76,17 +76,25 @@ func (p *PackageManager) Update() error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("updating packages %s failed: %w", p.os.Packages, err)
+		return fmt.Errorf("updating packages %s failed: %w", p.os.OperatingSystem.Packages, err)
 	}
 
 	p.monitor.Info("Packages updated")
 	return nil
 }
 
-func NewPackageManager(monitor mntr.Monitor, os OperatingSystem, systemd *SystemD) *PackageManager {
+func NewPackageManager(monitor mntr.Monitor, os OperatingSystemMajor, systemd *SystemD) *PackageManager {
 	return &PackageManager{monitor, os, nil, systemd}
 }
 
+// rembasedBinary returns dnf for EL8 and newer, yum otherwise
+func (p *PackageManager) rembasedBinary() string {
+	if p.os.UsesDNF() {
+		return "dnf"
+	}
+	return "yum"
+}
+
 func (p *PackageManager) CurrentVersions(possiblePackages ...string) []*Software {
 
 	software := make([]*Software, 0)
@@ -108,22 +116,22 @@ func (p *PackageManager) CurrentVersions(possiblePackages ...string) []*Software
 }
 
 func (p *PackageManager) Install(installVersion *Software, more ...*Software) error {
-	switch p.os.Packages {
+	switch p.os.OperatingSystem.Packages {
 	case DebianBased:
 		return p.debbasedInstall(installVersion, more...)
 	case REMBased:
 		return p.rembasedInstall(installVersion, more...)
 	}
-	return errors.Errorf("Package manager %s is not implemented", p.os.Packages)
+	return errors.Errorf("Package manager %s is not implemented", p.os.OperatingSystem.Packages)
 }
 
 func (p *PackageManager) Add(repo *Repository) error {
-	switch p.os.Packages {
+	switch p.os.OperatingSystem.Packages {
 	case DebianBased:
 		return p.debbasedAdd(repo)
 	case REMBased:
 		return p.rembasedAdd(repo)
 	default:
-		return errors.Errorf("Package manager %s is not implemented", p.os.Packages)
+		return errors.Errorf("Package manager %s is not implemented", p.os.OperatingSystem.Packages)
 	}
 }
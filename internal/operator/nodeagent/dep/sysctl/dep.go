@@ -42,8 +42,14 @@ func (*sysctlDep) Equals(other nodeagent.Installer) bool {
 	return ok
 }
 
+const confPath = "/etc/sysctl.d/90-orbiter.conf"
+
+// supportedModules are always managed
 var supportedModules = []common.KernelModule{common.IpForward, common.NonLocalBind, common.BridgeNfCallIptables, common.BridgeNfCallIp6tables}
 
+// optionalModules are only managed if they are desired, for example the IPv6 modules only in clusters with an IPv6 family
+var optionalModules = []common.KernelModule{common.Ipv6Forward, common.Ipv6NonLocalBind}
+
 func Contains(this common.Package, that common.Package) bool {
 	if that.Config == nil {
 		return true
@@ -76,8 +82,16 @@ func Enable(pkg *common.Package, property common.KernelModule) {
 
 func (s *sysctlDep) Current() (pkg common.Package, err error) {
 
-	for idx := range supportedModules {
-		module := supportedModules[idx]
+	// Optional modules are reported only if they were ensured before, so they don't differ from desired states which don't contain them
+	conf, err := ioutil.ReadFile(confPath)
+	if err != nil && !os.IsNotExist(err) {
+		return pkg, err
+	}
+
+	for _, module := range append(supportedModules, optionalModules...) {
+		if !isSupported(module) && !strings.Contains(string(conf), string(module)+" = ") {
+			continue
+		}
 		if err := currentSysctlConfig(s.monitor, module, &pkg); err != nil {
 			return pkg, err
 		}
@@ -88,17 +102,29 @@ func (s *sysctlDep) Current() (pkg common.Package, err error) {
 
 func (s *sysctlDep) Ensure(_ common.Package, ensure common.Package) error {
 
-	if err := ioutil.WriteFile("/etc/sysctl.d/90-orbiter.conf", []byte(fmt.Sprintf(
-		`%s = %s
-%s = %s
-%s = %s
-%s = %s
-`,
-		string(common.IpForward), oneOrZero(ensure.Config, common.IpForward),
-		string(common.NonLocalBind), oneOrZero(ensure.Config, common.NonLocalBind),
-		string(common.BridgeNfCallIptables), oneOrZero(ensure.Config, common.BridgeNfCallIptables),
-		string(common.BridgeNfCallIp6tables), oneOrZero(ensure.Config, common.BridgeNfCallIp6tables),
-	)), os.ModePerm); err != nil {
+	conf := new(bytes.Buffer)
+	for _, module := range append(supportedModules, optionalModules...) {
+		if _, ok := ensure.Config[string(module)]; !ok && !isSupported(module) {
+			continue
+		}
+		value := oneOrZero(ensure.Config, module)
+		if _, err := os.Stat(procPath(module)); err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+			// Modules like br_netfilter might be loaded later, so enabling the parameter is retried in the next iteration
+			monitor := s.monitor.WithField("parameter", module)
+			if value == "1" {
+				monitor.Info("Skipping kernel parameter as the kernel doesn't provide it yet")
+			} else {
+				monitor.Debug("Skipping kernel parameter as the kernel doesn't provide it")
+			}
+			continue
+		}
+		conf.WriteString(fmt.Sprintf("%s = %s\n", module, value))
+	}
+
+	if err := ioutil.WriteFile(confPath, conf.Bytes(), os.ModePerm); err != nil {
 		return err
 	}
 
@@ -109,6 +135,19 @@ func (s *sysctlDep) Ensure(_ common.Package, ensure common.Package) error {
 	return nil
 }
 
+func isSupported(module common.KernelModule) bool {
+	for _, supported := range supportedModules {
+		if module == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func procPath(module common.KernelModule) string {
+	return "/proc/sys/" + strings.ReplaceAll(string(module), ".", "/")
+}
+
 func oneOrZero(cfg map[string]string, property common.KernelModule) string {
 	val := cfg[string(property)]
 	if val == "1" {
@@ -3,9 +3,11 @@
 package dep
 
 import (
-	"bytes"
+	"bufio"
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -21,6 +23,8 @@ const (
 
 type OperatingSystem struct {
 	Packages Packages
+	// ID is the ID field from /etc/os-release
+	ID string
 }
 
 func (o OperatingSystem) String() string {
@@ -28,18 +32,29 @@ func (o OperatingSystem) String() string {
 	switch o {
 	case Ubuntu:
 		os = "Ubuntu"
+	case Debian:
+		os = "Debian"
 	case CentOS:
 		os = "CentOS"
+	case Rocky:
+		os = "Rocky Linux"
+	case Alma:
+		os = "AlmaLinux"
 	}
 	return os
 }
 
 var (
 	UnknownOS OperatingSystem = OperatingSystem{}
-	Ubuntu    OperatingSystem = OperatingSystem{DebianBased}
-	CentOS    OperatingSystem = OperatingSystem{REMBased}
+	Ubuntu    OperatingSystem = OperatingSystem{DebianBased, "ubuntu"}
+	Debian    OperatingSystem = OperatingSystem{DebianBased, "debian"}
+	CentOS    OperatingSystem = OperatingSystem{REMBased, "centos"}
+	Rocky     OperatingSystem = OperatingSystem{REMBased, "rocky"}
+	Alma      OperatingSystem = OperatingSystem{REMBased, "almalinux"}
 )
 
+// OperatingSystemMajor is an operating system release.
+// For debian based systems, the version is the release codename, for REM based systems it is the major version
 type OperatingSystemMajor struct {
 	OperatingSystem OperatingSystem
 	Version         string
@@ -50,61 +65,112 @@ func (o OperatingSystemMajor) String() string {
 	switch o {
 	case Bionic:
 		versionName = "18.04 LTS Bionic Beaver"
-	case CentOS7:
-		versionName = "7"
+	case Focal:
+		versionName = "20.04 LTS Focal Fossa"
+	case Jammy:
+		versionName = "22.04 LTS Jammy Jellyfish"
+	case Bullseye:
+		versionName = "11 Bullseye"
+	case Bookworm:
+		versionName = "12 Bookworm"
+	default:
+		versionName = o.Version
 	}
 
 	return fmt.Sprintf("%s %s", o.OperatingSystem, versionName)
 }
 
-var (
-	Unknown OperatingSystemMajor = OperatingSystemMajor{UnknownOS, ""}
-	Bionic  OperatingSystemMajor = OperatingSystemMajor{Ubuntu, "bionic"}
-	CentOS7 OperatingSystemMajor = OperatingSystemMajor{CentOS, "7"}
+// UsesDNF returns true if the system manages its packages with dnf instead of yum
+func (o OperatingSystemMajor) UsesDNF() bool {
+	if o.OperatingSystem.Packages != REMBased {
+		return false
+	}
+	major, err := strconv.Atoi(o.Version)
+	return err == nil && major >= 8
+}
 
-	//	Debian OperatingSystem = OperatingSystem{DebianBased}
+var (
+	Unknown  OperatingSystemMajor = OperatingSystemMajor{UnknownOS, ""}
+	Bionic   OperatingSystemMajor = OperatingSystemMajor{Ubuntu, "bionic"}
+	Focal    OperatingSystemMajor = OperatingSystemMajor{Ubuntu, "focal"}
+	Jammy    OperatingSystemMajor = OperatingSystemMajor{Ubuntu, "jammy"}
+	Bullseye OperatingSystemMajor = OperatingSystemMajor{Debian, "bullseye"}
+	Bookworm OperatingSystemMajor = OperatingSystemMajor{Debian, "bookworm"}
+	CentOS7  OperatingSystemMajor = OperatingSystemMajor{CentOS, "7"}
+	Rocky8   OperatingSystemMajor = OperatingSystemMajor{Rocky, "8"}
+	Rocky9   OperatingSystemMajor = OperatingSystemMajor{Rocky, "9"}
+	Alma8    OperatingSystemMajor = OperatingSystemMajor{Alma, "8"}
+	Alma9    OperatingSystemMajor = OperatingSystemMajor{Alma, "9"}
 )
 
-func GetOperatingSystem() (OperatingSystemMajor, error) {
-	buf := new(bytes.Buffer)
-	defer buf.Reset()
-
-	hostnamectl := exec.Command("hostnamectl")
-	hostnamectl.Stdout = buf
+// SupportedOperatingSystems contains all releases the node agent is able to manage
+var SupportedOperatingSystems = []OperatingSystemMajor{
+	Bionic,
+	Focal,
+	Jammy,
+	Bullseye,
+	Bookworm,
+	CentOS7,
+	Rocky8,
+	Rocky9,
+	Alma8,
+	Alma9,
+}
 
-	if err := hostnamectl.Run(); err != nil {
-		return Unknown, errors.Wrap(err, "running hostnamectl in order to get operating system information failed")
+func GetOperatingSystem() (OperatingSystemMajor, error) {
+	var lastErr error
+	for _, path := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		file, err := os.Open(path)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		defer file.Close()
+		return ParseOSRelease(file)
 	}
+	return Unknown, errors.Wrap(lastErr, "reading os-release in order to get operating system information failed")
+}
 
-	var (
-		osLine string
-		err    error
-	)
-	for err == nil {
-		osLine, err = buf.ReadString('\n')
-		if strings.Contains(osLine, "Operating System") {
-			break
+// ParseOSRelease maps the content of an os-release file to a supported operating system release
+func ParseOSRelease(osRelease io.Reader) (OperatingSystemMajor, error) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(osRelease)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
 		}
+		fields[parts[0]] = strings.Trim(parts[1], `"'`)
 	}
-
-	if err != nil {
-		return Unknown, errors.Wrap(err, "finding line containing \"Operating System\" in hostnamectl output failed")
+	if err := scanner.Err(); err != nil {
+		return Unknown, errors.Wrap(err, "reading os-release failed")
 	}
 
-	os := strings.Fields(osLine)[2]
-	switch os {
-	case "Ubuntu":
-		version := strings.Fields(osLine)[3]
-		if strings.HasPrefix(version, "18.04") {
-			return Bionic, nil
+	id := fields["ID"]
+	var version string
+	for _, supported := range SupportedOperatingSystems {
+		if supported.OperatingSystem.ID != id {
+			continue
+		}
+
+		switch supported.OperatingSystem.Packages {
+		case DebianBased:
+			version = fields["VERSION_CODENAME"]
+		case REMBased:
+			version = strings.Split(fields["VERSION_ID"], ".")[0]
 		}
-		return Unknown, errors.Errorf("Unsupported ubuntu version %s", version)
-	case "CentOS":
-		version := strings.Fields(osLine)[4]
-		if version == "7" {
-			return CentOS7, nil
+
+		if supported.Version == version {
+			return supported, nil
 		}
-		return Unknown, errors.Errorf("Unsupported centOS version %s", version)
 	}
-	return Unknown, errors.Errorf("Unknown operating system %s", os)
+
+	if version != "" {
+		return Unknown, errors.Errorf("Unsupported %s version %s", id, fields["VERSION_ID"])
+	}
+	return Unknown, errors.Errorf("Unknown operating system %s", id)
 }
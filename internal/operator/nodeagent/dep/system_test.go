@@ -0,0 +1,51 @@
+package dep_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/nodeagent/dep"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    dep.OperatingSystemMajor
+		wantDNF bool
+		wantErr bool
+	}{
+		{fixture: "ubuntu-18.04", want: dep.Bionic},
+		{fixture: "ubuntu-20.04", want: dep.Focal},
+		{fixture: "ubuntu-22.04", want: dep.Jammy},
+		{fixture: "debian-11", want: dep.Bullseye},
+		{fixture: "debian-12", want: dep.Bookworm},
+		{fixture: "centos-7", want: dep.CentOS7},
+		{fixture: "rocky-8", want: dep.Rocky8, wantDNF: true},
+		{fixture: "rocky-9", want: dep.Rocky9, wantDNF: true},
+		{fixture: "almalinux-8", want: dep.Alma8, wantDNF: true},
+		{fixture: "almalinux-9", want: dep.Alma9, wantDNF: true},
+		{fixture: "ubuntu-24.04", want: dep.Unknown, wantErr: true},
+		{fixture: "fedora-39", want: dep.Unknown, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			file, err := os.Open(filepath.Join("testdata", "os-release", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			got, err := dep.ParseOSRelease(file)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOSRelease() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseOSRelease() = %s, want %s", got, tt.want)
+			}
+			if got.UsesDNF() != tt.wantDNF {
+				t.Errorf("UsesDNF() = %t, want %t", got.UsesDNF(), tt.wantDNF)
+			}
+		})
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"github.com/caos/orbos/internal/operator/nodeagent"
 	"github.com/caos/orbos/internal/operator/nodeagent/dep"
 	"github.com/caos/orbos/internal/operator/nodeagent/firewall/centos"
+	"github.com/caos/orbos/internal/operator/nodeagent/firewall/nftables"
 	"github.com/caos/orbos/mntr"
 )
 
-func Ensurer(monitor mntr.Monitor, os dep.OperatingSystem, open []string) nodeagent.FirewallEnsurer {
-	switch os {
-	case dep.CentOS:
+func Ensurer(monitor mntr.Monitor, os dep.OperatingSystemMajor, open []string) nodeagent.FirewallEnsurer {
+	switch {
+	case os.OperatingSystem.Packages == dep.REMBased:
 		return centos.Ensurer(monitor, open)
+	case os.OperatingSystem.Packages == dep.DebianBased && os != dep.Bionic:
+		return nftables.Ensurer(monitor, open)
 	default:
 		return noopEnsurer()
 	}
@@ -0,0 +1,268 @@
+package nftables
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent"
+	"github.com/caos/orbos/mntr"
+	"github.com/pkg/errors"
+)
+
+const (
+	table        = "orbos"
+	inputPolicy  = "drop"
+	rulesetPath  = "/etc/orbos/nftables.nft"
+	nftablesConf = "/etc/nftables.conf"
+)
+
+// Ensurer manages the zones in a separate nftables table, so rules from kube-proxy and the CNI are never touched.
+// A zone accepts its ports only on its interfaces and from its sources.
+// Other incoming traffic is dropped, unless it belongs to an established connection, is ICMP or targets an open port
+func Ensurer(monitor mntr.Monitor, open []string) nodeagent.FirewallEnsurer {
+	return nodeagent.FirewallEnsurerFunc(func(desired common.Firewall) (common.FirewallCurrent, func() error, error) {
+
+		desiredZones := desiredCurrent(&desired, open)
+
+		current, policy, err := queryCurrent(monitor)
+		if err != nil {
+			return current, nil, err
+		}
+
+		if current.String() == desiredZones.String() && policy == inputPolicy {
+			monitor.Debug("Not changing firewall")
+			return current, nil, nil
+		}
+
+		ruleset, err := render(desiredZones, open)
+		if err != nil {
+			return current, nil, err
+		}
+
+		return current, func() error {
+			monitor.Debug("Ensuring firewall")
+			if err := os.MkdirAll(filepath.Dir(rulesetPath), 0700); err != nil {
+				return err
+			}
+
+			if err := ioutil.WriteFile(rulesetPath, []byte(ruleset), 0600); err != nil {
+				return err
+			}
+
+			if err := ensureIncluded(); err != nil {
+				return err
+			}
+
+			// The nftables service flushes the whole ruleset when started, so it is only enabled for the next boot
+			if _, err := runCommand(monitor, "systemctl", "enable", "nftables"); err != nil {
+				return err
+			}
+
+			_, err := runCommand(monitor, "nft", "-f", rulesetPath)
+			return err
+		}, nil
+	})
+}
+
+func desiredCurrent(desired *common.Firewall, open []string) common.FirewallCurrent {
+	zones := make(common.FirewallCurrent, 0)
+	for name, zone := range desired.Zones {
+		if zone == nil {
+			continue
+		}
+
+		ports := make([]*common.Allowed, 0)
+		seen := make(map[string]bool)
+		for _, port := range append(desired.Ports(name), ignoredPorts(open)...) {
+			key := port.Port + "/" + port.Protocol
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			ports = append(ports, port)
+		}
+
+		zones = append(zones, &common.ZoneDesc{
+			Name:       name,
+			Masquerade: zone.Masquerade,
+			Interfaces: append(common.MarshallableSlice{}, zone.Interfaces...),
+			Sources:    append(common.MarshallableSlice{}, zone.Sources...),
+			FW:         ports,
+			Services:   []*common.Service{},
+		})
+	}
+	zones.Sort()
+	return zones
+}
+
+func ignoredPorts(ports []string) []*common.Allowed {
+	allowed := make([]*common.Allowed, len(ports))
+	for idx, port := range ports {
+		allowed[idx] = &common.Allowed{
+			Port:     port,
+			Protocol: "tcp",
+		}
+	}
+	return allowed
+}
+
+// render returns an nftables script which atomically replaces the orbos table.
+// The open ports are accepted even without zones, so the node agent never locks itself out
+func render(zones common.FirewallCurrent, open []string) (string, error) {
+	sets := new(bytes.Buffer)
+	input := new(bytes.Buffer)
+	postrouting := new(bytes.Buffer)
+
+	// ICMP is accepted like in firewalld zones, as IPv6 needs it for neighbor discovery
+	input.WriteString(`		ct state established,related accept
+		ct state invalid drop
+		iifname "lo" accept
+		meta l4proto { icmp, ipv6-icmp } accept
+`)
+	if len(open) > 0 {
+		sorted := append([]string{}, open...)
+		sort.Strings(sorted)
+		fmt.Fprintf(input, "\t\ttcp dport { %s } accept comment \"open\"\n", strings.Join(sorted, ", "))
+	}
+
+	for _, zone := range zones {
+		ports := map[string][]string{"tcp": {}, "udp": {}}
+		for _, port := range zone.FW {
+			if _, ok := ports[port.Protocol]; !ok {
+				return "", errors.Errorf("protocol %s of port %s in zone %s is not supported by nftables", port.Protocol, port.Port, zone.Name)
+			}
+			ports[port.Protocol] = append(ports[port.Protocol], port.Port)
+		}
+
+		interfaces := make([]string, len(zone.Interfaces))
+		for idx, iface := range zone.Interfaces {
+			interfaces[idx] = fmt.Sprintf("%q", iface)
+		}
+
+		var ipv4Sources, ipv6Sources []string
+		for _, source := range zone.Sources {
+			if strings.Contains(source, ":") {
+				ipv6Sources = append(ipv6Sources, source)
+				continue
+			}
+			ipv4Sources = append(ipv4Sources, source)
+		}
+
+		writeSet(sets, zone.Name+"_interfaces", "ifname", false, interfaces)
+		writeSet(sets, zone.Name+"_sources", "ipv4_addr", true, ipv4Sources)
+		writeSet(sets, zone.Name+"_sources6", "ipv6_addr", true, ipv6Sources)
+		writeSet(sets, zone.Name+"_tcp", "inet_service", true, ports["tcp"])
+		writeSet(sets, zone.Name+"_udp", "inet_service", true, ports["udp"])
+
+		for _, match := range []string{"iifname @%[1]s_interfaces", "ip saddr @%[1]s_sources", "ip6 saddr @%[1]s_sources6"} {
+			for _, protocol := range []string{"tcp", "udp"} {
+				fmt.Fprintf(input, "\t\t"+match+" %[2]s dport @%[1]s_%[2]s accept comment \"%[1]s\"\n", zone.Name, protocol)
+			}
+		}
+
+		if zone.Masquerade {
+			fmt.Fprintf(postrouting, `		oifname @%[1]s_interfaces masquerade comment "%[1]s"
+		ip daddr @%[1]s_sources masquerade comment "%[1]s"
+		ip6 daddr @%[1]s_sources6 masquerade comment "%[1]s"
+`, zone.Name)
+		}
+	}
+
+	return fmt.Sprintf(`#!/usr/sbin/nft -f
+# This file is managed by ORBOS
+
+table inet %[1]s
+delete table inet %[1]s
+
+table inet %[1]s {
+%[2]s
+	chain input {
+		type filter hook input priority 0; policy %[5]s;
+%[3]s	}
+
+	chain postrouting {
+		type nat hook postrouting priority 100; policy accept;
+%[4]s	}
+}
+`, table, sets.String(), input.String(), postrouting.String(), inputPolicy), nil
+}
+
+func writeSet(buf *bytes.Buffer, name, ty string, interval bool, elements []string) {
+	fmt.Fprintf(buf, "\tset %s {\n\t\ttype %s\n", name, ty)
+	if interval {
+		buf.WriteString("\t\tflags interval\n")
+	}
+	if len(elements) > 0 {
+		sorted := append([]string{}, elements...)
+		sort.Strings(sorted)
+		fmt.Fprintf(buf, "\t\telements = { %s }\n", strings.Join(sorted, ", "))
+	}
+	buf.WriteString("\t}\n")
+}
+
+// ensureIncluded makes the nftables service load the orbos table at boot
+func ensureIncluded() error {
+	include := fmt.Sprintf("include %q", rulesetPath)
+
+	conf, err := ioutil.ReadFile(nftablesConf)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if strings.Contains(string(conf), include) {
+		return nil
+	}
+
+	if len(conf) == 0 {
+		conf = []byte("#!/usr/sbin/nft -f\n")
+	}
+
+	return ioutil.WriteFile(nftablesConf, []byte(strings.TrimSuffix(string(conf), "\n")+"\n\n"+include+"\n"), 0755)
+}
+
+func queryCurrent(monitor mntr.Monitor) (common.FirewallCurrent, string, error) {
+	out, err := runCommand(monitor, "nft", "-j", "list", "table", "inet", table)
+	if err != nil {
+		// The table does not exist until the first ensure
+		monitor.WithField("error", err.Error()).Debug("Listing orbos nftables table failed")
+		return make(common.FirewallCurrent, 0), "", nil
+	}
+	current, err := parseCurrent([]byte(out))
+	if err != nil {
+		return nil, "", err
+	}
+	policy, err := parseInputPolicy([]byte(out))
+	return current, policy, err
+}
+
+func runCommand(monitor mntr.Monitor, binary string, args ...string) (string, error) {
+
+	outBuf := new(bytes.Buffer)
+	defer outBuf.Reset()
+	errBuf := new(bytes.Buffer)
+	defer errBuf.Reset()
+
+	cmd := exec.Command(binary, args...)
+	cmd.Stderr = errBuf
+	cmd.Stdout = outBuf
+
+	fullCmd := fmt.Sprintf("'%s'", strings.Join(cmd.Args, "' '"))
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf(`running %s failed with stderr %s: %w`, fullCmd, errBuf.String(), err)
+	}
+
+	stdout := outBuf.String()
+	if monitor.IsVerbose() {
+		fmt.Println(fullCmd)
+		fmt.Println(stdout)
+	}
+
+	return strings.TrimSuffix(stdout, "\n"), nil
+}
@@ -0,0 +1,275 @@
+package nftables
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/common"
+)
+
+func testFirewall() *common.Firewall {
+	return &common.Firewall{Zones: map[string]*common.Zone{
+		"public": {},
+		"internal": {
+			Masquerade: true,
+			Sources:    []string{"10.0.0.2/32", "10.0.0.1/32", "fd00::/64"},
+		},
+		"external": {
+			Interfaces: []string{"eth0"},
+			FW: map[string]*common.Allowed{
+				"kubeapi":   {Port: "6443", Protocol: "tcp"},
+				"nodeports": {Port: "30000-32767", Protocol: "tcp"},
+				"wireguard": {Port: "51820", Protocol: "udp"},
+			},
+		},
+	}}
+}
+
+func TestRender(t *testing.T) {
+	ruleset, err := render(desiredCurrent(testFirewall(), []string{"22"}), []string{"22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"table inet orbos\ndelete table inet orbos\n",
+		"\tset external_interfaces {\n\t\ttype ifname\n\t\telements = { \"eth0\" }\n\t}",
+		"\tset external_tcp {\n\t\ttype inet_service\n\t\tflags interval\n\t\telements = { 22, 30000-32767, 6443 }\n\t}",
+		"\tset external_udp {\n\t\ttype inet_service\n\t\tflags interval\n\t\telements = { 51820 }\n\t}",
+		"\tset internal_sources {\n\t\ttype ipv4_addr\n\t\tflags interval\n\t\telements = { 10.0.0.1/32, 10.0.0.2/32 }\n\t}",
+		"\tset internal_sources6 {\n\t\ttype ipv6_addr\n\t\tflags interval\n\t\telements = { fd00::/64 }\n\t}",
+		"\tset public_sources {\n\t\ttype ipv4_addr\n\t\tflags interval\n\t}",
+		`ip saddr @internal_sources tcp dport @internal_tcp accept comment "internal"`,
+		`ip daddr @internal_sources masquerade comment "internal"`,
+		`ip6 saddr @internal_sources6 udp dport @internal_udp accept comment "internal"`,
+		`iifname @external_interfaces tcp dport @external_tcp accept comment "external"`,
+		`ip6 daddr @internal_sources6 masquerade comment "internal"`,
+		"type filter hook input priority 0; policy drop;",
+		"ct state established,related accept",
+		`iifname "lo" accept`,
+		`tcp dport { 22 } accept comment "open"`,
+	} {
+		if !strings.Contains(ruleset, want) {
+			t.Errorf("ruleset does not contain %q:\n%s", want, ruleset)
+		}
+	}
+
+	if strings.Contains(ruleset, `masquerade comment "external"`) {
+		t.Errorf("zone external should not masquerade:\n%s", ruleset)
+	}
+
+	fw := testFirewall()
+	fw.Zones["external"].FW["sctp"] = &common.Allowed{Port: "9", Protocol: "sctp"}
+	if _, err := render(desiredCurrent(fw, nil), nil); err == nil {
+		t.Error("rendering unsupported protocols should fail")
+	}
+}
+
+// packet is an incoming new connection
+type packet struct {
+	iface, source, protocol string
+	port                    int
+}
+
+var (
+	setPattern  = regexp.MustCompile(`set (\w+) \{[^}]*?(?:elements = \{ ([^}]*) \})?\s*\}`)
+	rulePattern = regexp.MustCompile(`^(?:(iifname|ip saddr|ip6 saddr) @(\w+) )?(tcp|udp) dport (?:@(\w+)|\{ ([^}]*) \}) accept`)
+)
+
+// accepts evaluates the port rules of the rendered input chain for a packet
+func accepts(t *testing.T, ruleset string, p packet) bool {
+	sets := make(map[string][]string)
+	for _, match := range setPattern.FindAllStringSubmatch(ruleset, -1) {
+		if match[2] != "" {
+			sets[match[1]] = strings.Split(match[2], ", ")
+		}
+	}
+
+	contains := func(elements []string, value string) bool {
+		for _, element := range elements {
+			element = strings.Trim(element, `"`)
+			if _, cidr, err := net.ParseCIDR(element); err == nil {
+				if ip := net.ParseIP(value); ip != nil && cidr.Contains(ip) {
+					return true
+				}
+				continue
+			}
+			if bounds := strings.Split(element, "-"); len(bounds) == 2 {
+				from, _ := strconv.Atoi(bounds[0])
+				to, _ := strconv.Atoi(bounds[1])
+				if port, err := strconv.Atoi(value); err == nil && port >= from && port <= to {
+					return true
+				}
+				continue
+			}
+			if element == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, line := range strings.Split(ruleset, "\n") {
+		rule := rulePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if rule == nil || rule[3] != p.protocol {
+			continue
+		}
+		switch rule[1] {
+		case "iifname":
+			if !contains(sets[rule[2]], p.iface) {
+				continue
+			}
+		case "ip saddr", "ip6 saddr":
+			if !contains(sets[rule[2]], p.source) {
+				continue
+			}
+		case "":
+		default:
+			t.Fatalf("unexpected rule %s", line)
+		}
+		ports := strings.Split(rule[5], ", ")
+		if rule[4] != "" {
+			ports = sets[rule[4]]
+		}
+		if contains(ports, strconv.Itoa(p.port)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRender_ZonePorts(t *testing.T) {
+	fw := testFirewall()
+	fw.Zones["internal"].FW = map[string]*common.Allowed{
+		"etcd":    {Port: "2379-2381", Protocol: "tcp"},
+		"kubelet": {Port: "10250", Protocol: "tcp"},
+	}
+	ruleset, err := render(desiredCurrent(fw, []string{"22"}), []string{"22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		packet packet
+		want   bool
+	}{
+		{name: "internal ports are accepted from internal sources", packet: packet{iface: "eth1", source: "10.0.0.1", protocol: "tcp", port: 2380}, want: true},
+		{name: "internal ports are accepted from internal IPv6 sources", packet: packet{iface: "eth1", source: "fd00::5", protocol: "tcp", port: 10250}, want: true},
+		{name: "internal ports are not accepted on external interfaces", packet: packet{iface: "eth0", source: "203.0.113.7", protocol: "tcp", port: 2379}},
+		{name: "internal ports are not accepted from foreign interfaces", packet: packet{iface: "eth1", source: "203.0.113.7", protocol: "tcp", port: 10250}},
+		{name: "internal tcp ports are not accepted as udp", packet: packet{iface: "eth1", source: "10.0.0.1", protocol: "udp", port: 10250}},
+		{name: "external ports are accepted on external interfaces", packet: packet{iface: "eth0", source: "203.0.113.7", protocol: "tcp", port: 6443}, want: true},
+		{name: "external ports are not accepted on other interfaces", packet: packet{iface: "eth1", source: "203.0.113.7", protocol: "udp", port: 51820}},
+		{name: "open ports are accepted everywhere", packet: packet{iface: "eth1", source: "203.0.113.7", protocol: "tcp", port: 22}, want: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accepts(t, ruleset, tt.packet); got != tt.want {
+				t.Errorf("accepts(%+v) = %t, want %t:\n%s", tt.packet, got, tt.want, ruleset)
+			}
+		})
+	}
+
+	for _, line := range strings.Split(ruleset, "\n") {
+		if strings.Contains(line, "accept") && strings.Contains(line, "@internal") && !strings.Contains(line, "dport") {
+			t.Errorf("zone internal accepts traffic regardless of the port: %s", line)
+		}
+	}
+}
+
+func TestRenderWithoutZones(t *testing.T) {
+	ruleset, err := render(desiredCurrent(&common.Firewall{}, []string{"22"}), []string{"22"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"policy drop;",
+		`tcp dport { 22 } accept comment "open"`,
+	} {
+		if !strings.Contains(ruleset, want) {
+			t.Errorf("ruleset does not contain %q:\n%s", want, ruleset)
+		}
+	}
+}
+
+func TestParseInputPolicy(t *testing.T) {
+	for listing, want := range map[string]string{
+		`{"nftables": [{"chain": {"family": "inet", "table": "orbos", "name": "input", "type": "filter", "hook": "input", "prio": 0, "policy": "accept"}}]}`: "accept",
+		`{"nftables": [{"chain": {"name": "postrouting", "policy": "accept"}}, {"chain": {"name": "input", "policy": "drop"}}]}`:                             "drop",
+		`{"nftables": []}`: "",
+	} {
+		got, err := parseInputPolicy([]byte(listing))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("parseInputPolicy() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestParseCurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		listing string
+		want    common.FirewallCurrent
+		wantErr bool
+	}{{
+		name: "It should map a listing of the rendered ruleset back to the desired zones",
+		listing: `{"nftables": [
+{"metainfo": {"version": "1.0.6", "release_name": "Lester Gooch #5", "json_schema_version": 1}},
+{"table": {"family": "inet", "name": "orbos", "handle": 3}},
+{"set": {"family": "inet", "name": "external_interfaces", "table": "orbos", "type": "ifname", "handle": 1, "elem": ["eth0"]}},
+{"set": {"family": "inet", "name": "external_sources", "table": "orbos", "type": "ipv4_addr", "handle": 2, "flags": ["interval"]}},
+{"set": {"family": "inet", "name": "external_tcp", "table": "orbos", "type": "inet_service", "handle": 3, "flags": ["interval"], "elem": [22, 6443, {"range": [30000, 32767]}]}},
+{"set": {"family": "inet", "name": "external_udp", "table": "orbos", "type": "inet_service", "handle": 4, "flags": ["interval"], "elem": [51820]}},
+{"set": {"family": "inet", "name": "internal_interfaces", "table": "orbos", "type": "ifname", "handle": 5}},
+{"set": {"family": "inet", "name": "internal_sources", "table": "orbos", "type": "ipv4_addr", "handle": 6, "flags": ["interval"], "elem": ["10.0.0.1", "10.0.0.2"]}},
+{"set": {"family": "inet", "name": "internal_sources6", "table": "orbos", "type": "ipv6_addr", "handle": 17, "flags": ["interval"], "elem": [{"prefix": {"addr": "fd00::", "len": 64}}]}},
+{"set": {"family": "inet", "name": "internal_tcp", "table": "orbos", "type": "inet_service", "handle": 7, "flags": ["interval"], "elem": [22]}},
+{"set": {"family": "inet", "name": "internal_udp", "table": "orbos", "type": "inet_service", "handle": 8, "flags": ["interval"]}},
+{"set": {"family": "inet", "name": "public_interfaces", "table": "orbos", "type": "ifname", "handle": 9}},
+{"set": {"family": "inet", "name": "public_sources", "table": "orbos", "type": "ipv4_addr", "handle": 10, "flags": ["interval"]}},
+{"set": {"family": "inet", "name": "public_tcp", "table": "orbos", "type": "inet_service", "handle": 11, "flags": ["interval"], "elem": [22]}},
+{"set": {"family": "inet", "name": "public_udp", "table": "orbos", "type": "inet_service", "handle": 12, "flags": ["interval"]}},
+{"chain": {"family": "inet", "table": "orbos", "name": "input", "handle": 13, "type": "filter", "hook": "input", "prio": 0, "policy": "accept"}},
+{"rule": {"family": "inet", "table": "orbos", "chain": "input", "handle": 14, "comment": "internal", "expr": [{"accept": null}]}},
+{"chain": {"family": "inet", "table": "orbos", "name": "postrouting", "handle": 15, "type": "nat", "hook": "postrouting", "prio": 100, "policy": "accept"}},
+{"rule": {"family": "inet", "table": "orbos", "chain": "postrouting", "handle": 16, "comment": "internal", "expr": [{"masquerade": null}]}}
+]}`,
+		want: desiredCurrent(testFirewall(), []string{"22"}),
+	}, {
+		name: "It should map prefixes",
+		listing: `{"nftables": [
+{"set": {"family": "inet", "name": "internal_sources", "table": "orbos", "type": "ipv4_addr", "handle": 6, "flags": ["interval"], "elem": [{"prefix": {"addr": "10.0.0.0", "len": 8}}]}}
+]}`,
+		want: common.FirewallCurrent{{Name: "internal", Sources: []string{"10.0.0.0/8"}}},
+	}, {
+		name: "It should map IPv6 addresses",
+		listing: `{"nftables": [
+{"set": {"family": "inet", "name": "internal_sources6", "table": "orbos", "type": "ipv6_addr", "handle": 6, "flags": ["interval"], "elem": ["fd00::1"]}}
+]}`,
+		want: common.FirewallCurrent{{Name: "internal", Sources: []string{"fd00::1/128"}}},
+	}, {
+		name:    "It should fail for unexpected elements",
+		listing: `{"nftables": [{"set": {"name": "internal_sources", "elem": [true]}}]}`,
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCurrent([]byte(tt.listing))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCurrent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.String() != tt.want.String() {
+				t.Errorf("parseCurrent() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package nftables
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/pkg/errors"
+)
+
+type listing struct {
+	Nftables []struct {
+		Set *struct {
+			Name string        `json:"name"`
+			Elem []interface{} `json:"elem"`
+		} `json:"set"`
+		Rule *struct {
+			Chain   string `json:"chain"`
+			Comment string `json:"comment"`
+		} `json:"rule"`
+		Chain *struct {
+			Name   string `json:"name"`
+			Policy string `json:"policy"`
+		} `json:"chain"`
+	} `json:"nftables"`
+}
+
+// parseInputPolicy returns the policy of the input chain, so tables rendered with other policies are replaced
+func parseInputPolicy(out []byte) (string, error) {
+	list := listing{}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return "", errors.Wrap(err, "parsing nftables listing failed")
+	}
+	for _, obj := range list.Nftables {
+		if obj.Chain != nil && obj.Chain.Name == "input" {
+			return obj.Chain.Policy, nil
+		}
+	}
+	return "", nil
+}
+
+// parseCurrent maps the JSON output of nft list table back to zones
+func parseCurrent(out []byte) (common.FirewallCurrent, error) {
+	list := listing{}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, errors.Wrap(err, "parsing nftables listing failed")
+	}
+
+	zonesByName := make(map[string]*common.ZoneDesc)
+	zone := func(name string) *common.ZoneDesc {
+		z, ok := zonesByName[name]
+		if !ok {
+			z = &common.ZoneDesc{
+				Name:       name,
+				Interfaces: common.MarshallableSlice{},
+				Sources:    common.MarshallableSlice{},
+				FW:         []*common.Allowed{},
+				Services:   []*common.Service{},
+			}
+			zonesByName[name] = z
+		}
+		return z
+	}
+
+	for _, obj := range list.Nftables {
+		if obj.Rule != nil && obj.Rule.Chain == "postrouting" && obj.Rule.Comment != "" {
+			zone(obj.Rule.Comment).Masquerade = true
+		}
+
+		if obj.Set == nil {
+			continue
+		}
+
+		sep := strings.LastIndex(obj.Set.Name, "_")
+		if sep < 0 {
+			continue
+		}
+		z := zone(obj.Set.Name[:sep])
+
+		for _, elem := range obj.Set.Elem {
+			value, err := element(elem)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing element of set %s failed", obj.Set.Name)
+			}
+
+			switch kind := obj.Set.Name[sep+1:]; kind {
+			case "interfaces":
+				z.Interfaces = append(z.Interfaces, value)
+			case "sources":
+				if !strings.Contains(value, "/") {
+					value += "/32"
+				}
+				z.Sources = append(z.Sources, value)
+			case "sources6":
+				if !strings.Contains(value, "/") {
+					value += "/128"
+				}
+				z.Sources = append(z.Sources, value)
+			case "tcp", "udp":
+				z.FW = append(z.FW, &common.Allowed{Port: value, Protocol: kind})
+			}
+		}
+	}
+
+	current := make(common.FirewallCurrent, 0, len(zonesByName))
+	for _, z := range zonesByName {
+		current = append(current, z)
+	}
+	current.Sort()
+	return current, nil
+}
+
+func element(elem interface{}) (string, error) {
+	switch value := elem.(type) {
+	case string:
+		return value, nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case map[string]interface{}:
+		if prefix, ok := value["prefix"].(map[string]interface{}); ok {
+			addr, err := element(prefix["addr"])
+			if err != nil {
+				return "", err
+			}
+			length, err := element(prefix["len"])
+			return addr + "/" + length, err
+		}
+		if rng, ok := value["range"].([]interface{}); ok && len(rng) == 2 {
+			from, err := element(rng[0])
+			if err != nil {
+				return "", err
+			}
+			to, err := element(rng[1])
+			return from + "-" + to, err
+		}
+	}
+	return "", fmt.Errorf("unexpected element %v", elem)
+}
@@ -6,9 +6,8 @@ import (
 	"fmt"
 	"io/ioutil"
 
-	"gopkg.in/yaml.v3"
-
 	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent/state"
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/git"
 )
@@ -17,11 +16,6 @@ type Rebooter interface {
 	Reboot() error
 }
 
-type event struct {
-	commit  string
-	current *common.NodeAgentCurrent
-}
-
 func RepoKey() ([]byte, error) {
 
 	path := "/var/orbiter/repo-key"
@@ -65,26 +59,17 @@ func Iterator(
 			return
 		}
 
-		if err := gitClient.Clone(); err != nil {
-			monitor.Error(err)
-			return
-		}
+		backend := state.Configured(monitor, gitClient)
 
-		desired := common.NodeAgentsDesiredKind{}
-		if err := yaml.Unmarshal(gitClient.Read("caos-internal/orbiter/node-agents-desired.yml"), &desired); err != nil {
+		naDesired, desiredCommit, err := backend.Desired(id)
+		if err != nil {
 			monitor.Error(err)
 			return
 		}
 
-		naDesired, ok := desired.Spec.NodeAgents.Get(id)
-		if !ok {
-			monitor.Error(fmt.Errorf("no desired state for node agent with id %s found", id))
-			return
-		}
-
-		if nodeAgentCommit != "debug" && desired.Spec.Commit != nodeAgentCommit {
+		if nodeAgentCommit != "debug" && desiredCommit != nodeAgentCommit {
 			monitor.WithFields(map[string]interface{}{
-				"desired": desired.Spec.Commit,
+				"desired": desiredCommit,
 				"current": nodeAgentCommit,
 			}).Info("Node Agent is on the wrong commit")
 			return
@@ -92,12 +77,12 @@ func Iterator(
 
 		curr := &common.NodeAgentCurrent{}
 
-		events := make([]*event, 0)
+		var events []*state.Report
 		monitor.OnChange = mntr.Concat(func(evt string, fields map[string]string) {
 			clone := *curr
-			events = append(events, &event{
-				commit:  mntr.CommitRecord(mntr.AggregateCommitFields(fields)),
-				current: &clone,
+			events = append(events, &state.Report{
+				Reason:  mntr.CommitRecord(mntr.AggregateCommitFields(fields)),
+				Current: &clone,
 			})
 		}, monitor.OnChange)
 
@@ -110,64 +95,36 @@ func Iterator(
 		}
 
 		ensure, err := QueryFuncGoroutine(query)*/
+		iterationMonitor, endIteration := monitor.WithField("node", id).StartSpan("node agent iteration")
+		_, endQuery := iterationMonitor.StartSpan("query node agent")
 		ensure, err := doQuery(*naDesired, curr)
+		endQuery(err)
 		if err != nil {
+			endIteration(err)
 			monitor.Error(err)
 			return
 
 		}
-		readCurrent := func() common.NodeAgentsCurrentKind {
-			if err := gitClient.Clone(); err != nil {
-				panic(err)
-			}
-			current := common.NodeAgentsCurrentKind{}
-			yaml.Unmarshal(gitClient.Read("caos-internal/orbiter/node-agents-current.yml"), &current)
-			current.Kind = "nodeagent.caos.ch/NodeAgents"
-			current.Version = "v0"
-			return current
-		}
-
-		current := readCurrent()
-		current.Current.Set(id, curr)
 
 		reconciledCurrentStateMsg := "Current state reconciled"
-		reconciledCurrent, err := gitClient.StageAndCommit(mntr.CommitRecord([]*mntr.Field{{Key: "evt", Value: reconciledCurrentStateMsg}}), git.File{
-			Path:    "caos-internal/orbiter/node-agents-current.yml",
-			Content: common.MarshalYAML(current),
-		})
-		if err != nil {
-			monitor.Error(fmt.Errorf("commiting event \"%s\" failed: %s", reconciledCurrentStateMsg, err.Error()))
-			return
-		}
-
-		if reconciledCurrent {
-			monitor.Error(gitClient.Push())
+		if err := backend.Report(id, &state.Report{
+			Reason:  mntr.CommitRecord([]*mntr.Field{{Key: "evt", Value: reconciledCurrentStateMsg}}),
+			Current: curr,
+		}); err != nil {
+			monitor.Error(fmt.Errorf("reporting event \"%s\" failed: %w", reconciledCurrentStateMsg, err))
 		}
 
-		if err := ensure(); err != nil {
+		_, endEnsure := iterationMonitor.StartSpan("ensure node agent")
+		err = ensure()
+		endEnsure(err)
+		endIteration(err)
+		if err != nil {
 			monitor.Error(err)
 			return
 		}
 
-		if events != nil && len(events) > 0 {
-			current := readCurrent()
-
-			for _, event := range events {
-				current.Current.Set(id, event.current)
-				changed, err := gitClient.StageAndCommit(event.commit, git.File{
-					Path:    "caos-internal/orbiter/node-agents-current.yml",
-					Content: common.MarshalYAML(current),
-				})
-				if err != nil {
-					monitor.Error(fmt.Errorf("commiting event \"%s\" failed: %s", event.commit, err.Error()))
-					return
-				}
-				if !changed {
-					monitor.Error(fmt.Errorf("event has no effect:", event.commit))
-					return
-				}
-			}
-			monitor.Error(gitClient.Push())
+		if err := backend.Report(id, events...); err != nil {
+			monitor.Error(fmt.Errorf("reporting events failed: %w", err))
 		}
 	}
 }
@@ -0,0 +1,59 @@
+package debian
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent"
+	"github.com/caos/orbos/internal/operator/nodeagent/networking/core"
+	"github.com/caos/orbos/mntr"
+	"github.com/pkg/errors"
+)
+
+// Ensurer persists the interfaces as systemd-networkd units.
+// On Ubuntu, netplan renders its configuration to systemd-networkd too, so both coexist
+func Ensurer(monitor mntr.Monitor) nodeagent.NetworkingEnsurer {
+	ensurer := core.Ensurer(monitor, getNetworkFiles)
+	return nodeagent.NetworkingEnsurerFunc(func(desired common.Networking) (common.NetworkingCurrent, func() error, error) {
+		current, ensure, err := ensurer.Query(desired)
+		if ensure == nil || err != nil {
+			return current, ensure, err
+		}
+		return current, func() error {
+			if err := ensure(); err != nil {
+				return err
+			}
+			return enableNetworkd(monitor)
+		}, nil
+	})
+}
+
+func enableNetworkd(monitor mntr.Monitor) error {
+	cmd := exec.Command("systemctl", "enable", "systemd-networkd")
+	if monitor.IsVerbose() {
+		fmt.Println(strings.Join(cmd.Args, " "))
+	}
+	out, err := cmd.CombinedOutput()
+	return errors.Wrapf(err, "enabling systemd-networkd failed with output %s", string(out))
+}
+
+func getNetworkFiles(name string, ty string, ips []string) map[string]string {
+	network := fmt.Sprintf(`[Match]
+Name=%s
+
+[Network]
+`, name)
+	for _, ip := range ips {
+		network += fmt.Sprintf("Address=%s\n", core.HostCIDR(ip))
+	}
+
+	return map[string]string{
+		"/etc/systemd/network/10-" + name + ".netdev": fmt.Sprintf(`[NetDev]
+Name=%s
+Kind=%s
+`, name, ty),
+		"/etc/systemd/network/10-" + name + ".network": network,
+	}
+}
@@ -4,13 +4,16 @@ import (
 	"github.com/caos/orbos/internal/operator/nodeagent"
 	"github.com/caos/orbos/internal/operator/nodeagent/dep"
 	"github.com/caos/orbos/internal/operator/nodeagent/networking/centos"
+	"github.com/caos/orbos/internal/operator/nodeagent/networking/debian"
 	"github.com/caos/orbos/mntr"
 )
 
-func Ensurer(monitor mntr.Monitor, os dep.OperatingSystem) nodeagent.NetworkingEnsurer {
-	switch os {
-	case dep.CentOS:
+func Ensurer(monitor mntr.Monitor, os dep.OperatingSystemMajor) nodeagent.NetworkingEnsurer {
+	switch {
+	case os.OperatingSystem.Packages == dep.REMBased:
 		return centos.Ensurer(monitor)
+	case os.OperatingSystem.Packages == dep.DebianBased && os != dep.Bionic:
+		return debian.Ensurer(monitor)
 	default:
 		return noopEnsurer()
 	}
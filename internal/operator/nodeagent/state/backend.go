@@ -0,0 +1,101 @@
+package state
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/git"
+)
+
+const (
+	DesiredPath = "caos-internal/orbiter/node-agents-desired.yml"
+	CurrentPath = "caos-internal/orbiter/node-agents-current.yml"
+	// URLPath is the file on a machine ORBITER writes its state endpoints address to
+	URLPath = "/var/orbiter/state-url"
+	// TokenPath is the file on a machine ORBITER writes the node agents token to
+	TokenPath = "/var/orbiter/state-token"
+)
+
+// Report is a node agents current state at the time a change happened
+type Report struct {
+	Reason  string
+	Current *common.NodeAgentCurrent
+}
+
+// Backend is the channel a node agent reads its desired state from and reports its current state to
+type Backend interface {
+	// Desired returns the node agents desired state and the commit the node agent is desired to run on
+	Desired(id string) (*common.NodeAgentSpec, string, error)
+	// Report persists the node agents current states in the given order
+	Report(id string, reports ...*Report) error
+}
+
+// NodeToken derives the secret a node agent authenticates with at ORBITERs state endpoints from a key only ORBITER knows.
+// As the token is bound to the node agents id, node agents can't exchange the states of other node agents
+func NodeToken(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id))
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+// Configured returns the HTTP backend if ORBITER wrote a state URL and a token to the machine.
+// As long as ORBITER doesn't serve the node agents states, for example while bootstrapping, git is used as fallback
+func Configured(monitor mntr.Monitor, gitClient *git.Client) Backend {
+	gitBackend := Git(gitClient)
+
+	url, err := ioutil.ReadFile(URLPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			monitor.WithField("error", err.Error()).Info("Reading state URL failed, falling back to git")
+		}
+		return gitBackend
+	}
+
+	trimmed := strings.TrimSpace(string(url))
+	if trimmed == "" {
+		return gitBackend
+	}
+
+	token, err := ioutil.ReadFile(TokenPath)
+	if err != nil || strings.TrimSpace(string(token)) == "" {
+		monitor.Info("No state token configured, falling back to git")
+		return gitBackend
+	}
+
+	return Fallback(monitor, HTTP(trimmed, strings.TrimSpace(string(token))), gitBackend)
+}
+
+type fallback struct {
+	monitor   mntr.Monitor
+	primary   Backend
+	secondary Backend
+}
+
+// Fallback uses the secondary backend whenever the primary backend fails
+func Fallback(monitor mntr.Monitor, primary, secondary Backend) Backend {
+	return &fallback{monitor: monitor, primary: primary, secondary: secondary}
+}
+
+func (f *fallback) Desired(id string) (*common.NodeAgentSpec, string, error) {
+	spec, commit, err := f.primary.Desired(id)
+	if err == nil {
+		return spec, commit, nil
+	}
+	f.monitor.WithField("error", err.Error()).Info("Reading desired state failed, falling back")
+	return f.secondary.Desired(id)
+}
+
+func (f *fallback) Report(id string, reports ...*Report) error {
+	err := f.primary.Report(id, reports...)
+	if err == nil {
+		return nil
+	}
+	f.monitor.WithField("error", err.Error()).Info("Reporting current state failed, falling back")
+	return f.secondary.Report(id, reports...)
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/pkg/git"
+)
+
+type gitBackend struct {
+	client *git.Client
+}
+
+// Git exchanges the states by committing them to the orbs repository. The client must already be configured
+func Git(client *git.Client) Backend {
+	return &gitBackend{client: client}
+}
+
+func (g *gitBackend) Desired(id string) (*common.NodeAgentSpec, string, error) {
+	if err := g.client.Clone(); err != nil {
+		return nil, "", err
+	}
+
+	desired := common.NodeAgentsDesiredKind{}
+	if err := yaml.Unmarshal(g.client.Read(DesiredPath), &desired); err != nil {
+		return nil, "", err
+	}
+
+	spec, ok := desired.Spec.NodeAgents.Get(id)
+	if !ok {
+		return nil, "", fmt.Errorf("no desired state for node agent with id %s found", id)
+	}
+	return spec, desired.Spec.Commit, nil
+}
+
+func (g *gitBackend) Report(id string, reports ...*Report) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	if err := g.client.Clone(); err != nil {
+		return err
+	}
+
+	current := common.NodeAgentsCurrentKind{}
+	yaml.Unmarshal(g.client.Read(CurrentPath), &current)
+	current.Kind = "nodeagent.caos.ch/NodeAgents"
+	current.Version = "v0"
+
+	var changed bool
+	for _, report := range reports {
+		current.Current.Set(id, report.Current)
+		committed, err := g.client.StageAndCommit(report.Reason, git.File{
+			Path:    CurrentPath,
+			Content: common.MarshalYAML(&current),
+		})
+		if err != nil {
+			return fmt.Errorf("commiting event \"%s\" failed: %w", report.Reason, err)
+		}
+		changed = changed || committed
+	}
+
+	if !changed {
+		return nil
+	}
+	return g.client.Push()
+}
@@ -0,0 +1,88 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/operator/common"
+)
+
+// Prefix is the path ORBITER serves the node agents states at
+const Prefix = "/nodeagents/"
+
+type desiredResponse struct {
+	Commit string
+	Spec   *common.NodeAgentSpec
+}
+
+type httpBackend struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// HTTP exchanges the states with ORBITERs state endpoints at the given URL
+func HTTP(url, token string) Backend {
+	return &httpBackend{
+		url:    strings.TrimSuffix(url, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (h *httpBackend) Desired(id string) (*common.NodeAgentSpec, string, error) {
+	body, err := h.do(http.MethodGet, id, "desired", nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	desired := desiredResponse{}
+	if err := yaml.Unmarshal(body, &desired); err != nil {
+		return nil, "", err
+	}
+
+	if desired.Spec == nil {
+		return nil, "", fmt.Errorf("no desired state for node agent with id %s found", id)
+	}
+	return desired.Spec, desired.Commit, nil
+}
+
+// Report only sends the latest report, as ORBITER is only interested in the most recent current state
+func (h *httpBackend) Report(id string, reports ...*Report) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	_, err := h.do(http.MethodPut, id, "current", common.MarshalYAML(reports[len(reports)-1].Current))
+	return err
+}
+
+func (h *httpBackend) do(method, id, resource string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%s%s%s/%s", h.url, Prefix, id, resource), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+h.token)
+	req.Header.Set("Content-Type", "application/yaml")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s returned status %d: %s", method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
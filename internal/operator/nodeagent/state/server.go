@@ -0,0 +1,149 @@
+package state
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/mntr"
+)
+
+// Addr is where ORBITER serves the node agents states, apart from its metrics and health endpoints
+const Addr = ":9001"
+
+// maxReportSize limits the size of a reported current state
+const maxReportSize = 1 << 20
+
+// Server is ORBITERs side of the HTTP backend.
+// It holds the node agents states in memory, so reporting current states doesn't result in commits
+type Server struct {
+	monitor mntr.Monitor
+	key     []byte
+	mux     sync.Mutex
+	desired map[string][]byte
+	current map[string]*common.NodeAgentCurrent
+}
+
+// NewServer returns a server which rejects all requests until a key is passed to UseKey
+func NewServer(monitor mntr.Monitor) *Server {
+	return &Server{
+		monitor: monitor,
+		current: make(map[string]*common.NodeAgentCurrent),
+	}
+}
+
+// UseKey makes the server accept the tokens derived from the key by NodeToken
+func (s *Server) UseKey(key []byte) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.key = key
+}
+
+// ListenAndServe serves the states on a dedicated mux
+func (s *Server) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(Prefix, s)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+// Publish makes the desired states available to the node agents.
+// Current states of node agents that are not desired anymore are forgotten
+func (s *Server) Publish(desired *common.NodeAgentsDesiredKind) {
+	marshalled := make(map[string][]byte)
+	for _, id := range desired.Spec.NodeAgents.List() {
+		spec, _ := desired.Spec.NodeAgents.Get(id)
+		marshalled[id] = common.MarshalYAML(&desiredResponse{
+			Commit: desired.Spec.Commit,
+			Spec:   spec,
+		})
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.desired = marshalled
+	for id := range s.current {
+		if _, ok := marshalled[id]; !ok {
+			delete(s.current, id)
+		}
+	}
+}
+
+// Current returns the current states the node agents reported since ORBITER started
+func (s *Server) Current() map[string]*common.NodeAgentCurrent {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	current := make(map[string]*common.NodeAgentCurrent, len(s.current))
+	for id, curr := range s.current {
+		current[id] = curr
+	}
+	return current
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, Prefix), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	id, resource := parts[0], parts[1]
+
+	// The token is only valid for the node agent it was derived for
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mux.Lock()
+	key := s.key
+	s.mux.Unlock()
+	if len(key) == 0 || subtle.ConstantTimeCompare([]byte(auth), []byte(NodeToken(key, id))) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case resource == "desired" && r.Method == http.MethodGet:
+		s.mux.Lock()
+		if s.desired == nil {
+			s.mux.Unlock()
+			// Node agents fall back to git until ORBITER published the first desired states
+			http.Error(w, "no desired states published yet", http.StatusServiceUnavailable)
+			return
+		}
+		desired, ok := s.desired[id]
+		s.mux.Unlock()
+		if !ok {
+			http.Error(w, "no desired state for node agent with id "+id+" found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(desired)
+	case resource == "current" && r.Method == http.MethodPut:
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxReportSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		current := &common.NodeAgentCurrent{}
+		if err := yaml.Unmarshal(body, current); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mux.Lock()
+		s.current[id] = current
+		s.mux.Unlock()
+		s.monitor.WithField("nodeagent", id).Debug("Current state reported")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,112 @@
+package state
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/mntr"
+)
+
+func TestServer(t *testing.T) {
+	key := []byte("statekey")
+	server := NewServer(mntr.Monitor{})
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	backend := HTTP(srv.URL, NodeToken(key, "a"))
+
+	desired := &common.NodeAgentsDesiredKind{Spec: common.NodeAgentsSpec{Commit: "abc"}}
+	desired.Spec.NodeAgents.NA = map[string]*common.NodeAgentSpec{"a": {ChangesAllowed: true}}
+	server.Publish(desired)
+
+	if _, _, err := backend.Desired("a"); err == nil {
+		t.Error("requests before a key is used should fail")
+	}
+
+	server.UseKey(key)
+	server.Publish(&common.NodeAgentsDesiredKind{})
+	if _, _, err := backend.Desired("a"); err == nil {
+		t.Error("reading the desired state of a node agent that is not published should fail")
+	}
+
+	server.Publish(desired)
+
+	spec, commit, err := backend.Desired("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit != "abc" || !spec.ChangesAllowed {
+		t.Errorf("Desired() = %+v, %s, want the published state", spec, commit)
+	}
+
+	if _, _, err := HTTP(srv.URL, NodeToken(key, "b")).Desired("b"); err == nil {
+		t.Error("reading the desired state of an unknown node agent should fail")
+	}
+
+	if err := backend.Report("a", &Report{Current: &common.NodeAgentCurrent{Commit: "old"}}, &Report{Current: &common.NodeAgentCurrent{Commit: "abc"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.Current()["a"]; got == nil || got.Commit != "abc" {
+		t.Errorf("Current() = %+v, want the latest report", got)
+	}
+
+	if _, _, err := HTTP(srv.URL, "wrong").Desired("a"); err == nil {
+		t.Error("requests with a wrong token should fail")
+	}
+
+	if err := HTTP(srv.URL, NodeToken(key, "b")).Report("a", &Report{Current: &common.NodeAgentCurrent{Commit: "forged"}}); err == nil {
+		t.Error("reporting the current state of another node agent should fail")
+	}
+	if got := server.Current()["a"]; got == nil || got.Commit != "abc" {
+		t.Errorf("Current() = %+v, want the state reported by the node agent itself", got)
+	}
+
+	if _, _, err := HTTP(srv.URL, NodeToken([]byte("other"), "a")).Desired("a"); err == nil {
+		t.Error("requests with a token derived from another key should fail")
+	}
+
+	server.Publish(&common.NodeAgentsDesiredKind{})
+	if _, ok := server.Current()["a"]; ok {
+		t.Error("current states of node agents that are not desired anymore should be forgotten")
+	}
+}
+
+type testBackend struct {
+	err      error
+	reported int
+}
+
+func (t *testBackend) Desired(string) (*common.NodeAgentSpec, string, error) {
+	return &common.NodeAgentSpec{}, "abc", t.err
+}
+
+func (t *testBackend) Report(_ string, reports ...*Report) error {
+	t.reported += len(reports)
+	return t.err
+}
+
+func TestFallback(t *testing.T) {
+	primary, secondary := &testBackend{err: errors.New("unreachable")}, &testBackend{}
+	backend := Fallback(mntr.Monitor{}, primary, secondary)
+
+	if _, commit, err := backend.Desired("a"); err != nil || commit != "abc" {
+		t.Errorf("Desired() = %s, %v, want the secondary backends desired state", commit, err)
+	}
+
+	if err := backend.Report("a", &Report{}, &Report{}); err != nil {
+		t.Fatal(err)
+	}
+	if secondary.reported != 2 {
+		t.Errorf("secondary backend got %d reports, want 2", secondary.reported)
+	}
+
+	primary.err = nil
+	if err := backend.Report("a", &Report{}); err != nil {
+		t.Fatal(err)
+	}
+	if secondary.reported != 2 {
+		t.Error("secondary backend should not be used when the primary backend works")
+	}
+}
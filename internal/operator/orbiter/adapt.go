@@ -1,6 +1,7 @@
 package orbiter
 
 import (
+	"github.com/caos/orbos/internal/operator/common"
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/secret"
 	"github.com/caos/orbos/pkg/tree"
@@ -19,3 +20,56 @@ type AdaptFunc func(
 	map[string]*secret.Secret,
 	error,
 )
+
+// TraceAdaptFunc traces the kind using a mntr.KindTrace.
+// Kinds that are adapted with the passed monitor are traced as children of the kind span
+func TraceAdaptFunc(id string, adapt AdaptFunc) AdaptFunc {
+	return func(monitor mntr.Monitor, finishedChan chan struct{}, desired *tree.Tree, current *tree.Tree) (QueryFunc, DestroyFunc, ConfigureFunc, bool, map[string]*secret.Secret, error) {
+
+		var kind string
+		if desired != nil && desired.Common != nil {
+			kind = desired.Common.Kind
+		}
+
+		trace := monitor.TraceKind(kind, id)
+		endAdapt := trace.Phase("adapt")
+		query, destroy, configure, migrate, secrets, err := adapt(trace.Monitor(), finishedChan, desired, current)
+		endAdapt(err)
+		if err != nil || query == nil {
+			trace.End(err)
+			return query, destroy, configure, migrate, secrets, err
+		}
+
+		if destroy != nil {
+			destroyKind := destroy
+			destroy = func(delegates map[string]interface{}) error {
+				endDestroy := trace.Phase("destroy")
+				err := destroyKind(delegates)
+				endDestroy(err)
+				trace.End(err)
+				return err
+			}
+		}
+
+		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, queried map[string]interface{}) (EnsureFunc, error) {
+			endQuery := trace.Phase("query")
+			ensure, err := query(nodeAgentsCurrent, nodeAgentsDesired, queried)
+			endQuery(err)
+			trace.End(err)
+			if err != nil || ensure == nil {
+				return ensure, err
+			}
+
+			return func(pdf func(monitor mntr.Monitor) error) *EnsureResult {
+				endEnsure := trace.Phase("ensure")
+				result := ensure(pdf)
+				var ensureErr error
+				if result != nil {
+					ensureErr = result.Err
+				}
+				endEnsure(ensureErr)
+				return result
+			}, nil
+		}, destroy, configure, migrate, secrets, nil
+	}
+}
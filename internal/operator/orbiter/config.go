@@ -2,6 +2,7 @@ package orbiter
 
 import (
 	"github.com/caos/orbos/internal/ingestion"
+	"github.com/caos/orbos/internal/operator/nodeagent/state"
 	"github.com/caos/orbos/pkg/git"
 	orbcfg "github.com/caos/orbos/pkg/orb"
 )
@@ -13,4 +14,11 @@ type Config struct {
 	FinishedChan  chan struct{}
 	PushEvents    func(events []*ingestion.EventRequest) error
 	OrbConfig     orbcfg.Orb
+	// NodeAgentStates is nil if ORBITER doesn't serve the node agents states
+	NodeAgentStates *state.Server
+}
+
+// StateKeyer is implemented by parsed desired states that hold the key the node agents tokens are derived from
+type StateKeyer interface {
+	StateKey() ([]byte, error)
 }
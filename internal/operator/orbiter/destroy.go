@@ -2,6 +2,7 @@ package orbiter
 
 import (
 	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent/state"
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/git"
 	"github.com/caos/orbos/pkg/tree"
@@ -32,13 +33,13 @@ func Destroy(monitor mntr.Monitor, gitClient *git.Client, adapt AdaptFunc, finis
 
 	monitor.OnChange = func(evt string, fields map[string]string) {
 		if err := gitClient.UpdateRemote(mntr.CommitRecord([]*mntr.Field{{Key: "evt", Value: evt}}), git.File{
-			Path:    "caos-internal/orbiter/current.yml",
+			Path:    CurrentPath,
 			Content: []byte(""),
 		}, git.File{
-			Path:    "caos-internal/orbiter/node-agents-current.yml",
+			Path:    state.CurrentPath,
 			Content: []byte(""),
 		}, git.File{
-			Path:    "caos-internal/orbiter/node-agents-desired.yml",
+			Path:    state.DesiredPath,
 			Content: []byte(""),
 		}, git.File{
 			Path:    "orbiter.yml",
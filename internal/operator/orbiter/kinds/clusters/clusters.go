@@ -35,7 +35,7 @@ func GetQueryAndDestroyFuncs(
 
 	switch clusterTree.Common.Kind {
 	case "orbiter.caos.ch/KubernetesCluster":
-		return kubernetes.AdaptFunc(
+		return orbiter.TraceAdaptFunc(clusterID, kubernetes.AdaptFunc(
 			labels.MustForAPI(operator, "KubernetesCluster", clusterTree.Common.Version),
 			clusterID,
 			oneoff,
@@ -51,7 +51,7 @@ func GetQueryAndDestroyFuncs(
 				monitor.Debug("Whitelist sent")
 			},
 			gitClient,
-		)(
+		))(
 			monitor.WithFields(map[string]interface{}{"cluster": clusterID}),
 			finishedChan,
 			clusterTree,
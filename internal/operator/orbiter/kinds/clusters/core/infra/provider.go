@@ -1,9 +1,10 @@
 package infra
 
 import (
-	"fmt"
 	"io"
+	"net"
 	"sort"
+	"strconv"
 )
 
 type Address struct {
@@ -13,7 +14,7 @@ type Address struct {
 }
 
 func (a Address) String() string {
-	return fmt.Sprintf("%s:%d", a.Location, a.FrontendPort)
+	return net.JoinHostPort(a.Location, strconv.Itoa(int(a.FrontendPort)))
 }
 
 type ProviderCurrent interface {
@@ -81,11 +81,20 @@ func AdaptFunc(
 			monitor = monitor.Verbose()
 		}
 
-		whitelist([]*orbiter.CIDR{&desiredKind.Spec.Networking.PodCidr})
+		podCidrs := desiredKind.Spec.podCidrs()
+		whitelisted := make([]*orbiter.CIDR, len(podCidrs))
+		for idx := range podCidrs {
+			whitelisted[idx] = &podCidrs[idx]
+		}
+		whitelist(whitelisted)
 
+		kubeconfig, err := desiredKind.Spec.Kubeconfig.Resolve()
+		if err != nil {
+			return nil, nil, nil, migrate, nil, err
+		}
 		var kc *string
-		if desiredKind.Spec.Kubeconfig != nil && desiredKind.Spec.Kubeconfig.Value != "" {
-			kc = &desiredKind.Spec.Kubeconfig.Value
+		if kubeconfig != "" {
+			kc = &kubeconfig
 		}
 		k8sClient, err := kubernetes.NewK8sClient(monitor, kc)
 		if err != nil {
@@ -136,13 +145,18 @@ func AdaptFunc(
 		}
 
 		currentKind := "orbiter.caos.ch/KubernetesCluster"
-		current := &CurrentCluster{}
+		current, err := carryOver(currentTree)
+		if err != nil {
+			monitor.Error(errors.Wrap(err, "starting with an empty current state"))
+			err = nil
+		}
 		currentTree.Parsed = &Current{
 			Common: tree.Common{
 				Kind:    currentKind,
 				Version: "v0",
 			},
 			Current: current,
+			client:  k8sClient,
 		}
 
 		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, providers map[string]interface{}) (orbiter.EnsureFunc, error) {
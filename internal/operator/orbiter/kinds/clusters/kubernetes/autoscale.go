@@ -0,0 +1,397 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/kubernetes"
+)
+
+type Autoscaling struct {
+	// Wait at least this duration after a pool was scaled before it is scaled again
+	//@default: 10m
+	Cooldown string `yaml:",omitempty"`
+	// Remove a node when the pods resource requests fit into the remaining nodes below this percentage of their allocatable resources
+	//@default: 50
+	ScaleDownUtilization int `yaml:",omitempty"`
+}
+
+func (a *Autoscaling) cooldown() time.Duration {
+	if a != nil && a.Cooldown != "" {
+		if cooldown, err := time.ParseDuration(a.Cooldown); err == nil {
+			return cooldown
+		}
+	}
+	return 10 * time.Minute
+}
+
+func (a *Autoscaling) scaleDownUtilization() int {
+	if a != nil && a.ScaleDownUtilization != 0 {
+		return a.ScaleDownUtilization
+	}
+	return 50
+}
+
+func (a *Autoscaling) validate() error {
+	if a.ScaleDownUtilization < 0 || a.ScaleDownUtilization > 100 {
+		return errors.Errorf("autoscaling scaleDownUtilization %d must be a percentage between 0 and 100", a.ScaleDownUtilization)
+	}
+	if a.Cooldown == "" {
+		return nil
+	}
+	_, err := time.ParseDuration(a.Cooldown)
+	return errors.Wrapf(err, "parsing autoscaling cooldown %s failed", a.Cooldown)
+}
+
+func (p *Pool) autoscaled() bool {
+	return p.MaxNodes > 0
+}
+
+func (p *Pool) validateAutoscaling() error {
+	if p.MinNodes < 0 || p.MaxNodes < 0 {
+		return errors.Errorf("pool %s must not have negative node bounds", p.Pool)
+	}
+	if p.MinNodes > 0 && !p.autoscaled() {
+		return errors.Errorf("pool %s has minNodes but no maxNodes", p.Pool)
+	}
+	if p.MinNodes > p.MaxNodes {
+		return errors.Errorf("pool %s has more minNodes %d than maxNodes %d", p.Pool, p.MinNodes, p.MaxNodes)
+	}
+	return nil
+}
+
+// AutoscaledPool is reported in the current state for each autoscaled pool.
+// It is carried over to the next iteration, which scales the pool to the decided number of nodes
+type AutoscaledPool struct {
+	Nodes      int
+	MinNodes   int
+	MaxNodes   int
+	LastScaled time.Time `yaml:",omitempty"`
+	LastEvent  string    `yaml:",omitempty"`
+}
+
+func poolKey(pool Pool) string {
+	return pool.Provider + "/" + pool.Pool
+}
+
+// poolLoad summarizes the pods that need a pools nodes
+type poolLoad struct {
+	// the pods couldn't be listed, so the pool is only kept within its bounds
+	unknown bool
+	// unschedulable pods that tolerate the pools taints
+	pending int
+	// ready nodes the requested and allocatable resources are summed up from
+	readyNodes             int
+	requestedMilliCPU      int64
+	allocatableMilliCPU    int64
+	requestedMemoryBytes   int64
+	allocatableMemoryBytes int64
+}
+
+// utilization returns the percentage of the allocatable resources of nodes ready nodes the pods would request
+func (p poolLoad) utilization(nodes int) int {
+	if p.readyNodes == 0 || nodes <= 0 {
+		return 100
+	}
+	utilization := 0
+	for _, res := range []struct{ requested, allocatable int64 }{
+		{p.requestedMilliCPU, p.allocatableMilliCPU},
+		{p.requestedMemoryBytes, p.allocatableMemoryBytes},
+	} {
+		if res.allocatable == 0 {
+			continue
+		}
+		perNode := res.allocatable / int64(p.readyNodes)
+		if perNode == 0 {
+			return 100
+		}
+		if u := int(res.requested * 100 / (perNode * int64(nodes))); u > utilization {
+			utilization = u
+		}
+	}
+	return utilization
+}
+
+// autoscale returns the number of nodes the pool should have.
+// The pool is scaled by at most one node per cooldown and only after it has converged to the previous decision
+func autoscale(pool Pool, machines int, remembered *AutoscaledPool, load poolLoad, autoscaling *Autoscaling, now time.Time) (nodes int, event string) {
+
+	nodes = machines
+	if remembered != nil {
+		nodes = remembered.Nodes
+	}
+
+	if nodes < pool.MinNodes {
+		return pool.MinNodes, fmt.Sprintf("Scaled up to the minimum of %d nodes", pool.MinNodes)
+	}
+	if nodes > pool.MaxNodes {
+		return pool.MaxNodes, fmt.Sprintf("Scaled down to the maximum of %d nodes", pool.MaxNodes)
+	}
+
+	if load.unknown || machines != nodes || remembered != nil && now.Before(remembered.LastScaled.Add(autoscaling.cooldown())) {
+		return nodes, ""
+	}
+
+	if load.pending > 0 {
+		if nodes < pool.MaxNodes {
+			return nodes + 1, fmt.Sprintf("Scaled up to %d nodes as %d pods are unschedulable", nodes+1, load.pending)
+		}
+		return nodes, ""
+	}
+
+	if nodes > pool.MinNodes && load.readyNodes == nodes {
+		if utilization := load.utilization(nodes - 1); utilization < autoscaling.scaleDownUtilization() {
+			return nodes - 1, fmt.Sprintf("Scaled down to %d nodes as the requested resources fit into %d%% of them", nodes-1, utilization)
+		}
+	}
+	return nodes, ""
+}
+
+// autoscaledNodes returns the number of nodes an autoscaled pool was scaled to within its bounds
+func autoscaledNodes(pool Pool, machines int, remembered *AutoscaledPool) int {
+	nodes := machines
+	if remembered != nil {
+		nodes = remembered.Nodes
+	}
+	if nodes < pool.MinNodes {
+		return pool.MinNodes
+	}
+	if nodes > pool.MaxNodes {
+		return pool.MaxNodes
+	}
+	return nodes
+}
+
+// pendingPods assigns each unschedulable pod to the first autoscaled worker pool with free capacity
+// whose nodes it could be scheduled to
+func pendingPods(pods []core.Pod, workers []*Pool, scaled map[string]*AutoscaledPool) map[string]int {
+	pending := make(map[string]int)
+
+pods:
+	for idx := range pods {
+		pod := &pods[idx]
+		if !unschedulable(pod) {
+			continue
+		}
+		for _, worker := range workers {
+			if !worker.autoscaled() {
+				continue
+			}
+			if remembered, ok := scaled[poolKey(*worker)]; ok && remembered.Nodes >= worker.MaxNodes {
+				continue
+			}
+			if tolerates(pod, worker.Taints.ToK8sTaints()) && selects(pod, worker.nodeLabels()) {
+				pending[poolKey(*worker)]++
+				continue pods
+			}
+		}
+	}
+	return pending
+}
+
+func unschedulable(pod *core.Pod) bool {
+	if pod.Status.Phase != core.PodPending || pod.Spec.NodeName != "" {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == core.PodScheduled && cond.Status == core.ConditionFalse && cond.Reason == core.PodReasonUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+func tolerates(pod *core.Pod, taints []core.Taint) bool {
+	for idx := range taints {
+		taint := &taints[idx]
+		if taint.Effect == core.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for _, toleration := range pod.Spec.Tolerations {
+			if toleration.ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeLabels returns the labels ORBITER keeps on the worker pools nodes
+func (p *Pool) nodeLabels() labels.Set {
+	nodeLabels := labels.Set{
+		"orbos.ch/pool": p.Pool,
+		"orbos.ch/tier": string(Workers),
+	}
+	for key, value := range p.Labels {
+		nodeLabels[key] = value
+	}
+	return nodeLabels
+}
+
+// selects returns true if the pods node selector and required node affinity match nodes with the labels.
+// Node fields are unknown for nodes that don't exist yet, so terms that match them are not considered
+func selects(pod *core.Pod, nodeLabels labels.Set) bool {
+	if !labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(nodeLabels) {
+		return false
+	}
+
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if len(term.MatchExpressions) == 0 || len(term.MatchFields) > 0 {
+			continue
+		}
+		selector, err := nodeSelector(term.MatchExpressions)
+		if err == nil && selector.Matches(nodeLabels) {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeSelector(requirements []core.NodeSelectorRequirement) (labels.Selector, error) {
+	selector := labels.NewSelector()
+	for _, requirement := range requirements {
+		var op selection.Operator
+		switch requirement.Operator {
+		case core.NodeSelectorOpIn:
+			op = selection.In
+		case core.NodeSelectorOpNotIn:
+			op = selection.NotIn
+		case core.NodeSelectorOpExists:
+			op = selection.Exists
+		case core.NodeSelectorOpDoesNotExist:
+			op = selection.DoesNotExist
+		case core.NodeSelectorOpGt:
+			op = selection.GreaterThan
+		case core.NodeSelectorOpLt:
+			op = selection.LessThan
+		default:
+			return nil, errors.Errorf("unknown node selector operator %s", requirement.Operator)
+		}
+		req, err := labels.NewRequirement(requirement.Key, op, requirement.Values)
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*req)
+	}
+	return selector, nil
+}
+
+// loadOf sums up the resources the pods bound to the machines ready nodes request
+func loadOf(machines []*initializedMachine, pods []core.Pod, pending int) poolLoad {
+	load := poolLoad{pending: pending}
+	nodes := make(map[string]bool)
+	for _, machine := range machines {
+		if machine.node == nil || !machine.currentMachine.Ready {
+			continue
+		}
+		nodes[machine.node.Name] = true
+		load.readyNodes++
+		load.allocatableMilliCPU += machine.node.Status.Allocatable.Cpu().MilliValue()
+		load.allocatableMemoryBytes += machine.node.Status.Allocatable.Memory().Value()
+	}
+
+	for idx := range pods {
+		pod := &pods[idx]
+		if !nodes[pod.Spec.NodeName] || pod.Status.Phase == core.PodSucceeded || pod.Status.Phase == core.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			load.requestedMilliCPU += container.Resources.Requests.Cpu().MilliValue()
+			load.requestedMemoryBytes += container.Resources.Requests.Memory().Value()
+		}
+	}
+	return load
+}
+
+// autoscaleWorkers decides how many nodes the autoscaled worker pools should have and reports the decisions in the current state.
+// The next iteration scales the pools accordingly. If the pods couldn't be listed, the pools are not scaled based on their load
+func autoscaleWorkers(monitor mntr.Monitor, curr *CurrentCluster, autoscaling *Autoscaling, k8s *kubernetes.Client, workers []*initializedPool, machines []*initializedMachine) {
+
+	var desired []*Pool
+	for _, pool := range workers {
+		if pool.desired.autoscaled() {
+			desired = append(desired, &pool.desired)
+		}
+	}
+	if len(desired) == 0 {
+		return
+	}
+
+	var (
+		podsListed bool
+		pods       []core.Pod
+		pending    map[string]int
+	)
+	if k8s != nil {
+		podList, err := k8s.ListPods("", nil)
+		if err != nil {
+			monitor.Error(fmt.Errorf("listing pods for autoscaling failed, skipping scaling decisions: %w", err))
+		} else {
+			podsListed = true
+			pods = podList.Items
+			pending = pendingPods(pods, desired, curr.Autoscaling)
+		}
+	}
+
+	now := time.Now()
+	for _, pool := range workers {
+		if !pool.desired.autoscaled() {
+			continue
+		}
+
+		var poolMachines []*initializedMachine
+		for _, machine := range machines {
+			if machine.pool == pool {
+				poolMachines = append(poolMachines, machine)
+			}
+		}
+
+		key := poolKey(pool.desired)
+		load := poolLoad{unknown: true}
+		if podsListed {
+			load = loadOf(poolMachines, pods, pending[key])
+		}
+
+		remembered := curr.Autoscaling[key]
+		nodes, event := autoscale(pool.desired, len(poolMachines), remembered, load, autoscaling, now)
+		scaled := &AutoscaledPool{
+			Nodes:    nodes,
+			MinNodes: pool.desired.MinNodes,
+			MaxNodes: pool.desired.MaxNodes,
+		}
+		if remembered != nil {
+			scaled.LastScaled = remembered.LastScaled
+			scaled.LastEvent = remembered.LastEvent
+		}
+		if event != "" {
+			scaled.LastScaled = now
+			scaled.LastEvent = event
+			monitor.WithFields(map[string]interface{}{
+				"pool":     pool.desired.Pool,
+				"provider": pool.desired.Provider,
+				"nodes":    nodes,
+			}).Changed(event)
+		}
+
+		if curr.Autoscaling == nil {
+			curr.Autoscaling = make(map[string]*AutoscaledPool)
+		}
+		curr.Autoscaling[key] = scaled
+	}
+}
@@ -0,0 +1,219 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	core "k8s.io/api/core/v1"
+)
+
+func TestAutoscale(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	pool := Pool{MinNodes: 1, MaxNodes: 3}
+	busy := poolLoad{readyNodes: 2, requestedMilliCPU: 3000, allocatableMilliCPU: 4000}
+	idle := poolLoad{readyNodes: 2, requestedMilliCPU: 500, allocatableMilliCPU: 4000}
+
+	tests := []struct {
+		name       string
+		machines   int
+		remembered *AutoscaledPool
+		load       poolLoad
+		wantNodes  int
+		wantEvent  bool
+	}{{
+		name:      "It should scale up to the minimum",
+		machines:  0,
+		wantNodes: 1,
+		wantEvent: true,
+	}, {
+		name:       "It should scale down to the maximum",
+		machines:   4,
+		remembered: &AutoscaledPool{Nodes: 4},
+		wantNodes:  3,
+		wantEvent:  true,
+	}, {
+		name:      "It should add a node for unschedulable pods",
+		machines:  2,
+		load:      poolLoad{pending: 2, readyNodes: 2},
+		wantNodes: 3,
+		wantEvent: true,
+	}, {
+		name:      "It should not exceed the maximum for unschedulable pods",
+		machines:  3,
+		load:      poolLoad{pending: 2, readyNodes: 3},
+		wantNodes: 3,
+	}, {
+		name:       "It should wait for the cooldown",
+		machines:   2,
+		remembered: &AutoscaledPool{Nodes: 2, LastScaled: now.Add(-time.Minute)},
+		load:       poolLoad{pending: 1, readyNodes: 2},
+		wantNodes:  2,
+	}, {
+		name:       "It should scale again after the cooldown",
+		machines:   2,
+		remembered: &AutoscaledPool{Nodes: 2, LastScaled: now.Add(-time.Hour)},
+		load:       poolLoad{pending: 1, readyNodes: 2},
+		wantNodes:  3,
+		wantEvent:  true,
+	}, {
+		name:       "It should wait until the pool converged",
+		machines:   2,
+		remembered: &AutoscaledPool{Nodes: 3},
+		load:       poolLoad{pending: 1, readyNodes: 2},
+		wantNodes:  3,
+	}, {
+		name:      "It should remove a node when the requests fit into the remaining ones",
+		machines:  2,
+		load:      idle,
+		wantNodes: 1,
+		wantEvent: true,
+	}, {
+		name:      "It should keep the nodes when the requests don't fit into the remaining ones",
+		machines:  2,
+		load:      busy,
+		wantNodes: 2,
+	}, {
+		name:      "It should keep the nodes while the pods are unknown",
+		machines:  2,
+		load:      poolLoad{unknown: true},
+		wantNodes: 2,
+	}, {
+		name:      "It should keep the minimum while the pods are unknown",
+		machines:  0,
+		load:      poolLoad{unknown: true},
+		wantNodes: 1,
+		wantEvent: true,
+	}, {
+		name:      "It should not scale down while nodes are not ready",
+		machines:  2,
+		load:      poolLoad{readyNodes: 1, requestedMilliCPU: 100, allocatableMilliCPU: 2000},
+		wantNodes: 2,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotNodes, gotEvent := autoscale(pool, tt.machines, tt.remembered, tt.load, nil, now)
+			if gotNodes != tt.wantNodes {
+				t.Errorf("autoscale() nodes = %d, want %d", gotNodes, tt.wantNodes)
+			}
+			if (gotEvent != "") != tt.wantEvent {
+				t.Errorf("autoscale() event = %q, want event %t", gotEvent, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestPendingPods(t *testing.T) {
+	taints := Taints([]Taint{{Key: "dedicated", Value: "gpu", Effect: core.TaintEffectNoSchedule}})
+	gpu := &Pool{Provider: "static", Pool: "gpu", MaxNodes: 2, Taints: &taints}
+	workers := &Pool{Provider: "static", Pool: "workers", MaxNodes: 5}
+	fixed := &Pool{Provider: "static", Pool: "fixed", Nodes: 3}
+	ssd := &Pool{Provider: "static", Pool: "ssd", MaxNodes: 2, Labels: map[string]string{"disk": "ssd"}}
+
+	unschedulablePod := func(tolerations ...core.Toleration) core.Pod {
+		return core.Pod{
+			Spec: core.PodSpec{Tolerations: tolerations},
+			Status: core.PodStatus{
+				Phase: core.PodPending,
+				Conditions: []core.PodCondition{{
+					Type:   core.PodScheduled,
+					Status: core.ConditionFalse,
+					Reason: core.PodReasonUnschedulable,
+				}},
+			},
+		}
+	}
+	withNodeSelector := func(pod core.Pod, selector map[string]string) core.Pod {
+		pod.Spec.NodeSelector = selector
+		return pod
+	}
+	withAffinity := func(pod core.Pod, requirements ...core.NodeSelectorRequirement) core.Pod {
+		pod.Spec.Affinity = &core.Affinity{NodeAffinity: &core.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &core.NodeSelector{
+				NodeSelectorTerms: []core.NodeSelectorTerm{{MatchExpressions: requirements}},
+			},
+		}}
+		return pod
+	}
+
+	pods := []core.Pod{
+		unschedulablePod(),
+		unschedulablePod(core.Toleration{Key: "dedicated", Operator: core.TolerationOpEqual, Value: "gpu", Effect: core.TaintEffectNoSchedule}),
+		{Status: core.PodStatus{Phase: core.PodRunning}},
+	}
+
+	tests := []struct {
+		name    string
+		pods    []core.Pod
+		workers []*Pool
+		scaled  map[string]*AutoscaledPool
+		want    map[string]int
+	}{{
+		name:    "It should assign pods to the first pool whose taints they tolerate",
+		pods:    pods,
+		workers: []*Pool{gpu, fixed, workers},
+		want:    map[string]int{"static/gpu": 1, "static/workers": 1},
+	}, {
+		name:    "It should not assign pods to pools that are not autoscaled",
+		pods:    pods,
+		workers: []*Pool{fixed},
+		want:    map[string]int{},
+	}, {
+		name:    "It should not assign pods to pools that are scaled to their maximum",
+		pods:    pods,
+		workers: []*Pool{workers},
+		scaled:  map[string]*AutoscaledPool{"static/workers": {Nodes: 5}},
+		want:    map[string]int{},
+	}, {
+		name: "It should not count pods that are pending for other reasons",
+		pods: []core.Pod{{Status: core.PodStatus{
+			Phase: core.PodPending,
+			Conditions: []core.PodCondition{{
+				Type:   core.PodScheduled,
+				Status: core.ConditionFalse,
+				Reason: "SchedulerError",
+			}},
+		}}},
+		workers: []*Pool{workers},
+		want:    map[string]int{},
+	}, {
+		name:    "It should assign pods to the pool whose labels match their node selector",
+		pods:    []core.Pod{withNodeSelector(unschedulablePod(), map[string]string{"disk": "ssd"})},
+		workers: []*Pool{workers, ssd},
+		want:    map[string]int{"static/ssd": 1},
+	}, {
+		name:    "It should match the pool label",
+		pods:    []core.Pod{withNodeSelector(unschedulablePod(), map[string]string{"orbos.ch/pool": "workers"})},
+		workers: []*Pool{ssd, workers},
+		want:    map[string]int{"static/workers": 1},
+	}, {
+		name: "It should assign pods to the pool whose labels match their required node affinity",
+		pods: []core.Pod{withAffinity(unschedulablePod(), core.NodeSelectorRequirement{
+			Key:      "disk",
+			Operator: core.NodeSelectorOpIn,
+			Values:   []string{"ssd", "nvme"},
+		})},
+		workers: []*Pool{workers, ssd},
+		want:    map[string]int{"static/ssd": 1},
+	}, {
+		name: "It should not assign pods whose required node affinity no pool matches",
+		pods: []core.Pod{withAffinity(unschedulablePod(), core.NodeSelectorRequirement{
+			Key:      "zone",
+			Operator: core.NodeSelectorOpExists,
+		})},
+		workers: []*Pool{workers, ssd},
+		want:    map[string]int{},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pendingPods(tt.pods, tt.workers, tt.scaled)
+			if len(got) != len(tt.want) {
+				t.Fatalf("pendingPods() = %v, want %v", got, tt.want)
+			}
+			for key, count := range tt.want {
+				if got[key] != count {
+					t.Errorf("pendingPods()[%s] = %d, want %d", key, got[key], count)
+				}
+			}
+		})
+	}
+}
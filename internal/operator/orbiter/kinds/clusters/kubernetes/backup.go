@@ -0,0 +1,177 @@
+package kubernetes
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/kubernetes/etcd"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/secret"
+)
+
+type Backup struct {
+	// How often ORBITER takes an etcd snapshot
+	//@default: 24h
+	Interval string `yaml:",omitempty"`
+	// How many snapshots are kept
+	//@default: 7
+	Keep int `yaml:",omitempty"`
+	// Store the snapshots in a directory on a machine
+	Local *BackupLocal `yaml:",omitempty"`
+	// Store the snapshots in an S3 compatible bucket
+	S3 *BackupS3 `yaml:",omitempty"`
+	// Key the snapshots are encrypted and authenticated with
+	EncryptionKey *secret.Secret `yaml:",omitempty"`
+}
+
+type BackupLocal struct {
+	// ID of the machine that stores the snapshots
+	//@default: the control plane machine taking the snapshot
+	Machine   string `yaml:",omitempty"`
+	Directory string
+}
+
+type BackupS3 struct {
+	// Leave empty for AWS S3
+	Endpoint        string `yaml:",omitempty"`
+	Region          string `yaml:",omitempty"`
+	Bucket          string
+	Prefix          string         `yaml:",omitempty"`
+	AccessKeyID     *secret.Secret `yaml:",omitempty"`
+	SecretAccessKey *secret.Secret `yaml:",omitempty"`
+}
+
+func (b *Backup) interval() time.Duration {
+	interval, err := time.ParseDuration(b.Interval)
+	if err != nil || b.Interval == "" {
+		return 24 * time.Hour
+	}
+	return interval
+}
+
+// SnapshotKey returns the key the snapshots are encrypted with
+func (b *Backup) SnapshotKey() (string, error) {
+	if !b.EncryptionKey.HasValue() {
+		return "", errors.New("no encryption key for etcd snapshots configured")
+	}
+	return b.EncryptionKey.Resolve()
+}
+
+func (b *Backup) keep() int {
+	if b.Keep <= 0 {
+		return 7
+	}
+	return b.Keep
+}
+
+func (b *Backup) validate() error {
+	if b.Interval != "" {
+		if _, err := time.ParseDuration(b.Interval); err != nil {
+			return errors.Wrapf(err, "parsing backup interval %s failed", b.Interval)
+		}
+	}
+	if (b.Local == nil) == (b.S3 == nil) {
+		return errors.New("exactly one backup target, either local or s3, must be configured")
+	}
+	if b.Local != nil && b.Local.Directory == "" {
+		return errors.New("no directory configured for the local backup target")
+	}
+	if b.S3 != nil && b.S3.Bucket == "" {
+		return errors.New("no bucket configured for the s3 backup target")
+	}
+	return nil
+}
+
+// BackupTarget returns the target configured in the backup spec.
+// The machine taking the snapshots stores them if no other machine is configured for a local target
+func BackupTarget(backup *Backup, snapshotting infra.Machine, machines map[string]infra.Machine) (etcd.Target, error) {
+	if backup.S3 != nil {
+		cfg := etcd.S3Config{
+			Endpoint: backup.S3.Endpoint,
+			Region:   backup.S3.Region,
+			Bucket:   backup.S3.Bucket,
+			Prefix:   backup.S3.Prefix,
+		}
+		var err error
+		if cfg.AccessKeyID, err = backup.S3.AccessKeyID.Resolve(); err != nil {
+			return nil, err
+		}
+		if cfg.SecretAccessKey, err = backup.S3.SecretAccessKey.Resolve(); err != nil {
+			return nil, err
+		}
+		return etcd.S3(cfg)
+	}
+
+	storing := snapshotting
+	if backup.Local.Machine != "" {
+		var ok bool
+		if storing, ok = machines[backup.Local.Machine]; !ok {
+			return nil, errors.Errorf("machine %s for storing etcd snapshots not found", backup.Local.Machine)
+		}
+	}
+	if storing == nil {
+		return nil, errors.New("no machine for storing etcd snapshots available")
+	}
+	return etcd.Local(storing, backup.Local.Directory), nil
+}
+
+// ensureBackup takes a snapshot from a joined control plane machine if the latest one is older than the configured interval
+func ensureBackup(monitor mntr.Monitor, clusterID string, backup *Backup, controlplaneMachines []*initializedMachine, allMachines []*initializedMachine) error {
+	if backup == nil {
+		return nil
+	}
+
+	var joined []*initializedMachine
+	for _, machine := range controlplaneMachines {
+		if machine.currentMachine.Joined && machine.currentMachine.Ready {
+			joined = append(joined, machine)
+		}
+	}
+	if len(joined) == 0 {
+		monitor.Info("No ready control plane machine for taking an etcd snapshot available")
+		return nil
+	}
+	sort.Sort(initializedMachines(joined))
+	snapshotting := joined[0].infra
+
+	machines := make(map[string]infra.Machine, len(allMachines))
+	for _, machine := range allMachines {
+		machines[machine.infra.ID()] = machine.infra
+	}
+
+	key, err := backup.SnapshotKey()
+	if err != nil {
+		return err
+	}
+
+	target, err := BackupTarget(backup, snapshotting, machines)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	due, err := etcd.Due(target, clusterID, backup.interval(), now)
+	if err != nil || !due {
+		return err
+	}
+
+	monitor, endSpan := monitor.WithField("machine", snapshotting.ID()).StartSpan("etcd snapshot")
+	defer func() { endSpan(err) }()
+
+	snapshot, err := etcd.Snapshot(snapshotting, key)
+	if err != nil {
+		return err
+	}
+
+	name := etcd.Name(clusterID, now)
+	if err = target.Put(name, snapshot); err != nil {
+		return errors.Wrapf(err, "storing etcd snapshot %s failed", name)
+	}
+	monitor.WithField("snapshot", name).Changed("Etcd snapshot taken")
+
+	err = etcd.Prune(target, clusterID, backup.keep())
+	return err
+}
@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// versionCatalog defines all supported Kubernetes minor releases, see versions.yaml
+//
+//go:embed versions.yaml
+var versionCatalog string
+
+type minorRelease struct {
+	Minor            int
+	LatestPatch      int
+	ContainerRuntime string
+}
+
+type release struct {
+	version string
+	minor   int
+	patch   int
+	runtime string
+}
+
+// releases is indexed by KubernetesVersion, so releases[Unknown] is the zero release
+var releases = mustParseCatalog(versionCatalog)
+
+func mustParseCatalog(catalog string) []release {
+	parsed, err := parseCatalog(catalog)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func parseCatalog(catalog string) ([]release, error) {
+	minors := make([]minorRelease, 0)
+	if err := yaml.Unmarshal([]byte(catalog), &minors); err != nil {
+		return nil, errors.Wrap(err, "parsing kubernetes version catalog failed")
+	}
+
+	sort.Slice(minors, func(i, j int) bool {
+		return minors[i].Minor < minors[j].Minor
+	})
+
+	parsed := []release{{version: "unknown"}}
+	for idx, minor := range minors {
+		if idx > 0 && minors[idx-1].Minor == minor.Minor {
+			return nil, errors.Errorf("kubernetes minor %d is defined multiple times in the version catalog", minor.Minor)
+		}
+		if minor.LatestPatch < 0 || len(strings.Fields(minor.ContainerRuntime)) != 2 {
+			return nil, errors.Errorf("kubernetes minor %d in the version catalog needs a latest patch and a container runtime in the form [runtime] [version]", minor.Minor)
+		}
+		for patch := 0; patch <= minor.LatestPatch; patch++ {
+			parsed = append(parsed, release{
+				version: fmt.Sprintf("v1.%d.%d", minor.Minor, patch),
+				minor:   minor.Minor,
+				patch:   patch,
+				runtime: minor.ContainerRuntime,
+			})
+		}
+	}
+	return parsed, nil
+}
+
+// AllowedVersions returns a human readable summary of all supported Kubernetes versions
+func AllowedVersions() string {
+	var ranges []string
+	for idx := 1; idx < len(releases); idx++ {
+		rel := releases[idx]
+		if rel.patch != 0 {
+			continue
+		}
+		last := rel
+		for next := idx + 1; next < len(releases) && releases[next].minor == rel.minor; next++ {
+			last = releases[next]
+		}
+		ranges = append(ranges, fmt.Sprintf("%s - %s", rel.version, last.version))
+	}
+	return strings.Join(ranges, ", ")
+}
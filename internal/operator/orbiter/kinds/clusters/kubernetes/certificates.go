@@ -0,0 +1,245 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/secret"
+)
+
+const (
+	certificatesCheckInterval = time.Hour
+	opensslTimeFormat         = "Jan _2 15:04:05 2006 MST"
+	listCertificatesCmd       = `sudo sh -c 'for crt in /etc/kubernetes/pki/*.crt /etc/kubernetes/pki/etcd/*.crt; do echo "${crt} $(openssl x509 -enddate -noout -in ${crt})"; done'`
+	// Etcd reloads its certificates by itself, so its member keeps running and the cluster keeps its quorum
+	renewCertificatesCmd = `sudo sh -c 'set -e
+kubeadm certs renew all || kubeadm alpha certs renew all
+mkdir -p /etc/kubernetes/orbos-restart
+for MANIFEST in kube-apiserver.yaml kube-controller-manager.yaml kube-scheduler.yaml; do
+  mv /etc/kubernetes/manifests/${MANIFEST} /etc/kubernetes/orbos-restart/
+done
+sleep 20
+mv /etc/kubernetes/orbos-restart/*.yaml /etc/kubernetes/manifests/'
+mkdir -p ${HOME}/.kube && yes | sudo cp -rf /etc/kubernetes/admin.conf ${HOME}/.kube/config && sudo chown $(id -u):$(id -g) ${HOME}/.kube/config`
+)
+
+var certificateExpiry = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "kubernetes_certificate_expiry_timestamp_seconds",
+		Help: "Expiry of the kubeadm issued certificates on control plane machines.",
+	},
+	[]string{"cluster", "machine", "certificate"},
+)
+
+func init() {
+	prometheus.MustRegister(certificateExpiry)
+}
+
+type Certificates struct {
+	// Renew the kubeadm issued certificates of a control plane machine when one of them expires within this duration
+	//@default: 720h
+	RenewBefore string `yaml:",omitempty"`
+	// Only track the expiry of the certificates
+	DisableRenewal bool `yaml:",omitempty"`
+}
+
+func (c *Certificates) renewBefore() time.Duration {
+	if c != nil && c.RenewBefore != "" {
+		if renewBefore, err := time.ParseDuration(c.RenewBefore); err == nil {
+			return renewBefore
+		}
+	}
+	return 30 * 24 * time.Hour
+}
+
+func (c *Certificates) validate() error {
+	if c.RenewBefore == "" {
+		return nil
+	}
+	_, err := time.ParseDuration(c.RenewBefore)
+	return errors.Wrapf(err, "parsing certificates renewBefore %s failed", c.RenewBefore)
+}
+
+type certificatesCheck struct {
+	clusterID string
+	checked   time.Time
+	expiry    map[string]time.Time
+}
+
+// checkedCertificates caches the expiry dates for certificatesCheckInterval,
+// as they only change when the certificates are renewed
+var checkedCertificates = struct {
+	sync.Mutex
+	machines map[string]*certificatesCheck
+}{machines: make(map[string]*certificatesCheck)}
+
+func certificatesExpiry(clusterID string, machine infra.Machine, now time.Time) (map[string]time.Time, error) {
+	checkedCertificates.Lock()
+	defer checkedCertificates.Unlock()
+
+	if check, ok := checkedCertificates.machines[machine.ID()]; ok && now.Before(check.checked.Add(certificatesCheckInterval)) {
+		return check.expiry, nil
+	}
+
+	out, err := machine.Execute(nil, listCertificatesCmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading certificates failed")
+	}
+
+	expiry, err := parseCertificatesExpiry(out)
+	if err != nil {
+		return nil, err
+	}
+	checkedCertificates.machines[machine.ID()] = &certificatesCheck{clusterID: clusterID, checked: now, expiry: expiry}
+	return expiry, nil
+}
+
+func forgetCertificates(machineID string) {
+	checkedCertificates.Lock()
+	defer checkedCertificates.Unlock()
+	delete(checkedCertificates.machines, machineID)
+}
+
+// forgetRemovedMachines deletes the cached expiry dates and the metrics of the machines that are no control plane machines of the cluster anymore
+func forgetRemovedMachines(clusterID string, machines []*initializedMachine) {
+	checkedCertificates.Lock()
+	defer checkedCertificates.Unlock()
+
+	existing := make(map[string]bool, len(machines))
+	for _, machine := range machines {
+		existing[machine.infra.ID()] = true
+	}
+
+	for id, check := range checkedCertificates.machines {
+		if check.clusterID != clusterID || existing[id] {
+			continue
+		}
+		for name := range check.expiry {
+			certificateExpiry.DeleteLabelValues(clusterID, id, name)
+		}
+		delete(checkedCertificates.machines, id)
+	}
+}
+
+// parseCertificatesExpiry parses lines like /etc/kubernetes/pki/etcd/peer.crt notAfter=Jan  1 00:00:00 2025 GMT
+func parseCertificatesExpiry(out []byte) (map[string]time.Time, error) {
+	expiry := make(map[string]time.Time)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		sep := strings.Index(line, " notAfter=")
+		if sep < 0 {
+			return nil, errors.Errorf("unexpected certificate expiry %s", line)
+		}
+		notAfter, err := time.Parse(opensslTimeFormat, strings.TrimSpace(line[sep+len(" notAfter="):]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing certificate expiry %s failed", line)
+		}
+		expiry[certificateName(line[:sep])] = notAfter
+	}
+	return expiry, scanner.Err()
+}
+
+func certificateName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".crt")
+	if filepath.Base(filepath.Dir(path)) == "etcd" {
+		name = "etcd-" + name
+	}
+	return name
+}
+
+// kubeadm certs renew doesn't renew certificate authorities
+func isCA(name string) bool {
+	return name == "ca" || name == "front-proxy-ca" || name == "etcd-ca"
+}
+
+// renewalDue returns true if a certificate that kubeadm renews expires before the deadline
+func renewalDue(expiry map[string]time.Time, deadline time.Time) bool {
+	for name, notAfter := range expiry {
+		if !isCA(name) && notAfter.Before(deadline) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureCertificates reports the certificates expiry of the joined control plane machines.
+// If renewal is not disabled, it renews the certificates on at most one machine per iteration
+func ensureCertificates(
+	monitor mntr.Monitor,
+	clusterID string,
+	desired *DesiredV0,
+	psf func(mntr.Monitor) error,
+	controlplaneMachines []*initializedMachine,
+) (done bool, err error) {
+
+	now := time.Now()
+	machines := append(initializedMachines{}, controlplaneMachines...)
+	sort.Sort(machines)
+	forgetRemovedMachines(clusterID, machines)
+
+	var renew *initializedMachine
+	for _, machine := range machines {
+		if !machine.currentMachine.Joined {
+			continue
+		}
+		expiry, checkErr := certificatesExpiry(clusterID, machine.infra, now)
+		if checkErr != nil {
+			// An unreachable machine must not block the iteration, like ensuring the backup doesn't
+			monitor.Error(errors.Wrapf(checkErr, "checking certificates of machine %s failed", machine.infra.ID()))
+			continue
+		}
+		machine.currentMachine.CertificatesExpiry = expiry
+		for name, notAfter := range expiry {
+			certificateExpiry.WithLabelValues(clusterID, machine.infra.ID(), name).Set(float64(notAfter.Unix()))
+		}
+		if renew == nil && renewalDue(expiry, now.Add(desired.Spec.Certificates.renewBefore())) {
+			renew = machine
+		}
+	}
+
+	if renew == nil {
+		return true, nil
+	}
+
+	machineMonitor := monitor.WithField("machine", renew.infra.ID())
+	if desired.Spec.Certificates != nil && desired.Spec.Certificates.DisableRenewal {
+		machineMonitor.Info("Certificates expire soon, but their renewal is disabled")
+		return true, nil
+	}
+
+	machineMonitor, endSpan := machineMonitor.StartSpan("renew certificates")
+	defer func() { endSpan(err) }()
+
+	if _, err = renew.infra.Execute(nil, renewCertificatesCmd); err != nil {
+		return false, errors.Wrapf(err, "renewing certificates of machine %s failed", renew.infra.ID())
+	}
+	forgetCertificates(renew.infra.ID())
+	machineMonitor.Changed("Certificates renewed")
+
+	// The admin client certificate is renewed too
+	kubeconfigBuf := new(bytes.Buffer)
+	defer kubeconfigBuf.Reset()
+	if err = renew.infra.ReadFile("${HOME}/.kube/config", kubeconfigBuf); err != nil {
+		return false, err
+	}
+	desired.Spec.Kubeconfig = &secret.Secret{Value: strings.ReplaceAll(kubeconfigBuf.String(), "kubernetes-admin", fmt.Sprintf("%s-admin", clusterID))}
+	err = psf(machineMonitor.WithFields(map[string]interface{}{
+		"type": "kubeconfig",
+	}))
+	return false, err
+}
@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseCertificatesExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    map[string]time.Time
+		wantErr bool
+	}{{
+		name: "It should name etcd certificates with an etcd prefix",
+		out: `/etc/kubernetes/pki/apiserver.crt notAfter=Jan  1 00:00:00 2025 GMT
+/etc/kubernetes/pki/etcd/peer.crt notAfter=Dec 24 12:30:00 2025 GMT
+
+`,
+		want: map[string]time.Time{
+			"apiserver": time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			"etcd-peer": time.Date(2025, 12, 24, 12, 30, 0, 0, time.UTC),
+		},
+	}, {
+		name:    "It should fail on lines without an expiry",
+		out:     "/etc/kubernetes/pki/apiserver.crt unable to load certificate",
+		wantErr: true,
+	}, {
+		name:    "It should fail on unparsable dates",
+		out:     "/etc/kubernetes/pki/apiserver.crt notAfter=tomorrow",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCertificatesExpiry([]byte(tt.out))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCertificatesExpiry() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseCertificatesExpiry() = %v, want %v", got, tt.want)
+			}
+			for name, notAfter := range tt.want {
+				if !got[name].Equal(notAfter) {
+					t.Errorf("parseCertificatesExpiry()[%s] = %s, want %s", name, got[name], notAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestRenewalDue(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	deadline := now.Add((&Certificates{}).renewBefore())
+
+	tests := []struct {
+		name   string
+		expiry map[string]time.Time
+		want   bool
+	}{{
+		name:   "It should not renew certificates expiring after the deadline",
+		expiry: map[string]time.Time{"apiserver": deadline.Add(time.Hour)},
+		want:   false,
+	}, {
+		name:   "It should renew certificates expiring before the deadline",
+		expiry: map[string]time.Time{"apiserver": deadline.Add(time.Hour), "etcd-peer": deadline.Add(-time.Hour)},
+		want:   true,
+	}, {
+		name:   "It should ignore certificate authorities",
+		expiry: map[string]time.Time{"ca": now, "front-proxy-ca": now, "etcd-ca": now},
+		want:   false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renewalDue(tt.expiry, deadline); got != tt.want {
+				t.Errorf("renewalDue() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+
+	if got := (&Certificates{RenewBefore: "48h"}).renewBefore(); got != 48*time.Hour {
+		t.Errorf("renewBefore() = %s, want 48h", got)
+	}
+}
+
+func TestForgetRemovedMachines(t *testing.T) {
+	now := time.Now()
+	for _, id := range []string{"kept", "removed"} {
+		checkedCertificates.machines[id] = &certificatesCheck{clusterID: "k8s", checked: now, expiry: map[string]time.Time{"apiserver": now}}
+		certificateExpiry.WithLabelValues("k8s", id, "apiserver").Set(float64(now.Unix()))
+	}
+	defer func() {
+		forgetRemovedMachines("k8s", nil)
+	}()
+
+	forgetRemovedMachines("k8s", []*initializedMachine{{infra: testMachine{id: "kept"}}})
+
+	if _, ok := checkedCertificates.machines["removed"]; ok {
+		t.Error("expected the certificates of the removed machine to be forgotten")
+	}
+	if _, ok := checkedCertificates.machines["kept"]; !ok {
+		t.Error("expected the certificates of the kept machine to be remembered")
+	}
+	if count := testutil.CollectAndCount(certificateExpiry); count != 1 {
+		t.Errorf("expected one certificate expiry metric, but got %d", count)
+	}
+}
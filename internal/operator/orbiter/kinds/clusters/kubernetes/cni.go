@@ -0,0 +1,460 @@
+package kubernetes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/executables"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/git"
+)
+
+const (
+	calicoVersion  = "v3.16.4"
+	ciliumVersion  = "v1.6.3"
+	hubbleVersion  = "v0.5.0"
+	flannelVersion = "v0.17.0"
+	cniConfigMap   = "orbos-cni"
+	kubectl        = "sudo kubectl --kubeconfig /etc/kubernetes/admin.conf"
+)
+
+// prebuiltManifest returns the manifest template from the networks directory
+var prebuiltManifest = executables.PreBuilt
+
+// CNI tunes the network plugin selected by the networks name
+type CNI struct {
+	// MTU of the pod network interfaces and tunnels
+	//@default: the plugins default
+	MTU     int      `yaml:",omitempty"`
+	Calico  *Calico  `yaml:",omitempty"`
+	Cilium  *Cilium  `yaml:",omitempty"`
+	Flannel *Flannel `yaml:",omitempty"`
+}
+
+type Calico struct {
+	// IPIP, IPIPCrossSubnet, VXLAN, VXLANCrossSubnet or None
+	//@default: IPIP
+	Encapsulation string `yaml:",omitempty"`
+	// Routers all nodes peer with
+	BGPPeers []*BGPPeer `yaml:",omitempty"`
+}
+
+type BGPPeer struct {
+	IP       orbiter.IPAddress
+	ASNumber uint32
+}
+
+type Cilium struct {
+	// vxlan, geneve or disabled
+	//@default: vxlan
+	Tunnel string `yaml:",omitempty"`
+	// Let Cilium handle services instead of kube-proxy, which is removed then
+	KubeProxyReplacement bool `yaml:",omitempty"`
+	// Deploy Hubble for observing the network flows
+	Hubble bool `yaml:",omitempty"`
+}
+
+type Flannel struct {
+	// vxlan or host-gw
+	//@default: vxlan
+	Backend string `yaml:",omitempty"`
+}
+
+func (c *CNI) validate(network string) error {
+
+	if c.MTU < 0 {
+		return errors.Errorf("cni mtu %d must not be negative", c.MTU)
+	}
+
+	for _, plugin := range []struct {
+		name       string
+		configured bool
+	}{
+		{name: "calico", configured: c.Calico != nil},
+		{name: "cilium", configured: c.Cilium != nil},
+		{name: "flannel", configured: c.Flannel != nil},
+	} {
+		if plugin.configured && network != plugin.name {
+			return errors.Errorf("cni is configured for %s, but network is %s", plugin.name, network)
+		}
+	}
+
+	if c.Calico != nil {
+		if _, _, _, err := c.Calico.modes(); err != nil {
+			return err
+		}
+		for _, peer := range c.Calico.BGPPeers {
+			if err := peer.IP.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.Cilium != nil {
+		switch c.Cilium.Tunnel {
+		case "", "vxlan", "geneve", "disabled":
+		default:
+			return errors.Errorf("unknown cilium tunnel %s, allowed are vxlan, geneve and disabled", c.Cilium.Tunnel)
+		}
+	}
+
+	if c.Flannel != nil {
+		switch c.Flannel.Backend {
+		case "", "vxlan", "host-gw":
+		default:
+			return errors.Errorf("unknown flannel backend %s, allowed are vxlan and host-gw", c.Flannel.Backend)
+		}
+	}
+
+	if network == "flannel" && c.MTU != 0 {
+		return errors.New("flannel derives the mtu from the host interface, so it can't be configured")
+	}
+	return nil
+}
+
+// modes returns the calico backend and the IPIP and VXLAN modes of the default IP pool
+func (c *Calico) modes() (backend string, ipip string, vxlan string, err error) {
+	encapsulation := "IPIP"
+	if c != nil && c.Encapsulation != "" {
+		encapsulation = c.Encapsulation
+	}
+	switch encapsulation {
+	case "IPIP":
+		return "bird", "Always", "Never", nil
+	case "IPIPCrossSubnet":
+		return "bird", "CrossSubnet", "Never", nil
+	case "VXLAN":
+		return "vxlan", "Never", "Always", nil
+	case "VXLANCrossSubnet":
+		return "bird", "Never", "CrossSubnet", nil
+	case "None":
+		return "bird", "Never", "Never", nil
+	}
+	return "", "", "", errors.Errorf("unknown calico encapsulation %s, allowed are IPIP, IPIPCrossSubnet, VXLAN, VXLANCrossSubnet and None", encapsulation)
+}
+
+func (s *Spec) cni() *CNI {
+	if s.Networking.CNI == nil {
+		return &CNI{}
+	}
+	return s.Networking.CNI
+}
+
+func (s *Spec) kubeProxyReplaced() bool {
+	cilium := s.cni().Cilium
+	return s.Networking.Network == "cilium" && cilium != nil && cilium.KubeProxyReplacement
+}
+
+// cniVersion identifies the plugin release the manifest is rendered from
+func (s *Spec) cniVersion() string {
+	switch s.Networking.Network {
+	case "calico":
+		return "calico " + calicoVersion
+	case "cilium":
+		return "cilium " + ciliumVersion
+	case "flannel":
+		return "flannel " + flannelVersion
+	}
+	return "file " + s.Networking.Network
+}
+
+// cniDaemonSet returns the name of the plugins daemon set and the image tag its first container runs.
+// Network files from the git repository are not known, so their daemon set is empty
+func (s *Spec) cniDaemonSet() (name string, tag string) {
+	switch s.Networking.Network {
+	case "calico":
+		return "calico-node", calicoVersion
+	case "cilium":
+		return "cilium", ciliumVersion
+	case "flannel":
+		return "kube-flannel-ds", flannelVersion
+	}
+	return "", ""
+}
+
+var (
+	yamlSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+	crdKind       = regexp.MustCompile(`(?m)^kind:[ \t]*CustomResourceDefinition[ \t]*$`)
+)
+
+// splitCRDs separates the custom resource definitions from the other documents of a manifest,
+// so they can be established before custom resources like calicos BGPPeers are applied
+func splitCRDs(manifest []byte) (crds []byte, others []byte) {
+	crdsBuf, othersBuf := new(bytes.Buffer), new(bytes.Buffer)
+	for _, doc := range yamlSeparator.Split(string(manifest), -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		buf := othersBuf
+		if crdKind.MatchString(doc) {
+			buf = crdsBuf
+		}
+		buf.WriteString("---\n")
+		buf.WriteString(strings.TrimLeft(doc, "\n"))
+		if !strings.HasSuffix(doc, "\n") {
+			buf.WriteString("\n")
+		}
+	}
+	return crdsBuf.Bytes(), othersBuf.Bytes()
+}
+
+// renderCNI returns the manifest of the network plugin or nil if no network is configured
+func renderCNI(spec Spec, gitClient *git.Client, kubeAPI *infra.Address) ([]byte, error) {
+
+	cni := spec.cni()
+	reg := spec.CustomImageRegistry
+	if reg != "" && !strings.HasSuffix(reg, "/") {
+		reg += "/"
+	}
+
+	buf := new(bytes.Buffer)
+	switch spec.Networking.Network {
+	case "":
+		return nil, nil
+	case "cilium":
+		ciliumReg := spec.CustomImageRegistry
+		if ciliumReg == "" {
+			ciliumReg = "docker.io"
+		}
+
+		cilium := cni.Cilium
+		if cilium == nil {
+			cilium = &Cilium{}
+		}
+		tunnel := cilium.Tunnel
+		if tunnel == "" {
+			tunnel = "vxlan"
+		}
+
+		data := struct {
+			IstioProxyImageRegistry string
+			CiliumImageRegistry     string
+			Version                 string
+			HubbleVersion           string
+			Tunnel                  string
+			MTU                     int
+			KubeProxyReplacement    bool
+			APIServerHost           string
+			APIServerPort           uint16
+		}{
+			IstioProxyImageRegistry: reg,
+			CiliumImageRegistry:     ciliumReg,
+			Version:                 ciliumVersion,
+			HubbleVersion:           hubbleVersion,
+			Tunnel:                  tunnel,
+			MTU:                     cni.MTU,
+			KubeProxyReplacement:    cilium.KubeProxyReplacement,
+			APIServerHost:           kubeAPI.Location,
+			APIServerPort:           kubeAPI.FrontendPort,
+		}
+		if err := executeManifest(buf, "cilium.yaml", data); err != nil {
+			return nil, err
+		}
+		if cilium.Hubble {
+			buf.WriteString("\n---\n\n")
+			if err := executeManifest(buf, "hubble.yaml", data); err != nil {
+				return nil, err
+			}
+		}
+	case "calico":
+		backend, ipip, vxlan, err := cni.Calico.modes()
+		if err != nil {
+			return nil, err
+		}
+		mtu := cni.MTU
+		if mtu == 0 {
+			mtu = 1440
+		}
+		var peers []*BGPPeer
+		if cni.Calico != nil {
+			peers = cni.Calico.BGPPeers
+		}
+		if err := executeManifest(buf, "calico.yaml", struct {
+			ImageRegistry string
+			Version       string
+			Backend       string
+			MTU           int
+			IPIPMode      string
+			VXLANMode     string
+			BGPPeers      []*BGPPeer
+		}{
+			ImageRegistry: reg,
+			Version:       calicoVersion,
+			Backend:       backend,
+			MTU:           mtu,
+			IPIPMode:      ipip,
+			VXLANMode:     vxlan,
+			BGPPeers:      peers,
+		}); err != nil {
+			return nil, err
+		}
+	case "flannel":
+		backend := "vxlan"
+		if cni.Flannel != nil && cni.Flannel.Backend != "" {
+			backend = cni.Flannel.Backend
+		}
+		var network, ipv6Network orbiter.CIDR
+		for _, cidr := range spec.podCidrs() {
+			if cidr.IPv6() {
+				ipv6Network = cidr
+				continue
+			}
+			network = cidr
+		}
+		if network == "" {
+			return nil, errors.New("flannel needs an IPv4 pod cidr")
+		}
+		if err := executeManifest(buf, "flannel.yaml", struct {
+			ImageRegistry string
+			Version       string
+			Network       orbiter.CIDR
+			IPv6Network   orbiter.CIDR
+			Backend       string
+		}{
+			ImageRegistry: reg,
+			Version:       flannelVersion,
+			Network:       network,
+			IPv6Network:   ipv6Network,
+			Backend:       backend,
+		}); err != nil {
+			return nil, err
+		}
+	default:
+		networkFile := gitClient.Read(spec.Networking.Network)
+		if len(networkFile) == 0 {
+			return nil, fmt.Errorf("network file %s is empty or not found in git repository", spec.Networking.Network)
+		}
+		return networkFile, nil
+	}
+	return buf.Bytes(), nil
+}
+
+func executeManifest(buf *bytes.Buffer, name string, data interface{}) error {
+	tmpl, err := template.New(name).Parse(string(prebuiltManifest(name)))
+	if err != nil {
+		return errors.Wrapf(err, "parsing manifest %s failed", name)
+	}
+	return errors.Wrapf(tmpl.Execute(buf, data), "rendering manifest %s failed", name)
+}
+
+// ensureCNI applies the network plugins manifest when it differs from the one applied before.
+// The checksum of the applied manifest is stored in a config map, so plugin upgrades and configuration changes are rolled out in place.
+// Clusters which were set up before the checksum was stored only get the checksum if they already run the desired plugin version
+func ensureCNI(
+	monitor mntr.Monitor,
+	desired *DesiredV0,
+	gitClient *git.Client,
+	kubeAPI *infra.Address,
+	controlplaneMachines []*initializedMachine,
+) (err error) {
+
+	manifest, err := renderCNI(desired.Spec, gitClient, kubeAPI)
+	if err != nil || manifest == nil {
+		return err
+	}
+
+	var ready []*initializedMachine
+	for _, machine := range controlplaneMachines {
+		if machine.currentMachine.Joined && machine.currentMachine.Ready {
+			ready = append(ready, machine)
+		}
+	}
+	if len(ready) == 0 {
+		return nil
+	}
+	sort.Sort(initializedMachines(ready))
+	applying := ready[0].infra
+
+	checksum := fmt.Sprintf("%x", sha256.Sum256(manifest))
+	cmd := fmt.Sprintf("%s --namespace kube-system get configmap %s --ignore-not-found --output jsonpath={.data.checksum}", kubectl, cniConfigMap)
+	applied, err := applying.Execute(nil, cmd)
+	if err != nil {
+		return errors.Wrapf(err, "executing %s failed", cmd)
+	}
+	if strings.TrimSpace(string(applied)) == checksum {
+		monitor.Debug("CNI is up to date")
+		return nil
+	}
+
+	monitor, endSpan := monitor.WithFields(map[string]interface{}{
+		"machine": applying.ID(),
+		"cni":     desired.Spec.cniVersion(),
+	}).StartSpan("apply cni")
+	defer func() { endSpan(err) }()
+
+	configMap := fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+  namespace: kube-system
+data:
+  version: %q
+  checksum: %q
+`, cniConfigMap, desired.Spec.cniVersion(), checksum)
+
+	if strings.TrimSpace(string(applied)) == "" {
+		running, err := runningCNI(applying, desired.Spec)
+		if err != nil {
+			return err
+		}
+		if running {
+			cmd = kubectl + " apply -f -"
+			if out, err := applying.Execute(strings.NewReader(configMap), cmd); err != nil {
+				return errors.Wrapf(err, "executing %s failed: %s", cmd, string(out))
+			}
+			monitor.Info("CNI already runs in the desired version, stored its checksum without applying it")
+			return nil
+		}
+	}
+
+	crds, others := splitCRDs(manifest)
+	if len(crds) > 0 {
+		cmd = kubectl + " apply -f -"
+		if out, err := applying.Execute(bytes.NewReader(crds), cmd); err != nil {
+			return errors.Wrapf(err, "executing %s failed: %s", cmd, string(out))
+		}
+		cmd = kubectl + " wait --for condition=established --timeout 60s -f -"
+		if out, err := applying.Execute(bytes.NewReader(crds), cmd); err != nil {
+			return errors.Wrapf(err, "executing %s failed: %s", cmd, string(out))
+		}
+	}
+
+	cmd = kubectl + " apply -f -"
+	if out, err := applying.Execute(concatYAML(bytes.NewReader(others), strings.NewReader(configMap)), cmd); err != nil {
+		return errors.Wrapf(err, "executing %s failed: %s", cmd, string(out))
+	}
+
+	if desired.Spec.kubeProxyReplaced() {
+		cmd = fmt.Sprintf("%[1]s --namespace kube-system delete daemonset kube-proxy --ignore-not-found && %[1]s --namespace kube-system delete configmap kube-proxy --ignore-not-found", kubectl)
+		if out, err := applying.Execute(nil, cmd); err != nil {
+			return errors.Wrapf(err, "executing %s failed: %s", cmd, string(out))
+		}
+	}
+
+	monitor.Changed("CNI applied")
+	return nil
+}
+
+// runningCNI returns true if the plugins daemon set already runs the desired version
+func runningCNI(machine infra.Machine, spec Spec) (bool, error) {
+	daemonSet, tag := spec.cniDaemonSet()
+	if daemonSet == "" {
+		return false, nil
+	}
+
+	cmd := fmt.Sprintf("%s --namespace kube-system get daemonset %s --ignore-not-found --output jsonpath={.spec.template.spec.containers[0].image}", kubectl, daemonSet)
+	image, err := machine.Execute(nil, cmd)
+	if err != nil {
+		return false, errors.Wrapf(err, "executing %s failed", cmd)
+	}
+	return strings.HasSuffix(strings.TrimSpace(string(image)), ":"+tag), nil
+}
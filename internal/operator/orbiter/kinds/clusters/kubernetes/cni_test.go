@@ -0,0 +1,184 @@
+package kubernetes
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+)
+
+func TestRenderCNI(t *testing.T) {
+	prebuiltManifest = func(name string) []byte {
+		manifest, err := ioutil.ReadFile(filepath.Join("networks", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return manifest
+	}
+
+	tests := []struct {
+		name        string
+		spec        func(*Spec)
+		contains    []string
+		notContains []string
+		wantErr     bool
+	}{{
+		name: "It should render calico with IPIP by default",
+		spec: func(s *Spec) { s.Networking.Network = "calico" },
+		contains: []string{
+			`calico_backend: "bird"`,
+			`veth_mtu: "1440"`,
+			"calico/node:" + calicoVersion,
+			"- -bird-live",
+		},
+		notContains: []string{"name: orbos-peer-0"},
+	}, {
+		name: "It should render calico with VXLAN and BGP peers",
+		spec: func(s *Spec) {
+			s.Networking.Network = "calico"
+			s.CustomImageRegistry = "registry.example.com"
+			s.Networking.CNI = &CNI{MTU: 1400, Calico: &Calico{
+				Encapsulation: "VXLAN",
+				BGPPeers:      []*BGPPeer{{IP: "10.0.0.254", ASNumber: 64512}},
+			}}
+		},
+		contains: []string{
+			`calico_backend: "vxlan"`,
+			`veth_mtu: "1400"`,
+			"image: registry.example.com/calico/node:" + calicoVersion,
+			"name: orbos-peer-0",
+			"peerIP: 10.0.0.254",
+			"asNumber: 64512",
+		},
+		notContains: []string{"- -bird-live", "- -bird-ready"},
+	}, {
+		name: "It should render cilium with hubble and without kube-proxy",
+		spec: func(s *Spec) {
+			s.Networking.Network = "cilium"
+			s.Networking.CNI = &CNI{Cilium: &Cilium{Tunnel: "geneve", KubeProxyReplacement: true, Hubble: true}}
+		},
+		contains: []string{
+			"tunnel: geneve",
+			`enable-node-port: "true"`,
+			`value: "10.0.0.1"`,
+			"docker.io/cilium/hubble:" + hubbleVersion,
+		},
+		notContains: []string{"mtu:"},
+	}, {
+		name: "It should render dual-stack flannel",
+		spec: func(s *Spec) {
+			s.Networking.Network = "flannel"
+			s.Networking.SecondaryPodCidr = "fd00:10:244::/56"
+			s.Networking.CNI = &CNI{Flannel: &Flannel{Backend: "host-gw"}}
+		},
+		contains: []string{
+			`"Network": "10.244.0.0/16"`,
+			`"IPv6Network": "fd00:10:244::/56"`,
+			`"Type": "host-gw"`,
+			"rancher/mirrored-flannelcni-flannel:" + flannelVersion,
+		},
+	}, {
+		name: "It should fail rendering flannel without IPv4 pod cidr",
+		spec: func(s *Spec) {
+			s.Networking.Network = "flannel"
+			s.Networking.PodCidr = "fd00:10:244::/56"
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := Spec{}
+			spec.Networking.PodCidr = "10.244.0.0/16"
+			spec.Networking.ServiceCidr = "10.96.0.0/12"
+			tt.spec(&spec)
+
+			manifest, err := renderCNI(spec, nil, &infra.Address{Location: "10.0.0.1", FrontendPort: 6443})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderCNI() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for _, want := range tt.contains {
+				if !bytes.Contains(manifest, []byte(want)) {
+					t.Errorf("manifest does not contain %q", want)
+				}
+			}
+			for _, unwanted := range tt.notContains {
+				if bytes.Contains(manifest, []byte(unwanted)) {
+					t.Errorf("manifest contains %q", unwanted)
+				}
+			}
+
+			decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+			for {
+				doc := map[string]interface{}{}
+				if err := decoder.Decode(&doc); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("manifest is not valid yaml: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestCNIValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		cni     CNI
+		wantErr string
+	}{
+		{name: "It should accept an empty config", network: "calico"},
+		{name: "It should reject configs for other plugins", network: "calico", cni: CNI{Cilium: &Cilium{}}, wantErr: "network is calico"},
+		{name: "It should reject unknown calico encapsulations", network: "calico", cni: CNI{Calico: &Calico{Encapsulation: "GRE"}}, wantErr: "unknown calico encapsulation"},
+		{name: "It should reject unknown cilium tunnels", network: "cilium", cni: CNI{Cilium: &Cilium{Tunnel: "gre"}}, wantErr: "unknown cilium tunnel"},
+		{name: "It should reject a flannel mtu", network: "flannel", cni: CNI{MTU: 1400}, wantErr: "flannel derives the mtu"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cni.validate(tt.network)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitCRDs(t *testing.T) {
+	manifest := []byte(`# Source: calico/templates/calico-config.yaml
+kind: ConfigMap
+metadata:
+  name: calico-config
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: bgppeers.crd.projectcalico.org
+spec:
+  names:
+    kind: BGPPeer
+---
+
+---
+apiVersion: crd.projectcalico.org/v1
+kind: BGPPeer
+metadata:
+  name: orbos-peer-0`)
+
+	crds, others := splitCRDs(manifest)
+	if strings.Count(string(crds), "---\n") != 1 || !strings.Contains(string(crds), "name: bgppeers.crd.projectcalico.org") {
+		t.Errorf("splitCRDs() crds = %s, want the bgppeers definition only", crds)
+	}
+	if strings.Count(string(others), "---\n") != 2 || strings.Contains(string(others), "CustomResourceDefinition") || !strings.Contains(string(others), "name: orbos-peer-0\n") {
+		t.Errorf("splitCRDs() others = %s, want the config map and the bgp peer", others)
+	}
+}
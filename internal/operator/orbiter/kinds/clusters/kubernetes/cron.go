@@ -0,0 +1,113 @@
+package kubernetes
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronSchedule matches times against a cron expression with the five fields minute, hour, day of month, month and day of week
+type cronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	domRestricted, dowRestricted  bool
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.Errorf("cron expression %s must have five fields", expr)
+	}
+
+	schedule := &cronSchedule{
+		domRestricted: fields[2] != "*",
+		dowRestricted: fields[4] != "*",
+	}
+	for _, field := range []struct {
+		values   *map[int]bool
+		min, max int
+	}{
+		{&schedule.minute, 0, 59},
+		{&schedule.hour, 0, 23},
+		{&schedule.dom, 1, 31},
+		{&schedule.month, 1, 12},
+		{&schedule.dow, 0, 7},
+	} {
+		values, err := parseCronField(fields[0], field.min, field.max)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing cron expression %s failed", expr)
+		}
+		*field.values = values
+		fields = fields[1:]
+	}
+	// Sunday is 0 or 7
+	if schedule.dow[7] {
+		schedule.dow[0] = true
+	}
+	return schedule, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[idx+1:]); err != nil || step < 1 {
+				return nil, errors.Errorf("invalid step in %s", part)
+			}
+			part = part[:idx]
+		}
+
+		from, to := min, max
+		switch idx := strings.Index(part, "-"); {
+		case part == "*":
+		case idx >= 0:
+			var err error
+			if from, err = strconv.Atoi(part[:idx]); err != nil {
+				return nil, errors.Errorf("invalid range %s", part)
+			}
+			if to, err = strconv.Atoi(part[idx+1:]); err != nil {
+				return nil, errors.Errorf("invalid range %s", part)
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, errors.Errorf("invalid value %s", part)
+			}
+			from, to = value, value
+		}
+
+		if from < min || to > max || from > to {
+			return nil, errors.Errorf("%s is not within %d and %d", part, min, max)
+		}
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// matches follows the cron convention that a time matches either restricted day field if both are restricted
+func (c *cronSchedule) matches(t time.Time) bool {
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	if c.domRestricted && c.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// startedWithin returns true if the schedule matched a minute within the duration before t
+func (c *cronSchedule) startedWithin(t time.Time, duration time.Duration) bool {
+	t = t.Truncate(time.Minute)
+	for start := t; t.Sub(start) < duration; start = start.Add(-time.Minute) {
+		if c.matches(start) {
+			return true
+		}
+	}
+	return false
+}
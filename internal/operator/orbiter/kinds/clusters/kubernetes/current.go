@@ -2,13 +2,67 @@ package kubernetes
 
 import (
 	"sync"
+	"time"
 
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/pkg/helper"
+	"github.com/caos/orbos/pkg/kubernetes"
 	"github.com/caos/orbos/pkg/tree"
 )
 
 type CurrentCluster struct {
 	Status   string
 	Machines Machines
+	// Autoscaling reports the node counts and the last scaling events of the autoscaled pools
+	Autoscaling map[string]*AutoscaledPool `yaml:",omitempty"`
+	// Rollouts reports the disrupted machines of the pools with rollout policies
+	Rollouts map[string]*PoolRollout `yaml:",omitempty"`
+	plan     func() (*orbiter.Plan, error)
+	previous map[string]*Machine
+}
+
+// carryOver starts the current state with the autoscaling decisions, the rollouts and the blocked drains of the previous iteration,
+// as the current state is built from scratch in each iteration but ORBITER decides based on them
+func carryOver(previousTree *tree.Tree) (*CurrentCluster, error) {
+	current := &CurrentCluster{}
+	if previousTree == nil || previousTree.Original == nil {
+		return current, nil
+	}
+
+	previous := &Current{}
+	if err := previousTree.Original.Decode(previous); err != nil {
+		return current, errors.Wrap(err, "parsing previous current state failed")
+	}
+	if previous.Current == nil {
+		return current, nil
+	}
+
+	current.Autoscaling = previous.Current.Autoscaling
+	current.Rollouts = previous.Current.Rollouts
+	current.previous = previous.Current.Machines.M
+	return current, nil
+}
+
+// forget removes the carried over states of pools that are not autoscaled or have no rollout policy anymore
+func (c *CurrentCluster) forget(desired DesiredV0) {
+	autoscaled := make(map[string]bool)
+	rolledOut := make(map[string]bool)
+	for _, pool := range append([]*Pool{&desired.Spec.ControlPlane}, desired.Spec.Workers...) {
+		autoscaled[poolKey(*pool)] = pool.autoscaled()
+		rolledOut[poolKey(*pool)] = pool.Rollout != nil
+	}
+	for key := range c.Autoscaling {
+		if !autoscaled[key] {
+			delete(c.Autoscaling, key)
+		}
+	}
+	for key := range c.Rollouts {
+		if !rolledOut[key] {
+			delete(c.Rollouts, key)
+		}
+	}
 }
 
 type Machines struct {
@@ -37,6 +91,15 @@ func (m *Machines) Set(id string, machine *Machine) {
 type Current struct {
 	Common  tree.Common `yaml:",inline"`
 	Current *CurrentCluster
+	client  kubernetes.ClientInt
+}
+
+// Client returns the client ORBITER manages the cluster with. It is nil if the cluster is not reachable
+func (c *Current) Client() kubernetes.ClientInt {
+	if helper.IsNil(c.client) {
+		return nil
+	}
+	return c.client
 }
 
 type Machine struct {
@@ -46,7 +109,11 @@ type Machine struct {
 	Ready           bool
 	FirewallIsReady bool
 	Unknown         bool
-	Metadata        MachineMetadata `yaml:",inline"`
+	// CertificatesExpiry is only reported for joined control plane machines
+	CertificatesExpiry map[string]time.Time `yaml:",omitempty"`
+	// DrainBlockedBy lists the pods that were still running when the last drain of the machine timed out
+	DrainBlockedBy []string        `yaml:",omitempty"`
+	Metadata       MachineMetadata `yaml:",inline"`
 }
 
 func (m *Machine) GetUpdating() bool {
@@ -83,3 +150,11 @@ const (
 	Controlplane Tier = "controlplane"
 	Workers      Tier = "workers"
 )
+
+// drainBlockedBy returns the pods that blocked the last drain of the machine
+func (c *CurrentCluster) drainBlockedBy(id string) []string {
+	if machine, ok := c.previous[id]; ok {
+		return machine.DrainBlockedBy
+	}
+	return nil
+}
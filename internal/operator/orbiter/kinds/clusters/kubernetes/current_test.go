@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+func TestCarryOver(t *testing.T) {
+	since := time.Date(2025, 1, 4, 3, 0, 0, 0, time.UTC)
+	previous := &CurrentCluster{
+		Status: "running",
+		Autoscaling: map[string]*AutoscaledPool{"static/workers": {
+			Nodes:      3,
+			MaxNodes:   5,
+			LastScaled: since,
+			LastEvent:  "Scaled up to 3 nodes as 1 pods are unschedulable",
+		}},
+		Rollouts: map[string]*PoolRollout{"static/workers": {
+			Disrupted: map[string]*Disruption{"machine-0": {Since: since, Unavailable: true}},
+			Failed:    []string{"machine-1"},
+			Recovered: since.Add(-time.Hour),
+		}},
+	}
+	previous.Machines.Set("machine-0", &Machine{DrainBlockedBy: []string{"default/web"}})
+
+	previousTree := &tree.Tree{}
+	if err := yaml.Unmarshal(common.MarshalYAML(&Current{
+		Common:  tree.Common{Kind: "orbiter.caos.ch/KubernetesCluster", Version: "v0"},
+		Current: previous,
+	}), previousTree); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := carryOver(previousTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Status != "" || len(current.Machines.M) != 0 {
+		t.Errorf("carryOver() must not carry over the observed state, got status %q and %d machines", current.Status, len(current.Machines.M))
+	}
+	if !reflect.DeepEqual(current.Autoscaling, previous.Autoscaling) {
+		t.Errorf("carryOver() autoscaling = %+v, want %+v", current.Autoscaling, previous.Autoscaling)
+	}
+	if !reflect.DeepEqual(current.Rollouts, previous.Rollouts) {
+		t.Errorf("carryOver() rollouts = %+v, want %+v", current.Rollouts, previous.Rollouts)
+	}
+	if got := current.drainBlockedBy("machine-0"); !reflect.DeepEqual(got, []string{"default/web"}) {
+		t.Errorf("drainBlockedBy() = %v, want [default/web]", got)
+	}
+}
+
+func TestCarryOverNothing(t *testing.T) {
+	current, err := carryOver(&tree.Tree{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Autoscaling != nil || current.Rollouts != nil || current.drainBlockedBy("machine-0") != nil {
+		t.Errorf("carryOver() = %+v, want an empty current state", current)
+	}
+}
@@ -2,12 +2,15 @@ package kubernetes
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	secret2 "github.com/caos/orbos/pkg/secret"
 
 	"github.com/caos/orbos/pkg/tree"
 	"github.com/pkg/errors"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/caos/orbos/internal/operator/orbiter"
 )
@@ -31,6 +34,12 @@ type Spec struct {
 		Network     string
 		ServiceCidr orbiter.CIDR
 		PodCidr     orbiter.CIDR
+		// Service CIDR of the other address family for dual-stack clusters
+		SecondaryServiceCidr orbiter.CIDR `yaml:",omitempty"`
+		// Pod CIDR of the other address family for dual-stack clusters
+		SecondaryPodCidr orbiter.CIDR `yaml:",omitempty"`
+		// Tunes the network plugin
+		CNI *CNI `yaml:",omitempty"`
 	}
 	Verbose  bool
 	Versions struct {
@@ -40,7 +49,66 @@ type Spec struct {
 	// Use this registry to pull all kubernetes and ORBITER container images from
 	//@default: ghcr.io
 	CustomImageRegistry string
-	Workers             []*Pool
+	// Configures containerd on versions which use it as container runtime
+	ContainerRuntime ContainerRuntime `yaml:",omitempty"`
+	Workers          []*Pool
+	// Periodically save encrypted etcd snapshots
+	Backup *Backup `yaml:",omitempty"`
+	// Configures the renewal of the kubeadm issued certificates
+	Certificates *Certificates `yaml:",omitempty"`
+	// Tunes the scaling of the worker pools that have maxNodes
+	Autoscaling *Autoscaling `yaml:",omitempty"`
+}
+
+type ContainerRuntime struct {
+	// Pull images from these endpoints instead of from the registry host, for example docker.io: [https://mirror.gcr.io]
+	RegistryMirrors map[string][]string `yaml:",omitempty"`
+	// The pods sandbox image
+	//@default: pause image from the CustomImageRegistry or from registry.k8s.io
+	SandboxImage string `yaml:",omitempty"`
+}
+
+func (s *Spec) containerRuntime() ContainerRuntime {
+	cri := s.ContainerRuntime
+	if cri.SandboxImage == "" {
+		registry := s.CustomImageRegistry
+		if registry == "" {
+			registry = "registry.k8s.io"
+		}
+		cri.SandboxImage = strings.TrimSuffix(registry, "/") + "/pause:3.9"
+	}
+	return cri
+}
+
+func (c ContainerRuntime) containerdConfig() string {
+	cfg := fmt.Sprintf(`version = 2
+
+[plugins."io.containerd.grpc.v1.cri"]
+  sandbox_image = "%s"
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+  runtime_type = "io.containerd.runc.v2"
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc.options]
+  SystemdCgroup = true
+`, c.SandboxImage)
+
+	hosts := make([]string, 0, len(c.RegistryMirrors))
+	for host := range c.RegistryMirrors {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		endpoints := make([]string, len(c.RegistryMirrors[host]))
+		for idx, endpoint := range c.RegistryMirrors[host] {
+			endpoints[idx] = fmt.Sprintf("%q", endpoint)
+		}
+		cfg += fmt.Sprintf(`
+[plugins."io.containerd.grpc.v1.cri".registry.mirrors.%q]
+  endpoint = [%s]
+`, host, strings.Join(endpoints, ", "))
+	}
+	return cfg
 }
 
 func parseDesiredV0(desiredTree *tree.Tree) (*DesiredV0, error) {
@@ -62,17 +130,67 @@ func (d *DesiredV0) validate() error {
 	}
 
 	if ParseString(d.Spec.Versions.Kubernetes) == Unknown {
-		return errors.Errorf("Unknown kubernetes version %s", d.Spec.Versions.Kubernetes)
+		return errors.Errorf("Unknown kubernetes version %s, allowed versions are %s", d.Spec.Versions.Kubernetes, AllowedVersions())
+	}
+
+	if err := orbiter.ValidateDualStack(d.Spec.serviceCidrs()...); err != nil {
+		return err
 	}
 
-	if err := d.Spec.Networking.ServiceCidr.Validate(); err != nil {
+	if err := orbiter.ValidateDualStack(d.Spec.podCidrs()...); err != nil {
 		return err
 	}
 
-	if err := d.Spec.Networking.PodCidr.Validate(); err != nil {
+	if d.Spec.dualStack() {
+		if (d.Spec.Networking.SecondaryServiceCidr == "") != (d.Spec.Networking.SecondaryPodCidr == "") {
+			return errors.New("dual-stack clusters need both a secondary service CIDR and a secondary pod CIDR")
+		}
+		if releases[ParseString(d.Spec.Versions.Kubernetes)].minor < 21 {
+			return errors.Errorf("dual-stack clusters need at least kubernetes v1.21, but desired is %s", d.Spec.Versions.Kubernetes)
+		}
+	}
+
+	if d.Spec.Networking.CNI != nil {
+		if err := d.Spec.Networking.CNI.validate(d.Spec.Networking.Network); err != nil {
+			return err
+		}
+	}
+
+	if d.Spec.Certificates != nil {
+		if err := d.Spec.Certificates.validate(); err != nil {
+			return err
+		}
+	}
+
+	if d.Spec.Backup != nil {
+		if err := d.Spec.Backup.validate(); err != nil {
+			return err
+		}
+	}
+
+	if d.Spec.Autoscaling != nil {
+		if err := d.Spec.Autoscaling.validate(); err != nil {
+			return err
+		}
+	}
+
+	if d.Spec.ControlPlane.MinNodes != 0 || d.Spec.ControlPlane.MaxNodes != 0 {
+		return errors.New("Controlplane nodes can't be autoscaled")
+	}
+
+	if err := d.Spec.ControlPlane.validate(); err != nil {
 		return err
 	}
 
+	for _, worker := range d.Spec.Workers {
+		if err := worker.validateAutoscaling(); err != nil {
+			return err
+		}
+		if err := worker.validate(); err != nil {
+			return err
+		}
+	}
+
 	seenPools := map[string][]string{
 		d.Spec.ControlPlane.Provider: {d.Spec.ControlPlane.Pool},
 	}
@@ -98,8 +216,58 @@ type Pool struct {
 	UpdatesDisabled bool
 	Provider        string
 	Nodes           int
-	Pool            string
-	Taints          *Taints `yaml:"taints,omitempty"`
+	// Let ORBITER scale the worker pool between minNodes and maxNodes depending on unschedulable pods and requested resources instead of to nodes
+	MinNodes int `yaml:",omitempty"`
+	MaxNodes int `yaml:",omitempty"`
+	Pool     string
+	Taints   *Taints `yaml:"taints,omitempty"`
+	// Labels ORBITER keeps on the pools nodes
+	Labels map[string]string `yaml:",omitempty"`
+	// Annotations ORBITER keeps on the pools nodes
+	Annotations map[string]string `yaml:",omitempty"`
+	// Configures the kubelets of the pool
+	Kubelet *Kubelet `yaml:",omitempty"`
+	// Limits when and how many machines are disrupted for upgrades, reboots, replacements and kubelet changes
+	Rollout *Rollout `yaml:",omitempty"`
+	// Configures how the pools nodes are drained
+	Drain *Drain `yaml:",omitempty"`
+}
+
+func (p *Pool) validate() error {
+	for key, value := range p.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return errors.Errorf("invalid label key %s in pool %s: %s", key, p.Pool, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return errors.Errorf("invalid value %s of label %s in pool %s: %s", value, key, p.Pool, strings.Join(errs, ", "))
+		}
+	}
+	for key := range p.Annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return errors.Errorf("invalid annotation key %s in pool %s: %s", key, p.Pool, strings.Join(errs, ", "))
+		}
+	}
+	for _, metadata := range []map[string]string{p.Labels, p.Annotations} {
+		for key := range metadata {
+			if strings.HasPrefix(key, "orbos.ch/") {
+				return errors.Errorf("the key %s in pool %s is reserved for ORBITER", key, p.Pool)
+			}
+		}
+	}
+	if p.Drain != nil {
+		if err := p.Drain.validate(); err != nil {
+			return err
+		}
+	}
+	if p.Rollout != nil {
+		if err := p.Rollout.validate(); err != nil {
+			return err
+		}
+	}
+	if p.Kubelet != nil {
+		return p.Kubelet.validate()
+	}
+	return nil
 }
 
 type Taint struct {
@@ -124,3 +292,32 @@ func (t *Taints) ToK8sTaints() []core.Taint {
 	}
 	return taints
 }
+
+func (s *Spec) serviceCidrs() []orbiter.CIDR {
+	return cidrs(s.Networking.ServiceCidr, s.Networking.SecondaryServiceCidr)
+}
+
+func (s *Spec) podCidrs() []orbiter.CIDR {
+	return cidrs(s.Networking.PodCidr, s.Networking.SecondaryPodCidr)
+}
+
+func (s *Spec) dualStack() bool {
+	return s.Networking.SecondaryServiceCidr != "" || s.Networking.SecondaryPodCidr != ""
+}
+
+// ipv6 returns true if pods or services get IPv6 addresses
+func (s *Spec) ipv6() bool {
+	for _, cidr := range append(s.serviceCidrs(), s.podCidrs()...) {
+		if cidr.IPv6() {
+			return true
+		}
+	}
+	return false
+}
+
+func cidrs(primary, secondary orbiter.CIDR) []orbiter.CIDR {
+	if secondary == "" {
+		return []orbiter.CIDR{primary}
+	}
+	return []orbiter.CIDR{primary, secondary}
+}
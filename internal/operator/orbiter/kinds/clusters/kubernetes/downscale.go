@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/kubernetes"
@@ -10,64 +11,88 @@ import (
 	macherrs "k8s.io/apimachinery/pkg/api/errors"
 )
 
-func scaleDown(pools []*initializedPool, k8sClient *kubernetes.Client, uninitializeMachine uninitializeMachineFunc, monitor mntr.Monitor, pdf func(mntr.Monitor) error) error {
+// scaleDown removes the downscaled machines. Machines that are replaced are only removed when the pools rollout policy allows disrupting them
+func scaleDown(pools []*initializedPool, machines []*initializedMachine, k8sClient *kubernetes.Client, uninitializeMachine uninitializeMachineFunc, monitor mntr.Monitor, pdf func(mntr.Monitor) error) error {
 	for _, pool := range pools {
 		for _, machine := range pool.downscaling {
-			id := machine.infra.ID()
-			var existingK8sNode *v1.Node
-			if k8sClient != nil {
-				foundK8sNode, err := k8sClient.GetNode(id)
-				if macherrs.IsNotFound(err) {
-					err = nil
-				} else {
-					existingK8sNode = foundK8sNode
-				}
-				if err != nil {
-					return fmt.Errorf("getting node %s from kube api failed: %w", id, err)
-				}
+			if pool.replacing && !mayDisrupt(monitor, machine, machines, time.Now()) {
+				continue
 			}
-
-			if existingK8sNode != nil {
-				if err := k8sClient.Drain(machine.currentMachine, existingK8sNode, kubernetes.Deleting); err != nil {
-					return err
-				}
+			if err := scaleDownMachine(machine, k8sClient, uninitializeMachine, monitor, pdf); err != nil {
+				return err
 			}
+		}
+	}
 
-			monitor.Info("Resetting kubeadm")
-			if _, resetErr := machine.infra.Execute(nil, "sudo kubeadm reset --force"); resetErr != nil {
-				if !strings.Contains(resetErr.Error(), "command not found") {
-					return resetErr
-				}
-			}
+	return nil
+}
 
-			if existingK8sNode != nil {
-				if err := k8sClient.DeleteNode(id); err != nil {
-				}
-			}
+func scaleDownMachine(machine *initializedMachine, k8sClient *kubernetes.Client, uninitializeMachine uninitializeMachineFunc, monitor mntr.Monitor, pdf func(mntr.Monitor) error) (err error) {
 
-			if !machine.currentMachine.GetUpdating() || machine.currentMachine.GetJoined() {
-				machine.currentMachine.SetUpdating(true)
-				machine.currentMachine.SetJoined(false)
-				monitor.Changed("Node deleted")
-			}
+	monitor, endSpan := monitor.WithFields(map[string]interface{}{
+		"machine": machine.infra.ID(),
+		"tier":    machine.pool.tier,
+	}).StartSpan("remove machine " + machine.infra.ID())
+	defer func() { endSpan(err) }()
 
-			uninitializeMachine(id)
-			if req, _, unreq := machine.infra.ReplacementRequired(); req {
-				unreq()
-				pdf(monitor.WithFields(map[string]interface{}{
-					"reason":   "unrequire machine replacement",
-					"replaced": id,
-				}))
-			}
-			if err := machine.infra.Remove(); err != nil {
-				return err
-			}
-			monitor.WithFields(map[string]interface{}{
-				"machine": id,
-				"tier":    machine.pool.tier,
-			}).Changed("Machine removed")
+	id := machine.infra.ID()
+	var existingK8sNode *v1.Node
+	if k8sClient != nil {
+		foundK8sNode, err := k8sClient.GetNode(id)
+		if macherrs.IsNotFound(err) {
+			err = nil
+		} else {
+			existingK8sNode = foundK8sNode
+		}
+		if err != nil {
+			return fmt.Errorf("getting node %s from kube api failed: %w", id, err)
+		}
+	}
+
+	if existingK8sNode != nil {
+		drained, err := drainMachine(k8sClient, machine, existingK8sNode, kubernetes.Deleting)
+		if err != nil {
+			return err
+		}
+		if !drained {
+			monitor.Info("Not removing machine as pods block the drain")
+			return nil
+		}
+	}
+
+	monitor.Info("Resetting kubeadm")
+	resetCmd := "sudo kubeadm reset --force"
+	if socket := criSocket(machine.currentNodeagent.Software.Containerruntime); socket != "" {
+		resetCmd += " --cri-socket " + socket
+	}
+	if _, resetErr := machine.infra.Execute(nil, resetCmd); resetErr != nil {
+		if !strings.Contains(resetErr.Error(), "command not found") {
+			return resetErr
 		}
 	}
 
+	if existingK8sNode != nil {
+		if err := k8sClient.DeleteNode(id); err != nil {
+		}
+	}
+
+	if !machine.currentMachine.GetUpdating() || machine.currentMachine.GetJoined() {
+		machine.currentMachine.SetUpdating(true)
+		machine.currentMachine.SetJoined(false)
+		monitor.Changed("Node deleted")
+	}
+
+	uninitializeMachine(id)
+	if req, _, unreq := machine.infra.ReplacementRequired(); req {
+		unreq()
+		pdf(monitor.WithFields(map[string]interface{}{
+			"reason":   "unrequire machine replacement",
+			"replaced": id,
+		}))
+	}
+	if err := machine.infra.Remove(); err != nil {
+		return err
+	}
+	monitor.Changed("Machine removed")
 	return nil
 }
@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/caos/orbos/pkg/kubernetes"
+)
+
+var errDrainSkipped = errors.New("drain skipped as pods block it")
+
+// Drain configures how the nodes of a pool are drained before they are upgraded, rebooted or removed
+type Drain struct {
+	// Stop evicting the pods of a node after this duration
+	//@default: 5m
+	Timeout string `yaml:",omitempty"`
+	// What to do when pods, for example because of PodDisruptionBudgets, are still running after the timeout.
+	// force deletes the pods, wait retries the drain in the next iteration and skip continues with other nodes
+	//@default: force
+	OnBlocked string `yaml:",omitempty"`
+}
+
+func (d *Drain) validate() error {
+	switch kubernetes.BlockedDrainPolicy(d.OnBlocked) {
+	case "", kubernetes.WaitOnBlockedDrain, kubernetes.SkipOnBlockedDrain, kubernetes.ForceOnBlockedDrain:
+	default:
+		return errors.Errorf("unknown drain onBlocked policy %s, allowed are wait, skip and force", d.OnBlocked)
+	}
+	if d.Timeout == "" {
+		return nil
+	}
+	_, err := time.ParseDuration(d.Timeout)
+	return errors.Wrapf(err, "parsing drain timeout %s failed", d.Timeout)
+}
+
+func (d *Drain) options() kubernetes.DrainOptions {
+	if d == nil {
+		return kubernetes.DrainOptions{}
+	}
+	timeout, _ := time.ParseDuration(d.Timeout)
+	return kubernetes.DrainOptions{
+		Timeout:   timeout,
+		OnBlocked: kubernetes.BlockedDrainPolicy(d.OnBlocked),
+	}
+}
+
+// drainMachine drains the machines node with the pools drain options and reports the pods that blocked it.
+// It returns false if the pods blocked the drain and the pools policy is to skip the node
+func drainMachine(k8sClient *kubernetes.Client, machine *initializedMachine, node *v1.Node, reason kubernetes.DrainReason) (drained bool, err error) {
+	report, err := k8sClient.Drain(machine.currentMachine, node, reason, machine.pool.desired.Drain.options())
+
+	var blocking []string
+	for _, pod := range report.Blocking {
+		blocking = append(blocking, pod.String())
+	}
+	machine.currentMachine.DrainBlockedBy = blocking
+
+	if err != nil {
+		return false, err
+	}
+	return !report.Skipped, nil
+}
@@ -0,0 +1,47 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caos/orbos/pkg/kubernetes"
+)
+
+func TestDrainOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		drain   *Drain
+		want    kubernetes.DrainOptions
+		wantErr bool
+	}{{
+		name: "It should use the clients defaults without configuration",
+		want: kubernetes.DrainOptions{},
+	}, {
+		name:  "It should pass the timeout and policy",
+		drain: &Drain{Timeout: "90s", OnBlocked: "skip"},
+		want:  kubernetes.DrainOptions{Timeout: 90 * time.Second, OnBlocked: kubernetes.SkipOnBlockedDrain},
+	}, {
+		name:    "It should fail on unknown policies",
+		drain:   &Drain{OnBlocked: "ignore"},
+		wantErr: true,
+	}, {
+		name:    "It should fail on invalid timeouts",
+		drain:   &Drain{Timeout: "forever"},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.drain != nil {
+				if err := tt.drain.validate(); (err != nil) != tt.wantErr {
+					t.Fatalf("validate() error = %v, wantErr %t", err, tt.wantErr)
+				}
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tt.drain.options(); got != tt.want {
+				t.Errorf("options() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/git"
 	"github.com/caos/orbos/pkg/kubernetes"
+	"github.com/pkg/errors"
 )
 
 func ensure(
 	monitor mntr.Monitor,
 	clusterID string,
 	desired *DesiredV0,
+	current *CurrentCluster,
 	kubeAPIAddress *infra.Address,
 	pdf func(mntr.Monitor) error,
 	k8sClient *kubernetes.Client,
@@ -30,7 +32,9 @@ func ensure(
 		desireFW(machine)
 	}
 
-	if err := scaleDown(append(workers, controlplane), k8sClient, uninitializeMachine, monitor, pdf); err != nil {
+	autoscaleWorkers(monitor, current, desired.Spec.Autoscaling, k8sClient, workers, workerMachines)
+
+	if err := scaleDown(append(workers, controlplane), append(controlplaneMachines, workerMachines...), k8sClient, uninitializeMachine, monitor, pdf); err != nil {
 		return false, err
 	}
 
@@ -44,6 +48,7 @@ func ensure(
 	upgradingDone, err := ensureSoftware(
 		monitor,
 		targetVersion,
+		desired.Spec.containerRuntime(),
 		k8sClient,
 		controlplaneMachines,
 		workerMachines)
@@ -66,7 +71,7 @@ func ensure(
 		oneoff,
 		func(created infra.Machine, pool *initializedPool) initializedMachine {
 			machine := initializeMachine(created, pool)
-			target := targetVersion.DefineSoftware()
+			target := targetVersion.DefineSoftware(desired.Spec.containerRuntime())
 			machine.desiredNodeagent.Software.Merge(target)
 			return *machine
 		},
@@ -76,5 +81,26 @@ func ensure(
 	if !scalingDone {
 		monitor.Info("Scaling is not done yet")
 	}
-	return scalingDone, err
+	if err != nil || !scalingDone {
+		return scalingDone, err
+	}
+
+	if err := ensureCNI(monitor, desired, gitClient, kubeAPIAddress, controlplaneMachines); err != nil {
+		return false, err
+	}
+
+	kubeletDone, err := ensureKubelet(monitor, append(controlplaneMachines, workerMachines...), pdf)
+	if err != nil || !kubeletDone {
+		return kubeletDone, err
+	}
+
+	certificatesDone, err := ensureCertificates(monitor, clusterID, desired, pdf, controlplaneMachines)
+	if err != nil || !certificatesDone {
+		return certificatesDone, err
+	}
+
+	if backupErr := ensureBackup(monitor, clusterID, desired.Spec.Backup, controlplaneMachines, append(controlplaneMachines, workerMachines...)); backupErr != nil {
+		monitor.Error(errors.Wrap(backupErr, "ensuring etcd backup failed"))
+	}
+	return true, nil
 }
@@ -0,0 +1,77 @@
+package etcd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const saltSize = 16
+
+// encrypt returns the scrypt salt, the GCM nonce and the data encrypted and authenticated with AES-256-GCM,
+// keyed with a scrypt derivation of the snapshot key
+func encrypt(key string, plain []byte) ([]byte, error) {
+	if key == "" {
+		return nil, errors.New("snapshot key must not be empty")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := snapshotCipher(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, 0, saltSize+len(nonce)+len(plain)+gcm.Overhead())
+	sealed = append(sealed, salt...)
+	sealed = append(sealed, nonce...)
+	return gcm.Seal(sealed, nonce, plain, nil), nil
+}
+
+// decrypt fails if the snapshot key is wrong or the data was modified
+func decrypt(key string, sealed []byte) ([]byte, error) {
+	if len(sealed) < saltSize {
+		return nil, errors.New("snapshot is too short")
+	}
+
+	gcm, err := snapshotCipher(key, sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	sealed = sealed[saltSize:]
+	if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("snapshot is too short")
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("decryption failed, the snapshot key is wrong or the snapshot was modified")
+	}
+	return plain, nil
+}
+
+func snapshotCipher(key string, salt []byte) (cipher.AEAD, error) {
+	derived, err := scrypt.Key([]byte(key), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,26 @@
+package etcd
+
+import "testing"
+
+func TestEncryptDecrypt(t *testing.T) {
+	encrypted, err := encrypt("snapshotkey", []byte("snapshot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, err := decrypt("snapshotkey", encrypted); err != nil || string(decrypted) != "snapshot" {
+		t.Errorf("decrypt() = %s, %v, want snapshot", decrypted, err)
+	}
+
+	if _, err := decrypt("wrong snapshotkey", encrypted); err == nil {
+		t.Error("decrypt() with a wrong key should fail")
+	}
+
+	encrypted[len(encrypted)-1] ^= 1
+	if _, err := decrypt("snapshotkey", encrypted); err == nil {
+		t.Error("decrypt() of modified data should fail")
+	}
+
+	if _, err := encrypt("", []byte("snapshot")); err == nil {
+		t.Error("encrypt() with an empty key should fail")
+	}
+}
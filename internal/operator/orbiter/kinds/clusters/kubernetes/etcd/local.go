@@ -0,0 +1,45 @@
+package etcd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+)
+
+type local struct {
+	machine   infra.Machine
+	directory string
+}
+
+// Local stores the snapshots in a directory on a machine
+func Local(machine infra.Machine, directory string) Target {
+	return &local{machine: machine, directory: directory}
+}
+
+func (l *local) List() ([]string, error) {
+	out, err := l.machine.Execute(nil, fmt.Sprintf("sudo mkdir -p %s && sudo ls -1 %s", l.directory, l.directory))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+func (l *local) Put(name string, snapshot []byte) error {
+	return l.machine.WriteFile(filepath.Join(l.directory, name), bytes.NewReader(snapshot), 600)
+}
+
+func (l *local) Get(name string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := l.machine.ReadFile(filepath.Join(l.directory, name), buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (l *local) Delete(name string) error {
+	_, err := l.machine.Execute(nil, "sudo rm -f "+filepath.Join(l.directory, name))
+	return err
+}
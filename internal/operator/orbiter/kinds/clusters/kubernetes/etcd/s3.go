@@ -0,0 +1,113 @@
+package etcd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+)
+
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+type s3Target struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+// S3 stores the snapshots in an S3 compatible bucket
+func S3(cfg S3Config) (Target, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("no bucket configured")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	awsCfg := &aws.Config{
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(cfg.Endpoint != ""),
+	}
+	if cfg.Endpoint != "" {
+		awsCfg.Endpoint = aws.String(cfg.Endpoint)
+	}
+	if cfg.AccessKeyID != "" || cfg.SecretAccessKey != "" {
+		awsCfg.Credentials = credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	}
+
+	sess, err := session.NewSession(awsCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating s3 session failed")
+	}
+
+	return &s3Target{
+		client: s3.New(sess),
+		bucket: cfg.Bucket,
+		prefix: strings.Trim(cfg.Prefix, "/"),
+	}, nil
+}
+
+func (s *s3Target) key(name string) string {
+	return path.Join(s.prefix, name)
+}
+
+func (s *s3Target) List() ([]string, error) {
+	var names []string
+	prefix := s.prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+	return names, s.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range page.Contents {
+			names = append(names, strings.TrimPrefix(aws.StringValue(obj.Key), prefix))
+		}
+		return true
+	})
+}
+
+func (s *s3Target) Put(name string, snapshot []byte) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+		Body:   bytes.NewReader(snapshot),
+	})
+	return err
+}
+
+func (s *s3Target) Get(name string) ([]byte, error) {
+	obj, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+	return ioutil.ReadAll(obj.Body)
+}
+
+func (s *s3Target) Delete(name string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(name)),
+	})
+	return err
+}
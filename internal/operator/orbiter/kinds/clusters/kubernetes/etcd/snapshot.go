@@ -0,0 +1,93 @@
+package etcd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/pkg/errors"
+)
+
+const (
+	// The kubeadm etcd pod mounts the data directory, so snapshots saved there are readable from the host
+	snapshotPath = "/var/lib/etcd/orbos-snapshot.db"
+	restorePath  = "/var/lib/orbos-restore/snapshot.db"
+	etcdctlFlags = "--endpoints https://127.0.0.1:2379 --cacert /etc/kubernetes/pki/etcd/ca.crt --cert /etc/kubernetes/pki/etcd/server.crt --key /etc/kubernetes/pki/etcd/server.key"
+)
+
+// Snapshot saves a snapshot of the etcd member running on the control plane machine and returns it encrypted and authenticated with the key.
+// The key is independent from the master key, so rotating the master key doesn't make existing snapshots unrestorable
+func Snapshot(machine infra.Machine, key string) ([]byte, error) {
+
+	if _, err := machine.Execute(nil, fmt.Sprintf(
+		"sudo kubectl --kubeconfig /etc/kubernetes/admin.conf --namespace kube-system exec etcd-%s -- etcdctl %s snapshot save %s",
+		machine.ID(),
+		etcdctlFlags,
+		snapshotPath,
+	)); err != nil {
+		return nil, errors.Wrap(err, "saving snapshot failed")
+	}
+	defer machine.Execute(nil, "sudo rm -f "+snapshotPath)
+
+	buf := new(bytes.Buffer)
+	if err := machine.ReadFile(snapshotPath, buf); err != nil {
+		return nil, errors.Wrap(err, "reading snapshot failed")
+	}
+
+	encrypted, err := encrypt(key, buf.Bytes())
+	return encrypted, errors.Wrap(err, "encrypting snapshot failed")
+}
+
+// Restore replaces the data of the etcd member running on the control plane machine with the snapshot encrypted with the key.
+// The member becomes a single member cluster, other control plane machines have to be replaced afterwards
+func Restore(machine infra.Machine, key string, encrypted []byte) error {
+
+	snapshot, err := decrypt(key, encrypted)
+	if err != nil {
+		return errors.Wrap(err, "decrypting snapshot failed")
+	}
+
+	if err := machine.WriteFile(restorePath, bytes.NewReader(snapshot), 600); err != nil {
+		return errors.Wrap(err, "writing snapshot failed")
+	}
+
+	if _, err := machine.Execute(nil, restoreScript(machine.ID(), machine.IP())); err != nil {
+		return errors.Wrap(err, "restoring snapshot failed")
+	}
+	return nil
+}
+
+// Stop stops the etcd member and the API server of a control plane machine that doesn't restore the snapshot,
+// so its etcd member doesn't join the restored cluster with the old data before the machine is replaced
+func Stop(machine infra.Machine) error {
+	if _, err := machine.Execute(nil, `sudo sh -c 'set -e
+mkdir -p /etc/kubernetes/orbos-restore
+for MANIFEST in etcd.yaml kube-apiserver.yaml; do
+  if [ -f /etc/kubernetes/manifests/${MANIFEST} ]; then
+    mv /etc/kubernetes/manifests/${MANIFEST} /etc/kubernetes/orbos-restore/
+  fi
+done'`); err != nil {
+		return errors.Wrapf(err, "stopping etcd on machine %s failed", machine.ID())
+	}
+	return nil
+}
+
+func restoreScript(id, ip string) string {
+	restore := fmt.Sprintf(
+		"etcdctl snapshot restore %s --name %s --initial-cluster %s=https://%s:2380 --initial-advertise-peer-urls https://%s:2380 --data-dir /var/lib/etcd",
+		restorePath, id, id, ip, ip,
+	)
+	return fmt.Sprintf(`sudo sh -c 'set -e
+IMAGE=$(sed -n "s/^ *image: *//p" /etc/kubernetes/manifests/etcd.yaml)
+mkdir -p /etc/kubernetes/orbos-restore
+mv /etc/kubernetes/manifests/etcd.yaml /etc/kubernetes/manifests/kube-apiserver.yaml /etc/kubernetes/orbos-restore/
+sleep 30
+mv /var/lib/etcd /var/lib/etcd.orbos-$(date +%%s)
+if command -v docker > /dev/null; then
+  docker run --rm --env ETCDCTL_API=3 --volume /var/lib:/var/lib ${IMAGE} %[1]s
+else
+  ctr --namespace k8s.io run --rm --env ETCDCTL_API=3 --mount type=bind,src=/var/lib,dst=/var/lib,options=rbind:rw ${IMAGE} orbos-etcd-restore %[1]s
+fi
+mv /etc/kubernetes/orbos-restore/etcd.yaml /etc/kubernetes/orbos-restore/kube-apiserver.yaml /etc/kubernetes/manifests/
+rm -f %[2]s'`, restore, restorePath)
+}
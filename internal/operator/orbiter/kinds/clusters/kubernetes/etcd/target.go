@@ -0,0 +1,82 @@
+package etcd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	nameTimeFormat = "20060102T150405Z"
+	nameSuffix     = ".db.enc"
+)
+
+// Target stores encrypted snapshots by their names
+type Target interface {
+	List() ([]string, error)
+	Put(name string, snapshot []byte) error
+	Get(name string) ([]byte, error)
+	Delete(name string) error
+}
+
+// Name returns the name of a snapshot of the clusters etcd taken at the given time.
+// The names of a cluster sort by the time the snapshots were taken
+func Name(clusterID string, taken time.Time) string {
+	return fmt.Sprintf("etcd-%s-%s%s", clusterID, taken.UTC().Format(nameTimeFormat), nameSuffix)
+}
+
+func parseName(clusterID, name string) (time.Time, bool) {
+	prefix := fmt.Sprintf("etcd-%s-", clusterID)
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, nameSuffix) {
+		return time.Time{}, false
+	}
+	taken, err := time.Parse(nameTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), nameSuffix))
+	return taken, err == nil
+}
+
+// Snapshots returns the names of the clusters snapshots in the target, the latest first
+func Snapshots(target Target, clusterID string) ([]string, error) {
+	names, err := target.List()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing snapshots failed")
+	}
+
+	var snapshots []string
+	for _, name := range names {
+		if _, ok := parseName(clusterID, name); ok {
+			snapshots = append(snapshots, name)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(snapshots)))
+	return snapshots, nil
+}
+
+// Due returns true if the latest snapshot of the cluster is older than the interval
+func Due(target Target, clusterID string, interval time.Duration, now time.Time) (bool, error) {
+	snapshots, err := Snapshots(target, clusterID)
+	if err != nil || len(snapshots) == 0 {
+		return err == nil, err
+	}
+	latest, _ := parseName(clusterID, snapshots[0])
+	return !now.Before(latest.Add(interval)), nil
+}
+
+// Prune deletes all but the latest keep snapshots of the cluster
+func Prune(target Target, clusterID string, keep int) error {
+	snapshots, err := Snapshots(target, clusterID)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) <= keep {
+		return nil
+	}
+	for _, name := range snapshots[keep:] {
+		if err := target.Delete(name); err != nil {
+			return errors.Wrapf(err, "deleting snapshot %s failed", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package etcd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/caos/orbos/pkg/secret"
+)
+
+type memory map[string][]byte
+
+func (m memory) List() ([]string, error) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (m memory) Put(name string, snapshot []byte) error {
+	m[name] = snapshot
+	return nil
+}
+
+func (m memory) Get(name string) ([]byte, error) { return m[name], nil }
+
+func (m memory) Delete(name string) error {
+	delete(m, name)
+	return nil
+}
+
+func TestDueAndPrune(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	target := memory{
+		Name("k8s", day):                       nil,
+		Name("k8s", day.Add(24*time.Hour)):     nil,
+		Name("k8s", day.Add(48*time.Hour)):     nil,
+		Name("other", day.Add(72*time.Hour)):   nil,
+		"etcd-k8s-unparsable.db.enc":           nil,
+		Name("k8s", day.Add(24*time.Hour))[1:]: nil,
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{name: "It should not be due before the interval passed", now: day.Add(60 * time.Hour), want: false},
+		{name: "It should be due when the interval passed", now: day.Add(72 * time.Hour), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Due(target, "k8s", 24*time.Hour, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Due() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+
+	if due, err := Due(memory{}, "k8s", time.Hour, day); err != nil || !due {
+		t.Errorf("Due() = %t, %v for an empty target, want true, nil", due, err)
+	}
+
+	if err := Prune(target, "k8s", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Snapshots(target, "k8s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{Name("k8s", day.Add(48*time.Hour)), Name("k8s", day.Add(24*time.Hour))}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshots() after Prune() = %v, want %v", got, want)
+	}
+	if _, ok := target[Name("other", day.Add(72*time.Hour))]; !ok {
+		t.Error("Prune() should not delete snapshots of other clusters")
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	secret.Masterkey = "testkey"
+	plain := []byte{0, 1, 2, 255, 254}
+	encrypted, err := secret.Encrypt(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := secret.Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decrypted, plain) {
+		t.Errorf("Decrypt(Encrypt(%v)) = %v", plain, decrypted)
+	}
+}
@@ -42,13 +42,15 @@ func firewallFunc(monitor mntr.Monitor, desired DesiredV0) (desire func(machine
 			}
 		}
 
+		var sources []string
+		for _, cidr := range append(desired.Spec.podCidrs(), desired.Spec.serviceCidrs()...) {
+			sources = append(sources, string(cidr))
+		}
+
 		firewall := common.ToFirewall("internal", fw)
 		firewallSources := common.Firewall{
 			Zones: map[string]*common.Zone{
-				"internal": {Sources: []string{
-					string(desired.Spec.Networking.PodCidr),
-					string(desired.Spec.Networking.ServiceCidr),
-				}},
+				"internal": {Sources: sources},
 			},
 		}
 		firewall.Merge(firewallSources)
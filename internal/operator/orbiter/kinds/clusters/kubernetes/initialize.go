@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/caos/orbos/pkg/kubernetes"
 
 	macherrs "k8s.io/apimachinery/pkg/api/errors"
 
 	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent/dep/sysctl"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
 	"github.com/caos/orbos/mntr"
 	core "k8s.io/api/core/v1"
@@ -19,10 +21,14 @@ import (
 type initializedPool struct {
 	upscaling   int
 	downscaling []*initializedMachine
-	infra       infra.Pool
-	tier        Tier
-	desired     Pool
-	machines    func() ([]*initializedMachine, error)
+	// replacing is true if the downscaled machines are replaced, so the rollout policy applies
+	replacing bool
+	// rollout is the state of the pools rollout if it has a rollout policy
+	rollout  *PoolRollout
+	infra    infra.Pool
+	tier     Tier
+	desired  Pool
+	machines func() ([]*initializedMachine, error)
 }
 
 func (i *initializedMachines) forEach(baseMonitor mntr.Monitor, do func(machine *initializedMachine, machineMonitor mntr.Monitor) (goon bool)) {
@@ -82,6 +88,7 @@ func initialize(
 	err error) {
 
 	curr.Status = "running"
+	curr.forget(desired)
 
 	initializePool := func(infraPool infra.Pool, desired Pool, tier Tier) (*initializedPool, error) {
 		pool := &initializedPool{
@@ -110,6 +117,13 @@ func initialize(
 			return pool, err
 		}
 
+		trackRollout(monitor, curr, pool, machines, time.Now())
+
+		if tier == Workers && desired.autoscaled() {
+			desired.Nodes = autoscaledNodes(desired, len(machines), curr.Autoscaling[poolKey(desired)])
+			pool.desired = desired
+		}
+
 		var replace initializedMachines
 		var backReplacement int
 		for _, machine := range machines {
@@ -129,6 +143,7 @@ func initialize(
 		}
 
 		if len(replace) > 0 {
+			pool.replacing = true
 			for backReplacement >= desired.Nodes && len(replace) > 0 {
 				backReplacement--
 				pool.downscaling = append(pool.downscaling, replace[0])
@@ -145,6 +160,7 @@ func initialize(
 	initializeMachine = func(machine infra.Machine, pool *initializedPool) *initializedMachine {
 
 		current := &Machine{
+			DrainBlockedBy: curr.drainBlockedBy(machine.ID()),
 			Metadata: MachineMetadata{
 				Tier:     pool.tier,
 				Provider: pool.desired.Provider,
@@ -188,7 +204,7 @@ func initialize(
 		machineMonitor := monitor.WithField("machine", machine.ID())
 
 		naSpec.ChangesAllowed = !pool.desired.UpdatesDisabled
-		k8sSoftware := ParseString(desired.Spec.Versions.Kubernetes).DefineSoftware()
+		k8sSoftware := ParseString(desired.Spec.Versions.Kubernetes).DefineSoftware(desired.Spec.containerRuntime())
 
 		if !softwareDefines(*naSpec.Software, k8sSoftware) {
 			k8sSoftware.Merge(KubernetesSoftware(naCurr.Software))
@@ -198,6 +214,11 @@ func initialize(
 			}
 		}
 
+		if desired.Spec.ipv6() && !sysctl.Contains(naSpec.Software.Sysctl, common.Package{Config: map[string]string{string(common.Ipv6Forward): "1"}}) {
+			sysctl.Enable(&naSpec.Software.Sysctl, common.Ipv6Forward)
+			machineMonitor.Debug("IPv6 forwarding desired")
+		}
+
 		initMachine := &initializedMachine{
 			infra:            machine,
 			currentNodeagent: naCurr,
@@ -304,6 +325,8 @@ func reconcileNodeFunc(node v1.Node, monitor mntr.Monitor, pool Pool, k8s *kuber
 	handleMaybe(reconcileLabel(n, "orbos.ch/pool", pool.Pool))
 	handleMaybe(reconcileLabel(n, "orbos.ch/tier", string(tier)))
 	handleMaybe(reconcileTaints(n, pool, k8s, naSpec, naCurr))
+	handleMaybe(reconcileMetadata(n, "labels", &n.Labels, pool.Labels))
+	handleMaybe(reconcileMetadata(n, "annotations", &n.Annotations, pool.Annotations))
 
 	if !reconcileNode {
 		return func() error { return nil }
@@ -337,7 +360,7 @@ outer:
 		break
 	}
 	// internal taints
-	if k8s.Tainted(node, kubernetes.Updating) && node.Labels["orbos.ch/updating"] == node.Status.NodeInfo.KubeletVersion {
+	if k8s.Tainted(node, kubernetes.Updating) && node.Labels[kubernetes.UpdatingLabel] == node.Status.NodeInfo.KubeletVersion {
 		newTaints = k8s.RemoveFromTaints(newTaints, kubernetes.Updating)
 		updateTaints = true
 	}
@@ -366,3 +389,53 @@ func reconcileLabel(node *v1.Node, key, value string) map[string]interface{} {
 		fmt.Sprintf("label.%s", key): value,
 	}
 }
+
+// reconcileMetadata keeps the pools labels or annotations on the node.
+// The managed keys are remembered in an annotation, so keys that are removed from the pool are removed from the node too
+func reconcileMetadata(node *v1.Node, kind string, metadata *map[string]string, desired map[string]string) map[string]interface{} {
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	if *metadata == nil {
+		*metadata = make(map[string]string)
+	}
+
+	changed := false
+	managedKey := "orbos.ch/managed-" + kind
+	for _, key := range strings.Split(node.Annotations[managedKey], ",") {
+		if _, ok := desired[key]; ok || key == "" {
+			continue
+		}
+		if _, ok := (*metadata)[key]; ok {
+			delete(*metadata, key)
+			changed = true
+		}
+	}
+
+	keys := make([]string, 0, len(desired))
+	for key, value := range desired {
+		keys = append(keys, key)
+		if existing, ok := (*metadata)[key]; !ok || existing != value {
+			(*metadata)[key] = value
+			changed = true
+		}
+	}
+	sort.Strings(keys)
+
+	managed := strings.Join(keys, ",")
+	if node.Annotations[managedKey] != managed {
+		if managed == "" {
+			delete(node.Annotations, managedKey)
+		} else {
+			node.Annotations[managedKey] = managed
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return map[string]interface{}{
+		kind: desired,
+	}
+}
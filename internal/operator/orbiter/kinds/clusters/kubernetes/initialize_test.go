@@ -3,15 +3,19 @@ package kubernetes
 import (
 	"reflect"
 	"testing"
+	"time"
 
-	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/pkg/kubernetes"
 	v1 "k8s.io/api/core/v1"
 )
 
 func Test_reconcileTaints(t *testing.T) {
 	type args struct {
-		node v1.Node
-		pool Pool
+		node   v1.Node
+		pool   Pool
+		naSpec common.NodeAgentSpec
+		naCurr common.NodeAgentCurrent
 	}
 	someTaintKey := "someKey"
 	someTaintEffect := v1.TaintEffectNoSchedule
@@ -23,6 +27,14 @@ func Test_reconcileTaints(t *testing.T) {
 		Key:    someTaintKey,
 		Effect: someTaintEffect,
 	}
+	updatingTaint := v1.Taint{
+		Key:    kubernetes.TaintKeyPrefix + kubernetes.Updating.String(),
+		Effect: v1.TaintEffectNoSchedule,
+	}
+	rebootingTaint := v1.Taint{
+		Key:    kubernetes.TaintKeyPrefix + kubernetes.Rebooting.String(),
+		Effect: v1.TaintEffectNoSchedule,
+	}
 
 	node := func(taint ...v1.Taint) v1.Node {
 		return v1.Node{Spec: v1.NodeSpec{Taints: append([]v1.Taint{}, taint...)}}
@@ -31,43 +43,94 @@ func Test_reconcileTaints(t *testing.T) {
 		taints := Taints(append([]Taint{}, taint...))
 		return Pool{Taints: &taints}
 	}
-
-	nodePtr := func(node v1.Node) *v1.Node {
-		return &node
-	}
+	now := time.Now()
 
 	tests := []struct {
-		name string
-		args args
-		want *v1.Node
+		name        string
+		args        args
+		wantChanged bool
+		wantTaints  []v1.Taint
 	}{
 		{
 			name: "It should add configured taints",
 			args: args{
 				node: v1.Node{},
 				pool: pool(someDesiredTaint),
-			}, want: nodePtr(node(someNodeTaint)),
+			},
+			wantChanged: true,
+			wantTaints:  []v1.Taint{someNodeTaint},
 		},
 		{
-			name: "It should leave the taints as they are if the taints property is nil",
+			name: "It should leave the taints as they are if they match the configured taints",
 			args: args{
 				node: node(someNodeTaint),
-				pool: Pool{},
-			}, want: nil,
+				pool: pool(someDesiredTaint),
+			},
+			wantChanged: false,
+			wantTaints:  []v1.Taint{someNodeTaint},
 		},
 		{
 			name: "It should remove existing taints if the empty slice is passed",
 			args: args{
 				node: node(someNodeTaint),
 				pool: pool(),
-			}, want: nodePtr(node()),
+			},
+			wantChanged: true,
+			wantTaints:  []v1.Taint{},
+		},
+		{
+			name: "It should keep internal taints",
+			args: args{
+				node: node(rebootingTaint),
+				pool: pool(),
+				naSpec: common.NodeAgentSpec{
+					RebootRequired: now,
+				},
+				naCurr: common.NodeAgentCurrent{
+					Booted: now.Add(-time.Minute),
+				},
+			},
+			wantChanged: false,
+			wantTaints:  []v1.Taint{rebootingTaint},
+		},
+		{
+			name: "It should remove the rebooting taint after the machine booted",
+			args: args{
+				node: node(rebootingTaint),
+				pool: pool(),
+				naSpec: common.NodeAgentSpec{
+					RebootRequired: now.Add(-time.Minute),
+				},
+				naCurr: common.NodeAgentCurrent{
+					Booted: now,
+				},
+			},
+			wantChanged: true,
+			wantTaints:  []v1.Taint{},
+		},
+		{
+			name: "It should remove the updating taint after the kubelet is updated",
+			args: args{
+				node: func() v1.Node {
+					n := node(updatingTaint)
+					n.Labels = map[string]string{kubernetes.UpdatingLabel: "v1.21.0"}
+					n.Status.NodeInfo.KubeletVersion = "v1.21.0"
+					return n
+				}(),
+				pool: pool(),
+			},
+			wantChanged: true,
+			wantTaints:  []v1.Taint{},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := reconcileTaints(tt.args.node, tt.args.pool, mntr.Monitor{})
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("reconcileTaints() got = %v, want %v", got, tt.want)
+			got := reconcileTaints(&tt.args.node, tt.args.pool, nil, &tt.args.naSpec, &tt.args.naCurr)
+			if changed := got != nil; changed != tt.wantChanged {
+				t.Errorf("reconcileTaints() changed = %t, want %t", changed, tt.wantChanged)
+			}
+			if !reflect.DeepEqual(tt.args.node.Spec.Taints, tt.wantTaints) {
+				t.Errorf("reconcileTaints() taints = %v, want %v", tt.args.node.Spec.Taints, tt.wantTaints)
 			}
 		})
 	}
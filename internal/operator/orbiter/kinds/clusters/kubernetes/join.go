@@ -4,16 +4,18 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/caos/orbos/pkg/git"
 
-	"github.com/caos/orbos/internal/executables"
 	"github.com/caos/orbos/pkg/kubernetes"
 	"github.com/pkg/errors"
 
+	"github.com/caos/orbos/internal/operator/orbiter"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
 	"github.com/caos/orbos/mntr"
 )
@@ -33,62 +35,27 @@ func join(
 	client *kubernetes.Client,
 	imageRepository string,
 	gitClient *git.Client,
-	providerK8sSpec infra.Kubernetes) (*string, error) {
+	providerK8sSpec infra.Kubernetes) (kubeconfig *string, err error) {
 
 	monitor = monitor.WithFields(map[string]interface{}{
 		"machine": joining.infra.ID(),
 		"tier":    joining.pool.tier,
 	})
 
-	applyResources := providerK8sSpec.Apply
-
-	switch desired.Spec.Networking.Network {
-	case "cilium":
-		buf := new(bytes.Buffer)
-		defer buf.Reset()
-
-		istioReg := desired.Spec.CustomImageRegistry
-		if istioReg != "" && !strings.HasSuffix(istioReg, "/") {
-			istioReg += "/"
-		}
-
-		ciliumReg := desired.Spec.CustomImageRegistry
-		if ciliumReg == "" {
-			ciliumReg = "docker.io"
-		}
+	monitor, endSpan := monitor.StartSpan("join machine " + joining.infra.ID())
+	defer func() { endSpan(err) }()
 
-		template.Must(template.New("").Parse(string(executables.PreBuilt("cilium.yaml")))).Execute(buf, struct {
-			IstioProxyImageRegistry string
-			CiliumImageRegistry     string
-		}{
-			IstioProxyImageRegistry: istioReg,
-			CiliumImageRegistry:     ciliumReg,
-		})
-		applyResources = concatYAML(applyResources, buf)
-	case "calico":
-
-		reg := desired.Spec.CustomImageRegistry
-		if reg != "" && !strings.HasSuffix(reg, "/") {
-			reg += "/"
-		}
-
-		buf := new(bytes.Buffer)
-		defer buf.Reset()
-		template.Must(template.New("").Parse(string(executables.PreBuilt("calico.yaml")))).Execute(buf, struct {
-			ImageRegistry string
-		}{
-			ImageRegistry: reg,
-		})
-		applyResources = concatYAML(applyResources, buf)
-	case "":
-	default:
-		networkFile := gitClient.Read(desired.Spec.Networking.Network)
-		if len(networkFile) == 0 {
-			return nil, fmt.Errorf("network file %s is empty or not found in git repository", desired.Spec.Networking.Network)
-		}
+	applyResources := providerK8sSpec.Apply
 
-		applyResources = concatYAML(applyResources, bytes.NewReader(networkFile))
+	cni, err := renderCNI(desired.Spec, gitClient, kubeAPI)
+	if err != nil {
+		return nil, err
 	}
+	if cni != nil {
+		applyResources = concatYAML(applyResources, bytes.NewReader(cni))
+	}
+
+	socket := criSocket(kubernetesVersion.DefineSoftware(desired.Spec.containerRuntime()).Containerruntime)
 
 	kubeadmCfgPath := "/etc/kubeadm/config.yaml"
 	cloudCfgPath := "/var/orbiter/cloud-config"
@@ -96,8 +63,10 @@ func join(
 	kubeadmCfg := new(bytes.Buffer)
 	defer kubeadmCfg.Reset()
 
-	template.Must(template.New("").Parse(`kind: ClusterConfiguration
-apiVersion: kubeadm.k8s.io/v1beta2
+	template.Must(template.New("").Funcs(template.FuncMap{
+		"hostPort": func(ip string) string { return net.JoinHostPort(ip, strconv.Itoa(int(kubeAPI.BackendPort))) },
+	}).Parse(`kind: ClusterConfiguration
+apiVersion: {{ .KubeadmAPIVersion }}
 apiServer:
   timeoutForControlPlane: 4m0s
   certSANs:
@@ -120,8 +89,8 @@ controllerManager:
 certificatesDir: /etc/kubernetes/pki
 clusterName: "{{ .ClusterName }}"
 controlPlaneEndpoint: "{{ .ControlPlaneEndpoint }}"
-dns:
-  type: CoreDNS
+dns:{{ if eq .KubeadmAPIVersion "kubeadm.k8s.io/v1beta2" }}
+  type: CoreDNS{{ else }} {}{{ end }}
 etcd:
   local:
     imageRepository: "{{ .ImageRepository }}"
@@ -144,15 +113,16 @@ cgroupDriver: systemd
 
 ---{{if .JoinAt }}
 kind: JoinConfiguration
-apiVersion: kubeadm.k8s.io/v1beta2
+apiVersion: {{ .KubeadmAPIVersion }}
 caCertPath: /etc/kubernetes/pki/ca.crt
 discovery:
   bootstrapToken:
-    apiServerEndpoint: {{ .JoinAt.IP }}:{{ .BindPort }}
+    apiServerEndpoint: "{{ .JoinAt.IP | hostPort }}"
     token: {{ .Token }}
     unsafeSkipCAVerification: true
   timeout: 5m0s
 nodeRegistration:
+  criSocket: "{{ .CRISocket }}"
   kubeletExtraArgs:
     node-ip: "{{ .Node.IP }}"{{if .ProviderK8sSpec.CloudController.Supported}}
     cloud-provider: "{{ .ProviderK8sSpec.CloudController.ProviderName }}"
@@ -164,7 +134,7 @@ controlPlane:
     bindPort: {{ .BindPort }}
   certificateKey: {{.CertKey}}{{end}}{{else}}
 kind: InitConfiguration
-apiVersion: kubeadm.k8s.io/v1beta2
+apiVersion: {{ .KubeadmAPIVersion }}
 bootstrapTokens:
 - groups:
   - system:bootstrappers:kubeadm:default-node-token
@@ -177,7 +147,7 @@ localAPIEndpoint:
   advertiseAddress: "{{ .Node.IP }}"
   bindPort: {{ .BindPort }}
 nodeRegistration:
-#	criSocket: /var/run/dockershim.sock
+  criSocket: "{{ .CRISocket }}"
   name:  "{{ .Node.ID }}"
   kubeletExtraArgs:
     node-ip: "{{ .Node.IP }}"{{if .ProviderK8sSpec.CloudController.Supported }}
@@ -196,6 +166,8 @@ nodeRegistration:
 		ControlPlaneEndpoint string
 		ImageRepository      string
 		KubernetesVersion    string
+		KubeadmAPIVersion    string
+		CRISocket            string
 		DNSDomain            string
 		PodSubnet            string
 		ServiceSubnet        string
@@ -213,9 +185,11 @@ nodeRegistration:
 		ControlPlaneEndpoint: kubeAPI.String(),
 		ImageRepository:      imageRepository,
 		KubernetesVersion:    kubernetesVersion.String(),
+		KubeadmAPIVersion:    kubernetesVersion.kubeadmAPIVersion(),
+		CRISocket:            socket,
 		DNSDomain:            desired.Spec.Networking.DNSDomain,
-		PodSubnet:            string(desired.Spec.Networking.PodCidr),
-		ServiceSubnet:        string(desired.Spec.Networking.ServiceCidr),
+		PodSubnet:            joinCidrs(desired.Spec.podCidrs()),
+		ServiceSubnet:        joinCidrs(desired.Spec.serviceCidrs()),
 		JoinAt:               joinAt,
 		IsControlPlane:       joining.pool.tier == Controlplane,
 		CertKey:              certKey,
@@ -243,7 +217,11 @@ nodeRegistration:
 		}).Debug("Written file")
 	}
 
-	cmd := "sudo kubeadm reset -f && sudo rm -rf /var/lib/etcd"
+	resetCmd := "sudo kubeadm reset -f"
+	if socket != "" {
+		resetCmd += " --cri-socket " + socket
+	}
+	cmd := resetCmd + " && sudo rm -rf /var/lib/etcd"
 	resetStdout, err := joining.infra.Execute(nil, cmd)
 	if err != nil {
 		return nil, errors.Wrapf(err, "executing %s failed", cmd)
@@ -252,8 +230,12 @@ nodeRegistration:
 		"stdout": string(resetStdout),
 	}).Debug("Cleaned up machine")
 
+	if err := writeKubeletArgs(joining.infra, joining.pool.desired.Kubelet.args()); err != nil {
+		return nil, err
+	}
+
 	if joinAt != nil {
-		cmd := fmt.Sprintf("sudo kubeadm join --ignore-preflight-errors=Port-%d %s:%d --config %s", kubeAPI.BackendPort, joinAt.IP(), kubeAPI.FrontendPort, kubeadmCfgPath)
+		cmd := fmt.Sprintf("sudo kubeadm join --ignore-preflight-errors=Port-%d %s --config %s", kubeAPI.BackendPort, net.JoinHostPort(joinAt.IP(), strconv.Itoa(int(kubeAPI.FrontendPort))), kubeadmCfgPath)
 		joinStdout, err := joining.infra.Execute(nil, cmd)
 		if err != nil {
 			return nil, errors.Wrapf(err, "executing %s failed", cmd)
@@ -285,12 +267,17 @@ nodeRegistration:
 		return nil, err
 	}
 
+	var skipPhases string
+	if desired.Spec.kubeProxyReplaced() {
+		skipPhases = " --skip-phases=addon/kube-proxy"
+	}
+
 	initCmd := fmt.Sprintf(`\
-sudo kubeadm init --ignore-preflight-errors=Port-%d --config %s && \
+sudo kubeadm init --ignore-preflight-errors=Port-%d --config %s%s && \
 mkdir -p ${HOME}/.kube && yes | sudo cp -rf /etc/kubernetes/admin.conf ${HOME}/.kube/config && \
 sudo chown $(id -u):$(id -g) ${HOME}/.kube/config && \
 kubectl -n kube-system patch deployment coredns --type='json' \
--p='[{"op": "add", "path": "/spec/template/spec/tolerations/0", "value": {"effect": "NoSchedule", key: "node.cloudprovider.kubernetes.io/uninitialized", value: "true" } }]'`, kubeAPI.BackendPort, kubeadmCfgPath)
+-p='[{"op": "add", "path": "/spec/template/spec/tolerations/0", "value": {"effect": "NoSchedule", key: "node.cloudprovider.kubernetes.io/uninitialized", value: "true" } }]'`, kubeAPI.BackendPort, kubeadmCfgPath, skipPhases)
 	initStdout, err := joining.infra.Execute(nil, initCmd)
 	if err != nil {
 		return nil, fmt.Errorf(`error initializing kubernetes by executing command (%s): %s: %w`, initCmd, initStdout, err)
@@ -328,3 +315,12 @@ func concatYAML(head, tail io.Reader) io.Reader {
 	}
 	return io.MultiReader(head, strings.NewReader("---\n\n"), tail)
 }
+
+// joinCidrs returns the comma separated CIDRs kubeadm expects for dual-stack clusters
+func joinCidrs(cidrs []orbiter.CIDR) string {
+	strs := make([]string, len(cidrs))
+	for idx, cidr := range cidrs {
+		strs[idx] = string(cidr)
+	}
+	return strings.Join(strs, ",")
+}
@@ -0,0 +1,239 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/mntr"
+)
+
+const (
+	kubeletEnvVar     = "KUBELET_EXTRA_ARGS"
+	kubeletEnvFileCmd = "if [ -d /etc/sysconfig ]; then echo /etc/sysconfig/kubelet; else echo /etc/default/kubelet; fi"
+)
+
+// Kubelet configures the kubelets of a pool.
+// Changes are rolled out by rebooting one machine after the other
+type Kubelet struct {
+	// Maximum number of pods per node
+	//@default: 110
+	MaxPods int `yaml:",omitempty"`
+	// Evict pods immediately when a signal crosses its threshold, for example memory.available: 100Mi
+	EvictionHard map[string]string `yaml:",omitempty"`
+	// Evict pods gracefully when a signal crosses its threshold for longer than its grace period
+	EvictionSoft map[string]string `yaml:",omitempty"`
+	// Grace periods of the soft eviction thresholds, for example memory.available: 1m30s
+	EvictionSoftGracePeriod map[string]string `yaml:",omitempty"`
+	// Resources reserved for the operating system, for example cpu: 500m
+	SystemReserved map[string]string `yaml:",omitempty"`
+	// Resources reserved for the kubelet and the container runtime, for example memory: 1Gi
+	KubeReserved map[string]string `yaml:",omitempty"`
+	FeatureGates map[string]bool   `yaml:",omitempty"`
+}
+
+func (k *Kubelet) validate() error {
+
+	if k.MaxPods < 0 {
+		return errors.Errorf("kubelet maxPods %d must not be negative", k.MaxPods)
+	}
+
+	for _, reserved := range []map[string]string{k.SystemReserved, k.KubeReserved} {
+		for name, quantity := range reserved {
+			if _, err := resource.ParseQuantity(quantity); err != nil {
+				return errors.Wrapf(err, "parsing reserved %s quantity %s failed", name, quantity)
+			}
+		}
+	}
+
+	for signal := range k.EvictionSoft {
+		if _, ok := k.EvictionSoftGracePeriod[signal]; !ok {
+			return errors.Errorf("kubelet soft eviction signal %s has no grace period", signal)
+		}
+	}
+
+	for _, thresholds := range []map[string]string{k.EvictionHard, k.EvictionSoft, k.EvictionSoftGracePeriod} {
+		for signal, threshold := range thresholds {
+			if strings.ContainsAny(signal+threshold, " ,=<'\"") {
+				return errors.Errorf("invalid kubelet eviction threshold %s: %s", signal, threshold)
+			}
+		}
+	}
+
+	for gate := range k.FeatureGates {
+		if strings.ContainsAny(gate, " ,='\"") {
+			return errors.Errorf("invalid kubelet feature gate %s", gate)
+		}
+	}
+	return nil
+}
+
+// args returns the kubelet flags in a stable order
+func (k *Kubelet) args() string {
+	if k == nil {
+		return ""
+	}
+
+	var args []string
+	if k.MaxPods > 0 {
+		args = append(args, fmt.Sprintf("--max-pods=%d", k.MaxPods))
+	}
+
+	for _, flag := range []struct {
+		name   string
+		values map[string]string
+		sep    string
+	}{
+		{name: "eviction-hard", values: k.EvictionHard, sep: "<"},
+		{name: "eviction-soft", values: k.EvictionSoft, sep: "<"},
+		{name: "eviction-soft-grace-period", values: k.EvictionSoftGracePeriod, sep: "="},
+		{name: "system-reserved", values: k.SystemReserved, sep: "="},
+		{name: "kube-reserved", values: k.KubeReserved, sep: "="},
+	} {
+		if len(flag.values) > 0 {
+			args = append(args, fmt.Sprintf("--%s=%s", flag.name, joinSorted(flag.values, flag.sep)))
+		}
+	}
+
+	if len(k.FeatureGates) > 0 {
+		gates := make(map[string]string, len(k.FeatureGates))
+		for gate, enabled := range k.FeatureGates {
+			gates[gate] = fmt.Sprintf("%t", enabled)
+		}
+		args = append(args, "--feature-gates="+joinSorted(gates, "="))
+	}
+	return strings.Join(args, " ")
+}
+
+func joinSorted(values map[string]string, sep string) string {
+	pairs := make([]string, 0, len(values))
+	for key, value := range values {
+		pairs = append(pairs, key+sep+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func kubeletEnv(args string) string {
+	return fmt.Sprintf("%s=\"%s\"\n", kubeletEnvVar, args)
+}
+
+// parseKubeletArgs returns the kubelet flags from an environment file
+func parseKubeletArgs(env []byte) string {
+	for _, line := range strings.Split(string(env), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, kubeletEnvVar+"=") {
+			continue
+		}
+		return strings.Trim(strings.TrimPrefix(line, kubeletEnvVar+"="), `"'`)
+	}
+	return ""
+}
+
+// appliedKubeletArgs remembers the flags ORBITER wrote or read per machine,
+// so a machines environment file is only read once per ORBITER process
+var appliedKubeletArgs = struct {
+	sync.Mutex
+	machines map[string]string
+}{machines: make(map[string]string)}
+
+func kubeletEnvFile(machine infra.Machine) (string, error) {
+	path, err := machine.Execute(nil, kubeletEnvFileCmd)
+	return strings.TrimSpace(string(path)), errors.Wrapf(err, "executing %s failed", kubeletEnvFileCmd)
+}
+
+// writeKubeletArgs writes the kubelet flags, which take effect when the kubelet is restarted the next time
+func writeKubeletArgs(machine infra.Machine, args string) error {
+	path, err := kubeletEnvFile(machine)
+	if err != nil {
+		return err
+	}
+	if err := machine.WriteFile(path, strings.NewReader(kubeletEnv(args)), 644); err != nil {
+		return err
+	}
+	appliedKubeletArgs.Lock()
+	defer appliedKubeletArgs.Unlock()
+	appliedKubeletArgs.machines[machine.ID()] = args
+	return nil
+}
+
+func kubeletArgsApplied(machine infra.Machine, args string) (bool, error) {
+	appliedKubeletArgs.Lock()
+	defer appliedKubeletArgs.Unlock()
+
+	if applied, ok := appliedKubeletArgs.machines[machine.ID()]; ok {
+		return applied == args, nil
+	}
+
+	path, err := kubeletEnvFile(machine)
+	if err != nil {
+		return false, err
+	}
+	cmd := fmt.Sprintf("cat %s 2>/dev/null || true", path)
+	env, err := machine.Execute(nil, cmd)
+	if err != nil {
+		return false, errors.Wrapf(err, "executing %s failed", cmd)
+	}
+	applied := parseKubeletArgs(env)
+	appliedKubeletArgs.machines[machine.ID()] = applied
+	return applied == args, nil
+}
+
+// ensureKubelet rolls out the kubelet flags of the pools machine by machine.
+// Without a rollout policy, a machine is only changed when all machines are ready. It is drained and rebooted then
+func ensureKubelet(monitor mntr.Monitor, machines initializedMachines, pdf func(mntr.Monitor) error) (done bool, err error) {
+
+	machines = append(initializedMachines{}, machines...)
+	sort.Sort(machines)
+	var outdated *initializedMachine
+	for _, machine := range machines {
+		if !machine.currentMachine.Joined {
+			continue
+		}
+		applied, err := kubeletArgsApplied(machine.infra, machine.pool.desired.Kubelet.args())
+		if err != nil {
+			return false, err
+		}
+		if !applied {
+			outdated = machine
+			break
+		}
+	}
+	if outdated == nil {
+		return true, nil
+	}
+
+	if outdated.pool.desired.Rollout == nil {
+		for _, machine := range machines {
+			if !machine.currentMachine.Ready || machine.currentMachine.Rebooting || machine.currentMachine.Updating {
+				monitor.WithField("machine", machine.infra.ID()).Info("Waiting for the machine to be ready before rolling out the kubelet configuration")
+				return false, nil
+			}
+		}
+	}
+	if !mayDisrupt(monitor, outdated, machines, time.Now()) {
+		return false, nil
+	}
+
+	args := outdated.pool.desired.Kubelet.args()
+	monitor, endSpan := monitor.WithFields(map[string]interface{}{
+		"machine": outdated.infra.ID(),
+		"kubelet": args,
+	}).StartSpan("configure kubelet")
+	defer func() { endSpan(err) }()
+
+	if err := writeKubeletArgs(outdated.infra, args); err != nil {
+		return false, err
+	}
+
+	_, require, _ := outdated.infra.RebootRequired()
+	require()
+	monitor.Changed("Kubelet configured, rebooting machine")
+	return false, pdf(monitor.WithField("reason", "reboot machine for applying the kubelet configuration"))
+}
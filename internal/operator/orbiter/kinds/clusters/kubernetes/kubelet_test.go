@@ -0,0 +1,72 @@
+package kubernetes
+
+import "testing"
+
+func TestKubeletArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		kubelet *Kubelet
+		want    string
+	}{{
+		name: "It should return no flags without configuration",
+		want: "",
+	}, {
+		name: "It should sort the flags values",
+		kubelet: &Kubelet{
+			MaxPods:                 250,
+			EvictionHard:            map[string]string{"nodefs.available": "10%", "memory.available": "100Mi"},
+			EvictionSoft:            map[string]string{"memory.available": "300Mi"},
+			EvictionSoftGracePeriod: map[string]string{"memory.available": "1m30s"},
+			SystemReserved:          map[string]string{"memory": "1Gi", "cpu": "500m"},
+			KubeReserved:            map[string]string{"cpu": "250m"},
+			FeatureGates:            map[string]bool{"GracefulNodeShutdown": true, "CSIMigration": false},
+		},
+		want: "--max-pods=250 " +
+			"--eviction-hard=memory.available<100Mi,nodefs.available<10% " +
+			"--eviction-soft=memory.available<300Mi " +
+			"--eviction-soft-grace-period=memory.available=1m30s " +
+			"--system-reserved=cpu=500m,memory=1Gi " +
+			"--kube-reserved=cpu=250m " +
+			"--feature-gates=CSIMigration=false,GracefulNodeShutdown=true",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.kubelet.args(); got != tt.want {
+				t.Errorf("args() = %q, want %q", got, tt.want)
+			}
+			if got := parseKubeletArgs([]byte("# written by ORBITER\n" + kubeletEnv(tt.kubelet.args()))); got != tt.want {
+				t.Errorf("parseKubeletArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubeletValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		kubelet Kubelet
+		wantErr bool
+	}{{
+		name:    "It should accept soft eviction thresholds with grace periods",
+		kubelet: Kubelet{EvictionSoft: map[string]string{"memory.available": "300Mi"}, EvictionSoftGracePeriod: map[string]string{"memory.available": "1m"}},
+	}, {
+		name:    "It should fail on soft eviction thresholds without grace periods",
+		kubelet: Kubelet{EvictionSoft: map[string]string{"memory.available": "300Mi"}},
+		wantErr: true,
+	}, {
+		name:    "It should fail on invalid reserved quantities",
+		kubelet: Kubelet{SystemReserved: map[string]string{"memory": "a lot"}},
+		wantErr: true,
+	}, {
+		name:    "It should fail on thresholds that break the flags",
+		kubelet: Kubelet{EvictionHard: map[string]string{"memory.available": "100Mi,nodefs.available<1%"}},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.kubelet.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -22,13 +22,17 @@ func maintainNodes(allInitializedMachines initializedMachines, monitor mntr.Moni
 
 	allInitializedMachines.forEach(monitor, func(machine *initializedMachine, machineMonitor mntr.Monitor) bool {
 		req, _, unreq := machine.infra.RebootRequired()
-		if !req {
+		if !req || !mayDisrupt(machineMonitor, machine, allInitializedMachines, time.Now()) {
 			return true
 		}
 		if k8sClient != nil {
-			if err = k8sClient.Drain(machine.currentMachine, machine.node, kubernetes.Rebooting); err != nil {
+			var drained bool
+			if drained, err = drainMachine(k8sClient, machine, machine.node, kubernetes.Rebooting); err != nil {
 				return false
 			}
+			if !drained {
+				return true
+			}
 		}
 		machine.currentMachine.Rebooting = true
 		machineMonitor.Info("Requiring reboot")
@@ -0,0 +1,65 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/mntr"
+)
+
+var _ orbiter.Planner = (*Current)(nil)
+
+func (c *Current) Plan() (*orbiter.Plan, error) {
+	if c.Current == nil || c.Current.plan == nil {
+		return nil, nil
+	}
+	return c.Current.plan()
+}
+
+// planChanges lists the machines the pools are scaled by and the upgrade steps ensureSoftware is going to take
+func planChanges(
+	monitor mntr.Monitor,
+	clusterID string,
+	desired *DesiredV0,
+	controlplane *initializedPool,
+	controlplaneMachines []*initializedMachine,
+	workers []*initializedPool,
+	workerMachines []*initializedMachine,
+) (*orbiter.Plan, error) {
+
+	p := &orbiter.Plan{}
+	for _, pool := range append([]*initializedPool{controlplane}, workers...) {
+		if pool == nil {
+			continue
+		}
+		id := fmt.Sprintf("%s.%s", pool.desired.Provider, pool.desired.Pool)
+		if pool.upscaling > 0 {
+			p.Machines = append(p.Machines, &orbiter.PlannedChange{
+				ID:     id,
+				Action: orbiter.Add,
+				To:     fmt.Sprintf("%d machines", pool.upscaling),
+			})
+		}
+		for _, machine := range pool.downscaling {
+			p.Machines = append(p.Machines, &orbiter.PlannedChange{
+				ID:     id,
+				Action: orbiter.Remove,
+				From:   machine.infra.ID(),
+			})
+		}
+	}
+
+	steps, err := upgradeSteps(monitor, append(controlplaneMachines, workerMachines...), ParseString(desired.Spec.Versions.Kubernetes))
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		p.Kubernetes = append(p.Kubernetes, &orbiter.PlannedChange{
+			ID:     clusterID,
+			Action: orbiter.Upgrade,
+			From:   step[0].String(),
+			To:     step[1].String(),
+		})
+	}
+	return p, nil
+}
@@ -50,11 +50,16 @@ func query(
 			firewallFunc(monitor, *desired)(machine)
 		})
 
+	current.plan = func() (*orbiter.Plan, error) {
+		return planChanges(monitor, clusterID, desired, controlplane, controlplaneMachines, workers, workerMachines)
+	}
+
 	return func(psf func(mntr.Monitor) error) *orbiter.EnsureResult {
 		return orbiter.ToEnsureResult(ensure(
 			monitor,
 			clusterID,
 			desired,
+			current,
 			kubeAPIAddress,
 			psf,
 			k8sClient,
@@ -0,0 +1,312 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/kubernetes"
+)
+
+// Rollout defines when and how fast ORBITER disrupts the machines of a pool for upgrades, reboots, replacements and kubelet changes
+type Rollout struct {
+	// Only disrupt machines while one of these windows is open
+	//@default: machines are disrupted anytime
+	MaintenanceWindows []*MaintenanceWindow `yaml:",omitempty"`
+	// Maximum number of the pools machines that are unavailable at the same time
+	//@default: 1
+	MaxUnavailable int `yaml:",omitempty"`
+	// Stop disrupting further machines while a disrupted machine is not available after the health gates timeout
+	PauseOnFailure bool `yaml:",omitempty"`
+	// Checks that must pass before the next machine is disrupted
+	HealthGates *HealthGates `yaml:",omitempty"`
+}
+
+type MaintenanceWindow struct {
+	// Cron expression with the fields minute, hour, day of month, month and day of week that opens the window, for example 0 2 * * 6
+	Start string
+	// How long the window stays open, at most 168h
+	Duration string
+	// IANA time zone name the start is evaluated in
+	//@default: UTC
+	TimeZone string `yaml:",omitempty"`
+}
+
+type HealthGates struct {
+	// Wait this long after a disrupted machine is available again before the next machine is disrupted
+	Settle string `yaml:",omitempty"`
+	// A disrupted machine that is not available after this duration fails the rollout
+	//@default: 30m
+	Timeout string `yaml:",omitempty"`
+	// URLs that must respond with a 2xx status code before the next machine is disrupted
+	HTTPChecks []string `yaml:",omitempty"`
+}
+
+func (r *Rollout) validate() error {
+	if r.MaxUnavailable < 0 {
+		return errors.Errorf("rollout maxUnavailable %d must not be negative", r.MaxUnavailable)
+	}
+	for _, window := range r.MaintenanceWindows {
+		if _, _, _, err := window.parse(); err != nil {
+			return err
+		}
+	}
+	if r.HealthGates == nil {
+		return nil
+	}
+	for _, duration := range []string{r.HealthGates.Settle, r.HealthGates.Timeout} {
+		if duration == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(duration); err != nil {
+			return errors.Wrapf(err, "parsing health gates duration %s failed", duration)
+		}
+	}
+	return nil
+}
+
+func (w *MaintenanceWindow) parse() (*cronSchedule, time.Duration, *time.Location, error) {
+	schedule, err := parseCron(w.Start)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil {
+		return nil, 0, nil, errors.Wrapf(err, "parsing maintenance window duration %s failed", w.Duration)
+	}
+	if duration <= 0 || duration > 7*24*time.Hour {
+		return nil, 0, nil, errors.Errorf("maintenance window duration %s must be positive and at most 168h", w.Duration)
+	}
+	location, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return nil, 0, nil, errors.Wrapf(err, "loading maintenance window time zone %s failed", w.TimeZone)
+	}
+	return schedule, duration, location, nil
+}
+
+func (w *MaintenanceWindow) open(now time.Time) bool {
+	schedule, duration, location, err := w.parse()
+	if err != nil {
+		return false
+	}
+	return schedule.startedWithin(now.In(location), duration)
+}
+
+func (r *Rollout) windowOpen(now time.Time) bool {
+	if len(r.MaintenanceWindows) == 0 {
+		return true
+	}
+	for _, window := range r.MaintenanceWindows {
+		if window.open(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rollout) maxUnavailable() int {
+	if r.MaxUnavailable > 0 {
+		return r.MaxUnavailable
+	}
+	return 1
+}
+
+func (r *Rollout) settle() time.Duration {
+	if r.HealthGates != nil && r.HealthGates.Settle != "" {
+		if settle, err := time.ParseDuration(r.HealthGates.Settle); err == nil {
+			return settle
+		}
+	}
+	return 0
+}
+
+func (r *Rollout) timeout() time.Duration {
+	if r.HealthGates != nil && r.HealthGates.Timeout != "" {
+		if timeout, err := time.ParseDuration(r.HealthGates.Timeout); err == nil {
+			return timeout
+		}
+	}
+	return 30 * time.Minute
+}
+
+// PoolRollout is reported in the current state for each pool with a rollout policy.
+// It is carried over to the next iteration, so the rollout continues where it stopped
+type PoolRollout struct {
+	WindowOpen bool
+	// Disrupted are the machines that are being disrupted
+	Disrupted map[string]*Disruption `yaml:",omitempty"`
+	// Failed are the disrupted machines that were not available in time
+	Failed []string `yaml:",omitempty"`
+	Paused string   `yaml:",omitempty"`
+	// Recovered is when the last disrupted machine was available again
+	Recovered time.Time `yaml:",omitempty"`
+}
+
+type Disruption struct {
+	Since       time.Time
+	Unavailable bool `yaml:",omitempty"`
+}
+
+func (p *PoolRollout) failed(id string) bool {
+	for _, failed := range p.Failed {
+		if failed == id {
+			return true
+		}
+	}
+	return false
+}
+
+// available returns false while ORBITER cordons the machines node for updating, rebooting or deleting it
+func available(machine *initializedMachine) bool {
+	return machine.currentMachine.Ready &&
+		!machine.currentMachine.Rebooting &&
+		!machine.currentMachine.Updating &&
+		(machine.currentNodeagent == nil || machine.currentNodeagent.NodeIsReady) &&
+		(machine.node == nil || !kubernetes.Cordoned(machine.node))
+}
+
+// trackRollout follows the disrupted machines of a pool until they are available again and reports the rollout
+func trackRollout(monitor mntr.Monitor, curr *CurrentCluster, pool *initializedPool, machines []*initializedMachine, now time.Time) {
+	rollout := pool.desired.Rollout
+	if rollout == nil {
+		return
+	}
+
+	key := poolKey(pool.desired)
+	state, ok := curr.Rollouts[key]
+	if !ok {
+		state = &PoolRollout{}
+	}
+	if state.Disrupted == nil {
+		state.Disrupted = make(map[string]*Disruption)
+	}
+
+	existing := make(map[string]*initializedMachine, len(machines))
+	for _, machine := range machines {
+		existing[machine.infra.ID()] = machine
+	}
+
+	state.Paused = ""
+	for id, disrupted := range state.Disrupted {
+		machine, ok := existing[id]
+		switch {
+		case !ok:
+			// replaced machines are removed
+			delete(state.Disrupted, id)
+			state.Recovered = now
+		case !available(machine):
+			disrupted.Unavailable = true
+			if now.Sub(disrupted.Since) < rollout.timeout() {
+				continue
+			}
+			if rollout.PauseOnFailure {
+				state.Paused = fmt.Sprintf("machine %s is not available after %s", id, rollout.timeout())
+				continue
+			}
+			delete(state.Disrupted, id)
+			if !state.failed(id) {
+				state.Failed = append(state.Failed, id)
+			}
+			monitor.WithField("machine", id).Info("Disrupted machine is not available in time, continuing rollout")
+		case disrupted.Unavailable || now.Sub(disrupted.Since) >= rollout.timeout():
+			delete(state.Disrupted, id)
+			state.Recovered = now
+		}
+	}
+
+	var failed []string
+	for _, id := range state.Failed {
+		if machine, ok := existing[id]; ok && !available(machine) {
+			failed = append(failed, id)
+		}
+	}
+	sort.Strings(failed)
+	state.Failed = failed
+	state.WindowOpen = rollout.windowOpen(now)
+
+	if curr.Rollouts == nil {
+		curr.Rollouts = make(map[string]*PoolRollout)
+	}
+	curr.Rollouts[key] = state
+	pool.rollout = state
+}
+
+var healthCheckClient = &http.Client{Timeout: 10 * time.Second}
+
+func healthChecksPass(urls []string) error {
+	for _, url := range urls {
+		resp, err := healthCheckClient.Get(url)
+		if err != nil {
+			return errors.Wrapf(err, "health check %s failed", url)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return errors.Errorf("health check %s responded with status %d", url, resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+// mayDisrupt returns true if the pools rollout policy allows disrupting the machine now.
+// Machines that are being disrupted already may always continue
+func mayDisrupt(monitor mntr.Monitor, machine *initializedMachine, machines []*initializedMachine, now time.Time) bool {
+	rollout := machine.pool.desired.Rollout
+	state := machine.pool.rollout
+	if rollout == nil || state == nil {
+		return true
+	}
+
+	id := machine.infra.ID()
+	if _, ok := state.Disrupted[id]; ok {
+		return true
+	}
+
+	monitor = monitor.WithFields(map[string]interface{}{
+		"machine": id,
+		"pool":    machine.pool.desired.Pool,
+	})
+
+	deny := func(reason string) bool {
+		monitor.WithField("reason", reason).Info("Rollout policy postpones disrupting the machine")
+		return false
+	}
+
+	if state.Paused != "" {
+		return deny(state.Paused)
+	}
+
+	if !rollout.windowOpen(now) {
+		return deny("no maintenance window is open")
+	}
+
+	unavailable := make(map[string]bool)
+	for disruptedID := range state.Disrupted {
+		unavailable[disruptedID] = true
+	}
+	for _, other := range machines {
+		otherID := other.infra.ID()
+		if other.pool == machine.pool && otherID != id && !state.failed(otherID) && !available(other) {
+			unavailable[otherID] = true
+		}
+	}
+	if len(unavailable) >= rollout.maxUnavailable() {
+		return deny(fmt.Sprintf("%d machines are unavailable", len(unavailable)))
+	}
+
+	if settled := state.Recovered.Add(rollout.settle()); now.Before(settled) {
+		return deny(fmt.Sprintf("settling until %s", settled.Format(time.RFC3339)))
+	}
+
+	if rollout.HealthGates != nil {
+		if err := healthChecksPass(rollout.HealthGates.HTTPChecks); err != nil {
+			return deny(err.Error())
+		}
+	}
+
+	state.Disrupted[id] = &Disruption{Since: now}
+	return true
+}
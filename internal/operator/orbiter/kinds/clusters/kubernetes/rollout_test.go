@@ -0,0 +1,222 @@
+package kubernetes
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	core "k8s.io/api/core/v1"
+
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/kubernetes"
+)
+
+func TestMaintenanceWindowOpen(t *testing.T) {
+	// Saturday
+	saturday := time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		window  MaintenanceWindow
+		now     time.Time
+		want    bool
+		wantErr bool
+	}{{
+		name:   "It should be open after the start",
+		window: MaintenanceWindow{Start: "0 2 * * 6", Duration: "4h"},
+		now:    saturday.Add(3 * time.Hour),
+		want:   true,
+	}, {
+		name:   "It should be closed after the duration",
+		window: MaintenanceWindow{Start: "0 2 * * 6", Duration: "4h"},
+		now:    saturday.Add(6 * time.Hour),
+	}, {
+		name:   "It should be closed on other days",
+		window: MaintenanceWindow{Start: "0 2 * * 6", Duration: "4h"},
+		now:    saturday.Add(27 * time.Hour),
+	}, {
+		name:   "It should evaluate the start in the time zone",
+		window: MaintenanceWindow{Start: "0 2 * * 6", Duration: "1h", TimeZone: "Europe/Zurich"},
+		now:    saturday.Add(time.Hour + 30*time.Minute),
+		want:   true,
+	}, {
+		name:   "It should stay open over midnight",
+		window: MaintenanceWindow{Start: "0 22 * * 1-5", Duration: "4h"},
+		now:    saturday.Add(time.Hour),
+		want:   true,
+	}, {
+		name:   "It should support lists and steps",
+		window: MaintenanceWindow{Start: "*/15 1,3 * * *", Duration: "5m"},
+		now:    saturday.Add(3*time.Hour + 47*time.Minute),
+		want:   true,
+	}, {
+		name:    "It should fail on invalid expressions",
+		window:  MaintenanceWindow{Start: "0 25 * * *", Duration: "1h"},
+		wantErr: true,
+	}, {
+		name:    "It should fail on unknown time zones",
+		window:  MaintenanceWindow{Start: "0 2 * * *", Duration: "1h", TimeZone: "Mars/Olympus"},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := tt.window.parse()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got := tt.window.open(tt.now); got != tt.want {
+				t.Errorf("open() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMayDisrupt(t *testing.T) {
+	now := time.Date(2025, 1, 4, 3, 0, 0, 0, time.UTC)
+
+	newPool := func(name string, rollout *Rollout) *initializedPool {
+		return &initializedPool{desired: Pool{Provider: "static", Pool: name, Rollout: rollout}}
+	}
+	newMachine := func(id string, pool *initializedPool, ready bool) *initializedMachine {
+		return &initializedMachine{
+			infra:          testMachine{id: id},
+			currentMachine: &Machine{Ready: ready},
+			pool:           pool,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		rollout   *Rollout
+		ready     []bool
+		disrupted map[string]*Disruption
+		recovered time.Time
+		want      bool
+	}{{
+		name:  "It should allow everything without a rollout policy",
+		ready: []bool{true, false},
+		want:  true,
+	}, {
+		name:    "It should postpone outside the maintenance windows",
+		rollout: &Rollout{MaintenanceWindows: []*MaintenanceWindow{{Start: "0 4 * * *", Duration: "1h"}}},
+		ready:   []bool{true, true},
+	}, {
+		name:    "It should allow within the maintenance windows",
+		rollout: &Rollout{MaintenanceWindows: []*MaintenanceWindow{{Start: "0 2 * * *", Duration: "2h"}}},
+		ready:   []bool{true, true},
+		want:    true,
+	}, {
+		name:    "It should postpone while other machines are unavailable",
+		rollout: &Rollout{},
+		ready:   []bool{true, false},
+	}, {
+		name:    "It should allow up to maxUnavailable machines",
+		rollout: &Rollout{MaxUnavailable: 2},
+		ready:   []bool{true, false},
+		want:    true,
+	}, {
+		name:      "It should allow continuing disruptions",
+		rollout:   &Rollout{},
+		ready:     []bool{false, true},
+		disrupted: map[string]*Disruption{"machine-0": {Since: now}},
+		want:      true,
+	}, {
+		name:      "It should postpone while settling",
+		rollout:   &Rollout{HealthGates: &HealthGates{Settle: "10m"}},
+		ready:     []bool{true, true},
+		recovered: now.Add(-5 * time.Minute),
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newPool(tt.name, tt.rollout)
+			var machines []*initializedMachine
+			for idx, ready := range tt.ready {
+				machines = append(machines, newMachine(fmt.Sprintf("machine-%d", idx), pool, ready))
+			}
+
+			if tt.rollout != nil {
+				pool.rollout = &PoolRollout{
+					Disrupted: make(map[string]*Disruption),
+					Recovered: tt.recovered,
+				}
+				if tt.disrupted != nil {
+					pool.rollout.Disrupted = tt.disrupted
+				}
+			}
+
+			if got := mayDisrupt(mntr.Monitor{}, machines[0], machines, now); got != tt.want {
+				t.Errorf("mayDisrupt() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackRollout(t *testing.T) {
+	now := time.Date(2025, 1, 4, 3, 0, 0, 0, time.UTC)
+	pool := &initializedPool{desired: Pool{Provider: "static", Pool: "workers", Rollout: &Rollout{}}}
+	machines := []*initializedMachine{{
+		infra:          testMachine{id: "recovered"},
+		currentMachine: &Machine{Ready: true},
+		pool:           pool,
+	}, {
+		infra:          testMachine{id: "failed"},
+		currentMachine: &Machine{},
+		pool:           pool,
+	}, {
+		infra:          testMachine{id: "disrupted"},
+		currentMachine: &Machine{},
+		pool:           pool,
+	}}
+
+	curr := &CurrentCluster{Rollouts: map[string]*PoolRollout{"static/workers": {
+		Disrupted: map[string]*Disruption{
+			"recovered": {Since: now.Add(-10 * time.Minute), Unavailable: true},
+			"failed":    {Since: now.Add(-time.Hour)},
+			"disrupted": {Since: now.Add(-time.Minute)},
+			"replaced":  {Since: now.Add(-time.Hour)},
+		},
+	}}}
+
+	trackRollout(mntr.Monitor{}, curr, pool, machines, now)
+
+	got := curr.Rollouts["static/workers"]
+	if pool.rollout != got {
+		t.Error("trackRollout() must pass the carried over rollout to the pool")
+	}
+	want := &PoolRollout{
+		WindowOpen: true,
+		Disrupted:  map[string]*Disruption{"disrupted": {Since: now.Add(-time.Minute), Unavailable: true}},
+		Failed:     []string{"failed"},
+		Recovered:  now,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("trackRollout() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	tests := []struct {
+		name string
+		node *core.Node
+		want bool
+	}{{
+		name: "It should be available without a node",
+		want: true,
+	}, {
+		name: "It should be available with an untainted node",
+		node: &core.Node{},
+		want: true,
+	}, {
+		name: "It should be unavailable while the node is cordoned",
+		node: &core.Node{Spec: core.NodeSpec{Taints: []core.Taint{{Key: kubernetes.TaintKeyPrefix + kubernetes.Deleting.String(), Effect: core.TaintEffectNoSchedule}}}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine := &initializedMachine{currentMachine: &Machine{Ready: true}, node: tt.node}
+			if got := available(machine); got != tt.want {
+				t.Errorf("available() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
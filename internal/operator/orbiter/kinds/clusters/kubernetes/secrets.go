@@ -8,7 +8,20 @@ func getSecretsMap(desiredKind *DesiredV0) map[string]*secret.Secret {
 	if desiredKind.Spec.Kubeconfig == nil {
 		desiredKind.Spec.Kubeconfig = &secret.Secret{}
 	}
-	return map[string]*secret.Secret{
+	secrets := map[string]*secret.Secret{
 		"kubeconfig": desiredKind.Spec.Kubeconfig,
 	}
+
+	if backup := desiredKind.Spec.Backup; backup != nil {
+		backup.EncryptionKey = secret.InitIfNil(backup.EncryptionKey)
+		secrets["backupencryptionkey"] = backup.EncryptionKey
+	}
+
+	if backup := desiredKind.Spec.Backup; backup != nil && backup.S3 != nil {
+		backup.S3.AccessKeyID = secret.InitIfNil(backup.S3.AccessKeyID)
+		backup.S3.SecretAccessKey = secret.InitIfNil(backup.S3.SecretAccessKey)
+		secrets["backups3accesskeyid"] = backup.S3.AccessKeyID
+		secrets["backups3secretaccesskey"] = backup.S3.SecretAccessKey
+	}
+	return secrets
 }
@@ -2,7 +2,6 @@ package kubernetes
 
 import (
 	"errors"
-	"strconv"
 	"strings"
 
 	"github.com/caos/orbos/internal/operator/nodeagent/dep/sysctl"
@@ -11,85 +10,21 @@ import (
 	"github.com/caos/orbos/mntr"
 )
 
+// KubernetesVersion indexes the releases from the version catalog
 type KubernetesVersion int
 
-const (
-	Unknown KubernetesVersion = iota
-	V1x15x0
-	V1x15x1
-	V1x15x2
-	V1x15x3
-	V1x15x4
-	V1x15x5
-	V1x15x6
-	V1x15x7
-	V1x15x8
-	V1x15x9
-	V1x15x10
-	V1x15x11
-	V1x15x12
-	V1x16x0
-	V1x16x1
-	V1x16x2
-	V1x16x3
-	V1x16x4
-	V1x16x5
-	V1x16x6
-	V1x16x7
-	V1x16x8
-	V1x16x9
-	V1x16x10
-	V1x16x11
-	V1x16x12
-	V1x16x13
-	V1x16x14
-	V1x17x0
-	V1x17x1
-	V1x17x2
-	V1x17x3
-	V1x17x4
-	V1x17x5
-	V1x17x6
-	V1x17x7
-	V1x17x8
-	V1x17x9
-	V1x17x10
-	V1x17x11
-	V1x18x0
-	V1x18x1
-	V1x18x2
-	V1x18x3
-	V1x18x4
-	V1x18x5
-	V1x18x6
-	V1x18x7
-	V1x18x8
-)
-
-var kubernetesVersions = []string{
-	"unknown",
-	"v1.15.0", "v1.15.1", "v1.15.2", "v1.15.3", "v1.15.4", "v1.15.5", "v1.15.6", "v1.15.7", "v1.15.8", "v1.15.9", "v1.15.10", "v1.15.11", "v1.15.12",
-	"v1.16.0", "v1.16.1", "v1.16.2", "v1.16.3", "v1.16.4", "v1.16.5", "v1.16.6", "v1.16.7", "v1.16.8", "v1.16.9", "v1.16.10", "v1.16.11", "v1.16.12", "v1.16.13", "v1.16.14",
-	"v1.17.0", "v1.17.1", "v1.17.2", "v1.17.3", "v1.17.4", "v1.17.5", "v1.17.6", "v1.17.7", "v1.17.8", "v1.17.9", "v1.17.10", "v1.17.11",
-	"v1.18.0", "v1.18.1", "v1.18.2", "v1.18.3", "v1.18.4", "v1.18.5", "v1.18.6", "v1.18.7", "v1.18.8"}
+const Unknown KubernetesVersion = 0
 
 func (k KubernetesVersion) String() string {
-	return kubernetesVersions[k]
+	return releases[k].version
 }
 
-func (k KubernetesVersion) DefineSoftware() common.Software {
-	dockerVersion := "docker-ce v19.03.5"
-	//	if minor, err := k.ExtractMinor(); err != nil && minor <= 15 {
-	//		dockerVersion = "docker-ce v18.09.6"
-	//	}
-
-	sysctlPkg := common.Package{}
-	sysctl.Enable(&sysctlPkg, common.IpForward)
-	sysctl.Enable(&sysctlPkg, common.BridgeNfCallIptables)
-	sysctl.Enable(&sysctlPkg, common.BridgeNfCallIp6tables)
-	return common.Software{
-		Swap: common.Package{Version: "disabled"},
-		Containerruntime: common.Package{Version: dockerVersion, Config: map[string]string{
+func (k KubernetesVersion) DefineSoftware(cri ContainerRuntime) common.Software {
+	containerRuntime := common.Package{Version: releases[k].runtime}
+	switch runtime := strings.Fields(containerRuntime.Version); {
+	case len(runtime) == 0:
+	case runtime[0] == "docker-ce":
+		containerRuntime.Config = map[string]string{
 			"daemon.json": `{
 	"exec-opts": ["native.cgroupdriver=systemd"],
 	"log-driver": "json-file",
@@ -98,12 +33,45 @@ func (k KubernetesVersion) DefineSoftware() common.Software {
 	},
 	"storage-driver": "overlay2"
 }`,
-		}},
-		Kubelet: common.Package{Version: k.String()},
-		Kubeadm: common.Package{Version: k.String()},
-		Kubectl: common.Package{Version: k.String()},
-		Sysctl:  sysctlPkg,
+		}
+	case runtime[0] == "containerd":
+		containerRuntime.Config = map[string]string{
+			"config.toml": cri.containerdConfig(),
+		}
+	}
+
+	sysctlPkg := common.Package{}
+	sysctl.Enable(&sysctlPkg, common.IpForward)
+	sysctl.Enable(&sysctlPkg, common.BridgeNfCallIptables)
+	sysctl.Enable(&sysctlPkg, common.BridgeNfCallIp6tables)
+	return common.Software{
+		Swap:             common.Package{Version: "disabled"},
+		Containerruntime: containerRuntime,
+		Kubelet:          common.Package{Version: k.String()},
+		Kubeadm:          common.Package{Version: k.String()},
+		Kubectl:          common.Package{Version: k.String()},
+		Sysctl:           sysctlPkg,
+	}
+}
+
+// criSocket returns the socket kubeadm and the kubelet use for talking to the container runtime
+func criSocket(containerRuntime common.Package) string {
+	switch fields := strings.Fields(containerRuntime.Version); {
+	case len(fields) == 0:
+		return ""
+	case fields[0] == "containerd":
+		return "unix:///run/containerd/containerd.sock"
+	default:
+		return "/var/run/dockershim.sock"
+	}
+}
+
+// kubeadmAPIVersion returns the newest kubeadm configuration API version the kubeadm of this release supports
+func (k KubernetesVersion) kubeadmAPIVersion() string {
+	if releases[k].minor >= 22 {
+		return "kubeadm.k8s.io/v1beta3"
 	}
+	return "kubeadm.k8s.io/v1beta2"
 }
 
 func KubernetesSoftware(current common.Software) common.Software {
@@ -118,29 +86,30 @@ func KubernetesSoftware(current common.Software) common.Software {
 }
 
 func ParseString(version string) KubernetesVersion {
-	for idx, k8sVersion := range kubernetesVersions {
-		if k8sVersion == version {
+	for idx := range releases {
+		if idx > 0 && releases[idx].version == version {
 			return KubernetesVersion(idx)
 		}
 	}
-	return KubernetesVersion(0)
-}
-
-func (k KubernetesVersion) equals(other KubernetesVersion) bool {
-	return string(k) == string(other)
+	return Unknown
 }
 
+// NextHighestMinor returns the latest patch release of the next minor
 func (k KubernetesVersion) NextHighestMinor() KubernetesVersion {
-	switch k {
-	case V1x15x0, V1x15x1, V1x15x2, V1x15x3, V1x15x4, V1x15x5, V1x15x6, V1x15x7, V1x15x8, V1x15x9, V1x15x10, V1x15x11, V1x15x12:
-		return V1x16x14
-	case V1x16x0, V1x16x1, V1x16x2, V1x16x3, V1x16x4, V1x16x5, V1x16x6, V1x16x7, V1x16x8, V1x16x9, V1x16x10, V1x16x11, V1x16x12, V1x16x13, V1x16x14:
-		return V1x17x11
-	case V1x17x0, V1x17x1, V1x17x2, V1x17x3, V1x17x4, V1x17x5, V1x17x6, V1x17x7, V1x17x8, V1x17x9, V1x17x10, V1x17x11:
-		return V1x18x8
-	default:
+	if k == Unknown {
 		return Unknown
 	}
+
+	next := Unknown
+	for idx := int(k) + 1; idx < len(releases); idx++ {
+		if releases[idx].minor > releases[k].minor+1 {
+			break
+		}
+		if releases[idx].minor == releases[k].minor+1 {
+			next = KubernetesVersion(idx)
+		}
+	}
+	return next
 }
 
 func (k KubernetesVersion) ExtractMinor(monitor mntr.Monitor) (int, error) {
@@ -156,10 +125,9 @@ func (k KubernetesVersion) extractNumber(monitor mntr.Monitor, position int) (in
 		return 0, errors.New("Unknown kubernetes version")
 	}
 
-	parts := strings.Split(k.String(), ".")
-	version, err := strconv.ParseInt(parts[position], 10, 8)
-	if err != nil {
-		return 0, err
+	version := releases[k].minor
+	if position == 2 {
+		version = releases[k].patch
 	}
 
 	monitor.WithFields(map[string]interface{}{
@@ -168,7 +136,7 @@ func (k KubernetesVersion) extractNumber(monitor mntr.Monitor, position int) (in
 		"string":   k,
 	}).Debug("Extracted from semantic version")
 
-	return int(version), nil
+	return version, nil
 }
 
 func softwareContains(this common.Software, that common.Software) bool {
@@ -0,0 +1,238 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/mntr"
+)
+
+func TestParseCatalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		catalog string
+		want    []string
+		wantErr bool
+	}{{
+		name: "It should expand all patches of a minor sorted by minor",
+		catalog: `
+- minor: 21
+  latestpatch: 0
+  containerruntime: docker-ce v20.10.24
+- minor: 20
+  latestpatch: 1
+  containerruntime: docker-ce v20.10.24
+`,
+		want: []string{"unknown", "v1.20.0", "v1.20.1", "v1.21.0"},
+	}, {
+		name: "It should fail for duplicate minors",
+		catalog: `
+- minor: 20
+  latestpatch: 1
+  containerruntime: docker-ce v20.10.24
+- minor: 20
+  latestpatch: 2
+  containerruntime: docker-ce v20.10.24
+`,
+		wantErr: true,
+	}, {
+		name: "It should fail for invalid container runtimes",
+		catalog: `
+- minor: 20
+  latestpatch: 1
+  containerruntime: docker-ce
+`,
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCatalog(tt.catalog)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCatalog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var versions []string
+			for _, rel := range got {
+				versions = append(versions, rel.version)
+			}
+			if strings.Join(versions, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parseCatalog() = %v, want %v", versions, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubernetesVersion_DefineSoftware(t *testing.T) {
+	for version, runtime := range map[string]string{
+		"v1.18.8":  "docker-ce v19.03.5",
+		"v1.23.17": "docker-ce v20.10.24",
+		"v1.28.15": "containerd v1.7.27",
+	} {
+		k8s := ParseString(version)
+		if k8s == Unknown {
+			t.Fatalf("version %s is not in the catalog", version)
+		}
+		sw := k8s.DefineSoftware(ContainerRuntime{})
+		if sw.Containerruntime.Version != runtime {
+			t.Errorf("container runtime for %s = %s, want %s", version, sw.Containerruntime.Version, runtime)
+		}
+		if sw.Kubeadm.Version != version || sw.Kubelet.Version != version || sw.Kubectl.Version != version {
+			t.Errorf("kubernetes packages for %s are not pinned to the cluster version: %+v", version, sw)
+		}
+	}
+
+	if ParseString("v1.15.13") != Unknown {
+		t.Error("patches after the latest patch should be unknown")
+	}
+}
+
+func TestKubernetesVersion_DefineSoftware_Containerd(t *testing.T) {
+	spec := &Spec{
+		CustomImageRegistry: "registry.example.com/",
+		ContainerRuntime: ContainerRuntime{
+			RegistryMirrors: map[string][]string{
+				"docker.io": {"https://mirror.example.com", "https://registry-1.docker.io"},
+			},
+		},
+	}
+
+	config := ParseString("v1.28.15").DefineSoftware(spec.containerRuntime()).Containerruntime.Config["config.toml"]
+	for _, want := range []string{
+		`sandbox_image = "registry.example.com/pause:3.9"`,
+		`SystemdCgroup = true`,
+		`[plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+  endpoint = ["https://mirror.example.com", "https://registry-1.docker.io"]`,
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("containerd config does not contain %s:\n%s", want, config)
+		}
+	}
+
+	if socket := criSocket(ParseString("v1.28.15").DefineSoftware(ContainerRuntime{}).Containerruntime); socket != "unix:///run/containerd/containerd.sock" {
+		t.Errorf("unexpected cri socket %s", socket)
+	}
+}
+
+type testMachine struct {
+	infra.Machine
+	id string
+}
+
+func (t testMachine) ID() string { return t.id }
+
+func TestFindPath(t *testing.T) {
+
+	machine := func(id, kubelet string) *initializedMachine {
+		node := &v1.Node{}
+		node.Status.NodeInfo.KubeletVersion = kubelet
+		return &initializedMachine{infra: testMachine{id: id}, node: node}
+	}
+
+	tests := []struct {
+		name     string
+		machines []*initializedMachine
+		target   string
+		wantFrom string
+		wantTo   string
+		wantErr  bool
+	}{{
+		name:     "It should stay on the target version",
+		machines: []*initializedMachine{machine("a", "v1.28.2"), machine("b", "v1.28.2")},
+		target:   "v1.28.2",
+		wantFrom: "v1.28.2",
+		wantTo:   "v1.28.2",
+	}, {
+		name:     "It should upgrade patches directly",
+		machines: []*initializedMachine{machine("a", "v1.28.2")},
+		target:   "v1.28.15",
+		wantFrom: "v1.28.2",
+		wantTo:   "v1.28.15",
+	}, {
+		name:     "It should upgrade from the lowest kubelet",
+		machines: []*initializedMachine{machine("a", "v1.29.1"), machine("b", "v1.28.2")},
+		target:   "v1.29.3",
+		wantFrom: "v1.28.2",
+		wantTo:   "v1.29.3",
+	}, {
+		name:     "It should upgrade one minor at a time",
+		machines: []*initializedMachine{machine("a", "v1.23.4")},
+		target:   "v1.26.1",
+		wantFrom: "v1.23.4",
+		wantTo:   "v1.24.17",
+	}, {
+		name:     "It should not downgrade minors",
+		machines: []*initializedMachine{machine("a", "v1.30.0")},
+		target:   "v1.29.15",
+		wantErr:  true,
+	}, {
+		name:     "It should fail for kubelets that are not in the catalog",
+		machines: []*initializedMachine{machine("a", "v1.99.0")},
+		target:   "v1.29.15",
+		wantErr:  true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := findPath(mntr.Monitor{}, tt.machines, ParseString(tt.target), ContainerRuntime{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if from.Kubelet.Version != tt.wantFrom {
+				t.Errorf("findPath() from = %s, want %s", from.Kubelet.Version, tt.wantFrom)
+			}
+			if to.Kubelet.Version != tt.wantTo {
+				t.Errorf("findPath() to = %s, want %s", to.Kubelet.Version, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestUpgradeSteps(t *testing.T) {
+
+	machine := func(id, kubelet string) *initializedMachine {
+		node := &v1.Node{}
+		node.Status.NodeInfo.KubeletVersion = kubelet
+		return &initializedMachine{infra: testMachine{id: id}, node: node}
+	}
+
+	tests := []struct {
+		name     string
+		machines []*initializedMachine
+		target   string
+		want     string
+	}{{
+		name:     "It should not plan any step for clusters on the target version",
+		machines: []*initializedMachine{machine("a", "v1.28.2")},
+		target:   "v1.28.2",
+		want:     "",
+	}, {
+		name:     "It should plan a single step for patches",
+		machines: []*initializedMachine{machine("a", "v1.28.2")},
+		target:   "v1.28.15",
+		want:     "v1.28.2->v1.28.15",
+	}, {
+		name:     "It should plan one step per minor",
+		machines: []*initializedMachine{machine("a", "v1.23.4"), machine("b", "v1.24.0")},
+		target:   "v1.26.1",
+		want:     "v1.23.4->v1.24.17,v1.24.17->v1.25.16,v1.25.16->v1.26.1",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := upgradeSteps(mntr.Monitor{}, tt.machines, ParseString(tt.target))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, step := range steps {
+				got = append(got, step[0].String()+"->"+step[1].String())
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("upgradeSteps() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/caos/orbos/pkg/kubernetes"
 
@@ -11,6 +12,8 @@ import (
 	"github.com/caos/orbos/mntr"
 )
 
+const criSocketAnnotation = "kubeadm.alpha.kubernetes.io/cri-socket"
+
 type initializedMachines []*initializedMachine
 
 func (c initializedMachines) Len() int           { return len(c) }
@@ -20,12 +23,13 @@ func (c initializedMachines) Less(i, j int) bool { return c[i].infra.ID() < c[j]
 func ensureSoftware(
 	monitor mntr.Monitor,
 	target KubernetesVersion,
+	cri ContainerRuntime,
 	k8sClient *kubernetes.Client,
 	controlplane []*initializedMachine,
 	workers []*initializedMachine) (bool, error) {
 
 	sortedMachines := append(controlplane, workers...)
-	from, to, err := findPath(monitor, sortedMachines, target)
+	from, to, err := findPath(monitor, sortedMachines, target, cri)
 	if err != nil {
 		return false, err
 	}
@@ -44,10 +48,10 @@ func findPath(
 	monitor mntr.Monitor,
 	machines []*initializedMachine,
 	target KubernetesVersion,
+	cri ContainerRuntime,
 ) (common.Software, common.Software, error) {
 
 	var overallLowKubelet KubernetesVersion
-	var overallLowKubeletMinor int
 	zeroSW := common.Software{}
 
 	for _, machine := range machines {
@@ -74,7 +78,6 @@ func findPath(
 
 		if overallLowKubelet == Unknown {
 			overallLowKubelet = kubelet
-			overallLowKubeletMinor = kubeletMinor
 			continue
 		}
 
@@ -94,12 +97,11 @@ func findPath(
 		if kubeletMinor < tmpOverallLowKubeletMinor ||
 			kubeletMinor == tmpOverallLowKubeletMinor && kubeletPatch < tmpOverallLowKubeletPatch {
 			overallLowKubelet = kubelet
-			overallLowKubeletMinor = kubeletMinor
 		}
 	}
 
 	if overallLowKubelet == target || overallLowKubelet == Unknown {
-		target := target.DefineSoftware()
+		target := target.DefineSoftware(cri)
 		monitor.WithFields(map[string]interface{}{
 			"from": overallLowKubelet,
 			"to":   target,
@@ -107,35 +109,74 @@ func findPath(
 		return target, target, nil
 	}
 
+	next, err := nextVersion(monitor, overallLowKubelet, target)
+	if err != nil {
+		return zeroSW, zeroSW, err
+	}
+	return overallLowKubelet.DefineSoftware(cri), next.DefineSoftware(cri), nil
+}
+
+// nextVersion returns the version the cluster is upgraded to next on its way from the lowest kubelet version to the target version
+func nextVersion(monitor mntr.Monitor, from KubernetesVersion, target KubernetesVersion) (KubernetesVersion, error) {
+
+	fromMinor, err := from.ExtractMinor(monitor)
+	if err != nil {
+		return Unknown, errors.Wrapf(err, "extracting minor from kubelet version %s failed", from)
+	}
+
 	targetMinor, err := target.ExtractMinor(monitor)
 	if err != nil {
-		return zeroSW, zeroSW, errors.Wrapf(err, "extracting minor from target version %s failed", target)
+		return Unknown, errors.Wrapf(err, "extracting minor from target version %s failed", target)
 	}
 
-	if targetMinor < overallLowKubeletMinor {
-		return zeroSW, zeroSW, errors.Errorf("downgrading from %s to %s is not possible as they are on different minors", overallLowKubelet, target)
+	if targetMinor < fromMinor {
+		return Unknown, errors.Errorf("downgrading from %s to %s is not possible as they are on different minors", from, target)
 	}
 
-	overallLowKubeletSoftware := overallLowKubelet.DefineSoftware()
-	if (targetMinor - overallLowKubeletMinor) < 2 {
+	if (targetMinor - fromMinor) < 2 {
 		monitor.WithFields(map[string]interface{}{
-			"from":                   overallLowKubelet,
+			"from":                   from,
 			"to":                     target,
 			"targetMinor":            targetMinor,
-			"overallLowKubeletMinor": overallLowKubeletMinor,
+			"overallLowKubeletMinor": fromMinor,
 		}).Debug("Desired version can be reached directly")
-		return overallLowKubeletSoftware, target.DefineSoftware(), nil
+		return target, nil
 	}
 
-	nextHighestMinor := overallLowKubelet.NextHighestMinor()
+	nextHighestMinor := from.NextHighestMinor()
+	if nextHighestMinor == Unknown {
+		return Unknown, errors.Errorf("the version catalog contains no minor after %s", from)
+	}
 	monitor.WithFields(map[string]interface{}{
-		"from":         overallLowKubelet,
-		"fromMinor":    overallLowKubeletMinor,
+		"from":         from,
+		"fromMinor":    fromMinor,
 		"intermediate": nextHighestMinor,
 		"to":           target,
 		"toMinor":      targetMinor,
 	}).Debug("Desired version can be reached via an intermediate version")
-	return overallLowKubeletSoftware, nextHighestMinor.DefineSoftware(), nil
+	return nextHighestMinor, nil
+}
+
+// upgradeSteps returns all versions the cluster passes until it runs the target version
+func upgradeSteps(monitor mntr.Monitor, machines []*initializedMachine, target KubernetesVersion) ([][2]KubernetesVersion, error) {
+	from, to, err := findPath(monitor, machines, target, ContainerRuntime{})
+	if err != nil {
+		return nil, err
+	}
+
+	var steps [][2]KubernetesVersion
+	current, next := ParseString(from.Kubelet.Version), ParseString(to.Kubelet.Version)
+	for current != next {
+		steps = append(steps, [2]KubernetesVersion{current, next})
+		if next == target {
+			break
+		}
+		current = next
+		if next, err = nextVersion(monitor, current, target); err != nil {
+			return nil, err
+		}
+	}
+	return steps, nil
 }
 
 func step(
@@ -146,9 +187,10 @@ func step(
 	to common.Software,
 ) (bool, error) {
 
+	var skipped bool
 	for _, machine := range sortedMachines {
-		if machine.node != nil && machine.node.Labels["orbos.ch/updating"] == machine.node.Status.NodeInfo.KubeletVersion {
-			delete(machine.node.Labels, "orbos.ch/updating")
+		if machine.node != nil && machine.node.Labels[kubernetes.UpdatingLabel] == machine.node.Status.NodeInfo.KubeletVersion {
+			delete(machine.node.Labels, kubernetes.UpdatingLabel)
 			if k8sClient.Tainted(machine.node, kubernetes.Updating) {
 				machine.node.Spec.Taints = k8sClient.RemoveFromTaints(machine.node.Spec.Taints, kubernetes.Updating)
 			}
@@ -159,17 +201,32 @@ func step(
 	}
 	for idx, machine := range sortedMachines {
 
-		next, err := plan(k8sClient, monitor, machine, idx == 0, from, to)
+		machineMonitor, endSpan := monitor.WithField("machine", machine.infra.ID()).StartSpan("upgrade machine " + machine.infra.ID())
+		next, err := plan(k8sClient, machineMonitor, machine, idx == 0, from, to)
 		if err != nil {
-			return false, errors.Wrapf(err, "planning machine %s failed", machine.infra.ID())
+			err = errors.Wrapf(err, "planning machine %s failed", machine.infra.ID())
+			endSpan(err)
+			return false, err
 		}
 
 		if next == nil {
+			endSpan(nil)
+			continue
+		}
+		if machine.currentMachine.Joined && !mayDisrupt(machineMonitor, machine, sortedMachines, time.Now()) {
+			endSpan(nil)
+			return false, nil
+		}
+		err = next()
+		endSpan(err)
+		if errors.Is(err, errDrainSkipped) {
+			machineMonitor.Info("Continuing with the next machine as pods block the drain")
+			skipped = true
 			continue
 		}
-		return false, next()
+		return false, err
 	}
-	return true, nil
+	return !skipped, nil
 }
 
 func plan(
@@ -196,8 +253,12 @@ func plan(
 		if isControlplane || machine.node == nil || machine.node.Spec.Unschedulable {
 			return nil
 		}
-		machine.node.Labels["orbos.ch/updating"] = to.Kubelet.Version
-		return k8sClient.Drain(machine.currentMachine, machine.node, kubernetes.Updating)
+		machine.node.Labels[kubernetes.UpdatingLabel] = to.Kubelet.Version
+		drained, err := drainMachine(k8sClient, machine, machine.node, kubernetes.Updating)
+		if err == nil && !drained {
+			err = errDrainSkipped
+		}
+		return err
 	}
 
 	ensureSoftware := func(packages common.Software, phase string) func() error {
@@ -232,6 +293,17 @@ func plan(
 			return err
 		}
 
+		// kubeadm reads the container runtime of a node from this annotation
+		if socket := criSocket(to.Containerruntime); socket != "" && machine.node.Annotations[criSocketAnnotation] != socket {
+			if machine.node.Annotations == nil {
+				machine.node.Annotations = make(map[string]string)
+			}
+			machine.node.Annotations[criSocketAnnotation] = socket
+			if err := k8sClient.UpdateNode(machine.node); err != nil {
+				return err
+			}
+		}
+
 		upgradeAction := "node"
 		if isFirstControlplane {
 			machinemonitor.Info("Migrating first controlplane node")
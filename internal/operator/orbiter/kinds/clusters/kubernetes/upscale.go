@@ -46,10 +46,19 @@ func ensureUpScale(
 	alignPool := func(pool *initializedPool, ensured func(int)) {
 		defer wg.Done()
 
+		var poolErr error
+		_, endSpan := monitor.WithFields(map[string]interface{}{
+			"pool":     pool.desired.Pool,
+			"provider": pool.desired.Provider,
+			"tier":     pool.tier,
+		}).StartSpan("align pool " + pool.desired.Provider + "/" + pool.desired.Pool)
+		defer func() { endSpan(poolErr) }()
+
 		if pool.upscaling > 0 {
 			upscalingDone = false
 			machines, alignErr := newMachines(pool.infra, pool.upscaling)
 			if alignErr != nil {
+				poolErr = alignErr
 				err = helpers.Concat(err, alignErr)
 				return
 			}
@@ -63,6 +72,7 @@ func ensureUpScale(
 		}
 		poolMachines, listErr := pool.machines()
 		if listErr != nil {
+			poolErr = listErr
 			err = helpers.Concat(err, listErr)
 			return
 		}
@@ -167,7 +177,7 @@ nodes:
 
 		defer certsCP.Execute(nil, "sudo kubeadm token delete "+jointoken)
 
-		if k8sVersion.equals(V1x18x0) {
+		if k8sVersion == ParseString("v1.18.0") {
 			if _, err := certsCP.Execute(nil, "sudo kubeadm init phase bootstrap-token"); err != nil {
 				return false, errors.Wrap(err, "Working around kubeadm bug failed, see https://kubernetes.io/docs/setup/production-environment/tools/kubeadm/troubleshooting-kubeadm/#not-possible-to-join-a-v1-18-node-to-a-v1-17-cluster-due-to-missing-rbac")
 			}
@@ -183,7 +193,7 @@ nodes:
 
 	if joinCP != nil {
 
-		if doKubeadmInit && (desired.Spec.Kubeconfig != nil && desired.Spec.Kubeconfig.Value != "" || !oneoff) {
+		if doKubeadmInit && (desired.Spec.Kubeconfig.HasValue() || !oneoff) {
 			return false, errors.New("initializing a cluster is not supported when kubeconfig exists or the flag --recur is passed")
 		}
 
@@ -3,6 +3,7 @@ package dynamic
 import (
 	"bytes"
 	"fmt"
+	"net"
 	"sort"
 	"strconv"
 	"strings"
@@ -91,6 +92,9 @@ func AdaptFunc(whitelist WhiteListFunc) orbiter.AdaptFunc {
 					}
 					if len(t.Whitelist) == 0 {
 						allIPs := orbiter.CIDR("0.0.0.0/0")
+						if orbiter.IPAddress(vip.IP).IPv6() {
+							allIPs = orbiter.CIDR("::/0")
+						}
 						t.Whitelist = []*orbiter.CIDR{&allIPs}
 						migrate = true
 					}
@@ -136,6 +140,16 @@ func AdaptFunc(whitelist WhiteListFunc) orbiter.AdaptFunc {
 				var lbMachines []infra.Machine
 
 				done := true
+
+				var ipv6 bool
+				for _, vips := range desiredKind.Spec {
+					for _, vip := range vips {
+						if orbiter.IPAddress(mapVIP(vip)).IPv6() {
+							ipv6 = true
+						}
+					}
+				}
+
 				desireNodeAgent := func(machine infra.Machine, fw common.Firewall, nginx, keepalived common.Package) {
 					machineMonitor := monitor.WithField("machine", machine.ID())
 					deepNa, _ := nodeagents.Get(machine.ID())
@@ -176,16 +190,20 @@ func AdaptFunc(whitelist WhiteListFunc) orbiter.AdaptFunc {
 							machineMonitor.Info("Awaiting NGINX")
 							done = false
 						}
-						if !sysctl.Contains(deepNa.Software.Sysctl, common.Package{
-							Config: map[string]string{
-								string(common.IpForward):    "1",
-								string(common.NonLocalBind): "1",
-							},
-						}) {
+						sysctlModules := []common.KernelModule{common.IpForward, common.NonLocalBind}
+						if ipv6 {
+							sysctlModules = append(sysctlModules, common.Ipv6Forward, common.Ipv6NonLocalBind)
+						}
+						sysctlPkg := common.Package{Config: map[string]string{}}
+						for _, module := range sysctlModules {
+							sysctlPkg.Config[string(module)] = "1"
+						}
+						if !sysctl.Contains(deepNa.Software.Sysctl, sysctlPkg) {
 							machineMonitor.Changed("sysctl desired")
 						}
-						sysctl.Enable(&deepNa.Software.Sysctl, common.IpForward)
-						sysctl.Enable(&deepNa.Software.Sysctl, common.NonLocalBind)
+						for _, module := range sysctlModules {
+							sysctl.Enable(&deepNa.Software.Sysctl, module)
+						}
 						if !sysctl.Contains(deepNaCurr.Software.Sysctl, deepNa.Software.Sysctl) {
 							machineMonitor.Info("Awaiting sysctl config")
 							done = false
@@ -222,13 +240,16 @@ func AdaptFunc(whitelist WhiteListFunc) orbiter.AdaptFunc {
 						}).Debug("Executed command")
 						return user, nil
 					},
-					"vip": mapVIP,
+					"vip":      mapVIP,
+					"ipv6":     func(ip string) bool { return orbiter.IPAddress(ip).IPv6() },
+					"hostPort": func(host string, port Port) string { return net.JoinHostPort(host, strconv.Itoa(int(port))) },
 					"routerID": func(vip *VIP) string {
-						vipParts := strings.Split(mapVIP(vip), ".")
-						if len(vipParts) != 4 || vipParts[3] == "0" {
+						// The last byte of the address, which is the last octet of IPv4 addresses
+						ip := net.ParseIP(mapVIP(vip))
+						if ip == nil || ip[len(ip)-1] == 0 {
 							return "55"
 						}
-						return vipParts[3]
+						return strconv.Itoa(int(ip[len(ip)-1]))
 					},
 					"derefBool": func(in *bool) bool { return in != nil && *in },
 				})
@@ -328,6 +349,10 @@ vrrp_instance VI_{{ $idx }} {
 	}
 
 {{ if $root.CustomMasterNotifyer }}	notify_master "/etc/keepalived/notifymaster.sh"
+{{ else if ipv6 (vip $vip) }}	# IPv6 VIPs are excluded from the VRRP advertisements, so the peers may still use IPv4 addresses
+	virtual_ipaddress_excluded {
+		{{ vip $vip }}
+	}
 {{ else }}	virtual_ipaddress {
 		{{ vip $vip }}
 	}
@@ -341,10 +366,10 @@ vrrp_instance VI_{{ $idx }} {
 
 stream { {{ range $vip := .VIPs }}{{ range $src := $vip.Transport }}
 	upstream {{ $src.Name }} {    {{ range $dest := $src.BackendPools }}{{ range $machine := forMachines $dest }}
-		server {{ $machine.IP }}:{{ $src.BackendPort }}; # {{ $dest }}{{end}}{{ end }}
+		server {{ hostPort $machine.IP $src.BackendPort }}; # {{ $dest }}{{end}}{{ end }}
 	}
 	server {
-		listen {{ vip $vip }}:{{ $src.FrontendPort }};
+		listen {{ hostPort (vip $vip) $src.FrontendPort }};
 {{ range $white := $src.Whitelist }}		allow {{ $white }};
 {{ end }}
 		deny all;
@@ -471,10 +496,10 @@ http {
 										Whitelist: transport.Whitelist,
 										Name:      transport.Name,
 										From: []string{
-											fmt.Sprintf("%s:%d", ip, transport.FrontendPort),           // VIP
-											fmt.Sprintf("%s:%d", machine.IP(), transport.FrontendPort), // Node IP
+											net.JoinHostPort(ip, strconv.Itoa(int(transport.FrontendPort))),           // VIP
+											net.JoinHostPort(machine.IP(), strconv.Itoa(int(transport.FrontendPort))), // Node IP
 										},
-										To:            fmt.Sprintf("%s:%d", machine.IP(), transport.BackendPort),
+										To:            net.JoinHostPort(machine.IP(), strconv.Itoa(int(transport.BackendPort))),
 										ProxyProtocol: *transport.ProxyProtocol,
 									})
 									nodesNats[machine.IP()] = nodeNatDesires
@@ -544,7 +569,7 @@ func probe(probeType, ip string, port uint16, proxyProtocol bool, hc HealthCheck
 	probes.With(prometheus.Labels{
 		"name":   source.Name,
 		"type":   probeType,
-		"target": fmt.Sprintf("%s://%s:%d%s", hc.Protocol, ip, port, hc.Path),
+		"target": fmt.Sprintf("%s://%s%s", hc.Protocol, net.JoinHostPort(ip, strconv.Itoa(int(port))), hc.Path),
 	}).Set(success)
 }
 
@@ -649,7 +674,7 @@ func curryEnrichedVIPs(desired Desired, machines poolMachinesFunc, adaptWhitelis
 						}
 					}
 				}
-				cidr := orbiter.CIDR(fmt.Sprintf("%s/32", machine.IP()))
+				cidr := orbiter.IPAddress(machine.IP()).Host()
 				addedCIDRs = append(addedCIDRs, &cidr)
 			}
 		}); err != nil {
@@ -80,6 +80,12 @@ type VIP struct {
 
 func (v *VIP) validate() error {
 
+	if v.IP != "" {
+		if err := orbiter.IPAddress(v.IP).Validate(); err != nil {
+			return err
+		}
+	}
+
 	if len(v.Transport) == 0 {
 		return errors.Errorf("vip %s has no transport configured", v.IP)
 	}
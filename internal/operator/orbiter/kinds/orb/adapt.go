@@ -4,6 +4,7 @@ import (
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers"
 	"github.com/caos/orbos/pkg/git"
+	"github.com/caos/orbos/pkg/kubernetes"
 	"github.com/caos/orbos/pkg/labels"
 	orbcfg "github.com/caos/orbos/pkg/orb"
 	"github.com/caos/orbos/pkg/secret"
@@ -59,6 +60,24 @@ func AdaptFunc(
 			monitor = monitor.Verbose()
 		}
 
+		if desiredKind.Spec.NodeAgentStateKey == nil {
+			desiredKind.Spec.NodeAgentStateKey = &secret.Secret{}
+		}
+		if desiredKind.Spec.NodeAgentStateURL != "" && !desiredKind.Spec.NodeAgentStateKey.HasValue() {
+			key, err := generateStateKey()
+			if err != nil {
+				return nil, nil, nil, migrate, nil, errors.Wrap(err, "generating node agent state key failed")
+			}
+			desiredKind.Spec.NodeAgentStateKey.Value = key
+			migrate = true
+		}
+		secrets["nodeagentstatekey"] = desiredKind.Spec.NodeAgentStateKey
+
+		stateKey, err := desiredKind.Spec.NodeAgentStateKey.Resolve()
+		if err != nil {
+			return nil, nil, nil, migrate, nil, err
+		}
+
 		providerCurrents := make(map[string]*tree.Tree)
 		providerQueriers := make([]orbiter.QueryFunc, 0)
 		providerDestroyers := make([]orbiter.DestroyFunc, 0)
@@ -86,6 +105,8 @@ func AdaptFunc(
 				orbiterCommit,
 				orbConfig.URL,
 				orbConfig.Repokey,
+				desiredKind.Spec.NodeAgentStateURL,
+				stateKey,
 				oneoff,
 				desiredKind.Spec.PProf,
 			)
@@ -122,13 +143,26 @@ func AdaptFunc(
 			return provCurr, nil
 		}
 
+		previous := struct {
+			Clusters map[string]*tree.Tree
+		}{}
+		if currentTree.Original != nil {
+			if err := currentTree.Original.Decode(&previous); err != nil {
+				return nil, nil, nil, migrate, nil, errors.Wrap(err, "parsing previous current state failed")
+			}
+		}
+
 		clusterCurrents := make(map[string]*tree.Tree)
 		clusterQueriers := make([]orbiter.QueryFunc, 0)
 		clusterDestroyers := make([]orbiter.DestroyFunc, 0)
 		clusterConfigurers := make([]orbiter.ConfigureFunc, 0)
 		for clusterID, clusterTree := range desiredKind.Clusters {
 
-			clusterCurrent := &tree.Tree{}
+			// clusters carry over what they remember from their previous current state
+			clusterCurrent := previous.Clusters[clusterID]
+			if clusterCurrent == nil {
+				clusterCurrent = &tree.Tree{}
+			}
 			clusterCurrents[clusterID] = clusterCurrent
 			query, destroy, configure, migrateLocal, clusterSecrets, err := clusters.GetQueryAndDestroyFuncs(
 				monitor,
@@ -156,7 +190,7 @@ func AdaptFunc(
 			}
 		}
 
-		currentTree.Parsed = &Current{
+		current := &Current{
 			Common: &tree.Common{
 				Kind:    "orbiter.caos.ch/Orb",
 				Version: "v0",
@@ -164,9 +198,20 @@ func AdaptFunc(
 			Clusters:  clusterCurrents,
 			Providers: providerCurrents,
 		}
+		currentTree.Parsed = current
 
 		return func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, _ map[string]interface{}) (ensureFunc orbiter.EnsureFunc, err error) {
 
+				// ORBITER secrets reference existing kubernetes secrets only by the kubernetes backend
+				var k8sClient kubernetes.ClientInt
+				for _, clusterCurrent := range clusterCurrents {
+					if clienter, ok := clusterCurrent.Parsed.(interface{ Client() kubernetes.ClientInt }); ok {
+						k8sClient = clienter.Client()
+					}
+				}
+				current.Secrets = secret.ValidateExisting(k8sClient, secrets, nil)
+				secret.ReportExisting(monitor, "orbiter", current.Secrets)
+
 				providerEnsurers := make([]orbiter.EnsureFunc, 0)
 				queriedProviders := make(map[string]interface{})
 				for _, querier := range providerQueriers {
@@ -1,6 +1,10 @@
 package orb
 
 import (
+	"sort"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/pkg/secret"
 	"github.com/caos/orbos/pkg/tree"
 )
 
@@ -8,4 +12,33 @@ type Current struct {
 	Common    *tree.Common `yaml:",inline"`
 	Clusters  map[string]*tree.Tree
 	Providers map[string]*tree.Tree
+	// Secrets contains the validation results of the references to existing kubernetes secrets
+	Secrets map[string]*secret.ExistingStatus `yaml:",omitempty"`
+}
+
+var _ orbiter.Planner = (*Current)(nil)
+
+// Plan merges the plans of all providers and clusters which implement orbiter.Planner
+func (c *Current) Plan() (*orbiter.Plan, error) {
+	plan := &orbiter.Plan{}
+	for _, trees := range []map[string]*tree.Tree{c.Providers, c.Clusters} {
+		ids := make([]string, 0, len(trees))
+		for id := range trees {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			planner, ok := trees[id].Parsed.(orbiter.Planner)
+			if !ok {
+				continue
+			}
+			planned, err := planner.Plan()
+			if err != nil {
+				return nil, err
+			}
+			plan.Merge(planned)
+		}
+	}
+	return plan, nil
 }
@@ -1,6 +1,10 @@
 package orb
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/caos/orbos/pkg/secret"
 	"github.com/caos/orbos/pkg/tree"
 	"github.com/pkg/errors"
 )
@@ -10,6 +14,12 @@ type DesiredV0 struct {
 	Spec   struct {
 		Verbose bool
 		PProf   bool
+		// NodeAgentStateURL is where node agents reach the state endpoints of the ORBITER running in the cluster.
+		// If it is empty, node agents exchange their states by committing to the orbs repository
+		NodeAgentStateURL string `yaml:"nodeAgentStateURL,omitempty"`
+		// NodeAgentStateKey derives the tokens node agents authenticate with at the state endpoints.
+		// It is generated if it is empty while NodeAgentStateURL is set
+		NodeAgentStateKey *secret.Secret `yaml:"nodeAgentStateKey,omitempty"`
 	}
 	Clusters  map[string]*tree.Tree
 	Providers map[string]*tree.Tree
@@ -26,6 +36,24 @@ func ParseDesiredV0(desiredTree *tree.Tree) (*DesiredV0, error) {
 	return desiredKind, nil
 }
 
+// StateKey implements orbiter.StateKeyer
+func (d *DesiredV0) StateKey() ([]byte, error) {
+	key, err := d.Spec.NodeAgentStateKey.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(key), nil
+}
+
+// generateStateKey returns a random key for deriving node agent tokens
+func generateStateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
 func (d *DesiredV0) validate() error {
 	if len(d.Clusters) < 1 {
 		return errors.New("No clusters configured")
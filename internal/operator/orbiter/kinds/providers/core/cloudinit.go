@@ -1,4 +1,4 @@
-package cs
+package core
 
 import "gopkg.in/yaml.v3"
 
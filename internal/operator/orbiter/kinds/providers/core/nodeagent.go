@@ -12,6 +12,7 @@ import (
 	"github.com/caos/orbos/internal/helpers"
 
 	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent/state"
 
 	"github.com/caos/orbos/internal/executables"
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
@@ -21,8 +22,8 @@ import (
 
 type IterateNodeAgentFuncs func(currentNodeAgents *common.CurrentNodeAgents) (queryNodeAgent func(machine infra.Machine, orbiterCommit string) (bool, error), install func(machine infra.Machine) error)
 
-func ConfigureNodeAgents(svc MachinesService, monitor mntr.Monitor, orb orbcfg.Orb, pprof bool) error {
-	configure, _ := NodeAgentFuncs(monitor, orb.URL, orb.Repokey, pprof)
+func ConfigureNodeAgents(svc MachinesService, monitor mntr.Monitor, orb orbcfg.Orb, stateURL, stateKey string, pprof bool) error {
+	configure, _ := NodeAgentFuncs(monitor, orb.URL, orb.Repokey, stateURL, stateKey, pprof)
 	return Each(svc, func(pool string, machine infra.Machine) error {
 		err := configure(machine)
 		if err != nil {
@@ -40,6 +41,8 @@ func NodeAgentFuncs(
 	monitor mntr.Monitor,
 	repoURL string,
 	repoKey string,
+	stateURL string,
+	stateKey string,
 	pprof bool,
 ) (
 	reconfigure func(machines infra.Machine) error,
@@ -73,6 +76,34 @@ func NodeAgentFuncs(
 				}).Debug("Written file")
 				return nil
 			},
+			func() error {
+				// An empty file makes the node agent exchange its states via git
+				if err := infra.Try(machineMonitor, time.NewTimer(8*time.Second), 2*time.Second, machine, func(cmp infra.Machine) error {
+					return errors.Wrapf(cmp.WriteFile(state.URLPath, strings.NewReader(stateURL), 400), "creating remote file %s failed", state.URLPath)
+				}); err != nil {
+					return errors.Wrap(err, "writing state url failed")
+				}
+				machineMonitor.WithFields(map[string]interface{}{
+					"path": state.URLPath,
+				}).Debug("Written file")
+				return nil
+			},
+			func() error {
+				// Each node agent gets its own token, so it can't exchange the states of other node agents
+				var token string
+				if stateURL != "" {
+					token = state.NodeToken([]byte(stateKey), machine.ID())
+				}
+				if err := infra.Try(machineMonitor, time.NewTimer(8*time.Second), 2*time.Second, machine, func(cmp infra.Machine) error {
+					return errors.Wrapf(cmp.WriteFile(state.TokenPath, strings.NewReader(token), 400), "creating remote file %s failed", state.TokenPath)
+				}); err != nil {
+					return errors.Wrap(err, "writing state token failed")
+				}
+				machineMonitor.WithFields(map[string]interface{}{
+					"path": state.TokenPath,
+				}).Debug("Written file")
+				return nil
+			},
 		})
 	}
 
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+// AdaptConfig contains everything ORBITER passes to a provider when adapting it
+type AdaptConfig struct {
+	ProviderID    string
+	OrbID         string
+	Whitelist     func() []*orbiter.CIDR
+	OrbiterCommit string
+	RepoURL       string
+	RepoKey       string
+	// NodeAgentStateURL is where node agents reach ORBITERs state endpoints. Node agents use git if it is empty
+	NodeAgentStateURL string
+	// NodeAgentStateKey derives the tokens node agents authenticate with at ORBITERs state endpoints
+	NodeAgentStateKey string
+	Oneoff            bool
+	PProf             bool
+}
+
+// Provider is implemented once per provider kind.
+// The secrets of a provider are returned by the AdaptFunc
+type Provider interface {
+	AdaptFunc(cfg AdaptConfig) orbiter.AdaptFunc
+	ListMachines(monitor mntr.Monitor, desiredTree *tree.Tree, orbID, providerID string) (map[string]infra.Machine, error)
+}
+
+var registry = struct {
+	providers map[string]Provider
+	sync.RWMutex
+}{
+	providers: make(map[string]Provider),
+}
+
+// RegisterProvider makes a provider kind available to ORBITER and orbctl.
+// It is meant to be called from the init function of a provider package and panics if the kind is already registered
+func RegisterProvider(kind string, provider Provider) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if provider == nil {
+		panic(errors.Errorf("registering provider kind %s failed: provider is nil", kind))
+	}
+
+	if _, ok := registry.providers[kind]; ok {
+		panic(errors.Errorf("registering provider kind %s failed: kind is already registered", kind))
+	}
+	registry.providers[kind] = provider
+}
+
+func GetProvider(kind string) (Provider, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	provider, ok := registry.providers[kind]
+	if !ok {
+		return nil, errors.Errorf("unknown provider kind %s", kind)
+	}
+	return provider, nil
+}
+
+// ProviderKinds returns all registered provider kinds in alphabetical order
+func ProviderKinds() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	kinds := make([]string, 0, len(registry.providers))
+	for kind := range registry.providers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+type testProvider struct{}
+
+func (testProvider) AdaptFunc(AdaptConfig) orbiter.AdaptFunc { return nil }
+
+func (testProvider) ListMachines(mntr.Monitor, *tree.Tree, string, string) (map[string]infra.Machine, error) {
+	return nil, nil
+}
+
+func TestRegisterProvider(t *testing.T) {
+	RegisterProvider("test.caos.ch/BProvider", testProvider{})
+	RegisterProvider("test.caos.ch/AProvider", testProvider{})
+
+	if _, err := GetProvider("test.caos.ch/AProvider"); err != nil {
+		t.Errorf("registered provider not found: %s", err)
+	}
+
+	if _, err := GetProvider("test.caos.ch/UnknownProvider"); err == nil {
+		t.Error("expected an error for an unregistered kind")
+	}
+
+	var a, b int
+	for idx, kind := range ProviderKinds() {
+		switch kind {
+		case "test.caos.ch/AProvider":
+			a = idx
+		case "test.caos.ch/BProvider":
+			b = idx
+		}
+	}
+	if a >= b {
+		t.Errorf("expected provider kinds to be sorted, got %v", ProviderKinds())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a kind twice to panic")
+		}
+	}()
+	RegisterProvider("test.caos.ch/AProvider", testProvider{})
+}
@@ -21,7 +21,9 @@ func AdaptFunc(
 	whitelist dynamic.WhiteListFunc,
 	orbiterCommit,
 	repoURL,
-	repoKey string,
+	repoKey,
+	stateURL string,
+	stateKey string,
 	oneoff bool,
 	pprof bool,
 ) orbiter.AdaptFunc {
@@ -92,7 +94,7 @@ func AdaptFunc(
 					return nil, err
 				}
 
-				_, naFuncs := core.NodeAgentFuncs(monitor, repoURL, repoKey, pprof)
+				_, naFuncs := core.NodeAgentFuncs(monitor, repoURL, repoKey, stateURL, stateKey, pprof)
 
 				return query(&desiredKind.Spec, current, lbCurrent.Parsed, ctx, nodeAgentsCurrent, nodeAgentsDesired, naFuncs, orbiterCommit)
 			}, func(delegates map[string]interface{}) error {
@@ -116,8 +118,8 @@ func AdaptFunc(
 				}
 
 				if desiredKind.Spec.SSHKey == nil ||
-					desiredKind.Spec.SSHKey.Private == nil || desiredKind.Spec.SSHKey.Private.Value == "" ||
-					desiredKind.Spec.SSHKey.Public == nil || desiredKind.Spec.SSHKey.Public.Value == "" {
+					!desiredKind.Spec.SSHKey.Private.HasValue() ||
+					!desiredKind.Spec.SSHKey.Public.HasValue() {
 					priv, pub, err := ssh.Generate()
 					if err != nil {
 						return err
@@ -132,7 +134,7 @@ func AdaptFunc(
 					panic(err)
 				}
 
-				return core.ConfigureNodeAgents(ctx.machinesService, ctx.monitor, orb, pprof)
+				return core.ConfigureNodeAgents(ctx.machinesService, ctx.monitor, orb, stateURL, stateKey, pprof)
 			}, migrate, secrets, nil
 	}
 }
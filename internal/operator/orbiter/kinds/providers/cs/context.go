@@ -30,7 +30,11 @@ func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string,
 		Timeout: 30 * time.Second,
 	})
 
-	client.AuthToken = desired.APIToken.Value
+	token, err := desired.APIToken.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	client.AuthToken = token
 
 	h := fnv.New32()
 	h.Write([]byte(orbID))
@@ -54,8 +54,7 @@ func (d Desired) validateAdapt() error {
 }
 
 func (d Desired) validateAPIToken() error {
-	if d.Spec.APIToken == nil ||
-		d.Spec.APIToken.Value == "" {
+	if !d.Spec.APIToken.HasValue() {
 		return errors.New("apitoken missing... please provide a cloudscale api token using orbctl writesecret command")
 	}
 	return nil
@@ -67,10 +66,8 @@ func (d Desired) validateQuery() error {
 		return err
 	}
 
-	if d.Spec.SSHKey.Private == nil ||
-		d.Spec.SSHKey.Private.Value == "" ||
-		d.Spec.SSHKey.Public == nil ||
-		d.Spec.SSHKey.Public.Value == "" {
+	if !d.Spec.SSHKey.Private.HasValue() ||
+		!d.Spec.SSHKey.Public.HasValue() {
 		return errors.New("ssh key missing... please initialize your orb using orbctl configure command")
 	}
 
@@ -75,38 +75,34 @@ func query(
 		NotifyMaster:  notifyMaster(hostPools, current, poolsWithUnassignedVIPs),
 		AuthCheck:     checkAuth,
 	}, desiredToCurrentVIP(current))
+
+	lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
+	if err != nil {
+		return nil, err
+	}
+
+	fwDone, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, true, []string{"eth0"})
+	if err != nil {
+		return nil, err
+	}
+
+	vips, err := allHostedVIPs(hostPools, context.machinesService, current)
+	if err != nil {
+		return nil, err
+	}
+	nwDone, err := core.DesireOSNetworking(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, "dummy", vips)
+	if err != nil {
+		return nil, err
+	}
+
 	return func(pdf func(mntr.Monitor) error) *orbiter.EnsureResult {
-		var done bool
-		return orbiter.ToEnsureResult(done, helpers.Fanout([]func() error{
+		return orbiter.ToEnsureResult(lbDone && fwDone && nwDone, helpers.Fanout([]func() error{
 			func() error {
-				return helpers.Fanout(ensureTokens(context.monitor, []byte(desired.APIToken.Value), authChecks))()
+				return helpers.Fanout(ensureTokens(context.monitor, []byte(context.client.AuthToken), authChecks))()
 			},
 			func() error { return helpers.Fanout(ensureFIPs)() },
 			func() error { return helpers.Fanout(removeFIPs)() },
 			func() error { return helpers.Fanout(ensureServers)() },
-			func() error {
-				lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
-				if err != nil {
-					return err
-				}
-
-				fwDone, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, true, []string{"eth0"})
-				if err != nil {
-					return err
-				}
-
-				vips, err := allHostedVIPs(hostPools, context.machinesService, current)
-				if err != nil {
-					return err
-				}
-				nwDone, err := core.DesireOSNetworking(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, "dummy", vips)
-				if err != nil {
-					return err
-				}
-
-				done = lbDone && fwDone && nwDone
-				return nil
-			},
 		})())
 	}, addPools(current, desired, wrappedMachines)
 }
@@ -50,10 +50,11 @@ func ListMachines(monitor mntr.Monitor, desiredTree *tree.Tree, orbID, providerI
 var _ core.MachinesService = (*machinesService)(nil)
 
 type machinesService struct {
-	context *context
-	oneoff  bool
-	key     *SSHKey
-	cache   struct {
+	context    *context
+	oneoff     bool
+	privateKey string
+	publicKey  string
+	cache      struct {
 		instances map[string][]*machine
 		sync.Mutex
 	}
@@ -68,10 +69,18 @@ func newMachinesService(context *context, oneoff bool) *machinesService {
 }
 
 func (m *machinesService) use(key *SSHKey) error {
-	if key == nil || key.Private == nil || key.Public == nil || key.Private.Value == "" || key.Public.Value == "" {
+	if key == nil || !key.Private.HasValue() || !key.Public.HasValue() {
 		return errors.New("machines are not connectable. have you configured the orb by running orbctl configure?")
 	}
-	m.key = key
+	privateKey, err := key.Private.Resolve()
+	if err != nil {
+		return err
+	}
+	publicKey, err := key.Public.Resolve()
+	if err != nil {
+		return err
+	}
+	m.privateKey, m.publicKey = privateKey, publicKey
 	return nil
 }
 
@@ -87,7 +96,7 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 
 	monitor.Debug("Creating instance")
 
-	userData, err := NewCloudinit().AddGroupWithoutUsers(
+	userData, err := core.NewCloudinit().AddGroupWithoutUsers(
 		"orbiter",
 	).AddUser(
 		"orbiter",
@@ -95,7 +104,7 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 		"",
 		[]string{"orbiter", "wheel"},
 		"orbiter",
-		[]string{m.context.desired.SSHKey.Public.Value},
+		[]string{m.publicKey},
 		"ALL=(ALL) NOPASSWD:ALL",
 	).AddCmd(
 		"sudo echo \"\n\nPermitRootLogin no\n\" >> /etc/ssh/sshd_config",
@@ -167,7 +176,7 @@ func (m *machinesService) toMachine(server *cloudscale.Server, monitor mntr.Moni
 	internalIP, sshIP := createdIPs(server.Interfaces, m.oneoff || true /* always use public ip */)
 
 	sshMachine := ssh.NewMachine(monitor, "orbiter", sshIP)
-	if err := sshMachine.UseKey([]byte(m.key.Private.Value)); err != nil {
+	if err := sshMachine.UseKey([]byte(m.privateKey)); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,81 @@
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+var _ ensureLBFunc = queryAddresses
+
+func queryAddresses(context *context, loadbalancing []*normalizedLoadbalancer) ([]func() error, []func() error, error) {
+
+	addresses, err := context.ec2.DescribeAddressesWithContext(context.ctx, &ec2.DescribeAddressesInput{
+		Filters: context.filters(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ensure []func() error
+	for _, lb := range normalizedLoadbalancing(loadbalancing).uniqueLoadbalancers() {
+		addr := lb.address
+		found := false
+		for _, awsAddr := range addresses.Addresses {
+			if tagValue(awsAddr.Tags, "id") == addr.id {
+				addr.allocationID = aws.StringValue(awsAddr.AllocationId)
+				addr.ip = aws.StringValue(awsAddr.PublicIp)
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		ensure = append(ensure, func(addr *address) func() error {
+			return func() error {
+				createLog(context.monitor, "elastic ip", addr.id, false)()
+				allocated, err := context.ec2.AllocateAddressWithContext(context.ctx, &ec2.AllocateAddressInput{
+					Domain: aws.String(ec2.DomainTypeVpc),
+				})
+				if err != nil {
+					return err
+				}
+				addr.allocationID = aws.StringValue(allocated.AllocationId)
+				addr.ip = aws.StringValue(allocated.PublicIp)
+
+				if _, err := context.ec2.CreateTagsWithContext(context.ctx, &ec2.CreateTagsInput{
+					Resources: []*string{allocated.AllocationId},
+					Tags:      context.tags(map[string]string{"id": addr.id}),
+				}); err != nil {
+					return err
+				}
+				createLog(context.monitor, "elastic ip", addr.id, true)()
+				return nil
+			}
+		}(addr))
+	}
+
+	var remove []func() error
+removeLoop:
+	for _, awsAddr := range addresses.Addresses {
+		for _, lb := range loadbalancing {
+			if tagValue(awsAddr.Tags, "id") == lb.address.id {
+				continue removeLoop
+			}
+		}
+		remove = append(remove, func(allocationID *string) func() error {
+			return func() error {
+				removeLog(context.monitor, "elastic ip", aws.StringValue(allocationID), false, true)()
+				if _, err := context.ec2.ReleaseAddressWithContext(context.ctx, &ec2.ReleaseAddressInput{
+					AllocationId: allocationID,
+				}); err != nil {
+					return err
+				}
+				removeLog(context.monitor, "elastic ip", aws.StringValue(allocationID), true, false)()
+				return nil
+			}
+		}(awsAddr.AllocationId))
+	}
+	return ensure, remove, nil
+}
@@ -0,0 +1,124 @@
+package ec2
+
+import (
+	ctxpkg "context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+
+	"github.com/caos/orbos/mntr"
+)
+
+type context struct {
+	monitor         mntr.Monitor
+	orbID           string
+	providerID      string
+	resourcePrefix  string
+	desired         *Spec
+	ec2             *ec2.EC2
+	elb             *elbv2.ELBV2
+	machinesService *machinesService
+	ctx             ctxpkg.Context
+	network         struct {
+		vpcID           string
+		vpcCIDR         string
+		securityGroupID string
+	}
+}
+
+func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string, oneoff bool) (*context, error) {
+
+	cfg := &aws.Config{
+		Region:     aws.String(desired.Region),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if desired.AccessKeyID != nil && desired.SecretAccessKey != nil {
+		accessKeyID, err := desired.AccessKeyID.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		secretAccessKey, err := desired.SecretAccessKey.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Credentials = credentials.NewStaticCredentials(accessKeyID, secretAccessKey, "")
+	}
+
+	if desired.Endpoint != "" {
+		cfg.Endpoint = aws.String(desired.Endpoint)
+	}
+
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load balancer and target group names are limited to 32 characters
+	// so we identify them by a hashed prefix instead of tags
+	h := fnv.New32()
+	h.Write([]byte(orbID + providerID))
+	resourcePrefix := fmt.Sprintf("orbos-%x", h.Sum32())
+	h.Reset()
+
+	newContext := &context{
+		monitor:        monitor.WithField("region", desired.Region),
+		orbID:          orbID,
+		providerID:     providerID,
+		resourcePrefix: resourcePrefix,
+		desired:        desired,
+		ec2:            ec2.New(sess),
+		elb:            elbv2.New(sess),
+		ctx:            ctxpkg.Background(),
+	}
+
+	newContext.machinesService = newMachinesService(newContext, oneoff)
+	return newContext, nil
+}
+
+func (c *context) resourceName(id string) string {
+	h := fnv.New32()
+	h.Write([]byte(id))
+	return fmt.Sprintf("%s-%x", c.resourcePrefix, h.Sum32())
+}
+
+func (c *context) filters() []*ec2.Filter {
+	return []*ec2.Filter{{
+		Name:   aws.String("tag:orb"),
+		Values: []*string{aws.String(c.orbID)},
+	}, {
+		Name:   aws.String("tag:provider"),
+		Values: []*string{aws.String(c.providerID)},
+	}}
+}
+
+func (c *context) tags(additional map[string]string) []*ec2.Tag {
+	tags := []*ec2.Tag{{
+		Key:   aws.String("orb"),
+		Value: aws.String(c.orbID),
+	}, {
+		Key:   aws.String("provider"),
+		Value: aws.String(c.providerID),
+	}}
+	for k, v := range additional {
+		tags = append(tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return tags
+}
+
+func tagValue(tags []*ec2.Tag, key string) string {
+	for idx := range tags {
+		tag := tags[idx]
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value)
+		}
+	}
+	return ""
+}
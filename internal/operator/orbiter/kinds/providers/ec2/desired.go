@@ -0,0 +1,127 @@
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/caos/orbos/pkg/secret"
+	"github.com/caos/orbos/pkg/tree"
+	"github.com/pkg/errors"
+)
+
+type Desired struct {
+	Common        *tree.Common `yaml:",inline"`
+	Spec          Spec
+	Loadbalancing *tree.Tree
+}
+
+type Pool struct {
+	InstanceType string
+	AMI          string
+	VolumeSizeGB int
+	// Subnet overrides the providers default subnet
+	Subnet           string `yaml:",omitempty"`
+	AvailabilityZone string `yaml:",omitempty"`
+}
+
+func (p Pool) validate() error {
+	if p.InstanceType == "" {
+		return errors.New("no instance type configured")
+	}
+	if p.AMI == "" {
+		return errors.New("no ami configured")
+	}
+	if p.VolumeSizeGB < 20 {
+		return errors.New("at least 20GB of storage is needed for the root volume")
+	}
+	return nil
+}
+
+type Spec struct {
+	Verbose bool
+	Region  string
+	// Endpoint overrides the default AWS API endpoint, e.g. for testing against a local fake
+	Endpoint            string         `yaml:",omitempty"`
+	AccessKeyID         *secret.Secret `yaml:",omitempty"`
+	SecretAccessKey     *secret.Secret `yaml:",omitempty"`
+	Subnet              string
+	Pools               map[string]*Pool
+	SSHKey              *SSHKey
+	RebootRequired      []string
+	ReplacementRequired []string
+	// Interfaces are the network interfaces the internal traffic is allowed on.
+	// They default to eth0 on Xen instances and ens5 on Nitro instances
+	Interfaces []string `yaml:",omitempty"`
+}
+
+func (s *Spec) interfaces() []string {
+	if len(s.Interfaces) > 0 {
+		return s.Interfaces
+	}
+	return []string{"eth0", "ens5"}
+}
+
+func (s *Spec) subnet(pool string) string {
+	if p, ok := s.Pools[pool]; ok && p.Subnet != "" {
+		return p.Subnet
+	}
+	return s.Subnet
+}
+
+type SSHKey struct {
+	Private *secret.Secret `yaml:",omitempty"`
+	Public  *secret.Secret `yaml:",omitempty"`
+}
+
+func (d Desired) validateAdapt() error {
+	if d.Loadbalancing == nil {
+		return errors.New("no loadbalancing configured")
+	}
+	if d.Spec.Region == "" {
+		return errors.New("no region configured")
+	}
+	if d.Spec.Subnet == "" {
+		return errors.New("no subnet configured")
+	}
+	if len(d.Spec.Pools) == 0 {
+		return errors.New("no pools configured")
+	}
+	for poolName, pool := range d.Spec.Pools {
+		if err := pool.validate(); err != nil {
+			return fmt.Errorf("configuring pool %s failed: %w", poolName, err)
+		}
+	}
+	return nil
+}
+
+func (d Desired) validateCredentials() error {
+	if !d.Spec.AccessKeyID.HasValue() ||
+		!d.Spec.SecretAccessKey.HasValue() {
+		return errors.New("aws credentials missing... please provide an access key id and a secret access key using orbctl writesecret command")
+	}
+	return nil
+}
+
+func (d Desired) validateQuery() error {
+	if err := d.validateCredentials(); err != nil {
+		return err
+	}
+	if d.Spec.SSHKey == nil ||
+		!d.Spec.SSHKey.Private.HasValue() ||
+		!d.Spec.SSHKey.Public.HasValue() {
+		return errors.New("ssh key missing... please initialize your orb using orbctl configure command")
+	}
+	return nil
+}
+
+func parseDesired(desiredTree *tree.Tree) (*Desired, error) {
+	desiredKind := &Desired{
+		Common: desiredTree.Common,
+		Spec:   Spec{},
+	}
+
+	if err := desiredTree.Original.Decode(desiredKind); err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+
+	return desiredKind, nil
+}
@@ -0,0 +1,53 @@
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/caos/orbos/internal/helpers"
+)
+
+func destroy(context *context) error {
+
+	destroyLB, err := queryLB(context, nil)
+	if err != nil {
+		return err
+	}
+	if err := destroyLB(); err != nil {
+		return err
+	}
+
+	pools, err := context.machinesService.ListPools()
+	if err != nil {
+		return err
+	}
+
+	var (
+		delFuncs    []func() error
+		instanceIDs []*string
+	)
+	for _, pool := range pools {
+		machines, err := context.machinesService.List(pool)
+		if err != nil {
+			return err
+		}
+		for _, machine := range machines {
+			delFuncs = append(delFuncs, machine.Remove)
+			instanceIDs = append(instanceIDs, aws.String(machine.ID()))
+		}
+	}
+	if err := helpers.Fanout(delFuncs)(); err != nil {
+		return err
+	}
+
+	if len(instanceIDs) > 0 {
+		// The security group can only be deleted as soon as no instance references it anymore
+		if err := context.ec2.WaitUntilInstanceTerminatedWithContext(context.ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: instanceIDs,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return destroyNetwork(context)
+}
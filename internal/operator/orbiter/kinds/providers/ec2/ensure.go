@@ -0,0 +1,188 @@
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic/wrap"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/mntr"
+)
+
+func query(
+	desired *Spec,
+	current *Current,
+	lb interface{},
+	context *context,
+	nodeAgentsCurrent *common.CurrentNodeAgents,
+	nodeAgentsDesired *common.DesiredNodeAgents,
+	naFuncs core.IterateNodeAgentFuncs,
+	orbiterCommit string,
+) (ensureFunc orbiter.EnsureFunc, err error) {
+
+	lbCurrent, ok := lb.(*dynamic.Current)
+	if !ok {
+		panic(errors.Errorf("Unknown or unsupported load balancing of type %T", lb))
+	}
+
+	svc := context.machinesService
+
+	vips, _, err := lbCurrent.Current.Spec(svc)
+	if err != nil {
+		return nil, err
+	}
+	normalized := normalize(context, vips)
+
+	var (
+		ensureLB, ensureFW func() error
+	)
+	if err := helpers.Fanout([]func() error{
+		func() error {
+			var err error
+			ensureLB, err = queryLB(context, normalized)
+			return err
+		},
+		func() error {
+			var err error
+			ensureFW, err = queryFirewall(context, normalized)
+			return err
+		},
+	})(); err != nil {
+		return nil, err
+	}
+
+	current.Current.Ingresses = make(map[string]*infra.Address)
+	for _, lb := range normalized {
+		current.Current.Ingresses[lb.transport] = &infra.Address{
+			Location:     lb.address.ip,
+			FrontendPort: lb.frontendPort,
+			BackendPort:  lb.backendPort,
+		}
+	}
+
+	queryNA, installNA := naFuncs(nodeAgentsCurrent)
+
+	desireHealthchecks := func(pool string, machine infra.Machine) {
+
+		machineID := machine.ID()
+		machineMonitor := context.monitor.WithField("machine", machineID)
+		na, _ := nodeAgentsDesired.Get(machineID)
+		if na.Software.Health.Config == nil {
+			na.Software.Health.Config = make(map[string]string)
+		}
+
+		for _, lb := range normalized {
+			for _, destPool := range lb.destPools {
+				if pool != destPool {
+					continue
+				}
+				key := fmt.Sprintf(
+					"%s:%d%s",
+					"0.0.0.0",
+					lb.healthcheckPort,
+					lb.healthcheck.Path)
+
+				value := fmt.Sprintf(
+					"--protocol %s --ip %s --port %d --path %s --status %d --proxy=%t",
+					lb.healthcheck.Protocol,
+					machine.IP(),
+					lb.backendPort,
+					lb.healthcheck.Path,
+					lb.healthcheck.Code,
+					lb.proxyProtocol,
+				)
+
+				if v := na.Software.Health.Config[key]; v != value {
+					na.Software.Health.Config[key] = value
+					machineMonitor.WithFields(map[string]interface{}{
+						"listen": key,
+						"checks": value,
+					}).Debug("Healthcheck desired")
+				}
+				fw := common.ToFirewall("external", map[string]*common.Allowed{
+					fmt.Sprintf("%s-healthcheck", lb.transport): {
+						Port:     fmt.Sprintf("%d", lb.healthcheckPort),
+						Protocol: "tcp",
+					},
+				})
+				if !na.Firewall.Contains(fw) {
+					machineMonitor.WithField("ports", fw.ToCurrent()).Debug("Firewall desired")
+				}
+				na.Firewall.Merge(fw)
+			}
+		}
+	}
+
+	ensureNodeAgent := func(machine infra.Machine) error {
+		running, err := queryNA(machine, orbiterCommit)
+		if err != nil {
+			return err
+		}
+		if !running {
+			return installNA(machine)
+		}
+		return nil
+	}
+
+	svc.onCreate = func(pool string, machine infra.Machine) error {
+		desireHealthchecks(pool, machine)
+		return ensureNodeAgent(machine)
+	}
+	wrappedMachines := wrap.MachinesService(svc, *lbCurrent, nil, func(vip *dynamic.VIP) string {
+		for _, transport := range vip.Transport {
+			address, ok := current.Current.Ingresses[transport.Name]
+			if ok {
+				return address.Location
+			}
+		}
+		panic(fmt.Errorf("external address for %v is not ensured", vip))
+	})
+
+	pools, err := svc.ListPools()
+	if err != nil {
+		return nil, err
+	}
+	var machines []infra.Machine
+	for _, pool := range pools {
+		poolMachines, err := svc.List(pool)
+		if err != nil {
+			return nil, err
+		}
+		for _, machine := range poolMachines {
+			desireHealthchecks(pool, machine)
+			machines = append(machines, machine)
+		}
+	}
+
+	lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
+	if err != nil {
+		return nil, err
+	}
+	fwDone, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, svc, false, desired.interfaces())
+	if err != nil {
+		return nil, err
+	}
+
+	return func(pdf func(mntr.Monitor) error) *orbiter.EnsureResult {
+
+		ensureNodeAgents := make([]func() error, len(machines))
+		for idx, machine := range machines {
+			ensureNodeAgents[idx] = func(m infra.Machine) func() error {
+				return func() error { return ensureNodeAgent(m) }
+			}(machine)
+		}
+
+		err := helpers.Fanout([]func() error{
+			ensureFW,
+			ensureLB,
+			helpers.Fanout(ensureNodeAgents),
+		})()
+		return orbiter.ToEnsureResult(lbDone && fwDone, err)
+	}, initPools(current, desired, svc, normalized, wrappedMachines)
+}
@@ -0,0 +1,104 @@
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+var _ ensureLBFunc = queryListeners
+
+func queryListeners(context *context, loadbalancing []*normalizedLoadbalancer) ([]func() error, []func() error, error) {
+
+	awsListeners := make(map[*loadbalancer][]*elbv2.Listener)
+	for _, lb := range normalizedLoadbalancing(loadbalancing).uniqueLoadbalancers() {
+		if lb.arn == "" {
+			continue
+		}
+		listeners, err := context.elb.DescribeListenersWithContext(context.ctx, &elbv2.DescribeListenersInput{
+			LoadBalancerArn: aws.String(lb.arn),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		awsListeners[lb] = listeners.Listeners
+	}
+
+	forward := func(lb *normalizedLoadbalancer) []*elbv2.Action {
+		return []*elbv2.Action{{
+			Type:           aws.String(elbv2.ActionTypeEnumForward),
+			TargetGroupArn: aws.String(lb.targetGroup.arn),
+		}}
+	}
+
+	var ensure []func() error
+createLoop:
+	for _, lb := range loadbalancing {
+		id := fmt.Sprintf("%s:%d", lb.loadbalancer.name, lb.frontendPort)
+		for _, awsListener := range awsListeners[lb.loadbalancer] {
+			if aws.Int64Value(awsListener.Port) != int64(lb.frontendPort) {
+				continue
+			}
+			if len(awsListener.DefaultActions) != 1 || aws.StringValue(awsListener.DefaultActions[0].TargetGroupArn) != lb.targetGroup.arn {
+				ensure = append(ensure, func(lb *normalizedLoadbalancer, arn *string) func() error {
+					return func() error {
+						if _, err := context.elb.ModifyListenerWithContext(context.ctx, &elbv2.ModifyListenerInput{
+							ListenerArn:    arn,
+							DefaultActions: forward(lb),
+						}); err != nil {
+							return err
+						}
+						context.monitor.WithFields(map[string]interface{}{
+							"type": "listener",
+							"id":   id,
+						}).Info("Target group updated")
+						return nil
+					}
+				}(lb, awsListener.ListenerArn))
+			}
+			continue createLoop
+		}
+
+		ensure = append(ensure, func(lb *normalizedLoadbalancer) func() error {
+			return func() error {
+				createLog(context.monitor, "listener", id, false)()
+				if _, err := context.elb.CreateListenerWithContext(context.ctx, &elbv2.CreateListenerInput{
+					LoadBalancerArn: aws.String(lb.loadbalancer.arn),
+					Port:            aws.Int64(int64(lb.frontendPort)),
+					Protocol:        aws.String(elbv2.ProtocolEnumTcp),
+					DefaultActions:  forward(lb),
+				}); err != nil {
+					return err
+				}
+				createLog(context.monitor, "listener", id, true)()
+				return nil
+			}
+		}(lb))
+	}
+
+	var remove []func() error
+	for lb, listeners := range awsListeners {
+	removeLoop:
+		for _, awsListener := range listeners {
+			for _, normalizedLB := range loadbalancing {
+				if normalizedLB.loadbalancer == lb && int64(normalizedLB.frontendPort) == aws.Int64Value(awsListener.Port) {
+					continue removeLoop
+				}
+			}
+			remove = append(remove, func(id string, arn *string) func() error {
+				return func() error {
+					removeLog(context.monitor, "listener", id, false, true)()
+					if _, err := context.elb.DeleteListenerWithContext(context.ctx, &elbv2.DeleteListenerInput{
+						ListenerArn: arn,
+					}); err != nil {
+						return err
+					}
+					removeLog(context.monitor, "listener", id, true, false)()
+					return nil
+				}
+			}(fmt.Sprintf("%s:%d", lb.name, aws.Int64Value(awsListener.Port)), awsListener.ListenerArn))
+		}
+	}
+	return ensure, remove, nil
+}
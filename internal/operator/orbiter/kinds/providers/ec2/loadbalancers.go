@@ -0,0 +1,95 @@
+package ec2
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+var _ ensureLBFunc = queryLoadbalancers
+
+func listLoadbalancers(context *context) ([]*elbv2.LoadBalancer, error) {
+	var lbs []*elbv2.LoadBalancer
+	return lbs, context.elb.DescribeLoadBalancersPagesWithContext(context.ctx, &elbv2.DescribeLoadBalancersInput{}, func(page *elbv2.DescribeLoadBalancersOutput, _ bool) bool {
+		for _, lb := range page.LoadBalancers {
+			if strings.HasPrefix(aws.StringValue(lb.LoadBalancerName), context.resourcePrefix+"-") {
+				lbs = append(lbs, lb)
+			}
+		}
+		return true
+	})
+}
+
+func queryLoadbalancers(context *context, loadbalancing []*normalizedLoadbalancer) ([]func() error, []func() error, error) {
+
+	awsLBs, err := listLoadbalancers(context)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ensure []func() error
+createLoop:
+	for _, lb := range normalizedLoadbalancing(loadbalancing).uniqueLoadbalancers() {
+		for _, awsLB := range awsLBs {
+			if aws.StringValue(awsLB.LoadBalancerName) == lb.name {
+				lb.arn = aws.StringValue(awsLB.LoadBalancerArn)
+				continue createLoop
+			}
+		}
+
+		ensure = append(ensure, func(lb *loadbalancer) func() error {
+			return func() error {
+				createLog(context.monitor, "network load balancer", lb.name, false)()
+				created, err := context.elb.CreateLoadBalancerWithContext(context.ctx, &elbv2.CreateLoadBalancerInput{
+					Name:   aws.String(lb.name),
+					Type:   aws.String(elbv2.LoadBalancerTypeEnumNetwork),
+					Scheme: aws.String(elbv2.LoadBalancerSchemeEnumInternetFacing),
+					SubnetMappings: []*elbv2.SubnetMapping{{
+						SubnetId:     aws.String(context.desired.Subnet),
+						AllocationId: aws.String(lb.address.allocationID),
+					}},
+					Tags: []*elbv2.Tag{{
+						Key:   aws.String("orb"),
+						Value: aws.String(context.orbID),
+					}, {
+						Key:   aws.String("provider"),
+						Value: aws.String(context.providerID),
+					}, {
+						Key:   aws.String("id"),
+						Value: aws.String(lb.address.id),
+					}},
+				})
+				if err != nil {
+					return err
+				}
+				lb.arn = aws.StringValue(created.LoadBalancers[0].LoadBalancerArn)
+				createLog(context.monitor, "network load balancer", lb.name, true)()
+				return nil
+			}
+		}(lb))
+	}
+
+	var remove []func() error
+removeLoop:
+	for _, awsLB := range awsLBs {
+		for _, lb := range loadbalancing {
+			if aws.StringValue(awsLB.LoadBalancerName) == lb.loadbalancer.name {
+				continue removeLoop
+			}
+		}
+		remove = append(remove, func(name string, arn *string) func() error {
+			return func() error {
+				removeLog(context.monitor, "network load balancer", name, false, true)()
+				if _, err := context.elb.DeleteLoadBalancerWithContext(context.ctx, &elbv2.DeleteLoadBalancerInput{
+					LoadBalancerArn: arn,
+				}); err != nil {
+					return err
+				}
+				removeLog(context.monitor, "network load balancer", name, true, false)()
+				return nil
+			}
+		}(aws.StringValue(awsLB.LoadBalancerName), awsLB.LoadBalancerArn))
+	}
+	return ensure, remove, nil
+}
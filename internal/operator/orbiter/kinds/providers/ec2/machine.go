@@ -0,0 +1,109 @@
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ssh"
+)
+
+var _ infra.Machine = (*machine)(nil)
+
+type action struct {
+	required  bool
+	require   func()
+	unrequire func()
+}
+
+type machine struct {
+	instance *ec2.Instance
+	*ssh.Machine
+	remove       func() error
+	context      *context
+	reboot       *action
+	replacement  *action
+	poolName     string
+	X_ID         string `header:"id"`
+	X_internalIP string `header:"internal ip"`
+	X_externalIP string `header:"external ip"`
+	X_Pool       string `header:"pool"`
+}
+
+func newMachine(instance *ec2.Instance, id, internalIP, externalIP string, sshMachine *ssh.Machine, remove func() error, context *context, poolName string) *machine {
+	return &machine{
+		instance:     instance,
+		X_ID:         id,
+		X_internalIP: internalIP,
+		X_externalIP: externalIP,
+		X_Pool:       poolName,
+		Machine:      sshMachine,
+		remove:       remove,
+		context:      context,
+		poolName:     poolName,
+	}
+}
+
+func (m *machine) ID() string    { return m.X_ID }
+func (m *machine) IP() string    { return m.X_internalIP }
+func (m *machine) Remove() error { return m.remove() }
+
+func (m *machine) RebootRequired() (required bool, require func(), unrequire func()) {
+
+	m.reboot = m.initAction(
+		m.reboot,
+		func() []string { return m.context.desired.RebootRequired },
+		func(machines []string) { m.context.desired.RebootRequired = machines })
+
+	return m.reboot.required, m.reboot.require, m.reboot.unrequire
+}
+
+func (m *machine) ReplacementRequired() (required bool, require func(), unrequire func()) {
+
+	m.replacement = m.initAction(
+		m.replacement,
+		func() []string { return m.context.desired.ReplacementRequired },
+		func(machines []string) { m.context.desired.ReplacementRequired = machines })
+
+	return m.replacement.required, m.replacement.require, m.replacement.unrequire
+}
+
+func (m *machine) initAction(a *action, getSlice func() []string, setSlice func([]string)) *action {
+	if a != nil {
+		return a
+	}
+
+	newAction := &action{
+		required:  false,
+		unrequire: func() {},
+		require: func() {
+			s := getSlice()
+			s = append(s, m.ID())
+			setSlice(s)
+		},
+	}
+
+	s := getSlice()
+	for sIdx := range s {
+		req := s[sIdx]
+		if req == m.ID() {
+			newAction.required = true
+			break
+		}
+	}
+
+	if newAction.required {
+		newAction.unrequire = func() {
+			s := getSlice()
+			for sIdx := range s {
+				req := s[sIdx]
+				if req == m.ID() {
+					s = append(s[0:sIdx], s[sIdx+1:]...)
+					break
+				}
+			}
+			setSlice(s)
+		}
+	}
+
+	return newAction
+}
@@ -0,0 +1,328 @@
+package ec2
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ssh"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/secret"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+func ListMachines(monitor mntr.Monitor, desiredTree *tree.Tree, orbID, providerID string) (map[string]infra.Machine, error) {
+	desired, err := parseDesired(desiredTree)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+	desiredTree.Parsed = desired
+
+	ctx, err := buildContext(monitor, &desired.Spec, orbID, providerID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.machinesService.use(desired.Spec.SSHKey); err != nil {
+		invalidKey := &secret.Secret{Value: "invalid"}
+		if err := ctx.machinesService.use(&SSHKey{
+			Private: invalidKey,
+			Public:  invalidKey,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return core.ListMachines(ctx.machinesService)
+}
+
+var _ core.MachinesService = (*machinesService)(nil)
+
+type machinesService struct {
+	context    *context
+	oneoff     bool
+	privateKey string
+	publicKey  string
+	cache      struct {
+		instances map[string][]*machine
+		sync.Mutex
+	}
+	onCreate func(pool string, machine infra.Machine) error
+}
+
+func newMachinesService(context *context, oneoff bool) *machinesService {
+	return &machinesService{
+		context: context,
+		oneoff:  oneoff,
+	}
+}
+
+func (m *machinesService) use(key *SSHKey) error {
+	if key == nil || !key.Private.HasValue() || !key.Public.HasValue() {
+		return errors.New("machines are not connectable. have you configured the orb by running orbctl configure?")
+	}
+	privateKey, err := key.Private.Resolve()
+	if err != nil {
+		return err
+	}
+	publicKey, err := key.Public.Resolve()
+	if err != nil {
+		return err
+	}
+	m.privateKey, m.publicKey = privateKey, publicKey
+	return nil
+}
+
+func (m *machinesService) Create(poolName string) (infra.Machine, error) {
+
+	desired, ok := m.context.desired.Pools[poolName]
+	if !ok {
+		return nil, fmt.Errorf("Pool %s is not configured", poolName)
+	}
+
+	name := newName()
+	monitor := machineMonitor(m.context.monitor, name, poolName)
+
+	userData, err := core.NewCloudinit().AddGroupWithoutUsers(
+		"orbiter",
+	).AddUser(
+		"orbiter",
+		true,
+		"",
+		[]string{"orbiter", "wheel"},
+		"orbiter",
+		[]string{m.publicKey},
+		"ALL=(ALL) NOPASSWD:ALL",
+	).AddCmd(
+		"sudo echo \"\n\nPermitRootLogin no\n\" >> /etc/ssh/sshd_config",
+	).AddCmd(
+		"sudo service sshd restart",
+	).ToYamlString()
+	if err != nil {
+		return nil, err
+	}
+
+	sgID, err := m.context.ensureSecurityGroup()
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := m.context.ec2.DescribeImagesWithContext(m.context.ctx, &ec2.DescribeImagesInput{
+		ImageIds: []*string{aws.String(desired.AMI)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(images.Images) != 1 {
+		return nil, errors.Errorf("ami %s not found", desired.AMI)
+	}
+
+	var placement *ec2.Placement
+	if desired.AvailabilityZone != "" {
+		placement = &ec2.Placement{AvailabilityZone: aws.String(desired.AvailabilityZone)}
+	}
+
+	monitor.Debug("Creating instance")
+
+	reservation, err := m.context.ec2.RunInstancesWithContext(m.context.ctx, &ec2.RunInstancesInput{
+		ImageId:      aws.String(desired.AMI),
+		InstanceType: aws.String(desired.InstanceType),
+		MinCount:     aws.Int64(1),
+		MaxCount:     aws.Int64(1),
+		Placement:    placement,
+		UserData:     aws.String(base64.StdEncoding.EncodeToString([]byte(userData))),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{{
+			DeviceName: images.Images[0].RootDeviceName,
+			Ebs: &ec2.EbsBlockDevice{
+				DeleteOnTermination: aws.Bool(true),
+				VolumeSize:          aws.Int64(int64(desired.VolumeSizeGB)),
+				VolumeType:          aws.String(ec2.VolumeTypeGp2),
+			},
+		}},
+		NetworkInterfaces: []*ec2.InstanceNetworkInterfaceSpecification{{
+			DeviceIndex:              aws.Int64(0),
+			SubnetId:                 aws.String(m.context.desired.subnet(poolName)),
+			Groups:                   []*string{aws.String(sgID)},
+			AssociatePublicIpAddress: aws.Bool(true), // Always use public ips, so orbctl can connect
+			DeleteOnTermination:      aws.Bool(true),
+		}},
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags: m.context.tags(map[string]string{
+				"Name": name,
+				"pool": poolName,
+			}),
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instanceIDs := []*string{reservation.Instances[0].InstanceId}
+	if err := m.context.ec2.WaitUntilInstanceRunningWithContext(m.context.ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}); err != nil {
+		return nil, err
+	}
+
+	described, err := m.context.ec2.DescribeInstancesWithContext(m.context.ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(described.Reservations) != 1 || len(described.Reservations[0].Instances) != 1 {
+		return nil, errors.Errorf("instance %s not found", aws.StringValue(instanceIDs[0]))
+	}
+
+	monitor.Info("Instance created")
+
+	infraMachine, err := m.toMachine(described.Reservations[0].Instances[0], monitor, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.cache.instances != nil {
+		if _, ok := m.cache.instances[poolName]; !ok {
+			m.cache.instances[poolName] = make([]*machine, 0)
+		}
+		m.cache.instances[poolName] = append(m.cache.instances[poolName], infraMachine)
+	}
+
+	if m.onCreate != nil {
+		if err := m.onCreate(poolName, infraMachine); err != nil {
+			return nil, err
+		}
+	}
+
+	monitor.Info("Machine created")
+	return infraMachine, nil
+}
+
+func (m *machinesService) toMachine(instance *ec2.Instance, monitor mntr.Monitor, poolName string) (*machine, error) {
+	internalIP := aws.StringValue(instance.PrivateIpAddress)
+	externalIP := aws.StringValue(instance.PublicIpAddress)
+	sshIP := internalIP
+	if m.oneoff {
+		sshIP = externalIP
+	}
+
+	sshMachine := ssh.NewMachine(monitor, "orbiter", sshIP)
+	if err := sshMachine.UseKey([]byte(m.privateKey)); err != nil {
+		return nil, err
+	}
+
+	id := aws.StringValue(instance.InstanceId)
+	return newMachine(
+		instance,
+		id,
+		internalIP,
+		externalIP,
+		sshMachine,
+		m.removeMachineFunc(poolName, id),
+		m.context,
+		poolName,
+	), nil
+}
+
+func (m *machinesService) ListPools() ([]string, error) {
+
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	var poolNames []string
+	for poolName := range pools {
+		poolNames = append(poolNames, poolName)
+	}
+	return poolNames, nil
+}
+
+func (m *machinesService) List(poolName string) (infra.Machines, error) {
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := pools[poolName]
+	machines := make([]infra.Machine, len(pool))
+	for idx := range pool {
+		machines[idx] = pool[idx]
+	}
+
+	return machines, nil
+}
+
+func (m *machinesService) machines() (map[string][]*machine, error) {
+	if m.cache.instances != nil {
+		return m.cache.instances, nil
+	}
+
+	var instances []*ec2.Instance
+	if err := m.context.ec2.DescribeInstancesPagesWithContext(m.context.ctx, &ec2.DescribeInstancesInput{
+		Filters: append(m.context.filters(), &ec2.Filter{
+			Name:   aws.String("instance-state-name"),
+			Values: aws.StringSlice([]string{"pending", "running", "stopping", "stopped"}),
+		}),
+	}, func(page *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, reservation := range page.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	m.cache.instances = make(map[string][]*machine)
+	for _, instance := range instances {
+		pool := tagValue(instance.Tags, "pool")
+		machine, err := m.toMachine(instance, machineMonitor(m.context.monitor, tagValue(instance.Tags, "Name"), pool), pool)
+		if err != nil {
+			return nil, err
+		}
+		m.cache.instances[pool] = append(m.cache.instances[pool], machine)
+	}
+
+	return m.cache.instances, nil
+}
+
+func (m *machinesService) removeMachineFunc(pool, id string) func() error {
+
+	return func() error {
+		m.cache.Lock()
+		if m.cache.instances != nil {
+			cleanMachines := make([]*machine, 0)
+			for idx := range m.cache.instances[pool] {
+				cachedMachine := m.cache.instances[pool][idx]
+				if cachedMachine.ID() != id {
+					cleanMachines = append(cleanMachines, cachedMachine)
+				}
+			}
+			m.cache.instances[pool] = cleanMachines
+		}
+		m.cache.Unlock()
+
+		removeLog(m.context.monitor, "instance", id, false, true)()
+		if _, err := m.context.ec2.TerminateInstancesWithContext(m.context.ctx, &ec2.TerminateInstancesInput{
+			InstanceIds: []*string{aws.String(id)},
+		}); err != nil {
+			return err
+		}
+		removeLog(m.context.monitor, "instance", id, true, false)()
+		return nil
+	}
+}
+
+func machineMonitor(monitor mntr.Monitor, name string, poolName string) mntr.Monitor {
+	return monitor.WithFields(map[string]interface{}{
+		"machine": name,
+		"pool":    poolName,
+	})
+}
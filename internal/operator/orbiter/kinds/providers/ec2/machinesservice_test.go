@@ -0,0 +1,269 @@
+package ec2
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/ssh"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/secret"
+)
+
+type fakeInstance struct {
+	id, privateIP, publicIP, pool string
+}
+
+// fakeEC2 serves the few EC2 query API actions the machines service and the firewall use
+type fakeEC2 struct {
+	sync.Mutex
+	instances  []*fakeInstance
+	terminated []string
+	run        []map[string]string
+	// noSecurityGroup makes the security group sg-1 exist only after it is created
+	noSecurityGroup bool
+	createdGroups   int
+	authorized      []map[string]string
+}
+
+func (f *fakeEC2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	switch action := r.Form.Get("Action"); action {
+	case "DescribeInstances":
+		ids := formList(r, "InstanceId")
+		var items string
+		for _, inst := range f.instances {
+			if len(ids) > 0 && !contains(ids, inst.id) {
+				continue
+			}
+			items += fmt.Sprintf(`<item><instanceId>%s</instanceId><privateIpAddress>%s</privateIpAddress><ipAddress>%s</ipAddress><instanceState><code>16</code><name>running</name></instanceState><tagSet><item><key>pool</key><value>%s</value></item><item><key>Name</key><value>%s</value></item></tagSet></item>`, inst.id, inst.privateIP, inst.publicIP, inst.pool, inst.id)
+		}
+		fmt.Fprintf(w, `<DescribeInstancesResponse><reservationSet><item><reservationId>r-1</reservationId><instancesSet>%s</instancesSet></item></reservationSet></DescribeInstancesResponse>`, items)
+	case "DescribeImages":
+		fmt.Fprint(w, `<DescribeImagesResponse><imagesSet><item><imageId>ami-1</imageId><rootDeviceName>/dev/sda1</rootDeviceName></item></imagesSet></DescribeImagesResponse>`)
+	case "DescribeSecurityGroups":
+		if f.noSecurityGroup && f.createdGroups == 0 {
+			fmt.Fprint(w, `<DescribeSecurityGroupsResponse><securityGroupInfo/></DescribeSecurityGroupsResponse>`)
+			return
+		}
+		fmt.Fprint(w, `<DescribeSecurityGroupsResponse><securityGroupInfo><item><groupId>sg-1</groupId></item></securityGroupInfo></DescribeSecurityGroupsResponse>`)
+	case "DescribeSubnets":
+		fmt.Fprint(w, `<DescribeSubnetsResponse><subnetSet><item><subnetId>subnet-default</subnetId><vpcId>vpc-1</vpcId></item></subnetSet></DescribeSubnetsResponse>`)
+	case "DescribeVpcs":
+		fmt.Fprint(w, `<DescribeVpcsResponse><vpcSet><item><vpcId>vpc-1</vpcId><cidrBlock>10.0.0.0/16</cidrBlock></item></vpcSet></DescribeVpcsResponse>`)
+	case "CreateSecurityGroup":
+		f.createdGroups++
+		fmt.Fprint(w, `<CreateSecurityGroupResponse><groupId>sg-1</groupId></CreateSecurityGroupResponse>`)
+	case "CreateTags":
+		fmt.Fprint(w, `<CreateTagsResponse><return>true</return></CreateTagsResponse>`)
+	case "AuthorizeSecurityGroupIngress":
+		params := make(map[string]string)
+		for key := range r.Form {
+			params[key] = r.Form.Get(key)
+		}
+		f.authorized = append(f.authorized, params)
+		fmt.Fprint(w, `<AuthorizeSecurityGroupIngressResponse><return>true</return></AuthorizeSecurityGroupIngressResponse>`)
+	case "RunInstances":
+		params := make(map[string]string)
+		for key := range r.Form {
+			params[key] = r.Form.Get(key)
+		}
+		f.run = append(f.run, params)
+		inst := &fakeInstance{
+			id:        fmt.Sprintf("i-%d", len(f.instances)),
+			privateIP: fmt.Sprintf("10.0.0.%d", len(f.instances)),
+			publicIP:  fmt.Sprintf("1.2.3.%d", len(f.instances)),
+		}
+		for key, value := range params {
+			if strings.HasPrefix(key, "TagSpecification.1.Tag.") && strings.HasSuffix(key, ".Key") && value == "pool" {
+				inst.pool = params[strings.TrimSuffix(key, ".Key")+".Value"]
+			}
+		}
+		f.instances = append(f.instances, inst)
+		fmt.Fprintf(w, `<RunInstancesResponse><reservationId>r-1</reservationId><instancesSet><item><instanceId>%s</instanceId></item></instancesSet></RunInstancesResponse>`, inst.id)
+	case "TerminateInstances":
+		f.terminated = append(f.terminated, formList(r, "InstanceId")...)
+		fmt.Fprint(w, `<TerminateInstancesResponse><instancesSet/></TerminateInstancesResponse>`)
+	default:
+		http.Error(w, "unsupported action "+action, http.StatusBadRequest)
+	}
+}
+
+func formList(r *http.Request, prefix string) []string {
+	var values []string
+	for key := range r.Form {
+		if strings.HasPrefix(key, prefix+".") {
+			values = append(values, r.Form.Get(key))
+		}
+	}
+	return values
+}
+
+func contains(list []string, item string) bool {
+	for _, el := range list {
+		if el == item {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestMachinesService(t *testing.T, fake *fakeEC2) *machinesService {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	priv, pub, err := ssh.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &Spec{
+		Region:          "eu-central-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     &secret.Secret{Value: "id"},
+		SecretAccessKey: &secret.Secret{Value: "key"},
+		Subnet:          "subnet-default",
+		Pools: map[string]*Pool{
+			"application": {InstanceType: "t3.medium", AMI: "ami-1", VolumeSizeGB: 20},
+			"management":  {InstanceType: "t3.large", AMI: "ami-1", VolumeSizeGB: 30, Subnet: "subnet-management"},
+		},
+	}
+
+	ctx, err := buildContext(mntr.Monitor{}, spec, "orb", "aws", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ctx.machinesService.use(&SSHKey{
+		Private: &secret.Secret{Value: priv},
+		Public:  &secret.Secret{Value: pub},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return ctx.machinesService
+}
+
+func TestMachinesService_List(t *testing.T) {
+	fake := &fakeEC2{instances: []*fakeInstance{
+		{id: "i-1", privateIP: "10.0.0.1", publicIP: "1.2.3.1", pool: "application"},
+		{id: "i-2", privateIP: "10.0.0.2", publicIP: "1.2.3.2", pool: "management"},
+		{id: "i-3", privateIP: "10.0.0.3", publicIP: "1.2.3.3", pool: "application"},
+	}}
+	svc := newTestMachinesService(t, fake)
+
+	pools, err := svc.ListPools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(pools)
+	if strings.Join(pools, ",") != "application,management" {
+		t.Errorf("ListPools() = %v, want [application management]", pools)
+	}
+
+	machines, err := svc.List("application")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := machines.IDs(); strings.Join(got, ",") != "i-1,i-3" {
+		t.Errorf("List() = %v, want [i-1 i-3]", got)
+	}
+	if ip := machines[0].IP(); ip != "10.0.0.1" {
+		t.Errorf("IP() = %s, want the private ip 10.0.0.1", ip)
+	}
+}
+
+func TestMachinesService_CreateAndRemove(t *testing.T) {
+	fake := &fakeEC2{}
+	svc := newTestMachinesService(t, fake)
+
+	var created []string
+	svc.onCreate = func(pool string, machine infra.Machine) error {
+		created = append(created, pool+"."+machine.ID())
+		return nil
+	}
+
+	machine, err := svc.Create("management")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.run) != 1 {
+		t.Fatalf("expected one RunInstances call, got %d", len(fake.run))
+	}
+	run := fake.run[0]
+	for key, want := range map[string]string{
+		"ImageId":                              "ami-1",
+		"InstanceType":                         "t3.large",
+		"NetworkInterface.1.SubnetId":          "subnet-management",
+		"NetworkInterface.1.SecurityGroupId.1": "sg-1",
+		"BlockDeviceMapping.1.DeviceName":      "/dev/sda1",
+		"BlockDeviceMapping.1.Ebs.VolumeSize":  "30",
+	} {
+		if got := run[key]; got != want {
+			t.Errorf("RunInstances parameter %s = %s, want %s", key, got, want)
+		}
+	}
+
+	if machine.IP() != "10.0.0.0" {
+		t.Errorf("IP() = %s, want 10.0.0.0", machine.IP())
+	}
+
+	if strings.Join(created, ",") != "management."+machine.ID() {
+		t.Errorf("onCreate was called for %v", created)
+	}
+
+	if _, err := svc.Create("unknown"); err == nil {
+		t.Error("creating a machine in an unconfigured pool should fail")
+	}
+
+	if err := machine.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(fake.terminated, ",") != machine.ID() {
+		t.Errorf("terminated instances = %v, want [%s]", fake.terminated, machine.ID())
+	}
+}
+
+func TestQueryFirewall(t *testing.T) {
+	fake := &fakeEC2{noSecurityGroup: true}
+	context := newTestMachinesService(t, fake).context
+
+	ensure, err := queryFirewall(context, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.createdGroups != 0 || len(fake.authorized) != 0 {
+		t.Fatalf("querying the firewall created %d security groups and authorized %d times", fake.createdGroups, len(fake.authorized))
+	}
+
+	if err := ensure(); err != nil {
+		t.Fatal(err)
+	}
+	if fake.createdGroups != 1 {
+		t.Errorf("ensuring the firewall created %d security groups, want 1", fake.createdGroups)
+	}
+	if len(fake.authorized) != 1 || fake.authorized[0]["GroupId"] != "sg-1" {
+		t.Fatalf("ensuring the firewall authorized %v, want the ingress rules of sg-1", fake.authorized)
+	}
+	var ssh, internal bool
+	for key, value := range fake.authorized[0] {
+		ssh = ssh || strings.HasSuffix(key, ".FromPort") && value == "22"
+		internal = internal || strings.HasSuffix(key, ".GroupId") && strings.Contains(key, "Groups") && value == "sg-1"
+	}
+	if !ssh || !internal {
+		t.Errorf("ensuring the firewall authorized %v, want ssh and the internal traffic", fake.authorized[0])
+	}
+}
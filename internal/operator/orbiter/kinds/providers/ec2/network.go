@@ -0,0 +1,280 @@
+package ec2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+)
+
+func (c *context) vpc() (string, string, error) {
+	if c.network.vpcID != "" {
+		return c.network.vpcID, c.network.vpcCIDR, nil
+	}
+
+	subnets, err := c.ec2.DescribeSubnetsWithContext(c.ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: []*string{aws.String(c.desired.Subnet)},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if len(subnets.Subnets) != 1 {
+		return "", "", errors.Errorf("subnet %s not found", c.desired.Subnet)
+	}
+
+	vpcID := aws.StringValue(subnets.Subnets[0].VpcId)
+	vpcs, err := c.ec2.DescribeVpcsWithContext(c.ctx, &ec2.DescribeVpcsInput{
+		VpcIds: []*string{aws.String(vpcID)},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if len(vpcs.Vpcs) != 1 {
+		return "", "", errors.Errorf("vpc %s not found", vpcID)
+	}
+
+	c.network.vpcID = vpcID
+	c.network.vpcCIDR = aws.StringValue(vpcs.Vpcs[0].CidrBlock)
+	return c.network.vpcID, c.network.vpcCIDR, nil
+}
+
+// securityGroup returns the ID of the security group all machines of this provider are member of.
+// It returns an empty ID if the group doesn't exist yet
+func (c *context) securityGroup() (string, error) {
+	if c.network.securityGroupID != "" {
+		return c.network.securityGroupID, nil
+	}
+
+	groups, err := c.ec2.DescribeSecurityGroupsWithContext(c.ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: c.filters(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(groups.SecurityGroups) > 0 {
+		c.network.securityGroupID = aws.StringValue(groups.SecurityGroups[0].GroupId)
+	}
+	return c.network.securityGroupID, nil
+}
+
+// ensureSecurityGroup returns the ID of the security group all machines of this provider are member of.
+// It is created if it doesn't exist yet, so it must only be called while ensuring
+func (c *context) ensureSecurityGroup() (string, error) {
+	sgID, err := c.securityGroup()
+	if err != nil || sgID != "" {
+		return sgID, err
+	}
+
+	vpcID, _, err := c.vpc()
+	if err != nil {
+		return "", err
+	}
+
+	monitor := c.monitor.WithField("type", "security group")
+	monitor.Debug("Creating resource")
+	created, err := c.ec2.CreateSecurityGroupWithContext(c.ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:   aws.String(c.resourcePrefix),
+		Description: aws.String(fmt.Sprintf("orb=%s;provider=%s", c.orbID, c.providerID)),
+		VpcId:       aws.String(vpcID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	c.network.securityGroupID = aws.StringValue(created.GroupId)
+	if _, err := c.ec2.CreateTagsWithContext(c.ctx, &ec2.CreateTagsInput{
+		Resources: []*string{created.GroupId},
+		Tags:      c.tags(nil),
+	}); err != nil {
+		return "", err
+	}
+	monitor.WithField("id", c.network.securityGroupID).Info("Resource created")
+	return c.network.securityGroupID, nil
+}
+
+type rule struct {
+	protocol string
+	from     int64
+	to       int64
+	cidr     string
+	group    string
+}
+
+func (r rule) String() string {
+	source := r.cidr
+	if r.group != "" {
+		source = r.group
+	}
+	return fmt.Sprintf("%s:%d-%d:%s", r.protocol, r.from, r.to, source)
+}
+
+func (r rule) toPermission() *ec2.IpPermission {
+	perm := &ec2.IpPermission{
+		IpProtocol: aws.String(r.protocol),
+	}
+	if r.protocol != "-1" {
+		perm.FromPort = aws.Int64(r.from)
+		perm.ToPort = aws.Int64(r.to)
+	}
+	if r.cidr != "" {
+		perm.IpRanges = []*ec2.IpRange{{CidrIp: aws.String(r.cidr)}}
+	}
+	if r.group != "" {
+		perm.UserIdGroupPairs = []*ec2.UserIdGroupPair{{GroupId: aws.String(r.group)}}
+	}
+	return perm
+}
+
+func toRules(perms []*ec2.IpPermission) []rule {
+	var rules []rule
+	for _, perm := range perms {
+		protocol := aws.StringValue(perm.IpProtocol)
+		from := aws.Int64Value(perm.FromPort)
+		to := aws.Int64Value(perm.ToPort)
+		if protocol == "-1" {
+			from, to = 0, 0
+		}
+		for _, ipRange := range perm.IpRanges {
+			rules = append(rules, rule{protocol: protocol, from: from, to: to, cidr: aws.StringValue(ipRange.CidrIp)})
+		}
+		for _, pair := range perm.UserIdGroupPairs {
+			rules = append(rules, rule{protocol: protocol, from: from, to: to, group: aws.StringValue(pair.GroupId)})
+		}
+	}
+	return rules
+}
+
+// desiredRules allows all internal traffic, SSH, the load balancers frontend ports from their whitelists
+// and the health check ports from within the VPC
+func desiredRules(sgID, vpcCIDR string, normalized []*normalizedLoadbalancer) []rule {
+	rules := []rule{
+		{protocol: "-1", group: sgID},
+		{protocol: "tcp", from: 22, to: 22, cidr: "0.0.0.0/0"},
+	}
+	for _, lb := range normalized {
+		for _, cidr := range lb.whitelist {
+			rules = append(rules, rule{protocol: "tcp", from: int64(lb.frontendPort), to: int64(lb.frontendPort), cidr: cidr})
+		}
+		rules = append(rules, rule{protocol: "tcp", from: lb.healthcheckPort, to: lb.healthcheckPort, cidr: vpcCIDR})
+	}
+
+	unique := make(map[string]rule)
+	for _, r := range rules {
+		unique[r.String()] = r
+	}
+	rules = make([]rule, 0, len(unique))
+	for _, r := range unique {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return strings.Compare(rules[i].String(), rules[j].String()) < 0 })
+	return rules
+}
+
+// diffRules returns the permissions to authorize and to revoke
+func diffRules(current, desired []rule) ([]*ec2.IpPermission, []*ec2.IpPermission) {
+	var authorize, revoke []*ec2.IpPermission
+desiredLoop:
+	for _, d := range desired {
+		for _, c := range current {
+			if c == d {
+				continue desiredLoop
+			}
+		}
+		authorize = append(authorize, d.toPermission())
+	}
+
+currentLoop:
+	for _, c := range current {
+		for _, d := range desired {
+			if c == d {
+				continue currentLoop
+			}
+		}
+		revoke = append(revoke, c.toPermission())
+	}
+	return authorize, revoke
+}
+
+func queryFirewall(context *context, normalized []*normalizedLoadbalancer) (func() error, error) {
+
+	sgID, err := context.securityGroup()
+	if err != nil {
+		return nil, err
+	}
+
+	_, vpcCIDR, err := context.vpc()
+	if err != nil {
+		return nil, err
+	}
+
+	var current []rule
+	if sgID != "" {
+		groups, err := context.ec2.DescribeSecurityGroupsWithContext(context.ctx, &ec2.DescribeSecurityGroupsInput{
+			GroupIds: []*string{aws.String(sgID)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(groups.SecurityGroups) != 1 {
+			return nil, errors.Errorf("security group %s not found", sgID)
+		}
+		current = toRules(groups.SecurityGroups[0].IpPermissions)
+	}
+
+	return func() error {
+		// The security group is only created while ensuring, as the query phase must not change anything
+		sgID, err := context.ensureSecurityGroup()
+		if err != nil {
+			return err
+		}
+
+		monitor := context.monitor.WithFields(map[string]interface{}{
+			"type": "security group",
+			"id":   sgID,
+		})
+
+		authorize, revoke := diffRules(current, desiredRules(sgID, vpcCIDR, normalized))
+		if len(revoke) > 0 {
+			if _, err := context.ec2.RevokeSecurityGroupIngressWithContext(context.ctx, &ec2.RevokeSecurityGroupIngressInput{
+				GroupId:       aws.String(sgID),
+				IpPermissions: revoke,
+			}); err != nil {
+				return err
+			}
+			monitor.WithField("rules", len(revoke)).Info("Ingress rules revoked")
+		}
+		if len(authorize) > 0 {
+			if _, err := context.ec2.AuthorizeSecurityGroupIngressWithContext(context.ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+				GroupId:       aws.String(sgID),
+				IpPermissions: authorize,
+			}); err != nil {
+				return err
+			}
+			monitor.WithField("rules", len(authorize)).Info("Ingress rules authorized")
+		}
+		return nil
+	}, nil
+}
+
+func destroyNetwork(context *context) error {
+	groups, err := context.ec2.DescribeSecurityGroupsWithContext(context.ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: context.filters(),
+	})
+	if err != nil {
+		return err
+	}
+	for _, group := range groups.SecurityGroups {
+		if _, err := context.ec2.DeleteSecurityGroupWithContext(context.ctx, &ec2.DeleteSecurityGroupInput{
+			GroupId: group.GroupId,
+		}); err != nil {
+			return err
+		}
+		removeLog(context.monitor, "security group", aws.StringValue(group.GroupId), true, false)()
+	}
+	context.network.securityGroupID = ""
+	return nil
+}
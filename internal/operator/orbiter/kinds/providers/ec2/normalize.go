@@ -0,0 +1,191 @@
+package ec2
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/mntr"
+)
+
+type normalizedLoadbalancer struct {
+	transport       string
+	frontendPort    uint16
+	backendPort     uint16
+	healthcheckPort int64
+	healthcheck     dynamic.HealthChecks
+	proxyProtocol   bool
+	destPools       []string
+	whitelist       []string
+	address         *address      // The same elastic ip reference appears in multiple normalizedLoadbalancer references
+	loadbalancer    *loadbalancer // The same network load balancer reference appears in multiple normalizedLoadbalancer references
+	targetGroup     *targetGroup  // unique
+}
+
+type address struct {
+	id           string
+	allocationID string
+	ip           string
+}
+
+type loadbalancer struct {
+	name    string
+	arn     string
+	address *address
+}
+
+type targetGroup struct {
+	name string
+	arn  string
+}
+
+type normalizedLoadbalancing []*normalizedLoadbalancer
+
+func (n normalizedLoadbalancing) Len() int           { return len(n) }
+func (n normalizedLoadbalancing) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n normalizedLoadbalancing) Less(i, j int) bool { return n[i].transport < n[j].transport }
+
+func (n normalizedLoadbalancing) uniqueLoadbalancers() []*loadbalancer {
+	lbs := make([]*loadbalancer, 0)
+loop:
+	for _, lb := range n {
+		for _, found := range lbs {
+			if lb.loadbalancer == found {
+				continue loop
+			}
+		}
+		lbs = append(lbs, lb.loadbalancer)
+	}
+	return lbs
+}
+
+// normalize returns a normalizedLoadbalancer for each transport
+// whereas all transports of a VIP share the same elastic ip and network load balancer
+func normalize(ctx *context, spec map[string][]*dynamic.VIP) []*normalizedLoadbalancer {
+	var normalized []*normalizedLoadbalancer
+
+	for poolName, vips := range spec {
+		for vipIdx, vip := range vips {
+			id := fmt.Sprintf("%s-%d", poolName, vipIdx)
+			addr := &address{id: id}
+			lb := &loadbalancer{
+				name:    ctx.resourceName("lb:" + id),
+				address: addr,
+			}
+			for _, src := range vip.Transport {
+				whitelist := make([]string, len(src.Whitelist))
+				for idx, cidr := range src.Whitelist {
+					whitelist[idx] = string(*cidr)
+				}
+				normalized = append(normalized, &normalizedLoadbalancer{
+					transport:     src.Name,
+					frontendPort:  uint16(src.FrontendPort),
+					backendPort:   uint16(src.BackendPort),
+					healthcheck:   src.HealthChecks,
+					proxyProtocol: src.ProxyProtocol != nil && *src.ProxyProtocol,
+					destPools:     src.BackendPools,
+					whitelist:     whitelist,
+					address:       addr,
+					loadbalancer:  lb,
+					targetGroup: &targetGroup{
+						name: ctx.resourceName("tg:" + src.Name),
+					},
+				})
+			}
+		}
+	}
+
+	sort.Sort(normalizedLoadbalancing(normalized))
+
+	var hcPort int64 = 6700
+	for _, lb := range normalized {
+		lb.healthcheckPort = hcPort
+		hcPort++
+	}
+
+	return normalized
+}
+
+type ensureLBFunc func(*context, []*normalizedLoadbalancer) ([]func() error, []func() error, error)
+
+func queryLB(context *context, normalized []*normalizedLoadbalancer) (func() error, error) {
+	lb, err := chainInEnsureOrder(
+		context, normalized,
+		queryAddresses,
+		queryTargetGroups,
+		queryLoadbalancers,
+		queryListeners,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		for _, fn := range lb {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+func chainInEnsureOrder(ctx *context, lb []*normalizedLoadbalancer, query ...ensureLBFunc) ([]func() error, error) {
+	var ensureOperations []func() error
+	var removeOperations []func() error
+
+	for _, fn := range query {
+		ensure, remove, err := fn(ctx, lb)
+		if err != nil {
+			return nil, err
+		}
+		ensureOperations = append(ensureOperations, helpers.Fanout(ensure))
+		removeOperations = append(removeOperations, helpers.Fanout(remove))
+	}
+
+	for i := 0; i < len(removeOperations)/2; i++ {
+		j := len(removeOperations) - i - 1
+		removeOperations[i], removeOperations[j] = removeOperations[j], removeOperations[i]
+	}
+
+	return append(removeOperations, ensureOperations...), nil
+}
+
+func removeLog(monitor mntr.Monitor, resource, id string, removed bool, debug bool) func() {
+	msg := "Removing resource"
+	if removed {
+		msg = "Resource removed"
+	}
+	monitor = monitor.WithFields(map[string]interface{}{
+		"type": resource,
+		"id":   id,
+	})
+	level := monitor.Info
+	if debug {
+		level = monitor.Debug
+	}
+	return func() {
+		level(msg)
+	}
+}
+
+func createLog(monitor mntr.Monitor, resource, id string, created bool) func() {
+	monitor = monitor.WithFields(map[string]interface{}{
+		"type": resource,
+		"id":   id,
+	})
+	msg := "Creating resource"
+	level := monitor.Debug
+	if created {
+		msg = "Resource created"
+		level = monitor.Info
+	}
+	return func() {
+		level(msg)
+	}
+}
+
+func newName() string {
+	return "orbos-" + helpers.RandomStringRunes(6, []rune("abcdefghijklmnopqrstuvwxyz0123456789"))
+}
@@ -0,0 +1,105 @@
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+)
+
+var _ infra.Pool = (*infraPool)(nil)
+
+type infraPool struct {
+	pool       string
+	normalized []*normalizedLoadbalancer
+	svc        *machinesService
+	machines   core.MachinesService
+}
+
+func newInfraPool(pool string, svc *machinesService, normalized []*normalizedLoadbalancer, machines core.MachinesService) *infraPool {
+	return &infraPool{
+		pool:       pool,
+		normalized: normalized,
+		svc:        svc,
+		machines:   machines,
+	}
+}
+
+func (i *infraPool) EnsureMember(machine infra.Machine) error {
+	return i.ensureMembers(machine)
+}
+
+func (i *infraPool) EnsureMembers() error {
+	return i.ensureMembers(nil)
+}
+
+func (i *infraPool) ensureMembers(machine infra.Machine) error {
+
+	pools, err := i.svc.machines()
+	if err != nil {
+		return err
+	}
+	poolMachines := pools[i.pool]
+	ctx := i.svc.context
+
+	for _, n := range i.normalized {
+	destpoolLoop:
+		for _, destPool := range n.destPools {
+			if destPool != i.pool || n.targetGroup.arn == "" {
+				continue destpoolLoop
+			}
+
+			health, err := ctx.elb.DescribeTargetHealthWithContext(ctx.ctx, &elbv2.DescribeTargetHealthInput{
+				TargetGroupArn: aws.String(n.targetGroup.arn),
+			})
+			if err != nil {
+				return err
+			}
+
+			var addTargets []*elbv2.TargetDescription
+		addTargetLoop:
+			for _, poolMachine := range poolMachines {
+				if machine != nil && machine.ID() != poolMachine.ID() {
+					continue addTargetLoop
+				}
+				for _, registered := range health.TargetHealthDescriptions {
+					if aws.StringValue(registered.Target.Id) == poolMachine.ID() {
+						continue addTargetLoop
+					}
+				}
+				addTargets = append(addTargets, &elbv2.TargetDescription{
+					Id:   aws.String(poolMachine.ID()),
+					Port: aws.Int64(int64(n.frontendPort)),
+				})
+			}
+
+			if len(addTargets) == 0 {
+				continue destpoolLoop
+			}
+
+			monitor := ctx.monitor.WithFields(map[string]interface{}{
+				"type":    "target group",
+				"id":      n.targetGroup.name,
+				"targets": len(addTargets),
+			})
+			monitor.Debug("Registering targets")
+			if _, err := ctx.elb.RegisterTargetsWithContext(ctx.ctx, &elbv2.RegisterTargetsInput{
+				TargetGroupArn: aws.String(n.targetGroup.arn),
+				Targets:        addTargets,
+			}); err != nil {
+				return err
+			}
+			monitor.Info("Targets registered")
+		}
+	}
+	return nil
+}
+
+func (i *infraPool) GetMachines() (infra.Machines, error) {
+	return i.machines.List(i.pool)
+}
+
+func (i *infraPool) AddMachine() (infra.Machine, error) {
+	return i.machines.Create(i.pool)
+}
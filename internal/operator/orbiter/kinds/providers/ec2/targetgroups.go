@@ -0,0 +1,111 @@
+package ec2
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+var _ ensureLBFunc = queryTargetGroups
+
+func queryTargetGroups(context *context, loadbalancing []*normalizedLoadbalancer) ([]func() error, []func() error, error) {
+
+	var awsTGs []*elbv2.TargetGroup
+	if err := context.elb.DescribeTargetGroupsPagesWithContext(context.ctx, &elbv2.DescribeTargetGroupsInput{}, func(page *elbv2.DescribeTargetGroupsOutput, _ bool) bool {
+		for _, tg := range page.TargetGroups {
+			if strings.HasPrefix(aws.StringValue(tg.TargetGroupName), context.resourcePrefix+"-") {
+				awsTGs = append(awsTGs, tg)
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	var ensure []func() error
+createLoop:
+	for _, lb := range loadbalancing {
+		hcPort := strconv.FormatInt(lb.healthcheckPort, 10)
+		hcPath := lb.healthcheck.Path
+		if hcPath == "" {
+			hcPath = "/"
+		}
+		for _, awsTG := range awsTGs {
+			if aws.StringValue(awsTG.TargetGroupName) != lb.targetGroup.name {
+				continue
+			}
+			lb.targetGroup.arn = aws.StringValue(awsTG.TargetGroupArn)
+			if aws.StringValue(awsTG.HealthCheckPort) != hcPort || aws.StringValue(awsTG.HealthCheckPath) != hcPath {
+				ensure = append(ensure, func(tg *targetGroup) func() error {
+					return func() error {
+						if _, err := context.elb.ModifyTargetGroupWithContext(context.ctx, &elbv2.ModifyTargetGroupInput{
+							TargetGroupArn:      aws.String(tg.arn),
+							HealthCheckProtocol: aws.String(elbv2.ProtocolEnumHttp),
+							HealthCheckPort:     aws.String(hcPort),
+							HealthCheckPath:     aws.String(hcPath),
+						}); err != nil {
+							return err
+						}
+						context.monitor.WithFields(map[string]interface{}{
+							"type": "target group",
+							"id":   tg.name,
+						}).Info("Healthcheck updated")
+						return nil
+					}
+				}(lb.targetGroup))
+			}
+			continue createLoop
+		}
+
+		ensure = append(ensure, func(lb *normalizedLoadbalancer) func() error {
+			return func() error {
+				vpcID, _, err := context.vpc()
+				if err != nil {
+					return err
+				}
+				createLog(context.monitor, "target group", lb.targetGroup.name, false)()
+				created, err := context.elb.CreateTargetGroupWithContext(context.ctx, &elbv2.CreateTargetGroupInput{
+					Name:                aws.String(lb.targetGroup.name),
+					Protocol:            aws.String(elbv2.ProtocolEnumTcp),
+					Port:                aws.Int64(int64(lb.frontendPort)),
+					VpcId:               aws.String(vpcID),
+					TargetType:          aws.String(elbv2.TargetTypeEnumInstance),
+					HealthCheckProtocol: aws.String(elbv2.ProtocolEnumHttp),
+					HealthCheckPort:     aws.String(hcPort),
+					HealthCheckPath:     aws.String(hcPath),
+				})
+				if err != nil {
+					return err
+				}
+				lb.targetGroup.arn = aws.StringValue(created.TargetGroups[0].TargetGroupArn)
+				createLog(context.monitor, "target group", lb.targetGroup.name, true)()
+				return nil
+			}
+		}(lb))
+	}
+
+	var remove []func() error
+removeLoop:
+	for _, awsTG := range awsTGs {
+		for _, lb := range loadbalancing {
+			if aws.StringValue(awsTG.TargetGroupName) == lb.targetGroup.name {
+				continue removeLoop
+			}
+		}
+		remove = append(remove, func(name string, arn *string) func() error {
+			return func() error {
+				removeLog(context.monitor, "target group", name, false, true)()
+				if _, err := context.elb.DeleteTargetGroupWithContext(context.ctx, &elbv2.DeleteTargetGroupInput{
+					TargetGroupArn: arn,
+				}); err != nil {
+					return err
+				}
+				removeLog(context.monitor, "target group", name, true, false)()
+				return nil
+			}
+		}(aws.StringValue(awsTG.TargetGroupName), awsTG.TargetGroupArn))
+	}
+	return ensure, remove, nil
+}
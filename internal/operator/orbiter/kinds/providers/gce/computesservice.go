@@ -19,10 +19,11 @@ import (
 var _ core.MachinesService = (*machinesService)(nil)
 
 type machinesService struct {
-	context *context
-	oneoff  bool
-	key     *SSHKey
-	cache   struct {
+	context    *context
+	oneoff     bool
+	privateKey string
+	publicKey  string
+	cache      struct {
 		instances map[string][]*instance
 		sync.Mutex
 	}
@@ -37,10 +38,18 @@ func newMachinesService(context *context, oneoff bool) *machinesService {
 }
 
 func (m *machinesService) use(key *SSHKey) error {
-	if key == nil || key.Private == nil || key.Public == nil || key.Private.Value == "" || key.Public.Value == "" {
+	if key == nil || !key.Private.HasValue() || !key.Public.HasValue() {
 		return errors.New("machines are not connectable. have you configured the orb by running orbctl configure?")
 	}
-	m.key = key
+	privateKey, err := key.Private.Resolve()
+	if err != nil {
+		return err
+	}
+	publicKey, err := key.Public.Resolve()
+	if err != nil {
+		return err
+	}
+	m.privateKey, m.publicKey = privateKey, publicKey
 	return nil
 }
 
@@ -124,7 +133,7 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 
 	name := newName()
 	nwTags := networkTags(m.context.orbID, m.context.providerID, poolName)
-	sshKey := fmt.Sprintf("orbiter:%s", m.key.Public.Value)
+	sshKey := fmt.Sprintf("orbiter:%s", m.publicKey)
 	createInstance := &compute.Instance{
 		Name:        name,
 		MachineType: fmt.Sprintf("zones/%s/machineTypes/custom-%d-%d", m.context.desired.Zone, cores, int(memory)),
@@ -171,7 +180,7 @@ func (m *machinesService) Create(poolName string) (infra.Machine, error) {
 		machine = newGCEMachine(m.context, monitor, createInstance.Name)
 	} else {
 		sshMachine := ssh.NewMachine(monitor, "orbiter", newInstance.NetworkInterfaces[0].NetworkIP)
-		if err := sshMachine.UseKey([]byte(m.key.Private.Value)); err != nil {
+		if err := sshMachine.UseKey([]byte(m.privateKey)); err != nil {
 			return nil, err
 		}
 		machine = sshMachine
@@ -288,7 +297,7 @@ func getAllInstances(m *machinesService) (map[string][]*instance, error) {
 			machine = newGCEMachine(m.context, m.context.monitor.WithFields(toFields(inst.Labels)), inst.Name)
 		} else {
 			sshMachine := ssh.NewMachine(m.context.monitor.WithFields(toFields(inst.Labels)), "orbiter", inst.NetworkInterfaces[0].NetworkIP)
-			if err := sshMachine.UseKey([]byte(m.key.Private.Value)); err != nil {
+			if err := sshMachine.UseKey([]byte(m.privateKey)); err != nil {
 				return nil, err
 			}
 			machine = sshMachine
@@ -20,6 +20,7 @@ type context struct {
 	providerID  string
 	projectID   string
 	desired     *Spec
+	jsonKey     string
 	client      *compute.Service
 	//	machinesService *machinesService
 	ctx  ctxpkg.Context
@@ -28,7 +29,11 @@ type context struct {
 
 func service(monitor mntr.Monitor, desired *Spec, orbID, providerID string, oneoff bool) (*machinesService, error) {
 
-	jsonKey := []byte(desired.JSONKey.Value)
+	resolvedKey, err := desired.JSONKey.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	jsonKey := []byte(resolvedKey)
 	ctx := ctxpkg.Background()
 	opt := option.WithCredentialsJSON(jsonKey)
 	computeClient, err := compute.NewService(ctx, opt)
@@ -56,6 +61,7 @@ func service(monitor mntr.Monitor, desired *Spec, orbID, providerID string, oneo
 		providerID:  providerID,
 		projectID:   key.ProjectID,
 		desired:     desired,
+		jsonKey:     resolvedKey,
 		client:      computeClient,
 		ctx:         ctx,
 		auth:        &opt,
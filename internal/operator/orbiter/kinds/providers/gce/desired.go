@@ -86,7 +86,7 @@ func (d Desired) validateAdapt() error {
 }
 
 func (d Desired) validateJSONKey() error {
-	if d.Spec.JSONKey == nil || d.Spec.JSONKey.Value == "" {
+	if !d.Spec.JSONKey.HasValue() {
 		return errors.New("jsonkey missing... please provide a google service accounts jsonkey using orbctl writesecret command")
 	}
 	return nil
@@ -97,10 +97,8 @@ func (d Desired) validateQuery() error {
 		return err
 	}
 	if d.Spec.SSHKey == nil ||
-		d.Spec.SSHKey.Private == nil ||
-		d.Spec.SSHKey.Private.Value == "" ||
-		d.Spec.SSHKey.Public == nil ||
-		d.Spec.SSHKey.Public.Value == "" {
+		!d.Spec.SSHKey.Private.HasValue() ||
+		!d.Spec.SSHKey.Public.HasValue() {
 		return errors.New("ssh key missing... please initialize your orb using orbctl configure command")
 	}
 	return nil
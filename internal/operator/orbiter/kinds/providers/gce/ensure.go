@@ -74,7 +74,7 @@ func query(
 
 	queryNA, installNA := naFuncs(nodeAgentsCurrent)
 
-	desireNodeAgent := func(pool string, machine infra.Machine) error {
+	desireHealthchecks := func(pool string, machine infra.Machine) {
 
 		machineID := machine.ID()
 		machineMonitor := svc.context.monitor.WithField("machine", machineID)
@@ -122,6 +122,9 @@ func query(
 				}
 			}
 		}
+	}
+
+	ensureNodeAgent := func(machine infra.Machine) error {
 		running, err := queryNA(machine, orbiterCommit)
 		if err != nil {
 			return err
@@ -129,11 +132,13 @@ func query(
 		if !running {
 			return installNA(machine)
 		}
-
 		return nil
 	}
 
-	svc.onCreate = desireNodeAgent
+	svc.onCreate = func(pool string, machine infra.Machine) error {
+		desireHealthchecks(pool, machine)
+		return ensureNodeAgent(machine)
+	}
 	wrappedMachines := wrap.MachinesService(svc, *lbCurrent, nil, func(vip *dynamic.VIP) string {
 		for _, transport := range vip.Transport {
 			address, ok := current.Current.Ingresses[transport.Name]
@@ -143,51 +148,47 @@ func query(
 		}
 		panic(fmt.Errorf("external address for %v is not ensured", vip))
 	})
+
+	pools, err := svc.ListPools()
+	if err != nil {
+		return nil, err
+	}
+	var machines []infra.Machine
+	for _, pool := range pools {
+		poolMachines, err := svc.List(pool)
+		if err != nil {
+			return nil, err
+		}
+		for _, machine := range poolMachines {
+			desireHealthchecks(pool, machine)
+			machines = append(machines, machine)
+		}
+	}
+
+	lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
+	if err != nil {
+		return nil, err
+	}
+	fwDone, err := core.DesireInternalOSFirewall(svc.context.monitor, nodeAgentsDesired, nodeAgentsCurrent, svc, false, []string{"eth0"})
+	if err != nil {
+		return nil, err
+	}
+
 	return func(pdf func(mntr.Monitor) error) *orbiter.EnsureResult {
 
-		var done bool
-		return orbiter.ToEnsureResult(done, helpers.Fanout([]func() error{
+		ensureNodeAgents := make([]func() error, len(machines))
+		for idx, machine := range machines {
+			ensureNodeAgents[idx] = func(m infra.Machine) func() error {
+				return func() error { return ensureNodeAgent(m) }
+			}(machine)
+		}
+
+		return orbiter.ToEnsureResult(lbDone && fwDone, helpers.Fanout([]func() error{
 			func() error { return ensureIdentityAwareProxyAPIEnabled(svc.context) },
 			func() error { return ensureNetwork(svc.context, createFWs, deleteFWs) },
 			svc.restartPreemptibleMachines,
 			ensureLB,
-			func() error {
-				pools, err := svc.ListPools()
-				if err != nil {
-					return err
-				}
-
-				var desireNodeAgents []func() error
-				for _, pool := range pools {
-					machines, listErr := svc.List(pool)
-					if listErr != nil {
-						err = helpers.Concat(err, listErr)
-					}
-					for _, machine := range machines {
-						desireNodeAgents = append(desireNodeAgents, func(p string, m infra.Machine) func() error {
-							return func() error {
-								return desireNodeAgent(p, m)
-							}
-						}(pool, machine))
-					}
-				}
-				return helpers.Fanout(desireNodeAgents)()
-			},
-			func() error {
-				var err error
-				lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
-				if err != nil {
-					return err
-				}
-
-				fwDone, err := core.DesireInternalOSFirewall(svc.context.monitor, nodeAgentsDesired, nodeAgentsCurrent, svc, false, []string{"eth0"})
-				if err != nil {
-					return err
-				}
-				done = lbDone && fwDone
-
-				return err
-			},
+			helpers.Fanout(ensureNodeAgents),
 		})())
 	}, initPools(current, desired, svc, normalized, wrappedMachines)
 }
@@ -52,7 +52,7 @@ func (c *gceMachine) execute(stdin io.Reader, command string) (outBuf *bytes.Buf
 		return nil, err
 	}
 
-	if err := gcloudSession(c.context.desired.JSONKey.Value, gcloud, func(bin string) error {
+	if err := gcloudSession(c.context.jsonKey, gcloud, func(bin string) error {
 		cmd := exec.Command(gcloud,
 			"compute",
 			"ssh",
@@ -84,7 +84,7 @@ func (c *gceMachine) Shell() error {
 		return err
 	}
 
-	if err := gcloudSession(c.context.desired.JSONKey.Value, gcloud, func(bin string) error {
+	if err := gcloudSession(c.context.jsonKey, gcloud, func(bin string) error {
 		cmd := exec.Command(gcloud,
 			"compute",
 			"ssh",
@@ -0,0 +1,312 @@
+package hetzner
+
+import (
+	"bytes"
+	ctxpkg "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const defaultEndpoint = "https://api.hetzner.cloud/v1"
+
+// client covers the few Hetzner Cloud API resources ORBITER manages
+type client struct {
+	endpoint string
+	token    string
+	http     *http.Client
+	ctx      ctxpkg.Context
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (a *apiError) Error() string {
+	return fmt.Sprintf("hetzner cloud api responded with %s: %s", a.Code, a.Message)
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := errors.Cause(err).(*apiError)
+	return ok && apiErr.Code == "not_found"
+}
+
+type server struct {
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`
+	Status    string            `json:"status"`
+	Labels    map[string]string `json:"labels"`
+	PublicNet struct {
+		IPv4 struct {
+			IP string `json:"ip"`
+		} `json:"ipv4"`
+	} `json:"public_net"`
+	PrivateNet []struct {
+		Network int    `json:"network"`
+		IP      string `json:"ip"`
+	} `json:"private_net"`
+}
+
+// privateIP returns the servers IP in the private network, ORBITER attaches each server to exactly one network
+func (s *server) privateIP() string {
+	if len(s.PrivateNet) == 0 {
+		return ""
+	}
+	return s.PrivateNet[0].IP
+}
+
+type createServerRequest struct {
+	Name       string            `json:"name"`
+	ServerType string            `json:"server_type"`
+	Image      string            `json:"image"`
+	Location   string            `json:"location,omitempty"`
+	UserData   string            `json:"user_data"`
+	SSHKeys    []int             `json:"ssh_keys"`
+	Networks   []int             `json:"networks"`
+	Labels     map[string]string `json:"labels"`
+}
+
+type floatingIP struct {
+	ID           int               `json:"id"`
+	Description  string            `json:"description"`
+	IP           string            `json:"ip"`
+	Server       *int              `json:"server"`
+	Labels       map[string]string `json:"labels"`
+	HomeLocation struct {
+		Name string `json:"name"`
+	} `json:"home_location"`
+}
+
+type createFloatingIPRequest struct {
+	Type         string            `json:"type"`
+	HomeLocation string            `json:"home_location"`
+	Description  string            `json:"description"`
+	Labels       map[string]string `json:"labels"`
+}
+
+type network struct {
+	ID      int               `json:"id"`
+	Name    string            `json:"name"`
+	IPRange string            `json:"ip_range"`
+	Labels  map[string]string `json:"labels"`
+}
+
+type subnet struct {
+	Type        string `json:"type"`
+	IPRange     string `json:"ip_range"`
+	NetworkZone string `json:"network_zone"`
+}
+
+type createNetworkRequest struct {
+	Name    string            `json:"name"`
+	IPRange string            `json:"ip_range"`
+	Subnets []subnet          `json:"subnets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+type sshKey struct {
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`
+	PublicKey string            `json:"public_key"`
+	Labels    map[string]string `json:"labels"`
+}
+
+func (c *client) do(method, path string, body interface{}, into interface{}) error {
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, strings.TrimSuffix(c.endpoint, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s failed", method, path)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "reading response of %s %s failed", method, path)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		errResp := struct {
+			Error *apiError `json:"error"`
+		}{}
+		if jsonErr := json.Unmarshal(data, &errResp); jsonErr != nil || errResp.Error == nil {
+			errResp.Error = &apiError{Code: resp.Status, Message: string(data)}
+		}
+		return errors.Wrapf(errResp.Error, "%s %s failed", method, path)
+	}
+
+	if into == nil || len(data) == 0 {
+		return nil
+	}
+	return errors.Wrapf(json.Unmarshal(data, into), "decoding response of %s %s failed", method, path)
+}
+
+// list follows the pagination of a list endpoint and decodes the items at the key of each page
+func (c *client) list(path, labelSelector, key string, appendPage func(items json.RawMessage) error) error {
+	for page := 1; page > 0; {
+		query := url.Values{}
+		query.Set("page", fmt.Sprintf("%d", page))
+		query.Set("per_page", "50")
+		if labelSelector != "" {
+			query.Set("label_selector", labelSelector)
+		}
+
+		resp := make(map[string]json.RawMessage)
+		if err := c.do(http.MethodGet, path+"?"+query.Encode(), nil, &resp); err != nil {
+			return err
+		}
+		if err := appendPage(resp[key]); err != nil {
+			return errors.Wrapf(err, "decoding %s failed", key)
+		}
+
+		meta := struct {
+			Pagination struct {
+				NextPage *int `json:"next_page"`
+			} `json:"pagination"`
+		}{}
+		if raw, ok := resp["meta"]; ok {
+			if err := json.Unmarshal(raw, &meta); err != nil {
+				return errors.Wrap(err, "decoding pagination failed")
+			}
+		}
+		page = 0
+		if meta.Pagination.NextPage != nil {
+			page = *meta.Pagination.NextPage
+		}
+	}
+	return nil
+}
+
+func (c *client) listServers(labelSelector string) ([]*server, error) {
+	var servers []*server
+	err := c.list("/servers", labelSelector, "servers", func(items json.RawMessage) error {
+		var page []*server
+		err := json.Unmarshal(items, &page)
+		servers = append(servers, page...)
+		return err
+	})
+	return servers, err
+}
+
+func (c *client) createServer(request *createServerRequest) (*server, error) {
+	resp := struct {
+		Server *server `json:"server"`
+	}{}
+	if err := c.do(http.MethodPost, "/servers", request, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Server, nil
+}
+
+func (c *client) getServer(id int) (*server, error) {
+	resp := struct {
+		Server *server `json:"server"`
+	}{}
+	if err := c.do(http.MethodGet, fmt.Sprintf("/servers/%d", id), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Server, nil
+}
+
+func (c *client) deleteServer(id int) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/servers/%d", id), nil, nil)
+}
+
+func (c *client) listFloatingIPs(labelSelector string) ([]*floatingIP, error) {
+	var ips []*floatingIP
+	err := c.list("/floating_ips", labelSelector, "floating_ips", func(items json.RawMessage) error {
+		var page []*floatingIP
+		err := json.Unmarshal(items, &page)
+		ips = append(ips, page...)
+		return err
+	})
+	return ips, err
+}
+
+func (c *client) createFloatingIP(request *createFloatingIPRequest) (*floatingIP, error) {
+	resp := struct {
+		FloatingIP *floatingIP `json:"floating_ip"`
+	}{}
+	if err := c.do(http.MethodPost, "/floating_ips", request, &resp); err != nil {
+		return nil, err
+	}
+	return resp.FloatingIP, nil
+}
+
+func (c *client) deleteFloatingIP(id int) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/floating_ips/%d", id), nil, nil)
+}
+
+func (c *client) listNetworks(labelSelector string) ([]*network, error) {
+	var networks []*network
+	err := c.list("/networks", labelSelector, "networks", func(items json.RawMessage) error {
+		var page []*network
+		err := json.Unmarshal(items, &page)
+		networks = append(networks, page...)
+		return err
+	})
+	return networks, err
+}
+
+func (c *client) createNetwork(request *createNetworkRequest) (*network, error) {
+	resp := struct {
+		Network *network `json:"network"`
+	}{}
+	if err := c.do(http.MethodPost, "/networks", request, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Network, nil
+}
+
+func (c *client) deleteNetwork(id int) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/networks/%d", id), nil, nil)
+}
+
+func (c *client) listSSHKeys(labelSelector string) ([]*sshKey, error) {
+	var keys []*sshKey
+	err := c.list("/ssh_keys", labelSelector, "ssh_keys", func(items json.RawMessage) error {
+		var page []*sshKey
+		err := json.Unmarshal(items, &page)
+		keys = append(keys, page...)
+		return err
+	})
+	return keys, err
+}
+
+func (c *client) createSSHKey(key *sshKey) (*sshKey, error) {
+	resp := struct {
+		SSHKey *sshKey `json:"ssh_key"`
+	}{}
+	if err := c.do(http.MethodPost, "/ssh_keys", key, &resp); err != nil {
+		return nil, err
+	}
+	return resp.SSHKey, nil
+}
+
+func (c *client) deleteSSHKey(id int) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/ssh_keys/%d", id), nil, nil)
+}
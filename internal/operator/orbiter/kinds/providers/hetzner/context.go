@@ -0,0 +1,77 @@
+package hetzner
+
+import (
+	ctxpkg "context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"time"
+
+	"github.com/caos/orbos/mntr"
+)
+
+type context struct {
+	monitor         mntr.Monitor
+	orbID           string
+	providerID      string
+	resourcePrefix  string
+	desired         *Spec
+	client          *client
+	machinesService *machinesService
+	ctx             ctxpkg.Context
+	cache           struct {
+		networkID int
+		sshKeyID  int
+		// floatingIPs maps the floating IPs addresses to their IDs
+		floatingIPs map[string]int
+	}
+}
+
+func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string, oneoff bool) (*context, error) {
+
+	ctx := ctxpkg.Background()
+
+	token, err := desired.APIToken.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	// Network and SSH key names must be unique within a Hetzner Cloud project
+	h := fnv.New32()
+	h.Write([]byte(orbID + providerID))
+	resourcePrefix := fmt.Sprintf("orbos-%x", h.Sum32())
+	h.Reset()
+
+	newContext := &context{
+		monitor:        monitor,
+		orbID:          orbID,
+		providerID:     providerID,
+		resourcePrefix: resourcePrefix,
+		desired:        desired,
+		client: &client{
+			endpoint: desired.endpoint(),
+			token:    token,
+			http:     &http.Client{Timeout: 30 * time.Second},
+			ctx:      ctx,
+		},
+		ctx: ctx,
+	}
+
+	newContext.machinesService = newMachinesService(newContext, oneoff)
+	return newContext, nil
+}
+
+func (c *context) labels(additional map[string]string) map[string]string {
+	labels := map[string]string{
+		"orb":      c.orbID,
+		"provider": c.providerID,
+	}
+	for k, v := range additional {
+		labels[k] = v
+	}
+	return labels
+}
+
+func (c *context) labelSelector() string {
+	return fmt.Sprintf("orb=%s,provider=%s", c.orbID, c.providerID)
+}
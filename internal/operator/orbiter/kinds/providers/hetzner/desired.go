@@ -0,0 +1,149 @@
+package hetzner
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/caos/orbos/pkg/secret"
+	"github.com/caos/orbos/pkg/tree"
+	"github.com/pkg/errors"
+)
+
+type Desired struct {
+	Common        *tree.Common `yaml:",inline"`
+	Spec          Spec
+	Loadbalancing *tree.Tree
+}
+
+type Pool struct {
+	// Hetzner Cloud server type, for example cx31
+	ServerType string
+	// Hetzner Cloud location, for example fsn1
+	Location string
+	// Hetzner Cloud image name, for example ubuntu-22.04
+	Image string
+}
+
+func (p Pool) validate() error {
+	if p.ServerType == "" {
+		return errors.New("no server type configured")
+	}
+	if p.Location == "" {
+		return errors.New("no location configured")
+	}
+	if p.Image == "" {
+		return errors.New("no image configured")
+	}
+	return nil
+}
+
+// Network configures the private network all machines of the provider are attached to
+type Network struct {
+	// IP range of the private network in CIDR notation
+	//@default: 10.0.0.0/16
+	IPRange string `yaml:",omitempty"`
+	// Network zone all pool locations are part of
+	//@default: eu-central
+	Zone string `yaml:",omitempty"`
+	// Name of the machines network interface the private network is attached to
+	//@default: ens10
+	Interface string `yaml:",omitempty"`
+}
+
+func (n *Network) ipRange() string {
+	if n != nil && n.IPRange != "" {
+		return n.IPRange
+	}
+	return "10.0.0.0/16"
+}
+
+func (n *Network) zone() string {
+	if n != nil && n.Zone != "" {
+		return n.Zone
+	}
+	return "eu-central"
+}
+
+func (n *Network) networkInterface() string {
+	if n != nil && n.Interface != "" {
+		return n.Interface
+	}
+	return "ens10"
+}
+
+type Spec struct {
+	Verbose bool
+	// Endpoint overrides the Hetzner Cloud API endpoint, e.g. for testing against a local fake
+	Endpoint            string         `yaml:",omitempty"`
+	APIToken            *secret.Secret `yaml:",omitempty"`
+	Network             *Network       `yaml:",omitempty"`
+	Pools               map[string]*Pool
+	SSHKey              *SSHKey
+	RebootRequired      []string
+	ReplacementRequired []string
+}
+
+func (s *Spec) endpoint() string {
+	if s.Endpoint != "" {
+		return s.Endpoint
+	}
+	return defaultEndpoint
+}
+
+type SSHKey struct {
+	Private *secret.Secret `yaml:",omitempty"`
+	Public  *secret.Secret `yaml:",omitempty"`
+}
+
+func (d Desired) validateAdapt() error {
+	if d.Loadbalancing == nil {
+		return errors.New("no loadbalancing configured")
+	}
+	if len(d.Spec.Pools) == 0 {
+		return errors.New("no pools configured")
+	}
+	for poolName, pool := range d.Spec.Pools {
+		if err := pool.validate(); err != nil {
+			return fmt.Errorf("configuring pool %s failed: %w", poolName, err)
+		}
+	}
+	if _, _, err := net.ParseCIDR(d.Spec.Network.ipRange()); err != nil {
+		return errors.Wrapf(err, "parsing network ip range %s failed", d.Spec.Network.ipRange())
+	}
+	return nil
+}
+
+func (d Desired) validateAPIToken() error {
+	if !d.Spec.APIToken.HasValue() {
+		return errors.New("apitoken missing... please provide a hetzner cloud api token using orbctl writesecret command")
+	}
+	return nil
+}
+
+func (d Desired) validateQuery() error {
+
+	if err := d.validateAPIToken(); err != nil {
+		return err
+	}
+
+	if d.Spec.SSHKey == nil ||
+		!d.Spec.SSHKey.Private.HasValue() ||
+		!d.Spec.SSHKey.Public.HasValue() {
+		return errors.New("ssh key missing... please initialize your orb using orbctl configure command")
+	}
+
+	return nil
+}
+
+func parseDesired(desiredTree *tree.Tree) (*Desired, error) {
+	desiredKind := &Desired{
+		Common: desiredTree.Common,
+		Spec:   Spec{},
+	}
+
+	if err := desiredTree.Original.Decode(desiredKind); err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+
+	return desiredKind, nil
+}
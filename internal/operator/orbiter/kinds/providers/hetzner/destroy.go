@@ -0,0 +1,58 @@
+package hetzner
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/helpers"
+)
+
+func destroy(context *context, current *Current) error {
+
+	_, delFuncs, _, err := queryFloatingIPs(context, nil, current)
+	if err != nil {
+		return err
+	}
+
+	pools, err := context.machinesService.ListPools()
+	if err != nil {
+		return err
+	}
+	for _, pool := range pools {
+		machines, err := context.machinesService.List(pool)
+		if err != nil {
+			return err
+		}
+		for _, machine := range machines {
+			delFuncs = append(delFuncs, machine.Remove)
+		}
+	}
+	if err := helpers.Fanout(delFuncs)(); err != nil {
+		return err
+	}
+
+	// The network can only be deleted as soon as no server is attached to it anymore
+	if err := waitForServersDeleted(context, 5*time.Minute, 5*time.Second); err != nil {
+		return err
+	}
+
+	return destroyNetwork(context)
+}
+
+func waitForServersDeleted(context *context, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		servers, err := context.client.listServers(context.labelSelector())
+		if err != nil {
+			return err
+		}
+		if len(servers) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Errorf("%d servers are not deleted after %s", len(servers), timeout)
+		}
+		time.Sleep(interval)
+	}
+}
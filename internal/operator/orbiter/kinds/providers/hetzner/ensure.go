@@ -0,0 +1,119 @@
+package hetzner
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	dynamiclbmodel "github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic/wrap"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/mntr"
+)
+
+func query(
+	desired *Spec,
+	current *Current,
+	lb interface{},
+	context *context,
+	nodeAgentsCurrent *common.CurrentNodeAgents,
+	nodeAgentsDesired *common.DesiredNodeAgents,
+	naFuncs core.IterateNodeAgentFuncs,
+	orbiterCommit string,
+) (ensureFunc orbiter.EnsureFunc, err error) {
+
+	lbCurrent, ok := lb.(*dynamiclbmodel.Current)
+	if !ok {
+		panic(errors.Errorf("Unknown or unsupported load balancing of type %T", lb))
+	}
+
+	hostPools, authChecks, err := lbCurrent.Current.Spec(context.machinesService)
+	if err != nil {
+		return nil, err
+	}
+
+	ensureFIPs, removeFIPs, poolsWithUnassignedVIPs, err := queryFloatingIPs(context, hostPools, current)
+	if err != nil {
+		return nil, err
+	}
+
+	queryNA, installNA := naFuncs(nodeAgentsCurrent)
+	ensureNodeAgent := func(m infra.Machine) error {
+		running, err := queryNA(m, orbiterCommit)
+		if err != nil {
+			return err
+		}
+		if !running {
+			return installNA(m)
+		}
+		return nil
+	}
+
+	pools, err := context.machinesService.machines()
+	if err != nil {
+		return nil, err
+	}
+	var ensureNodeAgents []func() error
+	for _, machines := range pools {
+		for _, machine := range machines {
+			ensureNodeAgents = append(ensureNodeAgents, func(m infra.Machine) func() error {
+				return func() error { return ensureNodeAgent(m) }
+			}(machine))
+		}
+	}
+
+	privateInterface := desired.Network.networkInterface()
+
+	context.machinesService.onCreate = func(pool string, m infra.Machine) error {
+		_, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, true, []string{privateInterface})
+		if err != nil {
+			return err
+		}
+
+		vips := hostedVIPs(hostPools, m, current)
+		_, err = core.DesireOSNetworkingForMachine(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, m, "dummy", vips)
+		if err != nil {
+			return err
+		}
+
+		return ensureNodeAgent(m)
+	}
+	wrappedMachines := wrap.MachinesService(context.machinesService, *lbCurrent, &dynamiclbmodel.VRRP{
+		VRRPInterface: privateInterface,
+		NotifyMaster:  notifyMaster(context, hostPools, current, poolsWithUnassignedVIPs),
+		AuthCheck:     checkAuth(context),
+	}, desiredToCurrentVIP(current))
+
+	lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
+	if err != nil {
+		return nil, err
+	}
+
+	fwDone, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, true, []string{privateInterface})
+	if err != nil {
+		return nil, err
+	}
+
+	vips, err := allHostedVIPs(hostPools, context.machinesService, current)
+	if err != nil {
+		return nil, err
+	}
+	nwDone, err := core.DesireOSNetworking(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, "dummy", vips)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(pdf func(mntr.Monitor) error) *orbiter.EnsureResult {
+		err := helpers.Fanout([]func() error{
+			func() error {
+				return helpers.Fanout(ensureTokens(context.monitor, []byte(context.client.token), authChecks))()
+			},
+			func() error { return helpers.Fanout(ensureFIPs)() },
+			func() error { return helpers.Fanout(removeFIPs)() },
+			func() error { return helpers.Fanout(ensureNodeAgents)() },
+		})()
+		return orbiter.ToEnsureResult(lbDone && fwDone && nwDone, err)
+	}, addPools(current, desired, wrappedMachines)
+}
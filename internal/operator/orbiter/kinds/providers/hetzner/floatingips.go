@@ -0,0 +1,132 @@
+package hetzner
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+)
+
+// queryFloatingIPs returns functions that create the missing floating IPs for the dynamic load balancers VIPs and that remove the floating IPs that are not needed anymore.
+// The floating IPs are assigned to the servers by the keepalived notify master scripts
+func queryFloatingIPs(context *context, loadbalancing map[string][]*dynamic.VIP, writeTo *Current) ([]func() error, []func() error, map[string]bool, error) {
+
+	haveUnassignedVIPs := make(map[string]bool)
+	floatingIPs, err := context.client.listFloatingIPs(context.labelSelector())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	context.cache.floatingIPs = make(map[string]int)
+	for _, floatingIP := range floatingIPs {
+		context.cache.floatingIPs[floatingIP.IP] = floatingIP.ID
+	}
+
+	var ensure []func() error
+	for hostPool, vips := range loadbalancing {
+		for vipIdx := range vips {
+			vip := vips[vipIdx]
+			alreadyExists := false
+			for transportIdx := range vip.Transport {
+				transport := vip.Transport[transportIdx]
+				initCurrent(writeTo, transport)
+
+				for _, floatingIP := range floatingIPs {
+					if ensureCurrentIngress(floatingIP, hostPool, vipIdx, writeTo, transport) {
+						alreadyExists = true
+						if floatingIP.Server == nil {
+							haveUnassignedVIPs[hostPool] = true
+						}
+					}
+				}
+			}
+			if alreadyExists {
+				continue
+			}
+
+			location := ""
+			if pool, ok := context.desired.Pools[hostPool]; ok {
+				location = pool.Location
+			}
+
+			ensure = append(ensure, func(hostPool string, vipIdx int, location string) func() error {
+				return func() error {
+					monitor := context.monitor.WithFields(map[string]interface{}{
+						"type": "floating ip",
+						"pool": hostPool,
+						"idx":  vipIdx,
+					})
+					monitor.Debug("Creating resource")
+					created, err := context.client.createFloatingIP(&createFloatingIPRequest{
+						Type:         "ipv4",
+						HomeLocation: location,
+						Description:  fmt.Sprintf("%s-%s-%d", context.resourcePrefix, hostPool, vipIdx),
+						Labels: context.labels(map[string]string{
+							"pool": hostPool,
+							"idx":  strconv.Itoa(vipIdx),
+						}),
+					})
+					if err != nil {
+						return err
+					}
+					monitor.WithField("ip", created.IP).Info("Resource created")
+					return nil
+				}
+			}(hostPool, vipIdx, location))
+		}
+	}
+
+	var remove []func() error
+
+removeLoop:
+	for _, floatingIP := range floatingIPs {
+		matches := false
+		for hostPool, vips := range loadbalancing {
+			for vipIdx := range vips {
+				vip := vips[vipIdx]
+				for transpIdx := range vip.Transport {
+					transport := vip.Transport[transpIdx]
+					initCurrent(writeTo, transport)
+					matches = ensureCurrentIngress(floatingIP, hostPool, vipIdx, writeTo, transport) || matches
+				}
+			}
+		}
+		if matches {
+			continue removeLoop
+		}
+		remove = append(remove, func(id int, ip string) func() error {
+			return func() error {
+				if err := context.client.deleteFloatingIP(id); err != nil && !isNotFound(err) {
+					return err
+				}
+				context.monitor.WithFields(map[string]interface{}{
+					"type": "floating ip",
+					"ip":   ip,
+				}).Info("Resource removed")
+				return nil
+			}
+		}(floatingIP.ID, floatingIP.IP))
+	}
+	return ensure, remove, haveUnassignedVIPs, nil
+}
+
+func initCurrent(writeTo *Current, transport *dynamic.Transport) {
+	if writeTo.Current.Ingresses == nil {
+		writeTo.Current.Ingresses = make(map[string]*infra.Address)
+	}
+	if writeTo.Current.Ingresses[transport.Name] == nil {
+		writeTo.Current.Ingresses[transport.Name] = &infra.Address{}
+	}
+}
+
+func ensureCurrentIngress(floatingIP *floatingIP, hostPool string, vipIdx int, writeTo *Current, transport *dynamic.Transport) bool {
+	matches := false
+	if floatingIP.Labels["pool"] == hostPool && floatingIP.Labels["idx"] == strconv.Itoa(vipIdx) {
+		matches = true
+		writeTo.Current.Ingresses[transport.Name].Location = floatingIP.IP
+		writeTo.Current.Ingresses[transport.Name].FrontendPort = uint16(transport.FrontendPort)
+		writeTo.Current.Ingresses[transport.Name].BackendPort = uint16(transport.BackendPort)
+	}
+	return matches
+}
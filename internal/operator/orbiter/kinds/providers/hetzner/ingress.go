@@ -0,0 +1,146 @@
+package hetzner
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/mntr"
+)
+
+const tokenPath = "/var/orbiter/hcloudtoken"
+
+func desiredToCurrentVIP(current *Current) func(vip *dynamic.VIP) string {
+	return func(vip *dynamic.VIP) string {
+		for idx := range vip.Transport {
+			transport := vip.Transport[idx]
+			address, ok := current.Current.Ingresses[transport.Name]
+			if ok {
+				return address.Location
+			}
+		}
+		panic(fmt.Errorf("external address for %v is not ensured", vip))
+	}
+}
+
+func checkAuth(context *context) func(infra.Machine) (string, int) {
+	return func(_ infra.Machine) (string, int) {
+		return fmt.Sprintf(`#!/bin/sh
+
+set -e
+
+curl -f -H "Authorization: Bearer $(cat %s)" "%s/floating_ips?per_page=1"
+`, tokenPath, context.desired.endpoint()), 0
+	}
+}
+
+func notifyMaster(context *context, hostPools map[string][]*dynamic.VIP, current *Current, poolsWithUnassignedVIPs map[string]bool) func(m infra.Machine) (string, bool) {
+	return func(m infra.Machine) (string, bool) {
+
+		machine := m.(*machine)
+
+		var floatingIPIDs []string
+		for _, vip := range hostedVIPs(hostPools, m, current) {
+			if id, ok := context.cache.floatingIPs[vip]; ok {
+				floatingIPIDs = append(floatingIPIDs, fmt.Sprintf("%d", id))
+			}
+		}
+		sort.Strings(floatingIPIDs)
+
+		return fmt.Sprintf(`#!/bin/sh
+
+set -e
+
+# API token (with read and write permissions) to access the Hetzner Cloud API.
+api_token="$(cat %s)"
+
+# IDs of the Floating IPs shared between the servers within the same VRRP group.
+floating_ip_ids='%s'
+
+# ID of the server that this script is running on.
+server_id='%d'
+
+# Call the Hetzner Cloud API to assign a specific Floating IP to this server.
+set_master() {
+	curl \
+		-f \
+		-X POST \
+		-H "Authorization: Bearer $api_token" \
+		-H "Content-Type: application/json" \
+		-d "{\"server\": $server_id}" \
+		"%s/floating_ips/$1/actions/assign"
+}
+
+for ID in $floating_ip_ids; do
+	set_master $ID
+done
+`, tokenPath, strings.Join(floatingIPIDs, " "), machine.server.ID, context.desired.endpoint()), poolsWithUnassignedVIPs[machine.poolName]
+	}
+}
+
+func allHostedVIPs(hostPools map[string][]*dynamic.VIP, service core.MachinesService, current *Current) (map[string][]string, error) {
+	vips := make(map[string][]string, 0)
+	pools, err := service.ListPools()
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range pools {
+		poolMachines, err := service.List(pool)
+		if err != nil {
+			return nil, err
+		}
+		for _, machine := range poolMachines {
+			vips[machine.ID()] = hostedVIPs(hostPools, machine, current)
+		}
+	}
+	return vips, nil
+}
+
+func hostedVIPs(hostPools map[string][]*dynamic.VIP, m infra.Machine, current *Current) []string {
+	seen := make(map[string]bool)
+	var locations []string
+	for hostPool, vips := range hostPools {
+		if m.(*machine).poolName != hostPool {
+			continue
+		}
+		for vipIdx := range vips {
+			vip := vips[vipIdx]
+			for transpIdx := range vip.Transport {
+				transp := vip.Transport[transpIdx]
+				addr, ok := current.Current.Ingresses[transp.Name]
+				if !ok || addr == nil || addr.Location == "" {
+					continue
+				}
+				if _, ok := seen[addr.Location]; !ok {
+					seen[addr.Location] = true
+					locations = append(locations, addr.Location)
+				}
+			}
+		}
+	}
+	return locations
+}
+
+func ensureTokens(monitor mntr.Monitor, token []byte, authCheckResult []dynamic.AuthCheckResult) []func() error {
+	var ensure []func() error
+	for idx := range authCheckResult {
+		ensure = append(ensure, func(result dynamic.AuthCheckResult) func() error {
+			return func() error { return ensureToken(monitor, token, result) }
+		}(authCheckResult[idx]))
+	}
+	return ensure
+}
+
+func ensureToken(monitor mntr.Monitor, token []byte, authCheckResult dynamic.AuthCheckResult) error {
+	if authCheckResult.ExitCode != 0 {
+		if err := authCheckResult.Machine.WriteFile(tokenPath, bytes.NewReader(token), 600); err != nil {
+			return err
+		}
+		monitor.WithField("machine", authCheckResult.Machine.ID()).Info("API token written")
+	}
+	return nil
+}
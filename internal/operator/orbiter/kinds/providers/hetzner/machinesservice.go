@@ -0,0 +1,295 @@
+package hetzner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ssh"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/secret"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+func ListMachines(monitor mntr.Monitor, desiredTree *tree.Tree, orbID, providerID string) (map[string]infra.Machine, error) {
+	desired, err := parseDesired(desiredTree)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+	desiredTree.Parsed = desired
+
+	ctx, err := buildContext(monitor, &desired.Spec, orbID, providerID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.machinesService.use(desired.Spec.SSHKey); err != nil {
+		invalidKey := &secret.Secret{Value: "invalid"}
+		if err := ctx.machinesService.use(&SSHKey{
+			Private: invalidKey,
+			Public:  invalidKey,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return core.ListMachines(ctx.machinesService)
+}
+
+var _ core.MachinesService = (*machinesService)(nil)
+
+type machinesService struct {
+	context    *context
+	oneoff     bool
+	privateKey string
+	publicKey  string
+	cache      struct {
+		instances map[string][]*machine
+		sync.Mutex
+	}
+	onCreate func(pool string, machine infra.Machine) error
+	// pollInterval is the time between checks whether a created server is running
+	pollInterval time.Duration
+}
+
+func newMachinesService(context *context, oneoff bool) *machinesService {
+	return &machinesService{
+		context:      context,
+		oneoff:       oneoff,
+		pollInterval: 5 * time.Second,
+	}
+}
+
+func (m *machinesService) use(key *SSHKey) error {
+	if key == nil || !key.Private.HasValue() || !key.Public.HasValue() {
+		return errors.New("machines are not connectable. have you configured the orb by running orbctl configure?")
+	}
+	privateKey, err := key.Private.Resolve()
+	if err != nil {
+		return err
+	}
+	publicKey, err := key.Public.Resolve()
+	if err != nil {
+		return err
+	}
+	m.privateKey, m.publicKey = privateKey, publicKey
+	return nil
+}
+
+func (m *machinesService) Create(poolName string) (infra.Machine, error) {
+
+	desired, ok := m.context.desired.Pools[poolName]
+	if !ok {
+		return nil, fmt.Errorf("Pool %s is not configured", poolName)
+	}
+
+	name := newName()
+	monitor := machineMonitor(m.context.monitor, name, poolName)
+
+	userData, err := core.NewCloudinit().AddGroupWithoutUsers(
+		"orbiter",
+	).AddUser(
+		"orbiter",
+		true,
+		"",
+		[]string{"orbiter", "wheel"},
+		"orbiter",
+		[]string{m.publicKey},
+		"ALL=(ALL) NOPASSWD:ALL",
+	).AddCmd(
+		"sudo echo \"\n\nPermitRootLogin no\n\" >> /etc/ssh/sshd_config",
+	).AddCmd(
+		"sudo service sshd restart",
+	).ToYamlString()
+	if err != nil {
+		return nil, err
+	}
+
+	networkID, err := m.context.network()
+	if err != nil {
+		return nil, err
+	}
+
+	sshKeyID, err := m.context.sshKey()
+	if err != nil {
+		return nil, err
+	}
+
+	monitor.Debug("Creating instance")
+
+	created, err := m.context.client.createServer(&createServerRequest{
+		Name:       name,
+		ServerType: desired.ServerType,
+		Image:      desired.Image,
+		Location:   desired.Location,
+		UserData:   userData,
+		SSHKeys:    []int{sshKeyID},
+		Networks:   []int{networkID},
+		Labels:     m.context.labels(map[string]string{"pool": poolName}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := m.waitForServer(created, 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	monitor.Info("Instance created")
+
+	infraMachine, err := m.toMachine(server, monitor, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.cache.instances != nil {
+		if _, ok := m.cache.instances[poolName]; !ok {
+			m.cache.instances[poolName] = make([]*machine, 0)
+		}
+		m.cache.instances[poolName] = append(m.cache.instances[poolName], infraMachine)
+	}
+
+	if m.onCreate != nil {
+		if err := m.onCreate(poolName, infraMachine); err != nil {
+			return nil, err
+		}
+	}
+
+	monitor.Info("Machine created")
+	return infraMachine, nil
+}
+
+// waitForServer waits until the server is running and attached to the private network
+func (m *machinesService) waitForServer(server *server, timeout time.Duration) (*server, error) {
+	deadline := time.Now().Add(timeout)
+	for server.Status != "running" || server.privateIP() == "" {
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("server %s is not running after %s", server.Name, timeout)
+		}
+		time.Sleep(m.pollInterval)
+		var err error
+		if server, err = m.context.client.getServer(server.ID); err != nil {
+			return nil, err
+		}
+	}
+	return server, nil
+}
+
+func (m *machinesService) toMachine(server *server, monitor mntr.Monitor, poolName string) (*machine, error) {
+	internalIP := server.privateIP()
+	externalIP := server.PublicNet.IPv4.IP
+	sshIP := internalIP
+	if m.oneoff {
+		sshIP = externalIP
+	}
+
+	sshMachine := ssh.NewMachine(monitor, "orbiter", sshIP)
+	if err := sshMachine.UseKey([]byte(m.privateKey)); err != nil {
+		return nil, err
+	}
+
+	return newMachine(
+		server,
+		internalIP,
+		externalIP,
+		sshMachine,
+		m.removeMachineFunc(poolName, server.Name, server.ID),
+		m.context,
+		poolName,
+	), nil
+}
+
+func (m *machinesService) ListPools() ([]string, error) {
+
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	var poolNames []string
+	for poolName := range pools {
+		poolNames = append(poolNames, poolName)
+	}
+	return poolNames, nil
+}
+
+func (m *machinesService) List(poolName string) (infra.Machines, error) {
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := pools[poolName]
+	machines := make([]infra.Machine, len(pool))
+	for idx := range pool {
+		machines[idx] = pool[idx]
+	}
+
+	return machines, nil
+}
+
+func (m *machinesService) machines() (map[string][]*machine, error) {
+	if m.cache.instances != nil {
+		return m.cache.instances, nil
+	}
+
+	servers, err := m.context.client.listServers(m.context.labelSelector())
+	if err != nil {
+		return nil, err
+	}
+
+	m.cache.instances = make(map[string][]*machine)
+	for _, server := range servers {
+		pool := server.Labels["pool"]
+		machine, err := m.toMachine(server, machineMonitor(m.context.monitor, server.Name, pool), pool)
+		if err != nil {
+			return nil, err
+		}
+		m.cache.instances[pool] = append(m.cache.instances[pool], machine)
+	}
+
+	return m.cache.instances, nil
+}
+
+func (m *machinesService) removeMachineFunc(pool, name string, id int) func() error {
+
+	return func() error {
+		m.cache.Lock()
+		if m.cache.instances != nil {
+			cleanMachines := make([]*machine, 0)
+			for idx := range m.cache.instances[pool] {
+				cachedMachine := m.cache.instances[pool][idx]
+				if cachedMachine.ID() != name {
+					cleanMachines = append(cleanMachines, cachedMachine)
+				}
+			}
+			m.cache.instances[pool] = cleanMachines
+		}
+		m.cache.Unlock()
+
+		monitor := machineMonitor(m.context.monitor, name, pool)
+		monitor.Debug("Removing instance")
+		if err := m.context.client.deleteServer(id); err != nil && !isNotFound(err) {
+			return err
+		}
+		monitor.Info("Instance removed")
+		return nil
+	}
+}
+
+func machineMonitor(monitor mntr.Monitor, name string, poolName string) mntr.Monitor {
+	return monitor.WithFields(map[string]interface{}{
+		"machine": name,
+		"pool":    poolName,
+	})
+}
+
+func newName() string {
+	return "orbos-" + helpers.RandomStringRunes(6, []rune("abcdefghijklmnopqrstuvwxyz0123456789"))
+}
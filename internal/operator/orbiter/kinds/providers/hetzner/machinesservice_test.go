@@ -0,0 +1,387 @@
+package hetzner
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/ssh"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/secret"
+)
+
+// fakeHetzner serves the few Hetzner Cloud API endpoints the provider uses
+type fakeHetzner struct {
+	sync.Mutex
+	nextID      int
+	servers     []*server
+	networks    []*network
+	sshKeys     []*sshKey
+	floatingIPs []*floatingIP
+	created     []*createServerRequest
+	deleted     []string
+}
+
+func (f *fakeHetzner) id() int {
+	f.nextID++
+	return f.nextID
+}
+
+func (f *fakeHetzner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"code":"unauthorized","message":"unable to authenticate"}}`)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	resource := parts[0]
+	id := 0
+	if len(parts) > 1 {
+		id, _ = strconv.Atoi(parts[1])
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == 0:
+		f.list(w, r, resource)
+	case r.Method == http.MethodGet && resource == "servers":
+		for _, s := range f.servers {
+			if s.ID == id {
+				// servers are running as soon as they are queried the first time
+				s.Status = "running"
+				respond(w, "server", s)
+				return
+			}
+		}
+		notFound(w)
+	case r.Method == http.MethodPost && resource == "servers":
+		req := &createServerRequest{}
+		json.NewDecoder(r.Body).Decode(req)
+		f.created = append(f.created, req)
+		s := &server{ID: f.id(), Name: req.Name, Status: "initializing", Labels: req.Labels}
+		s.PublicNet.IPv4.IP = fmt.Sprintf("1.2.3.%d", s.ID)
+		for _, networkID := range req.Networks {
+			s.PrivateNet = append(s.PrivateNet, struct {
+				Network int    `json:"network"`
+				IP      string `json:"ip"`
+			}{Network: networkID, IP: fmt.Sprintf("10.0.0.%d", s.ID)})
+		}
+		f.servers = append(f.servers, s)
+		respond(w, "server", s)
+	case r.Method == http.MethodPost && resource == "networks":
+		req := &createNetworkRequest{}
+		json.NewDecoder(r.Body).Decode(req)
+		n := &network{ID: f.id(), Name: req.Name, IPRange: req.IPRange, Labels: req.Labels}
+		f.networks = append(f.networks, n)
+		respond(w, "network", n)
+	case r.Method == http.MethodPost && resource == "ssh_keys":
+		k := &sshKey{}
+		json.NewDecoder(r.Body).Decode(k)
+		k.ID = f.id()
+		f.sshKeys = append(f.sshKeys, k)
+		respond(w, "ssh_key", k)
+	case r.Method == http.MethodPost && resource == "floating_ips":
+		req := &createFloatingIPRequest{}
+		json.NewDecoder(r.Body).Decode(req)
+		fip := &floatingIP{ID: f.id(), Description: req.Description, Labels: req.Labels}
+		fip.IP = fmt.Sprintf("5.6.7.%d", fip.ID)
+		fip.HomeLocation.Name = req.HomeLocation
+		f.floatingIPs = append(f.floatingIPs, fip)
+		respond(w, "floating_ip", fip)
+	case r.Method == http.MethodDelete:
+		f.deleted = append(f.deleted, fmt.Sprintf("%s/%d", resource, id))
+		switch resource {
+		case "servers":
+			for idx, s := range f.servers {
+				if s.ID == id {
+					f.servers = append(f.servers[:idx], f.servers[idx+1:]...)
+					break
+				}
+			}
+		case "floating_ips":
+			for idx, fip := range f.floatingIPs {
+				if fip.ID == id {
+					f.floatingIPs = append(f.floatingIPs[:idx], f.floatingIPs[idx+1:]...)
+					break
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		notFound(w)
+	}
+}
+
+// list pages through the resources two items at a time and filters them by the label selector
+func (f *fakeHetzner) list(w http.ResponseWriter, r *http.Request, resource string) {
+	selected := func(labels map[string]string) bool {
+		for _, requirement := range strings.Split(r.URL.Query().Get("label_selector"), ",") {
+			kv := strings.SplitN(requirement, "=", 2)
+			if len(kv) == 2 && labels[kv[0]] != kv[1] {
+				return false
+			}
+		}
+		return true
+	}
+
+	var items []interface{}
+	switch resource {
+	case "servers":
+		for _, s := range f.servers {
+			if selected(s.Labels) {
+				items = append(items, s)
+			}
+		}
+	case "networks":
+		for _, n := range f.networks {
+			if selected(n.Labels) {
+				items = append(items, n)
+			}
+		}
+	case "ssh_keys":
+		for _, k := range f.sshKeys {
+			if selected(k.Labels) {
+				items = append(items, k)
+			}
+		}
+	case "floating_ips":
+		for _, fip := range f.floatingIPs {
+			if selected(fip.Labels) {
+				items = append(items, fip)
+			}
+		}
+	default:
+		notFound(w)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	from, to := (page-1)*2, page*2
+	var next *int
+	if to < len(items) {
+		nextPage := page + 1
+		next = &nextPage
+	} else {
+		to = len(items)
+	}
+	if from > to {
+		from = to
+	}
+
+	resp := map[string]interface{}{
+		resource: append([]interface{}{}, items[from:to]...),
+		"meta":   map[string]interface{}{"pagination": map[string]interface{}{"next_page": next}},
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func respond(w http.ResponseWriter, key string, item interface{}) {
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{key: item})
+}
+
+func notFound(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `{"error":{"code":"not_found","message":"not found"}}`)
+}
+
+func (f *fakeHetzner) addServer(name, pool, orb string) {
+	s := &server{ID: f.id(), Name: name, Status: "running", Labels: map[string]string{"orb": orb, "provider": "hcloud", "pool": pool}}
+	s.PublicNet.IPv4.IP = fmt.Sprintf("1.2.3.%d", s.ID)
+	s.PrivateNet = append(s.PrivateNet, struct {
+		Network int    `json:"network"`
+		IP      string `json:"ip"`
+	}{Network: 1000, IP: fmt.Sprintf("10.0.0.%d", s.ID)})
+	f.servers = append(f.servers, s)
+}
+
+func newTestContext(t *testing.T, fake *fakeHetzner) *context {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	priv, pub, err := ssh.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &Spec{
+		Endpoint: server.URL,
+		APIToken: &secret.Secret{Value: "token"},
+		Network:  &Network{IPRange: "10.1.0.0/16"},
+		Pools: map[string]*Pool{
+			"application": {ServerType: "cx31", Location: "fsn1", Image: "ubuntu-22.04"},
+			"management":  {ServerType: "cx21", Location: "nbg1", Image: "ubuntu-22.04"},
+		},
+	}
+
+	ctx, err := buildContext(mntr.Monitor{}, spec, "orb", "hcloud", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.machinesService.pollInterval = time.Millisecond
+
+	if err := ctx.machinesService.use(&SSHKey{
+		Private: &secret.Secret{Value: priv},
+		Public:  &secret.Secret{Value: pub},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+func TestMachinesService_List(t *testing.T) {
+	fake := &fakeHetzner{}
+	fake.addServer("orbos-1", "application", "orb")
+	fake.addServer("orbos-2", "management", "orb")
+	fake.addServer("orbos-3", "application", "orb")
+	fake.addServer("orbos-4", "application", "anotherorb")
+	fake.addServer("orbos-5", "application", "orb")
+	svc := newTestContext(t, fake).machinesService
+
+	pools, err := svc.ListPools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(pools)
+	if strings.Join(pools, ",") != "application,management" {
+		t.Errorf("ListPools() = %v, want [application management]", pools)
+	}
+
+	machines, err := svc.List("application")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := machines.IDs(); strings.Join(got, ",") != "orbos-1,orbos-3,orbos-5" {
+		t.Errorf("List() = %v, want [orbos-1 orbos-3 orbos-5]", got)
+	}
+	if ip := machines[0].IP(); ip != "10.0.0.1" {
+		t.Errorf("IP() = %s, want the private ip 10.0.0.1", ip)
+	}
+}
+
+func TestMachinesService_CreateAndRemove(t *testing.T) {
+	fake := &fakeHetzner{}
+	ctx := newTestContext(t, fake)
+	svc := ctx.machinesService
+
+	var created []string
+	svc.onCreate = func(pool string, machine infra.Machine) error {
+		created = append(created, pool+"."+machine.ID())
+		return nil
+	}
+
+	first, err := svc.Create("management")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.Create("application")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.networks) != 1 || fake.networks[0].IPRange != "10.1.0.0/16" {
+		t.Fatalf("expected one network with the ip range 10.1.0.0/16, got %+v", fake.networks)
+	}
+	if len(fake.sshKeys) != 1 || fake.sshKeys[0].PublicKey != svc.publicKey {
+		t.Fatalf("expected the public key to be uploaded once, got %+v", fake.sshKeys)
+	}
+
+	if len(fake.created) != 2 {
+		t.Fatalf("expected two created servers, got %d", len(fake.created))
+	}
+	req := fake.created[0]
+	if req.ServerType != "cx21" || req.Location != "nbg1" || req.Image != "ubuntu-22.04" {
+		t.Errorf("created server %s in %s from %s, want cx21 in nbg1 from ubuntu-22.04", req.ServerType, req.Location, req.Image)
+	}
+	if len(req.Networks) != 1 || req.Networks[0] != fake.networks[0].ID {
+		t.Errorf("created server in networks %v, want [%d]", req.Networks, fake.networks[0].ID)
+	}
+	if len(req.SSHKeys) != 1 || req.SSHKeys[0] != fake.sshKeys[0].ID {
+		t.Errorf("created server with ssh keys %v, want [%d]", req.SSHKeys, fake.sshKeys[0].ID)
+	}
+	if req.Labels["orb"] != "orb" || req.Labels["provider"] != "hcloud" || req.Labels["pool"] != "management" {
+		t.Errorf("created server with labels %v", req.Labels)
+	}
+	if !strings.HasPrefix(req.UserData, "#cloud-config\n") || !strings.Contains(req.UserData, svc.publicKey) {
+		t.Errorf("user data is no cloud config that authorizes the public key: %s", req.UserData)
+	}
+
+	if first.IP() != "10.0.0.3" {
+		t.Errorf("IP() = %s, want the private ip 10.0.0.3", first.IP())
+	}
+
+	if strings.Join(created, ",") != "management."+first.ID()+",application."+second.ID() {
+		t.Errorf("onCreate was called for %v", created)
+	}
+
+	if _, err := svc.Create("unknown"); err == nil {
+		t.Error("creating a machine in an unconfigured pool should fail")
+	}
+
+	if err := first.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(fake.deleted, ",") != fmt.Sprintf("servers/%d", first.(*machine).server.ID) {
+		t.Errorf("deleted %v, want only the first server", fake.deleted)
+	}
+}
+
+func TestQueryFloatingIPs(t *testing.T) {
+	fake := &fakeHetzner{}
+	fake.floatingIPs = []*floatingIP{
+		{ID: 10, IP: "5.6.7.10", Labels: map[string]string{"orb": "orb", "provider": "hcloud", "pool": "application", "idx": "0"}},
+		{ID: 11, IP: "5.6.7.11", Labels: map[string]string{"orb": "orb", "provider": "hcloud", "pool": "storage", "idx": "0"}},
+		{ID: 12, IP: "5.6.7.12", Labels: map[string]string{"orb": "anotherorb", "provider": "hcloud", "pool": "storage", "idx": "0"}},
+	}
+	fake.nextID = 100
+	ctx := newTestContext(t, fake)
+
+	vip := func(transport string) *dynamic.VIP {
+		return &dynamic.VIP{Transport: []*dynamic.Transport{{Name: transport, FrontendPort: 443, BackendPort: 30443}}}
+	}
+	current := &Current{}
+	ensure, remove, unassigned, err := queryFloatingIPs(ctx, map[string][]*dynamic.VIP{
+		"application": {vip("httpsingress"), vip("kubeapi")},
+	}, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := current.Current.Ingresses["httpsingress"].Location; got != "5.6.7.10" {
+		t.Errorf("httpsingress location = %s, want the existing floating ip 5.6.7.10", got)
+	}
+	if !unassigned["application"] {
+		t.Error("the application pool should have unassigned floating ips")
+	}
+
+	for _, f := range append(ensure, remove...) {
+		if err := f(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if strings.Join(fake.deleted, ",") != "floating_ips/11" {
+		t.Errorf("deleted %v, want only the floating ip of the storage pool", fake.deleted)
+	}
+	var createdFIP *floatingIP
+	for _, fip := range fake.floatingIPs {
+		if fip.ID > 100 {
+			createdFIP = fip
+		}
+	}
+	if createdFIP == nil || createdFIP.Labels["pool"] != "application" || createdFIP.Labels["idx"] != "1" || createdFIP.HomeLocation.Name != "fsn1" {
+		t.Errorf("expected a floating ip for the second application vip in fsn1, got %+v", createdFIP)
+	}
+}
@@ -0,0 +1,112 @@
+package hetzner
+
+// network returns the ID of the private network all machines of this provider are attached to.
+// It is created if it doesn't exist yet
+func (c *context) network() (int, error) {
+	if c.cache.networkID != 0 {
+		return c.cache.networkID, nil
+	}
+
+	networks, err := c.client.listNetworks(c.labelSelector())
+	if err != nil {
+		return 0, err
+	}
+
+	if len(networks) > 0 {
+		c.cache.networkID = networks[0].ID
+		return c.cache.networkID, nil
+	}
+
+	monitor := c.monitor.WithField("type", "network")
+	monitor.Debug("Creating resource")
+	ipRange := c.desired.Network.ipRange()
+	created, err := c.client.createNetwork(&createNetworkRequest{
+		Name:    c.resourcePrefix,
+		IPRange: ipRange,
+		Subnets: []subnet{{
+			Type:        "cloud",
+			IPRange:     ipRange,
+			NetworkZone: c.desired.Network.zone(),
+		}},
+		Labels: c.labels(nil),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	c.cache.networkID = created.ID
+	monitor.WithField("id", created.ID).Info("Resource created")
+	return c.cache.networkID, nil
+}
+
+// sshKey returns the ID of the Hetzner Cloud SSH key resource that holds the providers public key.
+// Hetzner Cloud doesn't send root passwords by email for servers created with SSH keys.
+// The resource is replaced when the public key changes
+func (c *context) sshKey() (int, error) {
+	if c.cache.sshKeyID != 0 {
+		return c.cache.sshKeyID, nil
+	}
+
+	keys, err := c.client.listSSHKeys(c.labelSelector())
+	if err != nil {
+		return 0, err
+	}
+
+	monitor := c.monitor.WithField("type", "ssh key")
+	publicKey := c.machinesService.publicKey
+	for _, key := range keys {
+		if key.PublicKey == publicKey {
+			c.cache.sshKeyID = key.ID
+			continue
+		}
+		if err := c.client.deleteSSHKey(key.ID); err != nil {
+			return 0, err
+		}
+		monitor.WithField("id", key.ID).Info("Outdated resource removed")
+	}
+
+	if c.cache.sshKeyID != 0 {
+		return c.cache.sshKeyID, nil
+	}
+
+	monitor.Debug("Creating resource")
+	created, err := c.client.createSSHKey(&sshKey{
+		Name:      c.resourcePrefix,
+		PublicKey: publicKey,
+		Labels:    c.labels(nil),
+	})
+	if err != nil {
+		return 0, err
+	}
+	c.cache.sshKeyID = created.ID
+	monitor.WithField("id", created.ID).Info("Resource created")
+	return c.cache.sshKeyID, nil
+}
+
+func destroyNetwork(context *context) error {
+
+	keys, err := context.client.listSSHKeys(context.labelSelector())
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := context.client.deleteSSHKey(key.ID); err != nil && !isNotFound(err) {
+			return err
+		}
+		context.monitor.WithFields(map[string]interface{}{"type": "ssh key", "id": key.ID}).Info("Resource removed")
+	}
+	context.cache.sshKeyID = 0
+
+	networks, err := context.client.listNetworks(context.labelSelector())
+	if err != nil {
+		return err
+	}
+	for _, network := range networks {
+		if err := context.client.deleteNetwork(network.ID); err != nil && !isNotFound(err) {
+			return err
+		}
+		context.monitor.WithFields(map[string]interface{}{"type": "network", "id": network.ID}).Info("Resource removed")
+	}
+	context.cache.networkID = 0
+	return nil
+}
@@ -0,0 +1,49 @@
+package libvirt
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/caos/orbos/mntr"
+)
+
+type context struct {
+	monitor         mntr.Monitor
+	orbID           string
+	providerID      string
+	namePrefix      string
+	desired         *Spec
+	virsh           virsh
+	machinesService *machinesService
+}
+
+func buildContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string) (*context, error) {
+
+	connectionKey, err := desired.ConnectionKey.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	return newContext(monitor, desired, orbID, providerID, newVirshCLI(desired.URI, connectionKey, desired.HostKey, desired.NoVerify)), nil
+}
+
+func newContext(monitor mntr.Monitor, desired *Spec, orbID, providerID string, virsh virsh) *context {
+
+	// Domain names must be unique within a libvirt connection
+	h := fnv.New32()
+	h.Write([]byte(orbID + providerID))
+	namePrefix := fmt.Sprintf("orbos-%x", h.Sum32())
+	h.Reset()
+
+	newContext := &context{
+		monitor:    monitor,
+		orbID:      orbID,
+		providerID: providerID,
+		namePrefix: namePrefix,
+		desired:    desired,
+		virsh:      virsh,
+	}
+
+	newContext.machinesService = newMachinesService(newContext)
+	return newContext
+}
@@ -0,0 +1,185 @@
+package libvirt
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/caos/orbos/pkg/secret"
+	"github.com/caos/orbos/pkg/tree"
+	"github.com/pkg/errors"
+)
+
+type Desired struct {
+	Common        *tree.Common `yaml:",inline"`
+	Spec          Spec
+	Loadbalancing *tree.Tree
+}
+
+type Pool struct {
+	// Name of the volume in the storage pool the machines volumes are copy on write overlays of.
+	// The image must be a qcow2 cloud image with cloud-init installed
+	BaseImage string
+	// Libvirt storage pool the base image is in and the machines volumes are created in
+	//@default: default
+	StoragePool string `yaml:",omitempty"`
+	// Libvirt network the machines are attached to
+	//@default: default
+	Network string `yaml:",omitempty"`
+	//@default: 2
+	VCPUs int `yaml:",omitempty"`
+	//@default: 4096
+	MemoryMiB int `yaml:",omitempty"`
+	//@default: 20
+	DiskGB int `yaml:",omitempty"`
+	// Where the machines IP addresses are read from, lease for libvirt managed DHCP networks, agent for the QEMU guest agent or arp
+	//@default: lease
+	AddressSource string `yaml:",omitempty"`
+}
+
+func (p Pool) validate() error {
+	if p.BaseImage == "" {
+		return errors.New("no base image configured")
+	}
+	if p.VCPUs < 0 || p.MemoryMiB < 0 || p.DiskGB < 0 {
+		return errors.New("vcpus, memorymib and diskgb must not be negative")
+	}
+	switch p.AddressSource {
+	case "", "lease", "agent", "arp":
+	default:
+		return errors.Errorf("address source must be lease, agent or arp, but is %s", p.AddressSource)
+	}
+	return nil
+}
+
+func (p *Pool) storagePool() string {
+	if p.StoragePool != "" {
+		return p.StoragePool
+	}
+	return "default"
+}
+
+func (p *Pool) network() string {
+	if p.Network != "" {
+		return p.Network
+	}
+	return "default"
+}
+
+func (p *Pool) vcpus() int {
+	if p.VCPUs > 0 {
+		return p.VCPUs
+	}
+	return 2
+}
+
+func (p *Pool) memoryMiB() int {
+	if p.MemoryMiB > 0 {
+		return p.MemoryMiB
+	}
+	return 4096
+}
+
+func (p *Pool) diskGB() int {
+	if p.DiskGB > 0 {
+		return p.DiskGB
+	}
+	return 20
+}
+
+func (p *Pool) addressSource() string {
+	if p.AddressSource != "" {
+		return p.AddressSource
+	}
+	return "lease"
+}
+
+type Spec struct {
+	Verbose bool
+	// Libvirt connection URI, for example qemu:///system or qemu+ssh://orbiter@kvm.example.com/system
+	URI string
+	// Private SSH key virsh authenticates with for qemu+ssh connection URIs
+	ConnectionKey *secret.Secret `yaml:",omitempty"`
+	// Public SSH host key of the libvirt host for qemu+ssh connection URIs, for example the output of ssh-keyscan -t ed25519 kvm.example.com without the host name.
+	// If configured, virsh connects using the libssh transport and verifies the libvirt host against this key only
+	HostKey string `yaml:",omitempty"`
+	// Don't verify the SSH host key of the libvirt host for qemu+ssh connection URIs.
+	// Only enable this in trusted networks, otherwise the connection is open to man-in-the-middle attacks
+	//@default: false
+	NoVerify bool `yaml:",omitempty"`
+	Pools    map[string]*Pool
+	SSHKey   *SSHKey
+	// Name of the machines network interface keepalived announces the virtual IPs on
+	//@default: eth0
+	Interface string `yaml:",omitempty"`
+	// Network interfaces the machines firewalls don't restrict
+	ExternalInterfaces  []string
+	RebootRequired      []string
+	ReplacementRequired []string
+}
+
+func (s *Spec) networkInterface() string {
+	if s.Interface != "" {
+		return s.Interface
+	}
+	return "eth0"
+}
+
+type SSHKey struct {
+	Private *secret.Secret `yaml:",omitempty"`
+	Public  *secret.Secret `yaml:",omitempty"`
+}
+
+var internetHosts = regexp.MustCompile("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$")
+
+func validateName(name string) error {
+	if len(name) > 63 || !internetHosts.MatchString(name) {
+		return errors.Errorf("name must be compatible with https://tools.ietf.org/html/rfc1123#section-2, but %s is not", name)
+	}
+	return nil
+}
+
+func (d Desired) validateAdapt() error {
+	if d.Loadbalancing == nil {
+		return errors.New("no loadbalancing configured")
+	}
+	if d.Spec.URI == "" {
+		return errors.New("no libvirt connection uri configured")
+	}
+	if d.Spec.HostKey != "" && d.Spec.NoVerify {
+		return errors.New("hostkey and noverify must not be configured both")
+	}
+	if len(d.Spec.Pools) == 0 {
+		return errors.New("no pools configured")
+	}
+	for poolName, pool := range d.Spec.Pools {
+		if err := validateName(poolName); err != nil {
+			return fmt.Errorf("configuring pool %s failed: %w", poolName, err)
+		}
+		if err := pool.validate(); err != nil {
+			return fmt.Errorf("configuring pool %s failed: %w", poolName, err)
+		}
+	}
+	return nil
+}
+
+func (d Desired) validateQuery() error {
+	if d.Spec.SSHKey == nil ||
+		!d.Spec.SSHKey.Private.HasValue() ||
+		!d.Spec.SSHKey.Public.HasValue() {
+		return errors.New("ssh key missing... please initialize your orb using orbctl configure command")
+	}
+	return nil
+}
+
+func parseDesired(desiredTree *tree.Tree) (*Desired, error) {
+	desiredKind := &Desired{
+		Common: desiredTree.Common,
+		Spec:   Spec{},
+	}
+
+	if err := desiredTree.Original.Decode(desiredKind); err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+
+	return desiredKind, nil
+}
@@ -0,0 +1,25 @@
+package libvirt
+
+import (
+	"github.com/caos/orbos/internal/helpers"
+)
+
+func destroy(context *context) error {
+
+	pools, err := context.machinesService.ListPools()
+	if err != nil {
+		return err
+	}
+
+	var delFuncs []func() error
+	for _, pool := range pools {
+		machines, err := context.machinesService.List(pool)
+		if err != nil {
+			return err
+		}
+		for _, machine := range machines {
+			delFuncs = append(delFuncs, machine.Remove)
+		}
+	}
+	return helpers.Fanout(delFuncs)()
+}
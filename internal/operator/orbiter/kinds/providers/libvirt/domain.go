@@ -0,0 +1,183 @@
+package libvirt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type domain struct {
+	XMLName     xml.Name        `xml:"domain"`
+	Type        string          `xml:"type,attr"`
+	Name        string          `xml:"name"`
+	Description string          `xml:"description"`
+	Memory      domainMemory    `xml:"memory"`
+	VCPU        int             `xml:"vcpu"`
+	OS          domainOS        `xml:"os"`
+	Features    *domainFeatures `xml:"features,omitempty"`
+	CPU         *domainCPU      `xml:"cpu,omitempty"`
+	Devices     domainDevices   `xml:"devices"`
+}
+
+type domainFeatures struct {
+	ACPI struct{} `xml:"acpi"`
+	APIC struct{} `xml:"apic"`
+}
+
+type domainMemory struct {
+	Unit  string `xml:"unit,attr"`
+	Value int    `xml:",chardata"`
+}
+
+type domainOS struct {
+	Type struct {
+		Arch  string `xml:"arch,attr,omitempty"`
+		Value string `xml:",chardata"`
+	} `xml:"type"`
+	Boot struct {
+		Dev string `xml:"dev,attr"`
+	} `xml:"boot"`
+}
+
+type domainCPU struct {
+	Mode string `xml:"mode,attr"`
+}
+
+type domainDevices struct {
+	Disks      []domainDisk      `xml:"disk"`
+	Interfaces []domainInterface `xml:"interface"`
+	Serial     *domainPTY        `xml:"serial,omitempty"`
+	Console    *domainPTY        `xml:"console,omitempty"`
+	Channel    *domainChannel    `xml:"channel,omitempty"`
+}
+
+type domainDisk struct {
+	Type   string `xml:"type,attr"`
+	Device string `xml:"device,attr"`
+	Driver struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"driver"`
+	Source struct {
+		Pool   string `xml:"pool,attr"`
+		Volume string `xml:"volume,attr"`
+	} `xml:"source"`
+	Target struct {
+		Dev string `xml:"dev,attr"`
+		Bus string `xml:"bus,attr"`
+	} `xml:"target"`
+	ReadOnly *struct{} `xml:"readonly,omitempty"`
+}
+
+type domainInterface struct {
+	Type   string `xml:"type,attr"`
+	Source struct {
+		Network string `xml:"network,attr"`
+	} `xml:"source"`
+	Model struct {
+		Type string `xml:"type,attr"`
+	} `xml:"model"`
+}
+
+type domainPTY struct {
+	Type string `xml:"type,attr"`
+}
+
+type domainChannel struct {
+	Type   string `xml:"type,attr"`
+	Target struct {
+		Type string `xml:"type,attr"`
+		Name string `xml:"name,attr"`
+	} `xml:"target"`
+}
+
+func diskVolume(name string) string { return name + ".qcow2" }
+func seedVolume(name string) string { return name + "-cidata.iso" }
+
+// newDomain returns the definition of a machine that boots from a copy on write overlay of the pools base image
+// and reads its cloud-init configuration from an attached seed image
+func newDomain(name, description string, pool *Pool) *domain {
+
+	disk := domainDisk{Type: "volume", Device: "disk"}
+	disk.Driver.Name, disk.Driver.Type = "qemu", "qcow2"
+	disk.Source.Pool, disk.Source.Volume = pool.storagePool(), diskVolume(name)
+	disk.Target.Dev, disk.Target.Bus = "vda", "virtio"
+
+	seed := domainDisk{Type: "volume", Device: "cdrom", ReadOnly: &struct{}{}}
+	seed.Driver.Name, seed.Driver.Type = "qemu", "raw"
+	seed.Source.Pool, seed.Source.Volume = pool.storagePool(), seedVolume(name)
+	seed.Target.Dev, seed.Target.Bus = "sda", "sata"
+
+	nic := domainInterface{Type: "network"}
+	nic.Source.Network = pool.network()
+	nic.Model.Type = "virtio"
+
+	guestAgent := &domainChannel{Type: "unix"}
+	guestAgent.Target.Type, guestAgent.Target.Name = "virtio", "org.qemu.guest_agent.0"
+
+	d := &domain{
+		Type:        "kvm",
+		Name:        name,
+		Description: description,
+		Memory:      domainMemory{Unit: "MiB", Value: pool.memoryMiB()},
+		VCPU:        pool.vcpus(),
+		Features:    &domainFeatures{},
+		CPU:         &domainCPU{Mode: "host-passthrough"},
+		Devices: domainDevices{
+			Disks:      []domainDisk{disk, seed},
+			Interfaces: []domainInterface{nic},
+			Serial:     &domainPTY{Type: "pty"},
+			Console:    &domainPTY{Type: "pty"},
+			Channel:    guestAgent,
+		},
+	}
+	d.OS.Type.Arch, d.OS.Type.Value = "x86_64", "hvm"
+	d.OS.Boot.Dev = "hd"
+	return d
+}
+
+// description encodes the machines owner and pool, so they can be listed without further bookkeeping
+func description(orbID, providerID, pool string) string {
+	return fmt.Sprintf("orb=%s provider=%s pool=%s", orbID, providerID, pool)
+}
+
+func parseDescription(description string) map[string]string {
+	fields := make(map[string]string)
+	for _, field := range strings.Fields(description) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+	return fields
+}
+
+func parseDomain(domainXML []byte) (*domain, error) {
+	d := &domain{}
+	if err := xml.Unmarshal(domainXML, d); err != nil {
+		return nil, errors.Wrap(err, "parsing domain xml failed")
+	}
+	return d, nil
+}
+
+// parseIPv4 returns the first non loopback IPv4 address of a virsh domifaddr output
+func parseIPv4(domifaddr []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(domifaddr))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[2] != "ipv4" {
+			continue
+		}
+		ip := strings.SplitN(fields[3], "/", 2)[0]
+		// The guest agent also reports the loopback interface
+		if strings.HasPrefix(ip, "127.") {
+			continue
+		}
+		return ip
+	}
+	return ""
+}
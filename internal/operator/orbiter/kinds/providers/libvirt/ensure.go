@@ -0,0 +1,104 @@
+package libvirt
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	dynamiclbmodel "github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic/wrap"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/mntr"
+)
+
+func query(
+	desired *Spec,
+	current *Current,
+	lb interface{},
+	context *context,
+	nodeAgentsCurrent *common.CurrentNodeAgents,
+	nodeAgentsDesired *common.DesiredNodeAgents,
+	naFuncs core.IterateNodeAgentFuncs,
+	orbiterCommit string,
+) (ensureFunc orbiter.EnsureFunc, err error) {
+
+	lbCurrent, ok := lb.(*dynamiclbmodel.Current)
+	if !ok {
+		panic(errors.Errorf("Unknown or unsupported load balancing of type %T", lb))
+	}
+
+	hostPools, _, err := lbCurrent.Current.Spec(context.machinesService)
+	if err != nil {
+		return nil, err
+	}
+
+	// The virtual IPs are addresses in the libvirt network that keepalived moves between the machines
+	current.Current.Ingresses = make(map[string]*infra.Address)
+	for _, vips := range hostPools {
+		for _, vip := range vips {
+			for _, transport := range vip.Transport {
+				current.Current.Ingresses[transport.Name] = &infra.Address{
+					Location:     vip.IP,
+					FrontendPort: uint16(transport.FrontendPort),
+					BackendPort:  uint16(transport.BackendPort),
+				}
+			}
+		}
+	}
+
+	queryNA, installNA := naFuncs(nodeAgentsCurrent)
+	ensureNodeAgent := func(m infra.Machine) error {
+		running, err := queryNA(m, orbiterCommit)
+		if err != nil {
+			return err
+		}
+		if !running {
+			return installNA(m)
+		}
+		return nil
+	}
+
+	pools, err := context.machinesService.machines()
+	if err != nil {
+		return nil, err
+	}
+	var ensureNodeAgents []func() error
+	for _, machines := range pools {
+		for _, machine := range machines {
+			ensureNodeAgents = append(ensureNodeAgents, func(m infra.Machine) func() error {
+				return func() error { return ensureNodeAgent(m) }
+			}(machine))
+		}
+	}
+
+	context.machinesService.onCreate = func(pool string, m infra.Machine) error {
+		_, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, false, desired.ExternalInterfaces)
+		if err != nil {
+			return err
+		}
+		return ensureNodeAgent(m)
+	}
+	wrappedMachines := wrap.MachinesService(context.machinesService, *lbCurrent, &dynamiclbmodel.VRRP{
+		VRRPInterface: desired.networkInterface(),
+		NotifyMaster:  nil,
+		AuthCheck:     nil,
+	}, func(vip *dynamiclbmodel.VIP) string {
+		return vip.IP
+	})
+
+	lbDone, err := wrappedMachines.InitializeDesiredNodeAgents()
+	if err != nil {
+		return nil, err
+	}
+
+	fwDone, err := core.DesireInternalOSFirewall(context.monitor, nodeAgentsDesired, nodeAgentsCurrent, context.machinesService, false, desired.ExternalInterfaces)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(pdf func(mntr.Monitor) error) *orbiter.EnsureResult {
+		return orbiter.ToEnsureResult(lbDone && fwDone, helpers.Fanout(ensureNodeAgents)())
+	}, addPools(current, desired, wrappedMachines)
+}
@@ -0,0 +1,370 @@
+package libvirt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/caos/orbos/internal/helpers"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ssh"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/secret"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+func ListMachines(monitor mntr.Monitor, desiredTree *tree.Tree, orbID, providerID string) (map[string]infra.Machine, error) {
+	desired, err := parseDesired(desiredTree)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing desired state failed")
+	}
+	desiredTree.Parsed = desired
+
+	ctx, err := buildContext(monitor, &desired.Spec, orbID, providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.machinesService.use(desired.Spec.SSHKey); err != nil {
+		invalidKey := &secret.Secret{Value: "invalid"}
+		if err := ctx.machinesService.use(&SSHKey{
+			Private: invalidKey,
+			Public:  invalidKey,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return core.ListMachines(ctx.machinesService)
+}
+
+var _ core.MachinesService = (*machinesService)(nil)
+
+type machinesService struct {
+	context    *context
+	privateKey string
+	publicKey  string
+	cache      struct {
+		instances map[string][]*machine
+		sync.Mutex
+	}
+	onCreate func(pool string, machine infra.Machine) error
+	// pollInterval is the time between checks whether a created domain has an IP address
+	pollInterval time.Duration
+}
+
+func newMachinesService(context *context) *machinesService {
+	return &machinesService{
+		context:      context,
+		pollInterval: 5 * time.Second,
+	}
+}
+
+func (m *machinesService) use(key *SSHKey) error {
+	if key == nil || !key.Private.HasValue() || !key.Public.HasValue() {
+		return errors.New("machines are not connectable. have you configured the orb by running orbctl configure?")
+	}
+	privateKey, err := key.Private.Resolve()
+	if err != nil {
+		return err
+	}
+	publicKey, err := key.Public.Resolve()
+	if err != nil {
+		return err
+	}
+	m.privateKey, m.publicKey = privateKey, publicKey
+	return nil
+}
+
+func (m *machinesService) Create(poolName string) (infra.Machine, error) {
+
+	desired, ok := m.context.desired.Pools[poolName]
+	if !ok {
+		return nil, fmt.Errorf("Pool %s is not configured", poolName)
+	}
+
+	name := m.newName()
+	monitor := machineMonitor(m.context.monitor, name, poolName)
+
+	userData, err := core.NewCloudinit().AddGroupWithoutUsers(
+		"orbiter",
+	).AddUser(
+		"orbiter",
+		true,
+		"",
+		[]string{"orbiter", "wheel"},
+		"orbiter",
+		[]string{m.publicKey},
+		"ALL=(ALL) NOPASSWD:ALL",
+	).AddCmd(
+		"sudo echo \"\n\nPermitRootLogin no\n\" >> /etc/ssh/sshd_config",
+	).AddCmd(
+		"sudo service sshd restart",
+	).ToYamlString()
+	if err != nil {
+		return nil, err
+	}
+
+	domainXML, err := xml.MarshalIndent(newDomain(name, description(m.context.orbID, m.context.providerID, poolName), desired), "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling domain xml failed")
+	}
+
+	seed := seedImage(name, userData)
+	storagePool := desired.storagePool()
+
+	monitor.Debug("Creating domain")
+
+	if err := helpers.Fanout([]func() error{
+		func() error {
+			_, err := m.context.virsh.run(nil, "vol-create-as", storagePool, diskVolume(name), fmt.Sprintf("%dG", desired.diskGB()),
+				"--format", "qcow2", "--backing-vol", desired.BaseImage, "--backing-vol-format", "qcow2")
+			return err
+		},
+		func() error {
+			if _, err := m.context.virsh.run(nil, "vol-create-as", storagePool, seedVolume(name), strconv.Itoa(len(seed)), "--format", "raw"); err != nil {
+				return err
+			}
+			_, err := m.context.virsh.run(seed, "vol-upload", "--pool", storagePool, seedVolume(name), fileArg)
+			return err
+		},
+	})(); err != nil {
+		return nil, m.cleanup(name, poolName, storagePool, err)
+	}
+
+	if _, err := m.context.virsh.run(domainXML, "define", fileArg); err != nil {
+		return nil, m.cleanup(name, poolName, storagePool, err)
+	}
+
+	if _, err := m.context.virsh.run(nil, "start", name); err != nil {
+		return nil, m.cleanup(name, poolName, storagePool, err)
+	}
+
+	ip, err := m.waitForIP(name, desired.addressSource(), 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	monitor.Info("Domain created")
+
+	infraMachine, err := m.toMachine(name, ip, monitor, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	m.cache.Lock()
+	if m.cache.instances != nil {
+		if _, ok := m.cache.instances[poolName]; !ok {
+			m.cache.instances[poolName] = make([]*machine, 0)
+		}
+		m.cache.instances[poolName] = append(m.cache.instances[poolName], infraMachine)
+	}
+	m.cache.Unlock()
+
+	if m.onCreate != nil {
+		if err := m.onCreate(poolName, infraMachine); err != nil {
+			return nil, err
+		}
+	}
+
+	monitor.Info("Machine created")
+	return infraMachine, nil
+}
+
+// cleanup removes what has already been created for a machine that couldn't be started, so no volumes are leaked
+func (m *machinesService) cleanup(name, poolName, storagePool string, cause error) error {
+	if err := m.remove(name, poolName, storagePool); err != nil {
+		machineMonitor(m.context.monitor, name, poolName).Error(errors.Wrap(err, "cleaning up failed"))
+	}
+	return cause
+}
+
+// waitForIP waits until the domain reports an IPv4 address
+func (m *machinesService) waitForIP(name, addressSource string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		ip, err := m.ip(name, addressSource)
+		if err != nil {
+			return "", err
+		}
+		if ip != "" {
+			return ip, nil
+		}
+		if time.Now().After(deadline) {
+			return "", errors.Errorf("domain %s has no ip address after %s", name, timeout)
+		}
+		time.Sleep(m.pollInterval)
+	}
+}
+
+func (m *machinesService) ip(name, addressSource string) (string, error) {
+	out, err := m.context.virsh.run(nil, "domifaddr", name, "--source", addressSource)
+	if err != nil {
+		// Shut off domains and domains without a running guest agent have no addresses
+		if strings.Contains(err.Error(), "not running") || strings.Contains(err.Error(), "agent") {
+			return "", nil
+		}
+		return "", err
+	}
+	return parseIPv4(out), nil
+}
+
+func (m *machinesService) toMachine(name, ip string, monitor mntr.Monitor, poolName string) (*machine, error) {
+
+	sshMachine := ssh.NewMachine(monitor, "orbiter", ip)
+	if err := sshMachine.UseKey([]byte(m.privateKey)); err != nil {
+		return nil, err
+	}
+
+	return newMachine(
+		name,
+		ip,
+		sshMachine,
+		m.removeMachineFunc(poolName, name),
+		m.context,
+		poolName,
+	), nil
+}
+
+func (m *machinesService) ListPools() ([]string, error) {
+
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	var poolNames []string
+	for poolName := range pools {
+		poolNames = append(poolNames, poolName)
+	}
+	return poolNames, nil
+}
+
+func (m *machinesService) List(poolName string) (infra.Machines, error) {
+	pools, err := m.machines()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := pools[poolName]
+	machines := make([]infra.Machine, len(pool))
+	for idx := range pool {
+		machines[idx] = pool[idx]
+	}
+
+	return machines, nil
+}
+
+func (m *machinesService) machines() (map[string][]*machine, error) {
+	if m.cache.instances != nil {
+		return m.cache.instances, nil
+	}
+
+	out, err := m.context.virsh.run(nil, "list", "--all", "--name")
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make(map[string][]*machine)
+	for _, name := range strings.Fields(string(out)) {
+		if !strings.HasPrefix(name, m.context.namePrefix+"-") {
+			continue
+		}
+
+		domainXML, err := m.context.virsh.run(nil, "dumpxml", name)
+		if err != nil {
+			return nil, err
+		}
+		domain, err := parseDomain(domainXML)
+		if err != nil {
+			return nil, err
+		}
+		fields := parseDescription(domain.Description)
+		if fields["orb"] != m.context.orbID || fields["provider"] != m.context.providerID {
+			continue
+		}
+		pool := fields["pool"]
+
+		addressSource := (&Pool{}).addressSource()
+		if desired, ok := m.context.desired.Pools[pool]; ok {
+			addressSource = desired.addressSource()
+		}
+		ip, err := m.ip(name, addressSource)
+		if err != nil {
+			return nil, err
+		}
+
+		machine, err := m.toMachine(name, ip, machineMonitor(m.context.monitor, name, pool), pool)
+		if err != nil {
+			return nil, err
+		}
+		instances[pool] = append(instances[pool], machine)
+	}
+
+	m.cache.instances = instances
+	return m.cache.instances, nil
+}
+
+func (m *machinesService) removeMachineFunc(pool, name string) func() error {
+
+	return func() error {
+		m.cache.Lock()
+		if m.cache.instances != nil {
+			cleanMachines := make([]*machine, 0)
+			for idx := range m.cache.instances[pool] {
+				cachedMachine := m.cache.instances[pool][idx]
+				if cachedMachine.ID() != name {
+					cleanMachines = append(cleanMachines, cachedMachine)
+				}
+			}
+			m.cache.instances[pool] = cleanMachines
+		}
+		m.cache.Unlock()
+
+		storagePool := (&Pool{}).storagePool()
+		if desired, ok := m.context.desired.Pools[pool]; ok {
+			storagePool = desired.storagePool()
+		}
+		return m.remove(name, pool, storagePool)
+	}
+}
+
+func (m *machinesService) remove(name, pool, storagePool string) error {
+	monitor := machineMonitor(m.context.monitor, name, pool)
+	monitor.Debug("Removing domain")
+
+	if _, err := m.context.virsh.run(nil, "destroy", name); err != nil && !isNotFound(err) && !strings.Contains(err.Error(), "not running") {
+		return err
+	}
+
+	if _, err := m.context.virsh.run(nil, "undefine", name, "--remove-all-storage"); err != nil && !isNotFound(err) {
+		return err
+	}
+
+	// Volumes of domains that were never defined are not removed by undefine
+	for _, volume := range []string{diskVolume(name), seedVolume(name)} {
+		if _, err := m.context.virsh.run(nil, "vol-delete", "--pool", storagePool, volume); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+
+	monitor.Info("Domain removed")
+	return nil
+}
+
+func machineMonitor(monitor mntr.Monitor, name string, poolName string) mntr.Monitor {
+	return monitor.WithFields(map[string]interface{}{
+		"machine": name,
+		"pool":    poolName,
+	})
+}
+
+func (m *machinesService) newName() string {
+	return m.context.namePrefix + "-" + helpers.RandomStringRunes(6, []rune("abcdefghijklmnopqrstuvwxyz0123456789"))
+}
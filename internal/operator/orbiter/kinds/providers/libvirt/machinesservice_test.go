@@ -0,0 +1,334 @@
+package libvirt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/ssh"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/secret"
+)
+
+// fakeVirsh keeps the domains and volumes a libvirt connection would manage in memory
+type fakeVirsh struct {
+	sync.Mutex
+	domains  map[string]*domain
+	running  map[string]bool
+	volumes  map[string][]byte
+	ips      map[string]string
+	commands []string
+}
+
+func newFakeVirsh() *fakeVirsh {
+	return &fakeVirsh{
+		domains: make(map[string]*domain),
+		running: make(map[string]bool),
+		volumes: make(map[string][]byte),
+		ips:     make(map[string]string),
+	}
+}
+
+func (f *fakeVirsh) run(file []byte, args ...string) ([]byte, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.commands = append(f.commands, strings.Join(args, " "))
+
+	notFound := func(name string) error {
+		return fmt.Errorf("error: failed to get domain '%s'", name)
+	}
+
+	switch args[0] {
+	case "list":
+		var names []string
+		for name := range f.domains {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return []byte(strings.Join(names, "\n") + "\n"), nil
+	case "dumpxml":
+		d, ok := f.domains[args[1]]
+		if !ok {
+			return nil, notFound(args[1])
+		}
+		return xml.Marshal(d)
+	case "domifaddr":
+		if !f.running[args[1]] {
+			return nil, fmt.Errorf("error: Requested operation is not valid: domain is not running")
+		}
+		return []byte(fmt.Sprintf(` Name       MAC address          Protocol     Address
+-------------------------------------------------------------------------------
+ vnet0      52:54:00:6b:3c:58    ipv4         %s/24
+`, f.ips[args[1]])), nil
+	case "vol-create-as":
+		f.volumes[args[1]+"/"+args[2]] = nil
+	case "vol-upload":
+		key := args[2] + "/" + args[3]
+		if _, ok := f.volumes[key]; !ok {
+			return nil, fmt.Errorf("error: Storage volume not found: %s", key)
+		}
+		f.volumes[key] = file
+	case "vol-delete":
+		key := args[2] + "/" + args[3]
+		if _, ok := f.volumes[key]; !ok {
+			return nil, fmt.Errorf("error: failed to get vol '%s'\nerror: Storage volume not found: no storage vol with matching path", args[3])
+		}
+		delete(f.volumes, key)
+	case "define":
+		d, err := parseDomain(file)
+		if err != nil {
+			return nil, err
+		}
+		f.domains[d.Name] = d
+	case "start":
+		if _, ok := f.domains[args[1]]; !ok {
+			return nil, notFound(args[1])
+		}
+		f.running[args[1]] = true
+		f.ips[args[1]] = fmt.Sprintf("192.168.122.%d", 10+len(f.ips))
+	case "destroy":
+		if _, ok := f.domains[args[1]]; !ok {
+			return nil, notFound(args[1])
+		}
+		if !f.running[args[1]] {
+			return nil, fmt.Errorf("error: Requested operation is not valid: domain is not running")
+		}
+		f.running[args[1]] = false
+	case "undefine":
+		d, ok := f.domains[args[1]]
+		if !ok {
+			return nil, notFound(args[1])
+		}
+		for _, disk := range d.Devices.Disks {
+			delete(f.volumes, disk.Source.Pool+"/"+disk.Source.Volume)
+		}
+		delete(f.domains, args[1])
+	default:
+		return nil, fmt.Errorf("unexpected command %v", args)
+	}
+	return nil, nil
+}
+
+func (f *fakeVirsh) addDomain(name, orb, pool string, running bool) {
+	f.domains[name] = &domain{Name: name, Description: description(orb, "kvm", pool)}
+	f.running[name] = running
+	f.ips[name] = fmt.Sprintf("192.168.122.%d", 100+len(f.ips))
+}
+
+func newTestContext(t *testing.T, fake *fakeVirsh) *context {
+	priv, pub, err := ssh.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &Spec{
+		URI: "qemu:///system",
+		Pools: map[string]*Pool{
+			"application": {BaseImage: "ubuntu-22.04.qcow2", MemoryMiB: 8192},
+			"management":  {BaseImage: "ubuntu-22.04.qcow2", StoragePool: "fast", Network: "orb", DiskGB: 40},
+		},
+	}
+
+	ctx := newContext(mntr.Monitor{}, spec, "orb", "kvm", fake)
+	ctx.machinesService.pollInterval = time.Millisecond
+
+	if err := ctx.machinesService.use(&SSHKey{
+		Private: &secret.Secret{Value: priv},
+		Public:  &secret.Secret{Value: pub},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+func TestMachinesService_List(t *testing.T) {
+	fake := newFakeVirsh()
+	ctx := newTestContext(t, fake)
+	fake.addDomain(ctx.namePrefix+"-aaaaaa", "orb", "application", true)
+	fake.addDomain(ctx.namePrefix+"-bbbbbb", "orb", "management", true)
+	fake.addDomain(ctx.namePrefix+"-cccccc", "orb", "application", false)
+	fake.addDomain(ctx.namePrefix+"-dddddd", "anotherorb", "application", true)
+	fake.addDomain("handmade", "orb", "application", true)
+
+	pools, err := ctx.machinesService.ListPools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(pools)
+	if strings.Join(pools, ",") != "application,management" {
+		t.Errorf("ListPools() = %v, want [application management]", pools)
+	}
+
+	machines, err := ctx.machinesService.List("application")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ctx.namePrefix + "-aaaaaa," + ctx.namePrefix + "-cccccc"
+	if got := strings.Join(machines.IDs(), ","); got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+	if ip := machines[0].IP(); ip != "192.168.122.100" {
+		t.Errorf("IP() = %s, want 192.168.122.100", ip)
+	}
+	if ip := machines[1].IP(); ip != "" {
+		t.Errorf("IP() = %s, want no ip for a shut off domain", ip)
+	}
+}
+
+func TestMachinesService_CreateAndRemove(t *testing.T) {
+	fake := newFakeVirsh()
+	ctx := newTestContext(t, fake)
+	svc := ctx.machinesService
+
+	var created []string
+	svc.onCreate = func(pool string, machine infra.Machine) error {
+		created = append(created, pool+"."+machine.ID())
+		return nil
+	}
+
+	first, err := svc.Create("management")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.Create("application")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(first.ID(), ctx.namePrefix+"-") || validateName(first.ID()) != nil {
+		t.Errorf("ID() = %s, want a valid hostname prefixed by %s", first.ID(), ctx.namePrefix)
+	}
+	if first.IP() != "192.168.122.10" {
+		t.Errorf("IP() = %s, want 192.168.122.10", first.IP())
+	}
+	if strings.Join(created, ",") != "management."+first.ID()+",application."+second.ID() {
+		t.Errorf("onCreate was called for %v", created)
+	}
+
+	d := fake.domains[first.ID()]
+	if d == nil || !fake.running[first.ID()] {
+		t.Fatalf("expected the domain %s to be defined and running", first.ID())
+	}
+	if d.Description != description("orb", "kvm", "management") {
+		t.Errorf("description = %s", d.Description)
+	}
+	if d.Memory.Value != 4096 || d.VCPU != 2 || d.Devices.Interfaces[0].Source.Network != "orb" {
+		t.Errorf("domain has %d MiB memory, %d vcpus and network %s, want 4096, 2 and orb", d.Memory.Value, d.VCPU, d.Devices.Interfaces[0].Source.Network)
+	}
+	if memory := fake.domains[second.ID()].Memory.Value; memory != 8192 {
+		t.Errorf("second domain has %d MiB memory, want 8192", memory)
+	}
+
+	wantCommand := fmt.Sprintf("vol-create-as fast %s.qcow2 40G --format qcow2 --backing-vol ubuntu-22.04.qcow2 --backing-vol-format qcow2", first.ID())
+	found := false
+	for _, command := range fake.commands {
+		found = found || command == wantCommand
+	}
+	if !found {
+		t.Errorf("expected command %s in %v", wantCommand, fake.commands)
+	}
+
+	seed, ok := fake.volumes["fast/"+seedVolume(first.ID())]
+	if !ok {
+		t.Fatalf("expected a seed volume for %s", first.ID())
+	}
+	if userData := readISOFile(t, seed, "USER-DATA;1"); !strings.HasPrefix(userData, "#cloud-config\n") || !strings.Contains(userData, svc.publicKey) {
+		t.Errorf("user data is no cloud config that authorizes the public key: %s", userData)
+	}
+	if metaData := readISOFile(t, seed, "META-DATA;1"); !strings.Contains(metaData, "local-hostname: "+first.ID()) {
+		t.Errorf("meta data doesn't set the hostname: %s", metaData)
+	}
+
+	if _, err := svc.Create("unknown"); err == nil {
+		t.Error("creating a machine in an unconfigured pool should fail")
+	}
+
+	if err := first.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.domains[first.ID()]; ok {
+		t.Errorf("domain %s is not removed", first.ID())
+	}
+	for key := range fake.volumes {
+		if strings.Contains(key, first.ID()) {
+			t.Errorf("volume %s is not removed", key)
+		}
+	}
+	if _, ok := fake.domains[second.ID()]; !ok {
+		t.Errorf("domain %s should not be removed", second.ID())
+	}
+
+	machines, err := svc.List("management")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(machines) != 0 {
+		t.Errorf("List() = %v, want the removed machine not to be cached anymore", machines.IDs())
+	}
+}
+
+func TestParseIPv4(t *testing.T) {
+	tests := []struct {
+		name      string
+		domifaddr string
+		want      string
+	}{{
+		name: "lease",
+		domifaddr: ` Name       MAC address          Protocol     Address
+-------------------------------------------------------------------------------
+ vnet3      52:54:00:0b:7d:10    ipv6         fe80::5054:ff:fe0b:7d10/64
+ vnet3      52:54:00:0b:7d:10    ipv4         192.168.122.12/24
+`,
+		want: "192.168.122.12",
+	}, {
+		name: "agent",
+		domifaddr: ` Name       MAC address          Protocol     Address
+-------------------------------------------------------------------------------
+ lo         00:00:00:00:00:00    ipv4         127.0.0.1/8
+ enp1s0     52:54:00:0b:7d:10    ipv4         10.10.0.4/16
+`,
+		want: "10.10.0.4",
+	}, {
+		name: "no address yet",
+		domifaddr: ` Name       MAC address          Protocol     Address
+-------------------------------------------------------------------------------
+`,
+		want: "",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseIPv4([]byte(tt.domifaddr)); got != tt.want {
+				t.Errorf("parseIPv4() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// readISOFile reads a file from the root directory of an ISO 9660 image
+func readISOFile(t *testing.T, image []byte, identifier string) string {
+	pvd := image[pvdSector*sectorSize:]
+	if string(pvd[1:6]) != "CD001" || strings.TrimSpace(string(pvd[40:72])) != "CIDATA" {
+		t.Fatalf("image has no primary volume descriptor with the volume label CIDATA")
+	}
+	rootExtent := binary.LittleEndian.Uint32(pvd[158:162])
+	rootDir := image[int(rootExtent)*sectorSize : int(rootExtent+1)*sectorSize]
+	for offset := 0; offset < len(rootDir) && rootDir[offset] > 0; offset += int(rootDir[offset]) {
+		record := rootDir[offset:]
+		if !bytes.Equal(record[33:33+int(record[32])], []byte(identifier)) {
+			continue
+		}
+		extent := binary.LittleEndian.Uint32(record[2:6])
+		size := binary.LittleEndian.Uint32(record[10:14])
+		return string(image[int(extent)*sectorSize : int(extent)*sectorSize+int(size)])
+	}
+	t.Fatalf("image has no file %s", identifier)
+	return ""
+}
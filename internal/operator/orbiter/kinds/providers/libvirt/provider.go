@@ -0,0 +1,35 @@
+package libvirt
+
+import (
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+func init() {
+	core.RegisterProvider("orbiter.caos.ch/LibvirtProvider", provider{})
+}
+
+var _ core.Provider = provider{}
+
+type provider struct{}
+
+func (provider) AdaptFunc(cfg core.AdaptConfig) orbiter.AdaptFunc {
+	return AdaptFunc(
+		cfg.ProviderID,
+		cfg.OrbID,
+		cfg.Whitelist,
+		cfg.OrbiterCommit,
+		cfg.RepoURL,
+		cfg.RepoKey,
+		cfg.NodeAgentStateURL,
+		cfg.NodeAgentStateKey,
+		cfg.PProf,
+	)
+}
+
+func (provider) ListMachines(monitor mntr.Monitor, desiredTree *tree.Tree, orbID, providerID string) (map[string]infra.Machine, error) {
+	return ListMachines(monitor, desiredTree, orbID, providerID)
+}
@@ -0,0 +1,137 @@
+package libvirt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	sectorSize = 2048
+	// The first 16 sectors are reserved as system area
+	pvdSector         = 16
+	terminatorSector  = 17
+	lPathTableSector  = 18
+	mPathTableSector  = 19
+	rootDirSector     = 20
+	firstExtentSector = 21
+)
+
+// seedImage returns an ISO 9660 image with the volume label cidata containing the user-data and meta-data files.
+// cloud-init's NoCloud datasource reads its configuration from such an image when it is attached as cdrom
+func seedImage(hostname, userData string) []byte {
+	return iso9660("CIDATA", map[string][]byte{
+		"user-data": []byte(userData),
+		"meta-data": []byte(fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", hostname, hostname)),
+	})
+}
+
+// iso9660 returns a minimal ISO 9660 image with all files in its root directory.
+// Linux maps the uppercase file identifiers without version suffix back to lowercase names
+func iso9660(volumeID string, files map[string][]byte) []byte {
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	extents := make(map[string]uint32, len(files))
+	sectors := uint32(firstExtentSector)
+	for _, name := range names {
+		extents[name] = sectors
+		sectors += sectorsFor(len(files[name]))
+	}
+
+	image := make([]byte, int(sectors)*sectorSize)
+
+	pvd := image[pvdSector*sectorSize : (pvdSector+1)*sectorSize]
+	pvd[0] = 1
+	copy(pvd[1:6], "CD001")
+	pvd[6] = 1
+	fill(pvd[8:40], "")
+	fill(pvd[40:72], volumeID)
+	bothEndian32(pvd[80:88], sectors)
+	bothEndian16(pvd[120:124], 1)
+	bothEndian16(pvd[124:128], 1)
+	bothEndian16(pvd[128:132], sectorSize)
+	bothEndian32(pvd[132:140], 10)
+	binary.LittleEndian.PutUint32(pvd[140:144], lPathTableSector)
+	binary.BigEndian.PutUint32(pvd[148:152], mPathTableSector)
+	directoryRecord(pvd[156:190], []byte{0}, rootDirSector, sectorSize, true)
+	fill(pvd[190:813], "")
+	for _, date := range [][]byte{pvd[813:830], pvd[830:847], pvd[847:864], pvd[864:881]} {
+		copy(date, "0000000000000000")
+	}
+	pvd[881] = 1
+
+	terminator := image[terminatorSector*sectorSize:]
+	terminator[0] = 255
+	copy(terminator[1:6], "CD001")
+	terminator[6] = 1
+
+	pathTableRecord(image[lPathTableSector*sectorSize:], binary.LittleEndian)
+	pathTableRecord(image[mPathTableSector*sectorSize:], binary.BigEndian)
+
+	rootDir := image[rootDirSector*sectorSize : (rootDirSector+1)*sectorSize]
+	offset := directoryRecord(rootDir, []byte{0}, rootDirSector, sectorSize, true)
+	offset += directoryRecord(rootDir[offset:], []byte{1}, rootDirSector, sectorSize, true)
+	for _, name := range names {
+		identifier := strings.ToUpper(name) + ";1"
+		offset += directoryRecord(rootDir[offset:], []byte(identifier), extents[name], uint32(len(files[name])), false)
+		copy(image[int(extents[name])*sectorSize:], files[name])
+	}
+
+	return image
+}
+
+func directoryRecord(to []byte, identifier []byte, extent, size uint32, directory bool) int {
+	length := 33 + len(identifier)
+	if len(identifier)%2 == 0 {
+		length++
+	}
+	to[0] = byte(length)
+	bothEndian32(to[2:10], extent)
+	bothEndian32(to[10:18], size)
+	// Recording date: 1970-01-01 00:00:00 UTC
+	to[18], to[19], to[20] = 70, 1, 1
+	if directory {
+		to[25] = 2
+	}
+	bothEndian16(to[28:32], 1)
+	to[32] = byte(len(identifier))
+	copy(to[33:], identifier)
+	return length
+}
+
+func pathTableRecord(to []byte, order binary.ByteOrder) {
+	to[0] = 1
+	order.PutUint32(to[2:6], rootDirSector)
+	order.PutUint16(to[6:8], 1)
+}
+
+func sectorsFor(size int) uint32 {
+	sectors := (size + sectorSize - 1) / sectorSize
+	if sectors == 0 {
+		return 1
+	}
+	return uint32(sectors)
+}
+
+func fill(to []byte, value string) {
+	for idx := range to {
+		to[idx] = ' '
+	}
+	copy(to, value)
+}
+
+func bothEndian16(to []byte, value uint16) {
+	binary.LittleEndian.PutUint16(to[0:2], value)
+	binary.BigEndian.PutUint16(to[2:4], value)
+}
+
+func bothEndian32(to []byte, value uint32) {
+	binary.LittleEndian.PutUint32(to[0:4], value)
+	binary.BigEndian.PutUint32(to[4:8], value)
+}
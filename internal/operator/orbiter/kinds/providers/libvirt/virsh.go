@@ -0,0 +1,153 @@
+package libvirt
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fileArg is replaced by the path of a temporary file containing the passed file content
+const fileArg = "{file}"
+
+// virsh executes virsh commands against a libvirt connection
+type virsh interface {
+	run(file []byte, args ...string) ([]byte, error)
+}
+
+var _ virsh = (*virshCLI)(nil)
+
+type virshCLI struct {
+	uri           string
+	connectionKey string
+	hostKey       string
+	noVerify      bool
+}
+
+func newVirshCLI(uri, connectionKey, hostKey string, noVerify bool) *virshCLI {
+	return &virshCLI{
+		uri:           uri,
+		connectionKey: connectionKey,
+		hostKey:       hostKey,
+		noVerify:      noVerify,
+	}
+}
+
+func (v *virshCLI) run(file []byte, args ...string) ([]byte, error) {
+
+	uri, cleanupURI, err := v.connectionURI()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanupURI()
+
+	for idx := range args {
+		if args[idx] != fileArg {
+			continue
+		}
+		path, cleanup, err := tempFile("orbiter-libvirt-", file)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		args[idx] = path
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command("virsh", append([]string{"--quiet", "--connect", uri}, args...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "executing virsh %s failed: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// connectionURI adds the keyfile parameter to remote connection URIs if a connection key is configured.
+// If a host key is configured, the connection uses the libssh transport, as only this transport
+// accepts a dedicated known_hosts file. The returned cleanup func removes the written files
+func (v *virshCLI) connectionURI() (string, func(), error) {
+
+	cleanup := func() {}
+	if v.connectionKey == "" && v.hostKey == "" && !v.noVerify {
+		return v.uri, cleanup, nil
+	}
+
+	parsed, err := url.Parse(v.uri)
+	if err != nil {
+		return "", cleanup, errors.Wrap(err, "parsing libvirt connection uri failed")
+	}
+
+	var cleanups []func()
+	cleanup = func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+
+	query := parsed.Query()
+	if v.connectionKey != "" {
+		path, cleanupKey, err := tempFile("orbiter-libvirt-key-", []byte(v.connectionKey))
+		if err != nil {
+			return "", cleanup, err
+		}
+		cleanups = append(cleanups, cleanupKey)
+		query.Set("keyfile", path)
+	}
+	if v.hostKey != "" {
+		parsed.Scheme = strings.Replace(parsed.Scheme, "+ssh", "+libssh", 1)
+		path, cleanupKnownHosts, err := tempFile("orbiter-libvirt-known-hosts-", []byte(knownHostsLine(parsed, v.hostKey)))
+		if err != nil {
+			cleanup()
+			return "", func() {}, err
+		}
+		cleanups = append(cleanups, cleanupKnownHosts)
+		query.Set("known_hosts", path)
+		query.Set("known_hosts_verify", "normal")
+	}
+	if v.noVerify {
+		query.Set("no_verify", "1")
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), cleanup, nil
+}
+
+// knownHostsLine qualifies the host key with the libvirt hosts name,
+// non default ports are added like ssh-keyscan does
+func knownHostsLine(uri *url.URL, hostKey string) string {
+	host := uri.Hostname()
+	if port := uri.Port(); port != "" && port != "22" {
+		host = fmt.Sprintf("[%s]:%s", host, port)
+	}
+	return fmt.Sprintf("%s %s\n", host, strings.TrimSpace(hostKey))
+}
+
+func tempFile(prefix string, content []byte) (string, func(), error) {
+	file, err := ioutil.TempFile("", prefix)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "creating temporary file failed")
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		cleanup()
+		return "", nil, errors.Wrap(err, "writing temporary file failed")
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return "", nil, errors.Wrap(err, "closing temporary file failed")
+	}
+	return file.Name(), cleanup, nil
+}
+
+func isNotFound(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "failed to get domain") ||
+		strings.Contains(err.Error(), "Domain not found") ||
+		strings.Contains(err.Error(), "Storage volume not found"))
+}
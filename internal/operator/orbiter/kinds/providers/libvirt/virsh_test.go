@@ -0,0 +1,92 @@
+package libvirt
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestConnectionURI(t *testing.T) {
+	tests := []struct {
+		name           string
+		uri            string
+		connectionKey  string
+		hostKey        string
+		noVerify       bool
+		wantScheme     string
+		wantKeyfile    bool
+		wantKnownHosts string
+		wantNoVerify   bool
+	}{{
+		name:       "It should pass local uris unchanged",
+		uri:        "qemu:///system",
+		wantScheme: "qemu",
+	}, {
+		name:          "It should verify host keys by default",
+		uri:           "qemu+ssh://orbiter@kvm.example.com/system",
+		connectionKey: "key",
+		wantScheme:    "qemu+ssh",
+		wantKeyfile:   true,
+	}, {
+		name:          "It should skip host key verification only if configured",
+		uri:           "qemu+ssh://orbiter@kvm.example.com/system",
+		connectionKey: "key",
+		noVerify:      true,
+		wantScheme:    "qemu+ssh",
+		wantKeyfile:   true,
+		wantNoVerify:  true,
+	}, {
+		name:           "It should verify against the configured host key using libssh",
+		uri:            "qemu+ssh://orbiter@kvm.example.com:2222/system",
+		connectionKey:  "key",
+		hostKey:        "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOrbiter\n",
+		wantScheme:     "qemu+libssh",
+		wantKeyfile:    true,
+		wantKnownHosts: "[kvm.example.com]:2222 ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOrbiter\n",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			virsh := newVirshCLI(tt.uri, tt.connectionKey, tt.hostKey, tt.noVerify)
+			uri, cleanup, err := virsh.connectionURI()
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := url.Parse(uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Scheme != tt.wantScheme {
+				t.Errorf("connectionURI() = %s, want scheme %s", uri, tt.wantScheme)
+			}
+			query := parsed.Query()
+			if gotKeyfile := query.Get("keyfile") != ""; gotKeyfile != tt.wantKeyfile {
+				t.Errorf("connectionURI() = %s, want keyfile %t", uri, tt.wantKeyfile)
+			}
+			if gotNoVerify := query.Get("no_verify") == "1"; gotNoVerify != tt.wantNoVerify {
+				t.Errorf("connectionURI() = %s, want no_verify %t", uri, tt.wantNoVerify)
+			}
+			knownHostsPath := query.Get("known_hosts")
+			if knownHostsPath != "" {
+				knownHosts, err := ioutil.ReadFile(knownHostsPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(knownHosts) != tt.wantKnownHosts {
+					t.Errorf("known_hosts = %q, want %q", string(knownHosts), tt.wantKnownHosts)
+				}
+			} else if tt.wantKnownHosts != "" {
+				t.Errorf("connectionURI() = %s, want known_hosts", uri)
+			}
+
+			cleanup()
+			for _, param := range []string{"keyfile", "known_hosts"} {
+				if path := query.Get(param); path != "" {
+					if _, err := os.Stat(path); !os.IsNotExist(err) {
+						t.Errorf("expected %s %s to be removed after cleanup", param, path)
+					}
+				}
+			}
+		})
+	}
+}
@@ -6,20 +6,29 @@ import (
 
 	"github.com/caos/orbos/pkg/secret"
 
-	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/cs"
-
 	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
 
 	"github.com/caos/orbos/internal/operator/orbiter"
-	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/gce"
-	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/static"
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/tree"
-	"github.com/pkg/errors"
+
+	// Built-in providers register themselves in the core provider registry
+	_ "github.com/caos/orbos/internal/operator/orbiter/kinds/providers/cs"
+	_ "github.com/caos/orbos/internal/operator/orbiter/kinds/providers/ec2"
+	_ "github.com/caos/orbos/internal/operator/orbiter/kinds/providers/gce"
+	_ "github.com/caos/orbos/internal/operator/orbiter/kinds/providers/hetzner"
+	_ "github.com/caos/orbos/internal/operator/orbiter/kinds/providers/libvirt"
+	_ "github.com/caos/orbos/internal/operator/orbiter/kinds/providers/static"
 )
 
 var alphanum = regexp.MustCompile("[^a-zA-Z0-9]+")
 
+// Kinds returns all provider kinds that are compiled in
+func Kinds() []string {
+	return core.ProviderKinds()
+}
+
 func GetQueryAndDestroyFuncs(
 	monitor mntr.Monitor,
 	provID string,
@@ -27,7 +36,7 @@ func GetQueryAndDestroyFuncs(
 	providerCurrent *tree.Tree,
 	whitelistChan chan []*orbiter.CIDR,
 	finishedChan chan struct{},
-	orbiterCommit, repoURL, repoKey string,
+	orbiterCommit, repoURL, repoKey, nodeAgentStateURL, nodeAgentStateKey string,
 	oneoff bool,
 	pprof bool,
 ) (
@@ -47,51 +56,28 @@ func GetQueryAndDestroyFuncs(
 		return wl
 	}
 
-	switch providerTree.Common.Kind {
-	case "orbiter.caos.ch/GCEProvider":
-		return gce.AdaptFunc(
-			provID,
-			orbID(repoURL),
-			wlFunc,
-			orbiterCommit, repoURL, repoKey,
-			oneoff,
-			pprof,
-		)(
-			monitor,
-			finishedChan,
-			providerTree,
-			providerCurrent,
-		)
-	case "orbiter.caos.ch/CloudScaleProvider":
-		return cs.AdaptFunc(
-			provID,
-			orbID(repoURL),
-			wlFunc,
-			orbiterCommit, repoURL, repoKey,
-			oneoff,
-			pprof,
-		)(
-			monitor,
-			finishedChan,
-			providerTree,
-			providerCurrent,
-		)
-	case "orbiter.caos.ch/StaticProvider":
-		return static.AdaptFunc(
-			provID,
-			wlFunc,
-			orbiterCommit,
-			repoURL,
-			repoKey,
-			pprof,
-		)(
-			monitor.WithFields(map[string]interface{}{"provider": provID}),
-			finishedChan,
-			providerTree,
-			providerCurrent)
-	default:
-		return nil, nil, nil, false, nil, errors.Errorf("unknown provider kind %s", providerTree.Common.Kind)
+	provider, err := core.GetProvider(providerTree.Common.Kind)
+	if err != nil {
+		return nil, nil, nil, false, nil, err
 	}
+
+	return orbiter.TraceAdaptFunc(provID, provider.AdaptFunc(core.AdaptConfig{
+		ProviderID:        provID,
+		OrbID:             orbID(repoURL),
+		Whitelist:         wlFunc,
+		OrbiterCommit:     orbiterCommit,
+		RepoURL:           repoURL,
+		RepoKey:           repoKey,
+		NodeAgentStateURL: nodeAgentStateURL,
+		NodeAgentStateKey: nodeAgentStateKey,
+		Oneoff:            oneoff,
+		PProf:             pprof,
+	}))(
+		monitor,
+		finishedChan,
+		providerTree,
+		providerCurrent,
+	)
 }
 
 func ListMachines(
@@ -104,30 +90,17 @@ func ListMachines(
 	error,
 ) {
 
-	switch providerTree.Common.Kind {
-	case "orbiter.caos.ch/GCEProvider":
-		return gce.ListMachines(
-			monitor,
-			providerTree,
-			orbID(repoURL),
-			provID,
-		)
-	case "orbiter.caos.ch/CloudScaleProvider":
-		return cs.ListMachines(
-			monitor,
-			providerTree,
-			orbID(repoURL),
-			provID,
-		)
-	case "orbiter.caos.ch/StaticProvider":
-		return static.ListMachines(
-			monitor,
-			providerTree,
-			provID,
-		)
-	default:
-		return nil, errors.Errorf("unknown provider kind %s", providerTree.Common.Kind)
+	provider, err := core.GetProvider(providerTree.Common.Kind)
+	if err != nil {
+		return nil, err
 	}
+
+	return provider.ListMachines(
+		monitor,
+		providerTree,
+		orbID(repoURL),
+		provID,
+	)
 }
 
 func orbID(repoURL string) string {
@@ -20,7 +20,9 @@ func AdaptFunc(
 	whitelist dynamic.WhiteListFunc,
 	orbiterCommit,
 	repoURL,
-	repoKey string,
+	repoKey,
+	stateURL string,
+	stateKey string,
 	pprof bool,
 ) orbiter.AdaptFunc {
 	return func(monitor mntr.Monitor, finishedChan chan struct{}, desiredTree *tree.Tree, currentTree *tree.Tree) (queryFunc orbiter.QueryFunc, destroyFunc orbiter.DestroyFunc, configureFunc orbiter.ConfigureFunc, migrate bool, secrets map[string]*secret.Secret, err error) {
@@ -85,7 +87,7 @@ func AdaptFunc(
 				if err := svc.updateKeys(); err != nil {
 					return nil, err
 				}
-				_, iterateNA := core.NodeAgentFuncs(monitor, repoURL, repoKey, pprof)
+				_, iterateNA := core.NodeAgentFuncs(monitor, repoURL, repoKey, stateURL, stateKey, pprof)
 				return query(desiredKind, current, nodeAgentsDesired, nodeAgentsCurrent, lbCurrent.Parsed, monitor, svc, iterateNA, orbiterCommit)
 			}, func(delegates map[string]interface{}) error {
 				if err := lbDestroy(delegates); err != nil {
@@ -104,8 +106,8 @@ func AdaptFunc(
 
 				initKeys := desiredKind.Spec.Keys == nil
 				if initKeys ||
-					desiredKind.Spec.Keys.MaintenanceKeyPrivate == nil || desiredKind.Spec.Keys.MaintenanceKeyPrivate.Value == "" ||
-					desiredKind.Spec.Keys.MaintenanceKeyPublic == nil || desiredKind.Spec.Keys.MaintenanceKeyPublic.Value == "" {
+					!desiredKind.Spec.Keys.MaintenanceKeyPrivate.HasValue() ||
+					!desiredKind.Spec.Keys.MaintenanceKeyPublic.HasValue() {
 					priv, pub, err := ssh.Generate()
 					if err != nil {
 						return err
@@ -118,7 +120,7 @@ func AdaptFunc(
 					return nil
 				}
 
-				return core.ConfigureNodeAgents(svc, monitor, orb, pprof)
+				return core.ConfigureNodeAgents(svc, monitor, orb, stateURL, stateKey, pprof)
 			},
 			migrate,
 			secrets,
@@ -44,7 +44,10 @@ func (c *machinesService) updateKeys() error {
 		panic(err)
 	}
 
-	keys := privateKeys(c.desired.Spec)
+	keys, err := privateKeys(c.desired.Spec)
+	if err != nil {
+		return err
+	}
 
 	for _, pool := range pools {
 		machines, err := c.cachedPool(pool)
@@ -86,10 +89,15 @@ func (c *machinesService) Create(poolName string) (infra.Machine, error) {
 		return nil, err
 	}
 
+	publicKey, err := c.desired.Spec.Keys.MaintenanceKeyPublic.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
 	for _, machine := range pool {
 		if !machine.X_active {
 			machine.X_active = true
-			if err := machine.WriteFile(fmt.Sprintf("/home/orbiter/.ssh/authorized_keys"), bytes.NewReader([]byte(c.desired.Spec.Keys.MaintenanceKeyPublic.Value)), 600); err != nil {
+			if err := machine.WriteFile(fmt.Sprintf("/home/orbiter/.ssh/authorized_keys"), bytes.NewReader([]byte(publicKey)), 600); err != nil {
 				return nil, err
 			}
 
@@ -120,7 +128,10 @@ func (c *machinesService) cachedPool(poolName string) (cachedMachines, error) {
 		return cache, nil
 	}
 
-	keys := privateKeys(c.desired.Spec)
+	keys, err := privateKeys(c.desired.Spec)
+	if err != nil {
+		return nil, err
+	}
 
 	newCache := make([]*machine, 0)
 
@@ -177,14 +188,17 @@ func (c cachedMachines) Machines() infra.Machines {
 	return machines
 }
 
-func privateKeys(spec Spec) [][]byte {
+func privateKeys(spec Spec) ([][]byte, error) {
 	var privateKeys [][]byte
-	toBytes := func(key *secret2.Secret) {
-		if key != nil {
-			privateKeys = append(privateKeys, []byte(key.Value))
+	for _, key := range []*secret2.Secret{spec.Keys.BootstrapKeyPrivate, spec.Keys.MaintenanceKeyPrivate} {
+		if key == nil {
+			continue
+		}
+		resolved, err := key.Resolve()
+		if err != nil {
+			return nil, err
 		}
+		privateKeys = append(privateKeys, []byte(resolved))
 	}
-	toBytes(spec.Keys.BootstrapKeyPrivate)
-	toBytes(spec.Keys.MaintenanceKeyPrivate)
-	return privateKeys
+	return privateKeys, nil
 }
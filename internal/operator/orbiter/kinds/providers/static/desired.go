@@ -45,15 +45,12 @@ func (d DesiredV0) validateAdapt() error {
 func (d DesiredV0) validateQuery() error {
 
 	if d.Spec.Keys == nil ||
-		d.Spec.Keys.BootstrapKeyPrivate == nil ||
-		d.Spec.Keys.BootstrapKeyPrivate.Value == "" {
+		!d.Spec.Keys.BootstrapKeyPrivate.HasValue() {
 		return errors.New("bootstrap private ssh key missing... please provide a private ssh bootstrap key using orbctl writesecret command")
 	}
 
-	if d.Spec.Keys.MaintenanceKeyPrivate == nil ||
-		d.Spec.Keys.MaintenanceKeyPrivate.Value == "" ||
-		d.Spec.Keys.MaintenanceKeyPublic == nil ||
-		d.Spec.Keys.MaintenanceKeyPublic.Value == "" {
+	if !d.Spec.Keys.MaintenanceKeyPrivate.HasValue() ||
+		!d.Spec.Keys.MaintenanceKeyPublic.HasValue() {
 		return errors.New("maintenance ssh key missing... please initialize your orb using orbctl configure command")
 	}
 
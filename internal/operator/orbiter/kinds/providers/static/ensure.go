@@ -34,20 +34,23 @@ func query(
 
 	queryNA, installNA := naFuncs(nodeAgentsCurrent)
 
-	ensureNodeFunc := func(machine infra.Machine, pool string) error {
+	ensureNodeAgent := func(machine infra.Machine) error {
 		running, err := queryNA(machine, orbiterCommit)
 		if err != nil {
 			return err
 		}
 		if !running {
-			if err := installNA(machine); err != nil {
-				return err
-			}
+			return installNA(machine)
+		}
+		return nil
+	}
+	internalMachinesService.onCreate = func(machine infra.Machine, pool string) error {
+		if err := ensureNodeAgent(machine); err != nil {
+			return err
 		}
-		_, err = desireHostnameFunc(machine, pool)
+		_, err := desireHostnameFunc(machine, pool)
 		return err
 	}
-	internalMachinesService.onCreate = ensureNodeFunc
 
 	var externalMachinesService core.MachinesService = internalMachinesService
 
@@ -56,13 +59,22 @@ func query(
 		return nil, err
 	}
 
-	current.Current.Ingresses = make(map[string]*infra.Address)
-	ensureLBFunc := func() *orbiter.EnsureResult {
-		return &orbiter.EnsureResult{
-			Err:  nil,
-			Done: true,
+	var machines []infra.Machine
+	for _, pool := range pools {
+		poolMachines, err := internalMachinesService.List(pool)
+		if err != nil {
+			return nil, err
+		}
+		for _, machine := range poolMachines {
+			if _, err := desireHostnameFunc(machine, pool); err != nil {
+				return nil, err
+			}
+			machines = append(machines, machine)
 		}
 	}
+
+	current.Current.Ingresses = make(map[string]*infra.Address)
+	desireLB := func() (bool, error) { return true, nil }
 	switch lbCurrent := lb.(type) {
 	case *dynamiclbmodel.Current:
 
@@ -76,9 +88,7 @@ func query(
 			AuthCheck:     nil,
 		}, mapVIP)
 		externalMachinesService = wrappedMachinesService
-		ensureLBFunc = func() *orbiter.EnsureResult {
-			return orbiter.ToEnsureResult(wrappedMachinesService.InitializeDesiredNodeAgents())
-		}
+		desireLB = wrappedMachinesService.InitializeDesiredNodeAgents
 		deployPools, _, err := lbCurrent.Current.Spec(internalMachinesService)
 		if err != nil {
 			return nil, err
@@ -103,34 +113,30 @@ func query(
 		return nil, errors.Errorf("Unknown load balancer of type %T", lb)
 	}
 
-	return func(pdf func(mntr.Monitor) error) *orbiter.EnsureResult {
-		var wg sync.WaitGroup
-		for _, pool := range pools {
-			machines, listErr := internalMachinesService.List(pool)
-			if listErr != nil {
-				err = helpers.Concat(err, listErr)
-			}
-			for _, machine := range machines {
-				wg.Add(1)
-				go func(m infra.Machine, p string) {
-					err = helpers.Concat(err, ensureNodeFunc(m, p))
-					wg.Done()
-				}(machine, pool)
-			}
-		}
+	lbDone, err := desireLB()
+	if err != nil {
+		return nil, err
+	}
 
-		wg.Wait()
-		if err != nil {
-			return orbiter.ToEnsureResult(false, err)
-		}
-		result := ensureLBFunc()
+	fwDone, err := core.DesireInternalOSFirewall(monitor, nodeAgentsDesired, nodeAgentsCurrent, externalMachinesService, false, desired.Spec.ExternalInterfaces)
+	if err != nil {
+		return nil, err
+	}
 
-		if result.Err == nil {
-			fwDone, err := core.DesireInternalOSFirewall(monitor, nodeAgentsDesired, nodeAgentsCurrent, externalMachinesService, false, desired.Spec.ExternalInterfaces)
-			result.Err = err
-			result.Done = result.Done && fwDone
+	return func(pdf func(mntr.Monitor) error) *orbiter.EnsureResult {
+		var (
+			wg  sync.WaitGroup
+			err error
+		)
+		for _, machine := range machines {
+			wg.Add(1)
+			go func(m infra.Machine) {
+				err = helpers.Concat(err, ensureNodeAgent(m))
+				wg.Done()
+			}(machine)
 		}
+		wg.Wait()
 
-		return result
+		return orbiter.ToEnsureResult(lbDone && fwDone, err)
 	}, addPools(current, desired, externalMachinesService)
 }
@@ -0,0 +1,96 @@
+package static
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/orbiter"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/clusters/core/infra"
+	dynamiclbmodel "github.com/caos/orbos/internal/operator/orbiter/kinds/loadbalancers/dynamic"
+	"github.com/caos/orbos/internal/operator/orbiter/kinds/providers/core"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/tree"
+)
+
+func TestQueryDesiresNodeAgents(t *testing.T) {
+
+	monitor := mntr.Monitor{}
+	desired := &DesiredV0{
+		Spec: Spec{
+			Pools: map[string][]*Machine{
+				"workers": {{ID: "worker-1", Hostname: "worker-1", IP: "10.0.0.1"}},
+			},
+			ExternalInterfaces: []string{"eth1"},
+		},
+	}
+
+	lbDesired := &tree.Tree{}
+	if err := yaml.Unmarshal([]byte("kind: orbiter.caos.ch/DynamicLoadBalancer\nversion: v2\nspec: {}\n"), lbDesired); err != nil {
+		t.Fatal(err)
+	}
+	lbCurrent := &tree.Tree{}
+	lbQuery, _, _, _, _, err := dynamiclbmodel.AdaptFunc(func() []*orbiter.CIDR { return nil })(monitor, nil, lbDesired, lbCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodeAgentsCurrent := &common.CurrentNodeAgents{}
+	nodeAgentsDesired := &common.DesiredNodeAgents{}
+	if _, err := lbQuery(nodeAgentsCurrent, nodeAgentsDesired, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// machines are cached so that no machine is connected using SSH
+	svc := NewMachinesService(monitor, desired, "test")
+	spec := desired.Spec.Pools["workers"][0]
+	active := newMachine(monitor, svc.statusFile, "orbiter", &spec.ID, string(spec.IP), false, func() {}, func() {}, false, func() {}, func() {})
+	active.X_active = true
+	svc.cache = map[string]cachedMachines{"workers": {active}}
+
+	var queried, installed []string
+	naFuncs := core.IterateNodeAgentFuncs(func(*common.CurrentNodeAgents) (func(infra.Machine, string) (bool, error), func(infra.Machine) error) {
+		return func(machine infra.Machine, _ string) (bool, error) {
+				queried = append(queried, machine.ID())
+				return false, nil
+			}, func(machine infra.Machine) error {
+				installed = append(installed, machine.ID())
+				return nil
+			}
+	})
+
+	ensure, err := query(desired, &Current{}, nodeAgentsDesired, nodeAgentsCurrent, lbCurrent.Parsed, monitor, svc, naFuncs, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queried) > 0 || len(installed) > 0 {
+		t.Errorf("expected querying not to touch node agents, but queried %v and installed %v", queried, installed)
+	}
+
+	na, ok := nodeAgentsDesired.Get("worker-1")
+	if !ok {
+		t.Fatal("expected the node agent of worker-1 to be desired while querying")
+	}
+	if hostname := na.Software.Hostname.Config["hostname"]; hostname != "worker-1" {
+		t.Errorf("expected hostname worker-1 to be desired, but got %q", hostname)
+	}
+	if sources := na.Firewall.Zones["internal"].Sources; len(sources) != 1 || sources[0] != "10.0.0.1/32" {
+		t.Errorf("expected the internal zone to allow 10.0.0.1/32, but got %v", sources)
+	}
+	if interfaces := na.Firewall.Zones["external"].Interfaces; len(interfaces) != 1 || interfaces[0] != "eth1" {
+		t.Errorf("expected the external zone to contain eth1, but got %v", interfaces)
+	}
+
+	result := ensure(nil)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if result.Done {
+		t.Error("expected ensuring not to be done before the node agent reports its current state")
+	}
+	if len(installed) != 1 || installed[0] != "worker-1" {
+		t.Errorf("expected ensuring to install the node agent on worker-1, but installed %v", installed)
+	}
+}
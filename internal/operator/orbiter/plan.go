@@ -0,0 +1,254 @@
+package orbiter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent/state"
+	"github.com/caos/orbos/mntr"
+)
+
+const (
+	Add     = "add"
+	Remove  = "remove"
+	Change  = "change"
+	Upgrade = "upgrade"
+)
+
+// PlannedChange is a single action ORBITER would take when ensuring the desired state
+type PlannedChange struct {
+	ID     string `json:"id" yaml:"id"`
+	Action string `json:"action" yaml:"action"`
+	Path   string `json:"path,omitempty" yaml:"path,omitempty"`
+	From   string `json:"from,omitempty" yaml:"from,omitempty"`
+	To     string `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+func (p *PlannedChange) String() string {
+	str := fmt.Sprintf("%s %s", p.Action, p.ID)
+	if p.Path != "" {
+		str += " " + p.Path
+	}
+	if p.From != "" || p.To != "" {
+		str += fmt.Sprintf(": %s -> %s", p.From, p.To)
+	}
+	return str
+}
+
+// Plan groups the planned changes of an ORBITER iteration by what they affect
+type Plan struct {
+	Machines      []*PlannedChange `json:"machines,omitempty" yaml:"machines,omitempty"`
+	NodeAgents    []*PlannedChange `json:"nodeAgents,omitempty" yaml:"nodeAgents,omitempty"`
+	LoadBalancing []*PlannedChange `json:"loadBalancing,omitempty" yaml:"loadBalancing,omitempty"`
+	Kubernetes    []*PlannedChange `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
+}
+
+// Planner is implemented by parsed current states that know which changes their EnsureFunc is going to apply.
+// The plan is only complete after the QueryFunc ran
+type Planner interface {
+	Plan() (*Plan, error)
+}
+
+func (p *Plan) Merge(other *Plan) {
+	if other == nil {
+		return
+	}
+	p.Machines = append(p.Machines, other.Machines...)
+	p.NodeAgents = append(p.NodeAgents, other.NodeAgents...)
+	p.LoadBalancing = append(p.LoadBalancing, other.LoadBalancing...)
+	p.Kubernetes = append(p.Kubernetes, other.Kubernetes...)
+}
+
+func (p *Plan) Empty() bool {
+	return len(p.Machines) == 0 &&
+		len(p.NodeAgents) == 0 &&
+		len(p.LoadBalancing) == 0 &&
+		len(p.Kubernetes) == 0
+}
+
+// PlanTakeoff runs the adapt and query phases like Takeoff does.
+// Instead of calling the returned EnsureFunc and pushing the results, it returns the changes that would be applied
+func PlanTakeoff(monitor mntr.Monitor, conf *Config) (*Plan, error) {
+
+	query, _, _, migrate, _, treeCurrent, _, err := Adapt(conf.GitClient, monitor, conf.FinishedChan, conf.Adapt)
+	if err != nil {
+		return nil, err
+	}
+
+	if migrate {
+		monitor.Info("The desired state would be migrated to the latest API versions")
+	}
+
+	var reportedNodeAgents map[string]*common.NodeAgentCurrent
+	if conf.NodeAgentStates != nil {
+		reportedNodeAgents = conf.NodeAgentStates.Current()
+	}
+
+	currentNodeAgents, err := readCurrentNodeAgents(conf.GitClient, reportedNodeAgents)
+	if err != nil {
+		return nil, err
+	}
+
+	previousNodeAgents := common.NodeAgentsDesiredKind{}
+	if err := yaml.Unmarshal(conf.GitClient.Read(state.DesiredPath), &previousNodeAgents); err != nil {
+		return nil, err
+	}
+
+	desiredNodeAgents := common.NodeAgentsDesiredKind{
+		Kind:    "nodeagent.caos.ch/NodeAgents",
+		Version: "v0",
+		Spec: common.NodeAgentsSpec{
+			Commit: conf.OrbiterCommit,
+		},
+	}
+
+	if _, err := query(&currentNodeAgents.Current, &desiredNodeAgents.Spec.NodeAgents, nil); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	if planner, ok := treeCurrent.Parsed.(Planner); ok {
+		planned, err := planner.Plan()
+		if err != nil {
+			return nil, err
+		}
+		plan.Merge(planned)
+	}
+
+	nodeAgentChanges, err := planNodeAgents(&previousNodeAgents.Spec.NodeAgents, &desiredNodeAgents.Spec.NodeAgents)
+	if err != nil {
+		return nil, err
+	}
+	plan.Merge(nodeAgentChanges)
+
+	ingressChanges, err := diffYAML("current", conf.GitClient.Read(CurrentPath), common.MarshalYAML(treeCurrent))
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range ingressChanges {
+		if strings.Contains(change.Path, ".ingresses.") {
+			plan.LoadBalancing = append(plan.LoadBalancing, change)
+		}
+	}
+
+	return plan, nil
+}
+
+// planNodeAgents compares the node agents desired state of the last iteration with the freshly queried one.
+// Changes to virtual IPs and to the load balancing software are listed as load balancing changes
+func planNodeAgents(previous, desired *common.DesiredNodeAgents) (*Plan, error) {
+	plan := &Plan{}
+
+	ids := make(map[string]struct{})
+	for _, id := range previous.List() {
+		ids[id] = struct{}{}
+	}
+	for _, id := range desired.List() {
+		ids[id] = struct{}{}
+	}
+
+	sortedIDs := make([]string, 0, len(ids))
+	for id := range ids {
+		sortedIDs = append(sortedIDs, id)
+	}
+	sort.Strings(sortedIDs)
+
+	for _, id := range sortedIDs {
+		prev, hadPrev := previous.NA[id]
+		des, hasDes := desired.NA[id]
+
+		switch {
+		case !hadPrev:
+			plan.NodeAgents = append(plan.NodeAgents, &PlannedChange{ID: id, Action: Add})
+			continue
+		case !hasDes:
+			plan.NodeAgents = append(plan.NodeAgents, &PlannedChange{ID: id, Action: Remove})
+			continue
+		}
+
+		changes, err := diffYAML(id, common.MarshalYAML(prev), common.MarshalYAML(des))
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			if strings.HasPrefix(change.Path, "networking.") ||
+				strings.HasPrefix(change.Path, "software.keepalived.") ||
+				strings.HasPrefix(change.Path, "software.nginx.") {
+				plan.LoadBalancing = append(plan.LoadBalancing, change)
+				continue
+			}
+			plan.NodeAgents = append(plan.NodeAgents, change)
+		}
+	}
+	return plan, nil
+}
+
+// diffYAML returns a change for each scalar that differs between the two documents
+func diffYAML(id string, from, to []byte) ([]*PlannedChange, error) {
+	fromFlat, toFlat := make(map[string]string), make(map[string]string)
+	for _, doc := range []struct {
+		content []byte
+		flat    map[string]string
+	}{{from, fromFlat}, {to, toFlat}} {
+		var parsed interface{}
+		if err := yaml.Unmarshal(doc.content, &parsed); err != nil {
+			return nil, err
+		}
+		flatten("", parsed, doc.flat)
+	}
+
+	paths := make(map[string]struct{})
+	for path := range fromFlat {
+		paths[path] = struct{}{}
+	}
+	for path := range toFlat {
+		paths[path] = struct{}{}
+	}
+	sortedPaths := make([]string, 0, len(paths))
+	for path := range paths {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+
+	var changes []*PlannedChange
+	for _, path := range sortedPaths {
+		fromValue, inFrom := fromFlat[path]
+		toValue, inTo := toFlat[path]
+		switch {
+		case !inFrom:
+			changes = append(changes, &PlannedChange{ID: id, Action: Add, Path: path, To: toValue})
+		case !inTo:
+			changes = append(changes, &PlannedChange{ID: id, Action: Remove, Path: path, From: fromValue})
+		case fromValue != toValue:
+			changes = append(changes, &PlannedChange{ID: id, Action: Change, Path: path, From: fromValue, To: toValue})
+		}
+	}
+	return changes, nil
+}
+
+func flatten(prefix string, node interface{}, flat map[string]string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		for key, value := range typed {
+			flatten(join(strings.ToLower(key)), value, flat)
+		}
+	case []interface{}:
+		for idx, value := range typed {
+			flatten(join(fmt.Sprintf("%d", idx)), value, flat)
+		}
+	case nil:
+	default:
+		flat[prefix] = fmt.Sprintf("%v", typed)
+	}
+}
@@ -0,0 +1,85 @@
+package orbiter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/common"
+)
+
+func TestDiffYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []string
+	}{{
+		name: "It should not list anything for equal documents",
+		from: "a: {b: 1}",
+		to:   "a: {b: 1}",
+	}, {
+		name: "It should list changed, added and removed scalars by their lowercased path",
+		from: "A: {B: 1, C: [x, y]}",
+		to:   "a: {b: 2, d: true, c: [x]}",
+		want: []string{
+			"change x a.b: 1 -> 2",
+			"remove x a.c.1: y -> ",
+			"add x a.d:  -> true",
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := diffYAML("x", []byte(tt.from), []byte(tt.to))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("diffYAML() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanNodeAgents(t *testing.T) {
+	spec := func(kubelet, keepalived string) *common.NodeAgentSpec {
+		return &common.NodeAgentSpec{
+			Software: &common.Software{
+				Kubelet:    common.Package{Version: kubelet},
+				KeepaliveD: common.Package{Version: keepalived},
+			},
+		}
+	}
+
+	previous := &common.DesiredNodeAgents{NA: map[string]*common.NodeAgentSpec{
+		"a": spec("v1.28.2", "keepalived 2.0"),
+		"b": spec("v1.28.2", ""),
+	}}
+	desired := &common.DesiredNodeAgents{NA: map[string]*common.NodeAgentSpec{
+		"a": spec("v1.28.15", "keepalived 2.1"),
+		"c": spec("v1.28.15", ""),
+	}}
+
+	plan, err := planNodeAgents(previous, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	str := func(changes []*PlannedChange) string {
+		var lines []string
+		for _, change := range changes {
+			lines = append(lines, change.String())
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	if got, want := str(plan.NodeAgents), "change a software.kubelet.version: v1.28.2 -> v1.28.15\nremove b\nadd c"; got != want {
+		t.Errorf("node agent changes = %q, want %q", got, want)
+	}
+	if got, want := str(plan.LoadBalancing), "change a software.keepalived.version: keepalived 2.0 -> keepalived 2.1"; got != want {
+		t.Errorf("load balancing changes = %q, want %q", got, want)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/caos/orbos/internal/operator/common"
+	"github.com/caos/orbos/internal/operator/nodeagent/state"
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/git"
 	"github.com/caos/orbos/pkg/secret"
@@ -37,10 +38,15 @@ func NoopConfigure(orb orbcfg.Orb) error {
 	return nil
 }
 
+// QueryFunc reads the current state and desires the node agents without changing anything,
+// so PlanTakeoff can list the changes the returned EnsureFunc applies
 type QueryFunc func(nodeAgentsCurrent *common.CurrentNodeAgents, nodeAgentsDesired *common.DesiredNodeAgents, queried map[string]interface{}) (EnsureFunc, error)
 
 type EnsureFunc func(pdf func(monitor mntr.Monitor) error) *EnsureResult
 
+// CurrentPath is where ORBITER commits the current state
+const CurrentPath = "caos-internal/orbiter/current.yml"
+
 func NoopEnsure(_ func(monitor mntr.Monitor) error) *EnsureResult {
 	return &EnsureResult{Done: true}
 }
@@ -100,18 +106,55 @@ func Adapt(gitClient *git.Client, monitor mntr.Monitor, finished chan struct{},
 	if err != nil {
 		return nil, nil, nil, false, nil, nil, nil, err
 	}
+	// Kinds carry over what they need to remember between iterations from the previous current state
 	treeCurrent := &tree.Tree{}
+	if err := yaml.Unmarshal(gitClient.Read(CurrentPath), treeCurrent); err != nil {
+		return nil, nil, nil, false, nil, nil, nil, err
+	}
 
-	query, destroy, configure, migrate, secrets, err := adapt(monitor, finished, treeDesired, treeCurrent)
+	query, destroy, configure, migrate, secrets, err := TraceAdaptFunc("", adapt)(monitor, finished, treeDesired, treeCurrent)
 	return query, destroy, configure, migrate, treeDesired, treeCurrent, secrets, err
 }
 
+// readCurrentNodeAgents reads the node agents current states from git and overwrites them by the states the node agents reported over HTTP
+func readCurrentNodeAgents(gitClient *git.Client, reported map[string]*common.NodeAgentCurrent) (*common.NodeAgentsCurrentKind, error) {
+	current := &common.NodeAgentsCurrentKind{}
+	if err := yaml.Unmarshal(gitClient.Read(state.CurrentPath), current); err != nil {
+		return nil, err
+	}
+	current.Kind = "nodeagent.caos.ch/NodeAgents"
+	current.Version = "v0"
+	for id, curr := range reported {
+		current.Current.Set(id, curr)
+	}
+	return current, nil
+}
+
+// nodeAgentsStatusChanged reports whether the node agents readiness or commits differ
+func nodeAgentsStatusChanged(committed, current map[string]*common.NodeAgentCurrent) bool {
+	if len(committed) != len(current) {
+		return true
+	}
+	for id, curr := range current {
+		comm, ok := committed[id]
+		if !ok || comm == nil || curr == nil {
+			return true
+		}
+		if comm.NodeIsReady != curr.NodeIsReady || comm.Commit != curr.Commit {
+			return true
+		}
+	}
+	return false
+}
+
 func Takeoff(monitor mntr.Monitor, conf *Config, healthyChan chan bool) func() {
 
 	return func() {
 
 		var err error
+		monitor, endIteration := monitor.StartSpan("ORBITER iteration")
 		defer func() {
+			endIteration(err)
 			go func() {
 				if err != nil {
 					healthyChan <- false
@@ -135,14 +178,44 @@ func Takeoff(monitor mntr.Monitor, conf *Config, healthyChan chan bool) func() {
 			},
 		}
 
+		var reportedNodeAgents map[string]*common.NodeAgentCurrent
+		if conf.NodeAgentStates != nil {
+			reportedNodeAgents = conf.NodeAgentStates.Current()
+		}
+
+		readNodeAgents := func() (*common.NodeAgentsCurrentKind, error) {
+			return readCurrentNodeAgents(conf.GitClient, reportedNodeAgents)
+		}
+
 		marshalCurrentFiles := func() []git.File {
-			return []git.File{{
-				Path:    "caos-internal/orbiter/current.yml",
+			files := []git.File{{
+				Path:    CurrentPath,
 				Content: common.MarshalYAML(treeCurrent),
 			}, {
-				Path:    "caos-internal/orbiter/node-agents-desired.yml",
+				Path:    state.DesiredPath,
 				Content: common.MarshalYAML(desiredNodeAgents),
 			}}
+			// Node agents that report over HTTP don't commit their current states themselves,
+			// so they are committed whenever a node agent is added, removed, gets ready or unready or runs another commit
+			if len(reportedNodeAgents) > 0 {
+				committedNodeAgents, err := readCurrentNodeAgents(conf.GitClient, nil)
+				if err != nil {
+					monitor.Error(fmt.Errorf("reading node agents current states failed: %w", err))
+					return files
+				}
+				currentNodeAgents, err := readNodeAgents()
+				if err != nil {
+					monitor.Error(fmt.Errorf("reading node agents current states failed: %w", err))
+					return files
+				}
+				if nodeAgentsStatusChanged(committedNodeAgents.Current.NA, currentNodeAgents.Current.NA) {
+					files = append(files, git.File{
+						Path:    state.CurrentPath,
+						Content: common.MarshalYAML(currentNodeAgents),
+					})
+				}
+			}
+			return files
 		}
 
 		if migrate {
@@ -155,8 +228,18 @@ func Takeoff(monitor mntr.Monitor, conf *Config, healthyChan chan bool) func() {
 			}
 		}
 
-		currentNodeAgents := common.NodeAgentsCurrentKind{}
-		if err := yaml.Unmarshal(conf.GitClient.Read("caos-internal/orbiter/node-agents-current.yml"), &currentNodeAgents); err != nil {
+		// A generated key is only used after it is pushed, so tokens derived from it stay valid
+		if keyer, ok := treeDesired.Parsed.(StateKeyer); ok && conf.NodeAgentStates != nil {
+			key, err := keyer.StateKey()
+			if err != nil {
+				monitor.Error(err)
+				return
+			}
+			conf.NodeAgentStates.UseKey(key)
+		}
+
+		currentNodeAgents, err := readNodeAgents()
+		if err != nil {
 			monitor.Error(err)
 			return
 		}
@@ -201,6 +284,10 @@ func Takeoff(monitor mntr.Monitor, conf *Config, healthyChan chan bool) func() {
 			return
 		}
 
+		if conf.NodeAgentStates != nil {
+			conf.NodeAgentStates.Publish(&desiredNodeAgents)
+		}
+
 		if result.Done {
 			monitor.Info("Desired state is ensured")
 		} else {
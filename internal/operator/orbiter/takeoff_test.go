@@ -0,0 +1,43 @@
+package orbiter
+
+import (
+	"testing"
+
+	"github.com/caos/orbos/internal/operator/common"
+)
+
+func TestNodeAgentsStatusChanged(t *testing.T) {
+	committed := map[string]*common.NodeAgentCurrent{
+		"a": {NodeIsReady: true, Commit: "abc"},
+	}
+	tests := []struct {
+		name    string
+		current map[string]*common.NodeAgentCurrent
+		want    bool
+	}{{
+		name:    "It should ignore changes apart from readiness and commits",
+		current: map[string]*common.NodeAgentCurrent{"a": {NodeIsReady: true, Commit: "abc", Open: common.FirewallCurrent{{Name: "internal"}}}},
+	}, {
+		name:    "It should detect node agents getting unready",
+		current: map[string]*common.NodeAgentCurrent{"a": {Commit: "abc"}},
+		want:    true,
+	}, {
+		name:    "It should detect node agents running another commit",
+		current: map[string]*common.NodeAgentCurrent{"a": {NodeIsReady: true, Commit: "def"}},
+		want:    true,
+	}, {
+		name: "It should detect added node agents",
+		current: map[string]*common.NodeAgentCurrent{
+			"a": {NodeIsReady: true, Commit: "abc"},
+			"b": {},
+		},
+		want: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nodeAgentsStatusChanged(committed, tt.current); got != tt.want {
+				t.Errorf("nodeAgentsStatusChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package orbiter
 
 import (
 	"fmt"
+	"net"
 	"regexp"
+	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -27,15 +29,60 @@ func (c CIDRs) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
 func (c CIDRs) Less(i, j int) bool { return *c[i] < *c[j] }
 
 func (c CIDR) Validate() error {
-	if !compiledCIDR.MatchString(string(c)) {
-		return errors.Errorf("Value %s is not in valid CIDR notation. It does not match the regular expression %s", c, compiledCIDR.String())
+	if compiledCIDR.MatchString(string(c)) {
+		return nil
 	}
-	return nil
+	if c.IPv6() {
+		if _, _, err := net.ParseCIDR(string(c)); err == nil {
+			return nil
+		}
+	}
+	return errors.Errorf("Value %s is not in valid CIDR notation. It neither matches the regular expression %s nor is it an IPv6 CIDR", c, compiledCIDR.String())
+}
+
+// IPv6 returns true if the CIDR belongs to the IPv6 address family
+func (c CIDR) IPv6() bool {
+	return IPAddress(c).IPv6()
 }
 
 func (i IPAddress) Validate() error {
-	if !compiledIP.MatchString(string(i)) {
-		return errors.Errorf("Value %s is not a valid IP address. It does not match the regular expression %s", i, compiledIP.String())
+	if compiledIP.MatchString(string(i)) {
+		return nil
+	}
+	if i.IPv6() && net.ParseIP(string(i)) != nil {
+		return nil
+	}
+	return errors.Errorf("Value %s is not a valid IP address. It neither matches the regular expression %s nor is it an IPv6 address", i, compiledIP.String())
+}
+
+// IPv6 returns true if the address belongs to the IPv6 address family
+func (i IPAddress) IPv6() bool {
+	return strings.Contains(string(i), ":")
+}
+
+// Host returns the CIDR that only contains the address
+func (i IPAddress) Host() CIDR {
+	if i.IPv6() {
+		return CIDR(fmt.Sprintf("%s/128", i))
+	}
+	return CIDR(fmt.Sprintf("%s/32", i))
+}
+
+// ValidateDualStack validates the CIDRs and ensures they belong to different address families
+func ValidateDualStack(cidrs ...CIDR) error {
+	var ipv4, ipv6 int
+	for _, cidr := range cidrs {
+		if err := cidr.Validate(); err != nil {
+			return err
+		}
+		if cidr.IPv6() {
+			ipv6++
+		} else {
+			ipv4++
+		}
+	}
+	if ipv4 > 1 || ipv6 > 1 {
+		return errors.Errorf("CIDRs %v must belong to different address families", cidrs)
 	}
 	return nil
 }
@@ -0,0 +1,57 @@
+package orbiter
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		cidr    bool
+		wantErr bool
+	}{
+		{name: "It should accept IPv4 addresses", value: "10.0.0.1"},
+		{name: "It should accept IPv6 addresses", value: "fd00::1"},
+		{name: "It should reject IPv4 addresses out of range", value: "10.0.0.256", wantErr: true},
+		{name: "It should reject malformed IPv6 addresses", value: "fd00:::1", wantErr: true},
+		{name: "It should reject CIDRs as addresses", value: "fd00::/64", wantErr: true},
+		{name: "It should accept IPv4 CIDRs", value: "10.0.0.0/8", cidr: true},
+		{name: "It should accept IPv6 CIDRs", value: "fd00::/64", cidr: true},
+		{name: "It should reject IPv6 CIDRs with too long prefixes", value: "fd00::/129", cidr: true, wantErr: true},
+		{name: "It should reject addresses as CIDRs", value: "fd00::1", cidr: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := IPAddress(tt.value).Validate()
+			if tt.cidr {
+				err = CIDR(tt.value).Validate()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDualStack(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidrs   []CIDR
+		wantErr bool
+	}{
+		{name: "It should accept single stack CIDRs", cidrs: []CIDR{"fd00::/64"}},
+		{name: "It should accept a CIDR per address family", cidrs: []CIDR{"10.0.0.0/8", "fd00::/64"}},
+		{name: "It should reject multiple CIDRs of the same address family", cidrs: []CIDR{"10.0.0.0/8", "192.168.0.0/16"}, wantErr: true},
+		{name: "It should reject invalid CIDRs", cidrs: []CIDR{"10.0.0.0/8", "fd00::/200"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDualStack(tt.cidrs...); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDualStack() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+
+	if host := IPAddress("fd00::1").Host(); host != "fd00::1/128" {
+		t.Errorf("Host() = %s, want fd00::1/128", host)
+	}
+}
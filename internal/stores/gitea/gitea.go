@@ -0,0 +1,259 @@
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/git"
+)
+
+// Repository is the subset of a Gitea repository the store uses
+type Repository struct {
+	ID       int    `json:"id"`
+	FullName string `json:"full_name"`
+	SSHURL   string `json:"ssh_url"`
+	Owner    struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Name string `json:"name"`
+}
+
+// DeployKey is a Gitea deploy key
+type DeployKey struct {
+	ID       int    `json:"id,omitempty"`
+	Title    string `json:"title"`
+	Key      string `json:"key"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+type user struct {
+	Login string `json:"login"`
+}
+
+// NotFoundError is returned for requests Gitea answers with 404 Not Found
+type NotFoundError struct {
+	Path string
+}
+
+func (n *NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found", n.Path)
+}
+
+type giteaAPI struct {
+	monitor mntr.Monitor
+	client  *http.Client
+	baseURL string
+	token   string
+	user    *user
+	status  error
+}
+
+func (g *giteaAPI) GetStatus() error {
+	return g.status
+}
+
+// New returns a store for the Gitea instance at the base URL, for example https://gitea.example.com
+func New(monitor mntr.Monitor, baseURL string) *giteaAPI {
+	giteaMonitor := monitor.WithFields(map[string]interface{}{
+		"store": "gitea",
+	})
+	return &giteaAPI{
+		client:  http.DefaultClient,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		monitor: giteaMonitor,
+	}
+}
+
+func (g *giteaAPI) IsLoggedIn() bool {
+	return g.user != nil
+}
+
+// LoginToken authenticates with an access token
+func (g *giteaAPI) LoginToken(token string) *giteaAPI {
+	if g.status != nil {
+		return g
+	}
+
+	g.token = strings.TrimSpace(token)
+	authenticated := &user{}
+	if g.status = g.do(http.MethodGet, "/user", nil, authenticated); g.status != nil {
+		g.token = ""
+		return g
+	}
+
+	g.monitor.Info("PersonalAccessTokenFlow succeeded")
+	g.user = authenticated
+	return g
+}
+
+func (g *giteaAPI) GetRepository(owner, name string) (*Repository, error) {
+	if g.GetStatus() != nil {
+		return nil, g.status
+	}
+
+	repo := &Repository{}
+	if err := g.do(http.MethodGet, repoPath(owner, name), nil, repo); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// EnsureRepository creates the repository as private repository with an initial commit if it doesn't exist yet
+func (g *giteaAPI) EnsureRepository(remote *git.Remote) error {
+	owner, name, err := ownerAndName(remote)
+	if err != nil {
+		return err
+	}
+	_, err = g.ensureRepository(owner, name)
+	return err
+}
+
+// EnsureDeployKey replaces the repositories orbos-system deploy keys by a key with write access
+func (g *giteaAPI) EnsureDeployKey(remote *git.Remote, publicKey string) error {
+	owner, name, err := ownerAndName(remote)
+	if err != nil {
+		return err
+	}
+	repo, err := g.ensureRepository(owner, name)
+	if err != nil {
+		return err
+	}
+	return g.EnsureNoDeployKey(repo).CreateDeployKey(repo, publicKey).GetStatus()
+}
+
+func ownerAndName(remote *git.Remote) (string, string, error) {
+	parts := strings.Split(remote.Path, "/")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("repository path %s is not of the form owner/name", remote.Path)
+	}
+	return parts[0], parts[1], nil
+}
+
+func (g *giteaAPI) ensureRepository(owner, name string) (*Repository, error) {
+	if g.GetStatus() == nil && !g.IsLoggedIn() {
+		g.status = errors.New("not logged in")
+	}
+
+	repo, err := g.GetRepository(owner, name)
+	if _, notFound := err.(*NotFoundError); !notFound {
+		if err != nil {
+			g.status = err
+		}
+		return repo, err
+	}
+
+	create := map[string]interface{}{
+		"name":      name,
+		"private":   true,
+		"auto_init": true,
+	}
+
+	createPath := "/user/repos"
+	if !strings.EqualFold(owner, g.user.Login) {
+		createPath = fmt.Sprintf("/orgs/%s/repos", url.PathEscape(owner))
+	}
+
+	repo = &Repository{}
+	if g.status = g.do(http.MethodPost, createPath, create, repo); g.status != nil {
+		return nil, g.status
+	}
+	g.monitor.WithField("repository", repo.FullName).Info("Repository created")
+	return repo, nil
+}
+
+func (g *giteaAPI) getDeployKeys(repo *Repository) []*DeployKey {
+	if g.GetStatus() != nil {
+		return nil
+	}
+
+	keys := make([]*DeployKey, 0)
+	g.status = g.do(http.MethodGet, repoPath(repo.Owner.Login, repo.Name)+"/keys", nil, &keys)
+	return keys
+}
+
+func (g *giteaAPI) CreateDeployKey(repo *Repository, value string) *giteaAPI {
+	if g.GetStatus() != nil {
+		return g
+	}
+
+	g.status = g.do(http.MethodPost, repoPath(repo.Owner.Login, repo.Name)+"/keys", &DeployKey{
+		Title:    "orbos-system",
+		Key:      value,
+		ReadOnly: false,
+	}, nil)
+	return g
+}
+
+func (g *giteaAPI) EnsureNoDeployKey(repo *Repository) *giteaAPI {
+	if g.GetStatus() != nil {
+		return g
+	}
+
+	keys := g.getDeployKeys(repo)
+	if g.status != nil {
+		return g
+	}
+
+	for _, key := range keys {
+		if key.Title == "orbos-system" {
+			if g.status = g.do(http.MethodDelete, fmt.Sprintf("%s/keys/%d", repoPath(repo.Owner.Login, repo.Name), key.ID), nil, nil); g.status != nil {
+				return g
+			}
+		}
+	}
+	return g
+}
+
+func repoPath(owner, name string) string {
+	return fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(name))
+}
+
+func (g *giteaAPI) do(method, apiPath string, body, result interface{}) error {
+
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, g.baseURL+"/api/v1"+apiPath, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "token "+g.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &NotFoundError{Path: apiPath}
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned %s: %s", method, apiPath, resp.Status, string(respBody))
+	}
+
+	if result == nil || len(respBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, result)
+}
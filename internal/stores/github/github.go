@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -19,6 +20,7 @@ import (
 	"github.com/caos/oidc/pkg/oidc"
 	"github.com/caos/oidc/pkg/utils"
 	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/git"
 	"github.com/ghodss/yaml"
 	"github.com/google/go-github/v31/github"
 	"github.com/google/uuid"
@@ -249,6 +251,78 @@ func (g *githubAPI) GetRepositorySSH(url string) (*github.Repository, error) {
 	return repo, err
 }
 
+// EnsureRepository creates the repository as private repository with an initial commit if it doesn't exist yet
+func (g *githubAPI) EnsureRepository(remote *git.Remote) error {
+	owner, name, err := ownerAndName(remote)
+	if err != nil {
+		return err
+	}
+	_, err = g.ensureRepository(owner, name)
+	return err
+}
+
+// EnsureDeployKey replaces the repositories orbos-system deploy keys by a key with write access
+func (g *githubAPI) EnsureDeployKey(remote *git.Remote, publicKey string) error {
+	owner, name, err := ownerAndName(remote)
+	if err != nil {
+		return err
+	}
+	repo, err := g.ensureRepository(owner, name)
+	if err != nil {
+		return err
+	}
+	return g.EnsureNoDeployKey(repo).CreateDeployKey(repo, publicKey).GetStatus()
+}
+
+func ownerAndName(remote *git.Remote) (string, string, error) {
+	parts := strings.Split(remote.Path, "/")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("repository path %s is not of the form owner/name", remote.Path)
+	}
+	return parts[0], parts[1], nil
+}
+
+func (g *githubAPI) ensureRepository(owner, name string) (*github.Repository, error) {
+	if g.GetStatus() != nil {
+		return nil, g.status
+	}
+
+	ctx := context.Background()
+	repo, resp, err := g.client.Repositories.Get(ctx, owner, name)
+	if err == nil {
+		return repo, nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		g.status = err
+		return nil, err
+	}
+
+	user, _, err := g.client.Users.Get(ctx, "")
+	if err != nil {
+		g.status = err
+		return nil, err
+	}
+
+	// An empty organization creates the repository for the authenticated user
+	org := owner
+	if strings.EqualFold(user.GetLogin(), owner) {
+		org = ""
+	}
+
+	t := true
+	repo, _, err = g.client.Repositories.Create(ctx, org, &github.Repository{
+		Name:     &name,
+		Private:  &t,
+		AutoInit: &t,
+	})
+	if err != nil {
+		g.status = err
+		return nil, err
+	}
+	g.monitor.WithField("repository", repo.GetFullName()).Info("Repository created")
+	return repo, nil
+}
+
 func (g *githubAPI) GetRepositories() ([]*github.Repository, error) {
 	if g.GetStatus() != nil {
 		return nil, g.status
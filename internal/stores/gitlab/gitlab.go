@@ -0,0 +1,265 @@
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/git"
+)
+
+// Project is the subset of a GitLab project the store uses
+type Project struct {
+	ID                int    `json:"id"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	SSHURLToRepo      string `json:"ssh_url_to_repo"`
+}
+
+// DeployKey is a GitLab deploy key
+type DeployKey struct {
+	ID      int    `json:"id,omitempty"`
+	Title   string `json:"title"`
+	Key     string `json:"key"`
+	CanPush bool   `json:"can_push"`
+}
+
+type namespace struct {
+	ID int `json:"id"`
+}
+
+type user struct {
+	Username string `json:"username"`
+}
+
+// NotFoundError is returned for requests GitLab answers with 404 Not Found
+type NotFoundError struct {
+	Path string
+}
+
+func (n *NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found", n.Path)
+}
+
+type gitlabAPI struct {
+	monitor mntr.Monitor
+	client  *http.Client
+	baseURL string
+	token   string
+	user    *user
+	status  error
+}
+
+func (g *gitlabAPI) GetStatus() error {
+	return g.status
+}
+
+// New returns a store for the GitLab instance at the base URL, for example https://gitlab.example.com
+func New(monitor mntr.Monitor, baseURL string) *gitlabAPI {
+	gitlabMonitor := monitor.WithFields(map[string]interface{}{
+		"store": "gitlab",
+	})
+	return &gitlabAPI{
+		client:  http.DefaultClient,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		monitor: gitlabMonitor,
+	}
+}
+
+func (g *gitlabAPI) IsLoggedIn() bool {
+	return g.user != nil
+}
+
+// LoginToken authenticates with a personal, group or project access token with the api scope
+func (g *gitlabAPI) LoginToken(token string) *gitlabAPI {
+	if g.status != nil {
+		return g
+	}
+
+	g.token = strings.TrimSpace(token)
+	authenticated := &user{}
+	if g.status = g.do(http.MethodGet, "/user", nil, authenticated); g.status != nil {
+		g.token = ""
+		return g
+	}
+
+	g.monitor.Info("PersonalAccessTokenFlow succeeded")
+	g.user = authenticated
+	return g
+}
+
+func (g *gitlabAPI) GetRepository(projectPath string) (*Project, error) {
+	if g.GetStatus() != nil {
+		return nil, g.status
+	}
+
+	project := &Project{}
+	if err := g.do(http.MethodGet, "/projects/"+url.PathEscape(projectPath), nil, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// EnsureRepository creates the project as private project with an initial commit if it doesn't exist yet
+func (g *gitlabAPI) EnsureRepository(remote *git.Remote) error {
+	_, err := g.ensureRepository(remote.Path)
+	return err
+}
+
+// EnsureDeployKey replaces the projects orbos-system deploy keys by a key with write access
+func (g *gitlabAPI) EnsureDeployKey(remote *git.Remote, publicKey string) error {
+	project, err := g.ensureRepository(remote.Path)
+	if err != nil {
+		return err
+	}
+	return g.EnsureNoDeployKey(project).CreateDeployKey(project, publicKey).GetStatus()
+}
+
+func (g *gitlabAPI) ensureRepository(projectPath string) (*Project, error) {
+	if g.GetStatus() == nil && !g.IsLoggedIn() {
+		g.status = errors.New("not logged in")
+	}
+
+	project, err := g.GetRepository(projectPath)
+	if _, notFound := err.(*NotFoundError); !notFound {
+		if err != nil {
+			g.status = err
+		}
+		return project, err
+	}
+
+	namespacePath, name := path.Split(projectPath)
+	namespacePath = strings.TrimSuffix(namespacePath, "/")
+
+	create := map[string]interface{}{
+		"path":                   name,
+		"name":                   name,
+		"visibility":             "private",
+		"initialize_with_readme": true,
+	}
+
+	// Without namespace, the project is created in the users namespace
+	if namespacePath != "" && namespacePath != g.user.Username {
+		ns := &namespace{}
+		if g.status = g.do(http.MethodGet, "/namespaces/"+url.PathEscape(namespacePath), nil, ns); g.status != nil {
+			return nil, g.status
+		}
+		create["namespace_id"] = ns.ID
+	}
+
+	project = &Project{}
+	if g.status = g.do(http.MethodPost, "/projects", create, project); g.status != nil {
+		return nil, g.status
+	}
+	g.monitor.WithField("repository", project.PathWithNamespace).Info("Repository created")
+	return project, nil
+}
+
+// getDeployKeys reads all pages of the projects deploy keys, as GitLab returns at most 100 keys per page
+func (g *gitlabAPI) getDeployKeys(project *Project) []*DeployKey {
+	if g.GetStatus() != nil {
+		return nil
+	}
+
+	keys := make([]*DeployKey, 0)
+	for page := "1"; page != ""; {
+		pageKeys := make([]*DeployKey, 0)
+		header, err := g.send(http.MethodGet, fmt.Sprintf("/projects/%d/deploy_keys?per_page=100&page=%s", project.ID, page), nil, &pageKeys)
+		if err != nil {
+			g.status = err
+			return nil
+		}
+		keys = append(keys, pageKeys...)
+		page = header.Get("X-Next-Page")
+	}
+	return keys
+}
+
+func (g *gitlabAPI) CreateDeployKey(project *Project, value string) *gitlabAPI {
+	if g.GetStatus() != nil {
+		return g
+	}
+
+	g.status = g.do(http.MethodPost, fmt.Sprintf("/projects/%d/deploy_keys", project.ID), &DeployKey{
+		Title:   "orbos-system",
+		Key:     value,
+		CanPush: true,
+	}, nil)
+	return g
+}
+
+func (g *gitlabAPI) EnsureNoDeployKey(project *Project) *gitlabAPI {
+	if g.GetStatus() != nil {
+		return g
+	}
+
+	keys := g.getDeployKeys(project)
+	if g.status != nil {
+		return g
+	}
+
+	for _, key := range keys {
+		if key.Title == "orbos-system" {
+			if g.status = g.do(http.MethodDelete, fmt.Sprintf("/projects/%d/deploy_keys/%d", project.ID, key.ID), nil, nil); g.status != nil {
+				return g
+			}
+		}
+	}
+	return g
+}
+
+func (g *gitlabAPI) do(method, apiPath string, body, result interface{}) error {
+	_, err := g.send(method, apiPath, body, result)
+	return err
+}
+
+// send returns the response header, so paginated resources can be read
+func (g *gitlabAPI) send(method, apiPath string, body, result interface{}) (http.Header, error) {
+
+	var reqBody []byte
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	// The request keeps the escaped slashes of project and namespace paths
+	req, err := http.NewRequest(method, g.baseURL+"/api/v4"+apiPath, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &NotFoundError{Path: apiPath}
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, apiPath, resp.Status, string(respBody))
+	}
+
+	if result == nil || len(respBody) == 0 {
+		return resp.Header, nil
+	}
+	return resp.Header, json.Unmarshal(respBody, result)
+}
@@ -0,0 +1,135 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/caos/orbos/internal/stores/gitea"
+	"github.com/caos/orbos/internal/stores/github"
+	"github.com/caos/orbos/internal/stores/gitlab"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/git"
+)
+
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+	Gitea  = "gitea"
+)
+
+// Store creates orb repositories and their deploy keys at a git hosting service
+type Store interface {
+	// EnsureRepository creates the repository with an initial commit if it doesn't exist yet
+	EnsureRepository(remote *git.Remote) error
+	// EnsureDeployKey replaces the repositories orbos-system deploy keys by a key with write access
+	EnsureDeployKey(remote *git.Remote, publicKey string) error
+}
+
+type Config struct {
+	// Token authenticates against the hosting services API.
+	// It defaults to the environment variables GITHUB_TOKEN, GITLAB_TOKEN and GITEA_TOKEN.
+	// Without a token, GitHub is logged in using OAuth
+	Token              string
+	GitHubClientID     string
+	GitHubClientSecret string
+	// CacheDir is where the GitHub OAuth token is cached
+	CacheDir string
+}
+
+// New selects the store from the repository URL.
+// GitHub is only supported at github.com, GitLab and Gitea instances are detected by their hostnames or their version endpoints
+func New(ctx context.Context, monitor mntr.Monitor, remote *git.Remote, conf *Config) (Store, error) {
+
+	if remote.HTTP && remote.Scheme != "https" {
+		return nil, errors.New("access tokens are only sent over https, use an https repository url")
+	}
+
+	baseURL := BaseURL(remote)
+	kind, err := Detect(http.DefaultClient, remote, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	token := conf.Token
+	if token == "" {
+		token = os.Getenv(strings.ToUpper(kind) + "_TOKEN")
+	}
+
+	switch kind {
+	case GitHub:
+		g := github.New(monitor)
+		if token != "" {
+			g = g.LoginToken(token)
+		} else {
+			g = g.LoginOAuth(ctx, conf.CacheDir, conf.GitHubClientID, conf.GitHubClientSecret)
+		}
+		if err := g.GetStatus(); err != nil {
+			return nil, fmt.Errorf("github login failed: %w", err)
+		}
+		return g, nil
+	case GitLab:
+		if token == "" {
+			return nil, errors.New("gitlab needs an access token with the api scope")
+		}
+		g := gitlab.New(monitor, baseURL).LoginToken(token)
+		if err := g.GetStatus(); err != nil {
+			return nil, fmt.Errorf("gitlab login failed: %w", err)
+		}
+		return g, nil
+	default:
+		if token == "" {
+			return nil, errors.New("gitea needs an access token")
+		}
+		g := gitea.New(monitor, baseURL).LoginToken(token)
+		if err := g.GetStatus(); err != nil {
+			return nil, fmt.Errorf("gitea login failed: %w", err)
+		}
+		return g, nil
+	}
+}
+
+// BaseURL returns the web URL of the hosting service. For SSH URLs, https on the default port is assumed
+func BaseURL(remote *git.Remote) string {
+	if remote.HTTP {
+		return fmt.Sprintf("%s://%s", remote.Scheme, remote.Host)
+	}
+	return "https://" + remote.Hostname()
+}
+
+// Detect returns the kind of the hosting service at the base URL
+func Detect(client *http.Client, remote *git.Remote, baseURL string) (string, error) {
+	hostname := strings.ToLower(remote.Hostname())
+	switch {
+	case hostname == "github.com":
+		return GitHub, nil
+	case strings.Contains(hostname, GitLab):
+		return GitLab, nil
+	case strings.Contains(hostname, Gitea):
+		return Gitea, nil
+	}
+
+	// Gitea serves its version without authentication
+	if resp, err := client.Get(baseURL + "/api/v1/version"); err == nil {
+		version := struct{ Version string }{}
+		decodeErr := json.NewDecoder(resp.Body).Decode(&version)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK && decodeErr == nil && version.Version != "" {
+			return Gitea, nil
+		}
+	}
+
+	// GitLab requires authentication for its version
+	if resp, err := client.Get(baseURL + "/api/v4/version"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusUnauthorized {
+			return GitLab, nil
+		}
+	}
+
+	return "", fmt.Errorf("git hosting service at %s is not supported, only github.com, GitLab and Gitea are", baseURL)
+}
@@ -0,0 +1,217 @@
+package stores
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caos/orbos/internal/stores/gitea"
+	"github.com/caos/orbos/internal/stores/gitlab"
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/git"
+)
+
+func newMonitor() mntr.Monitor {
+	return mntr.Monitor{
+		OnInfo:   mntr.LogMessage,
+		OnChange: mntr.LogMessage,
+		OnError:  mntr.LogError,
+	}
+}
+
+func TestDetect(t *testing.T) {
+	giteaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/version" {
+			w.Write([]byte(`{"version":"1.13.0"}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer giteaServer.Close()
+
+	gitlabServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v4/version" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer gitlabServer.Close()
+
+	unknownServer := httptest.NewServer(http.NotFoundHandler())
+	defer unknownServer.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{name: "github", url: "git@github.com:caos/orb.git", want: GitHub},
+		{name: "gitlab hostname", url: "git@gitlab.com:caos/group/orb.git", want: GitLab},
+		{name: "gitea hostname", url: "ssh://git@gitea.example.com:2222/caos/orb.git", want: Gitea},
+		{name: "gitea version", url: giteaServer.URL + "/caos/orb.git", want: Gitea},
+		{name: "gitlab version", url: gitlabServer.URL + "/caos/orb.git", want: GitLab},
+		{name: "unknown", url: unknownServer.URL + "/caos/orb.git", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote, err := git.ParseRemote(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Detect(http.DefaultClient, remote, BaseURL(remote))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Detect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Detect() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeHost records the requests and serves a logged in user, a missing repository and former deploy keys
+type fakeHost struct {
+	requests []string
+	created  map[string]interface{}
+	key      map[string]interface{}
+	headers  map[string]http.Header
+}
+
+func (f *fakeHost) handler(t *testing.T, tokenHeader, token string, responses map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(tokenHeader) != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		request := r.Method + " " + r.URL.EscapedPath()
+		if r.URL.RawQuery != "" {
+			request += "?" + r.URL.RawQuery
+		}
+		f.requests = append(f.requests, request)
+
+		switch r.Method {
+		case http.MethodPost:
+			body := make(map[string]interface{})
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := body["key"]; ok {
+				f.key = body
+			} else {
+				f.created = body
+			}
+		}
+
+		response, ok := responses[request]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		for key, values := range f.headers[request] {
+			w.Header()[key] = values
+		}
+		w.Write([]byte(response))
+	})
+}
+
+func TestGitLabStore(t *testing.T) {
+	host := &fakeHost{headers: map[string]http.Header{
+		"GET /api/v4/projects/42/deploy_keys?per_page=100&page=1": {"X-Next-Page": []string{"2"}},
+	}}
+	server := httptest.NewServer(host.handler(t, "PRIVATE-TOKEN", "glpat", map[string]string{
+		"GET /api/v4/user":                                        `{"username":"elio"}`,
+		"GET /api/v4/namespaces/caos%2Fgroup":                     `{"id":7}`,
+		"POST /api/v4/projects":                                   `{"id":42,"path_with_namespace":"caos/group/orb"}`,
+		"GET /api/v4/projects/42/deploy_keys?per_page=100&page=1": `[{"id":1,"title":"orbos-system"},{"id":2,"title":"other"}]`,
+		"GET /api/v4/projects/42/deploy_keys?per_page=100&page=2": `[{"id":5,"title":"orbos-system"}]`,
+		"DELETE /api/v4/projects/42/deploy_keys/1":                ``,
+		"DELETE /api/v4/projects/42/deploy_keys/5":                ``,
+		"POST /api/v4/projects/42/deploy_keys":                    `{"id":3}`,
+		"GET /api/v4/projects/caos%2Fgroup%2Fexisting":            `{"id":43}`,
+		"GET /api/v4/projects/43/deploy_keys?per_page=100&page=1": `[]`,
+		"POST /api/v4/projects/43/deploy_keys":                    `{"id":4}`,
+	}))
+	defer server.Close()
+
+	store := gitlab.New(newMonitor(), server.URL).LoginToken("glpat")
+	if err := store.EnsureDeployKey(&git.Remote{Path: "caos/group/orb"}, "ssh-ed25519 AAAA"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"GET /api/v4/user",
+		"GET /api/v4/projects/caos%2Fgroup%2Forb",
+		"GET /api/v4/namespaces/caos%2Fgroup",
+		"POST /api/v4/projects",
+		"GET /api/v4/projects/42/deploy_keys?per_page=100&page=1",
+		"GET /api/v4/projects/42/deploy_keys?per_page=100&page=2",
+		"DELETE /api/v4/projects/42/deploy_keys/1",
+		"DELETE /api/v4/projects/42/deploy_keys/5",
+		"POST /api/v4/projects/42/deploy_keys",
+	}
+	assertRequests(t, host.requests, want)
+
+	if host.created["namespace_id"] != float64(7) || host.created["visibility"] != "private" || host.created["initialize_with_readme"] != true {
+		t.Errorf("project created with %v", host.created)
+	}
+	if host.key["title"] != "orbos-system" || host.key["can_push"] != true {
+		t.Errorf("deploy key created with %v", host.key)
+	}
+
+	host.requests = nil
+	if err := store.EnsureDeployKey(&git.Remote{Path: "caos/group/existing"}, "ssh-ed25519 AAAA"); err != nil {
+		t.Fatal(err)
+	}
+	assertRequests(t, host.requests, []string{
+		"GET /api/v4/projects/caos%2Fgroup%2Fexisting",
+		"GET /api/v4/projects/43/deploy_keys?per_page=100&page=1",
+		"POST /api/v4/projects/43/deploy_keys",
+	})
+}
+
+func TestGiteaStore(t *testing.T) {
+	host := &fakeHost{}
+	server := httptest.NewServer(host.handler(t, "Authorization", "token gitea", map[string]string{
+		"GET /api/v1/user":                     `{"login":"elio"}`,
+		"POST /api/v1/user/repos":              `{"id":42,"full_name":"elio/orb","owner":{"login":"elio"},"name":"orb"}`,
+		"GET /api/v1/repos/elio/orb/keys":      `[{"id":1,"title":"orbos-system"}]`,
+		"DELETE /api/v1/repos/elio/orb/keys/1": ``,
+		"POST /api/v1/repos/elio/orb/keys":     `{"id":2}`,
+	}))
+	defer server.Close()
+
+	store := gitea.New(newMonitor(), server.URL).LoginToken("gitea")
+	if err := store.EnsureDeployKey(&git.Remote{Path: "elio/orb"}, "ssh-ed25519 AAAA"); err != nil {
+		t.Fatal(err)
+	}
+
+	assertRequests(t, host.requests, []string{
+		"GET /api/v1/user",
+		"GET /api/v1/repos/elio/orb",
+		"POST /api/v1/user/repos",
+		"GET /api/v1/repos/elio/orb/keys",
+		"DELETE /api/v1/repos/elio/orb/keys/1",
+		"POST /api/v1/repos/elio/orb/keys",
+	})
+	if host.created["private"] != true || host.created["auto_init"] != true {
+		t.Errorf("repository created with %v", host.created)
+	}
+	if host.key["title"] != "orbos-system" || host.key["read_only"] != false {
+		t.Errorf("deploy key created with %v", host.key)
+	}
+
+	if err := store.EnsureRepository(&git.Remote{Path: "group/subgroup/orb"}); err == nil {
+		t.Error("EnsureRepository() accepted a nested repository path")
+	}
+}
+
+func assertRequests(t *testing.T, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = \n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
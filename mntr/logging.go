@@ -0,0 +1,123 @@
+package mntr
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	EnvLogFormat = "ORBOS_LOG_FORMAT"
+	EnvLogLevel  = "ORBOS_LOG_LEVEL"
+	EnvLogSinks  = "ORBOS_LOG_SINKS"
+)
+
+// LogConfig selects how and where LogMessage, LogError and LogPanic write records to.
+// Sinks are addressed by stdout, stderr, syslog, file:<path> or an http(s) URL of an OTLP/HTTP logs endpoint
+type LogConfig struct {
+	Format Format
+	Level  Level
+	Sinks  []string
+}
+
+// LogConfigFromEnv reads the config from the ORBOS_LOG_* environment variables.
+// Multiple sinks are separated by commas
+func LogConfigFromEnv() (LogConfig, error) {
+	cfg := LogConfig{}
+	var err error
+	if cfg.Format, err = ParseFormat(os.Getenv(EnvLogFormat)); err != nil {
+		return cfg, err
+	}
+	if cfg.Level, err = ParseLevel(os.Getenv(EnvLogLevel)); err != nil {
+		return cfg, err
+	}
+	for _, sink := range strings.Split(os.Getenv(EnvLogSinks), ",") {
+		if sink = strings.TrimSpace(sink); sink != "" {
+			cfg.Sinks = append(cfg.Sinks, sink)
+		}
+	}
+	return cfg, nil
+}
+
+var logging = struct {
+	sync.RWMutex
+	level Level
+	sinks []Sink
+}{
+	level: LevelDebug,
+	sinks: []Sink{WriterSink(os.Stdout, FormatText)},
+}
+
+// ConfigureLogging replaces the sinks. If none are configured, records are written to stdout
+func ConfigureLogging(cfg LogConfig) error {
+	sinks := make([]Sink, 0, len(cfg.Sinks))
+	for _, address := range cfg.Sinks {
+		sink, err := parseSink(address, cfg.Format)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		sinks = append(sinks, WriterSink(os.Stdout, cfg.Format))
+	}
+
+	logging.Lock()
+	defer logging.Unlock()
+	logging.level = cfg.Level
+	logging.sinks = sinks
+	return nil
+}
+
+// Flush sends the log records and spans that are still buffered, so they are not lost when the process exits
+func Flush() {
+	logging.RLock()
+	sinks := logging.sinks
+	logging.RUnlock()
+	for _, sink := range sinks {
+		if f, ok := sink.(flusher); ok {
+			f.flush()
+		}
+	}
+
+	tracing.RLock()
+	exporter := tracing.exporter
+	tracing.RUnlock()
+	if exporter != nil {
+		exporter.flush(otlpFlushTimeout)
+	}
+}
+
+func parseSink(address string, format Format) (Sink, error) {
+	switch {
+	case address == "stdout":
+		return WriterSink(os.Stdout, format), nil
+	case address == "stderr":
+		return WriterSink(os.Stderr, format), nil
+	case address == "syslog":
+		return SyslogSink()
+	case strings.HasPrefix(address, "file:"):
+		return FileSink(strings.TrimPrefix(strings.TrimPrefix(address, "file:"), "//"), format)
+	case strings.HasPrefix(address, "http://"), strings.HasPrefix(address, "https://"):
+		return OTLPSink(address), nil
+	}
+	return nil, fmt.Errorf("unknown log sink %s, allowed sinks are stdout, stderr, syslog, file:<path> and http(s) URLs of OTLP endpoints", address)
+}
+
+func log(fields map[string]string) {
+	level := levelOf(fields)
+
+	logging.RLock()
+	defer logging.RUnlock()
+	if level < logging.level {
+		return
+	}
+
+	for _, sink := range logging.sinks {
+		if err := sink.Write(level, fields); err != nil {
+			// Never lose records because a sink failed
+			os.Stderr.WriteString(fmt.Sprintf("writing log record failed: %s\n", err.Error()))
+		}
+	}
+}
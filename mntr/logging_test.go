@@ -0,0 +1,133 @@
+package mntr
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mntr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "orbos.log")
+
+	if err := ConfigureLogging(LogConfig{Format: FormatJSON, Level: LevelInfo, Sinks: []string{"file:" + path}}); err != nil {
+		t.Fatal(err)
+	}
+	defer ConfigureLogging(LogConfig{})
+
+	monitor := Monitor{OnInfo: LogMessage, OnError: LogError}.Verbose().WithField("machine", "a")
+	monitor.Debug("filtered")
+	monitor.Info("written")
+	monitor.Error(os.ErrNotExist)
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected two records, but got %d:\n%s", len(lines), content)
+	}
+
+	for idx, want := range []map[string]string{
+		{"level": "info", "msg": "written", "machine": "a"},
+		{"level": "error", "err": os.ErrNotExist.Error(), "machine": "a"},
+	} {
+		record := make(map[string]string)
+		if err := json.Unmarshal([]byte(lines[idx]), &record); err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range want {
+			if record[key] != value {
+				t.Errorf("record %d has %s=%s, want %s", idx, key, record[key], value)
+			}
+		}
+	}
+
+	if err := ConfigureLogging(LogConfig{Sinks: []string{"kafka://localhost"}}); err == nil {
+		t.Error("configuring unknown sinks should fail")
+	}
+}
+
+func TestFlush(t *testing.T) {
+	received := make(chan int, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := struct {
+			ResourceLogs []struct {
+				ScopeLogs []struct {
+					LogRecords []json.RawMessage
+				}
+			}
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		records := 0
+		for _, resource := range payload.ResourceLogs {
+			for _, scope := range resource.ScopeLogs {
+				records += len(scope.LogRecords)
+			}
+		}
+		received <- records
+	}))
+	defer server.Close()
+
+	if err := ConfigureLogging(LogConfig{Sinks: []string{server.URL}}); err != nil {
+		t.Fatal(err)
+	}
+	defer ConfigureLogging(LogConfig{})
+
+	Monitor{OnInfo: LogMessage}.Info("buffered")
+	Flush()
+
+	select {
+	case records := <-received:
+		if records != 1 {
+			t.Errorf("expected one record to be sent, but got %d", records)
+		}
+	default:
+		t.Error("expected the buffered record to be sent before Flush returns")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]Level{"": LevelDebug, "DEBUG": LevelDebug, "info": LevelInfo, "error": LevelError} {
+		got, err := ParseLevel(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("ParseLevel(%q) = %s, want %s", input, got, want)
+		}
+	}
+	if _, err := ParseLevel("warn"); err == nil {
+		t.Error("parsing unknown levels should fail")
+	}
+}
+
+func TestOTLPPayload(t *testing.T) {
+	payload, err := json.Marshal(otlpPayload("orbctl", []otlpRecord{{
+		level:  LevelError,
+		fields: map[string]string{"err": "boom", "ts": "now", "machine": "a"},
+		time:   time.Unix(1, 0),
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"orbctl"}}]},"scopeLogs":[{"logRecords":[{"timeUnixNano":"1000000000","severityNumber":17,"severityText":"error","body":{"stringValue":"boom"},"attributes":[{"key":"machine","value":{"stringValue":"a"}}]}],"scope":{"name":"github.com/caos/orbos/mntr"}}]}]}`
+	if string(payload) != want {
+		t.Errorf("otlpPayload() = %s, want %s", payload, want)
+	}
+}
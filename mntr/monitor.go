@@ -18,6 +18,7 @@ type Monitor struct {
 	OnError        OnError
 	OnRecoverPanic OnRecoverPanic
 	verbose        bool
+	span           *spanContext
 }
 
 func (m Monitor) WithField(key string, value interface{}) Monitor {
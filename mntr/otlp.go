@@ -0,0 +1,233 @@
+package mntr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	otlpBatchSize     = 100
+	otlpFlushInterval = 2 * time.Second
+	otlpFlushTimeout  = 5 * time.Second
+)
+
+type otlpRecord struct {
+	level  Level
+	fields map[string]string
+	time   time.Time
+}
+
+// otlpExporter sends items in batches to an OTLP/HTTP endpoint.
+// Items are dropped if the endpoint can't keep up, so observing never blocks the operators
+type otlpExporter struct {
+	endpoint string
+	items    chan interface{}
+	flushes  chan chan struct{}
+	client   *http.Client
+	payload  func(batch []interface{}) interface{}
+}
+
+func newOTLPExporter(endpoint string, payload func(batch []interface{}) interface{}) *otlpExporter {
+	exporter := &otlpExporter{
+		endpoint: endpoint,
+		items:    make(chan interface{}, 10*otlpBatchSize),
+		flushes:  make(chan chan struct{}),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		payload:  payload,
+	}
+	go exporter.run()
+	return exporter
+}
+
+func (o *otlpExporter) enqueue(item interface{}) error {
+	select {
+	case o.items <- item:
+		return nil
+	default:
+		return fmt.Errorf("dropping item as the OTLP endpoint %s is too slow", o.endpoint)
+	}
+}
+
+func (o *otlpExporter) run() {
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]interface{}, 0, otlpBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := o.send(batch); err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("sending %d items to %s failed: %s\n", len(batch), o.endpoint, err.Error()))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case item := <-o.items:
+			batch = append(batch, item)
+			if len(batch) >= otlpBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case done := <-o.flushes:
+			for queued := len(o.items); queued > 0; queued-- {
+				batch = append(batch, <-o.items)
+				if len(batch) >= otlpBatchSize {
+					flush()
+				}
+			}
+			flush()
+			close(done)
+		}
+	}
+}
+
+// flush returns as soon as the items enqueued so far are sent or the timeout expired
+func (o *otlpExporter) flush(timeout time.Duration) {
+	expired := time.After(timeout)
+	done := make(chan struct{})
+	select {
+	case o.flushes <- done:
+	case <-expired:
+		return
+	}
+	select {
+	case <-done:
+	case <-expired:
+	}
+}
+
+func (o *otlpExporter) send(batch []interface{}) error {
+	body, err := json.Marshal(o.payload(batch))
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.client.Post(o.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type otlpSink struct {
+	exporter *otlpExporter
+}
+
+// OTLPSink sends the records in batches to an OTLP/HTTP logs endpoint like http://localhost:4318/v1/logs
+func OTLPSink(endpoint string) Sink {
+	service := serviceName()
+	return &otlpSink{exporter: newOTLPExporter(endpoint, func(batch []interface{}) interface{} {
+		records := make([]otlpRecord, len(batch))
+		for idx := range batch {
+			records[idx] = batch[idx].(otlpRecord)
+		}
+		return otlpPayload(service, records)
+	})}
+}
+
+func (o *otlpSink) Write(level Level, fields map[string]string) error {
+	return o.exporter.enqueue(otlpRecord{level: level, fields: fields, time: time.Now()})
+}
+
+func (o *otlpSink) flush() {
+	o.exporter.flush(otlpFlushTimeout)
+}
+
+func serviceName() string {
+	return filepath.Base(os.Args[0])
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string          `json:"timeUnixNano"`
+	SeverityNumber int             `json:"severityNumber"`
+	SeverityText   string          `json:"severityText"`
+	Body           otlpValue       `json:"body"`
+	Attributes     []otlpAttribute `json:"attributes"`
+}
+
+// otlpPayload maps the records to the JSON encoding of an OTLP ExportLogsServiceRequest
+func otlpPayload(service string, batch []otlpRecord) interface{} {
+	records := make([]otlpLogRecord, len(batch))
+	for idx, record := range batch {
+		var body string
+		attributes := make(map[string]string, len(record.fields))
+		for key, value := range record.fields {
+			switch key {
+			case "msg", "dbg", "evt", "err":
+				body = value
+			case "ts":
+			default:
+				attributes[key] = value
+			}
+		}
+
+		records[idx] = otlpLogRecord{
+			TimeUnixNano:   strconv.FormatInt(record.time.UnixNano(), 10),
+			SeverityNumber: severityNumber(record.level),
+			SeverityText:   record.level.String(),
+			Body:           otlpValue{StringValue: body},
+			Attributes:     otlpAttributes(attributes),
+		}
+	}
+
+	return map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": otlpResource(service),
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope":      map[string]string{"name": "github.com/caos/orbos/mntr"},
+				"logRecords": records,
+			}},
+		}},
+	}
+}
+
+// severityNumber maps the levels to the OTLP severity numbers DEBUG, INFO and ERROR
+func severityNumber(level Level) int {
+	switch level {
+	case LevelDebug:
+		return 5
+	case LevelInfo:
+		return 9
+	default:
+		return 17
+	}
+}
+
+func otlpResource(service string) interface{} {
+	return map[string]interface{}{
+		"attributes": []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: service}}},
+	}
+}
+
+func otlpAttributes(fields map[string]string) []otlpAttribute {
+	attributes := make([]otlpAttribute, 0, len(fields))
+	for key, value := range fields {
+		attributes = append(attributes, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].Key < attributes[j].Key })
+	return attributes
+}
@@ -0,0 +1,128 @@
+package mntr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	default:
+		return "error"
+	}
+}
+
+func ParseLevel(level string) (Level, error) {
+	switch strings.ToLower(level) {
+	// Debug records are only produced by verbose monitors anyway
+	case "", "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %s, allowed levels are debug, info and error", level)
+}
+
+// levelOf derives the level from the reserved keys a record contains
+func levelOf(fields map[string]string) Level {
+	if _, ok := fields["err"]; ok {
+		return LevelError
+	}
+	if _, ok := fields["panic"]; ok {
+		return LevelError
+	}
+	if _, ok := fields["dbg"]; ok {
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return FormatText, fmt.Errorf("unknown log format %s, allowed formats are text and json", format)
+}
+
+// Sink receives all log records that pass the level filter
+type Sink interface {
+	Write(level Level, fields map[string]string) error
+}
+
+// flusher is implemented by sinks that buffer records
+type flusher interface {
+	flush()
+}
+
+// JSONRecord returns the fields as a single line JSON object with the level added
+func JSONRecord(level Level, fields map[string]string) string {
+	record := make(map[string]string, len(fields)+1)
+	for key, value := range fields {
+		record[key] = value
+	}
+	record["level"] = level.String()
+	line, err := json.Marshal(record)
+	if err != nil {
+		panic(err)
+	}
+	return string(line) + "\n"
+}
+
+type writerSink struct {
+	mux    sync.Mutex
+	writer io.Writer
+	format Format
+}
+
+// WriterSink writes each record as a line to the writer
+func WriterSink(writer io.Writer, format Format) Sink {
+	return &writerSink{writer: writer, format: format}
+}
+
+func (w *writerSink) Write(level Level, fields map[string]string) error {
+	record := LogRecord(AggregateLogFields(fields))
+	if w.format == FormatJSON {
+		record = JSONRecord(level, fields)
+	}
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	_, err := io.WriteString(w.writer, record)
+	return err
+}
+
+// FileSink appends the records to the file at path
+func FileSink(path string, format Format) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening log file %s failed: %w", path, err)
+	}
+	return WriterSink(file, format), nil
+}
@@ -0,0 +1,36 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package mntr
+
+import (
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+// SyslogSink writes the records to the local syslog daemon, tagged by the executables name
+func SyslogSink() (Sink, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, filepath.Base(os.Args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{writer: writer}, nil
+}
+
+func (s *syslogSink) Write(level Level, fields map[string]string) error {
+	record := strings.TrimSuffix(JSONRecord(level, fields), "\n")
+	switch level {
+	case LevelDebug:
+		return s.writer.Debug(record)
+	case LevelInfo:
+		return s.writer.Info(record)
+	default:
+		return s.writer.Err(record)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package mntr
+
+import (
+	"errors"
+	"runtime"
+)
+
+func SyslogSink() (Sink, error) {
+	return nil, errors.New("syslog is not supported on " + runtime.GOOS)
+}
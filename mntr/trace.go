@@ -0,0 +1,188 @@
+package mntr
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const EnvTracesEndpoint = "ORBOS_TRACES_ENDPOINT"
+
+type spanContext struct {
+	traceID string
+	spanID  string
+}
+
+type finishedSpan struct {
+	spanContext
+	parentID   string
+	name       string
+	start, end time.Time
+	attributes map[string]string
+	err        error
+}
+
+var tracing = struct {
+	sync.RWMutex
+	exporter *otlpExporter
+}{}
+
+// ConfigureTracing makes monitors export their spans to the OTLP/HTTP traces endpoint like http://localhost:4318/v1/traces.
+// Passing an empty endpoint disables tracing
+func ConfigureTracing(endpoint string) {
+	var exporter *otlpExporter
+	if endpoint != "" {
+		service := serviceName()
+		exporter = newOTLPExporter(endpoint, func(batch []interface{}) interface{} {
+			spans := make([]*finishedSpan, len(batch))
+			for idx := range batch {
+				spans[idx] = batch[idx].(*finishedSpan)
+			}
+			return otlpTracesPayload(service, spans)
+		})
+	}
+
+	tracing.Lock()
+	defer tracing.Unlock()
+	tracing.exporter = exporter
+}
+
+// ConfigureTracingFromEnv reads the traces endpoint from ORBOS_TRACES_ENDPOINT
+func ConfigureTracingFromEnv() {
+	ConfigureTracing(os.Getenv(EnvTracesEndpoint))
+}
+
+// StartSpan starts a span that is a child of the span the monitor carries.
+// The returned monitor carries the new span, so spans started from it are nested.
+// The monitors fields are recorded as the spans attributes.
+// The returned function ends the span and records the error if it is not nil
+func (m Monitor) StartSpan(name string) (Monitor, func(err error)) {
+	tracing.RLock()
+	exporter := tracing.exporter
+	tracing.RUnlock()
+
+	if exporter == nil {
+		return m, func(error) {}
+	}
+
+	span := &finishedSpan{
+		spanContext: spanContext{spanID: randomID(8)},
+		name:        name,
+		start:       time.Now(),
+		attributes:  normalize(m.Fields),
+	}
+	if m.span != nil {
+		span.traceID = m.span.traceID
+		span.parentID = m.span.spanID
+	} else {
+		span.traceID = randomID(16)
+	}
+
+	m.span = &span.spanContext
+	var once sync.Once
+	return m, func(err error) {
+		once.Do(func() {
+			span.end = time.Now()
+			span.err = err
+			if exportErr := exporter.enqueue(span); exportErr != nil {
+				os.Stderr.WriteString(exportErr.Error() + "\n")
+			}
+		})
+	}
+}
+
+// KindTrace traces an operator kind in a span which lasts from adapting until querying or destroying finished.
+// Adapting, querying, ensuring and destroying are traced in child spans of the kind span.
+// As the ensure phase doesn't run in plan mode, its span outlives the kind span instead of keeping it open
+type KindTrace struct {
+	monitor Monitor
+	name    string
+	end     func(err error)
+}
+
+// TraceKind starts the span of the kind with the given id
+func (m Monitor) TraceKind(kind, id string) *KindTrace {
+	name := strings.TrimSpace(kind + " " + id)
+	monitor, end := m.StartSpan(name)
+	return &KindTrace{monitor: monitor, name: name, end: end}
+}
+
+// Monitor carries the kind span, so kinds adapted with it are traced as children
+func (k *KindTrace) Monitor() Monitor {
+	return k.monitor
+}
+
+// Phase starts a child span for a phase like adapt, query or ensure and returns the function which ends it
+func (k *KindTrace) Phase(phase string) func(err error) {
+	_, end := k.monitor.StartSpan(phase + " " + k.name)
+	return end
+}
+
+// End ends the kind span. Only the first call has an effect
+func (k *KindTrace) End(err error) {
+	k.end(err)
+}
+
+func randomID(bytes int) string {
+	id := make([]byte, bytes)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            otlpStatus      `json:"status"`
+}
+
+// otlpTracesPayload maps the spans to the JSON encoding of an OTLP ExportTraceServiceRequest
+func otlpTracesPayload(service string, finished []*finishedSpan) interface{} {
+	spans := make([]otlpSpan, len(finished))
+	for idx, span := range finished {
+		// Status code OK
+		status := otlpStatus{Code: 1}
+		if span.err != nil {
+			// Status code ERROR
+			status = otlpStatus{Code: 2, Message: span.err.Error()}
+		}
+		spans[idx] = otlpSpan{
+			TraceID:      span.traceID,
+			SpanID:       span.spanID,
+			ParentSpanID: span.parentID,
+			Name:         span.name,
+			// Span kind INTERNAL
+			Kind:              1,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.attributes),
+			Status:            status,
+		}
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": otlpResource(service),
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "github.com/caos/orbos/mntr"},
+				"spans": spans,
+			}},
+		}},
+	}
+}
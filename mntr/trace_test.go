@@ -0,0 +1,100 @@
+package mntr
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartSpan(t *testing.T) {
+	unconfigured, end := Monitor{}.StartSpan("unconfigured")
+	end(nil)
+	if unconfigured.span != nil {
+		t.Fatal("monitors should not carry spans when tracing is not configured")
+	}
+
+	exporter := &otlpExporter{items: make(chan interface{}, 10)}
+	tracing.exporter = exporter
+	defer ConfigureTracing("")
+
+	parentMonitor, endParent := Monitor{}.WithField("provider", "a").StartSpan("parent")
+	childMonitor, endChild := parentMonitor.WithField("machine", "b").StartSpan("child")
+	endChild(errors.New("failed"))
+	endChild(nil)
+	endParent(nil)
+
+	if childMonitor.span == nil || parentMonitor.span == nil {
+		t.Fatal("monitors should carry their spans")
+	}
+
+	child := (<-exporter.items).(*finishedSpan)
+	parent := (<-exporter.items).(*finishedSpan)
+	if len(exporter.items) != 0 {
+		t.Fatalf("ending a span twice should export it once, but %d more spans are queued", len(exporter.items))
+	}
+
+	if parent.parentID != "" || len(parent.traceID) != 32 || len(parent.spanID) != 16 {
+		t.Errorf("unexpected root span context %+v", parent.spanContext)
+	}
+	if child.traceID != parent.traceID || child.parentID != parent.spanID {
+		t.Errorf("child span %+v is not nested in parent span %+v", child.spanContext, parent.spanContext)
+	}
+	if child.err == nil || child.err.Error() != "failed" {
+		t.Errorf("child span should record the error, but got %v", child.err)
+	}
+	if child.attributes["provider"] != "a" || child.attributes["machine"] != "b" {
+		t.Errorf("child span should record the monitors fields as attributes, but got %v", child.attributes)
+	}
+}
+
+func TestTraceKind(t *testing.T) {
+	exporter := &otlpExporter{items: make(chan interface{}, 10)}
+	tracing.exporter = exporter
+	defer ConfigureTracing("")
+
+	trace := Monitor{}.TraceKind("orbiter.caos.ch/Orb", "orb")
+	endQuery := trace.Phase("query")
+	endQuery(nil)
+	trace.End(errors.New("failed"))
+	endEnsure := trace.Phase("ensure")
+	endEnsure(nil)
+	trace.End(nil)
+
+	query := (<-exporter.items).(*finishedSpan)
+	kind := (<-exporter.items).(*finishedSpan)
+	ensure := (<-exporter.items).(*finishedSpan)
+	if len(exporter.items) != 0 {
+		t.Fatalf("ending the kind twice should export it once, but %d more spans are queued", len(exporter.items))
+	}
+
+	if kind.name != "orbiter.caos.ch/Orb orb" || query.name != "query orbiter.caos.ch/Orb orb" || ensure.name != "ensure orbiter.caos.ch/Orb orb" {
+		t.Errorf("unexpected span names %s, %s and %s", kind.name, query.name, ensure.name)
+	}
+	if query.parentID != kind.spanID || ensure.parentID != kind.spanID {
+		t.Error("phase spans should be children of the kind span")
+	}
+	if kind.err == nil || kind.err.Error() != "failed" {
+		t.Errorf("kind span should record the first error, but got %v", kind.err)
+	}
+}
+
+func TestOTLPTracesPayload(t *testing.T) {
+	payload, err := json.Marshal(otlpTracesPayload("orbctl", []*finishedSpan{{
+		spanContext: spanContext{traceID: "t", spanID: "s"},
+		parentID:    "p",
+		name:        "ensure",
+		start:       time.Unix(1, 0),
+		end:         time.Unix(2, 0),
+		attributes:  map[string]string{"machine": "a"},
+		err:         errors.New("boom"),
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"orbctl"}}]},"scopeSpans":[{"scope":{"name":"github.com/caos/orbos/mntr"},"spans":[{"traceId":"t","spanId":"s","parentSpanId":"p","name":"ensure","kind":1,"startTimeUnixNano":"1000000000","endTimeUnixNano":"2000000000","attributes":[{"key":"machine","value":{"stringValue":"a"}}],"status":{"code":2,"message":"boom"}}]}]}]}`
+	if string(payload) != want {
+		t.Errorf("otlpTracesPayload() = %s, want %s", payload, want)
+	}
+}
@@ -19,13 +19,11 @@ func LogError(err error, fields map[string]string) {
 func LogPanic(recov interface{}, fields map[string]string) {
 	if recov != nil {
 		log(fields)
+		// The panic is rethrown, so the process exits
+		Flush()
 	}
 }
 
-func log(fields map[string]string) {
-	WriteToStdout(LogRecord(AggregateLogFields(fields)))
-}
-
 func WriteToStdout(record string) {
 	if _, err := os.Stdout.WriteString(record); err != nil {
 		panic(err)
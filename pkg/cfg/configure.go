@@ -1,6 +1,7 @@
 package cfg
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/caos/orbos/pkg/helper"
@@ -19,6 +20,7 @@ import (
 	"github.com/caos/orbos/pkg/secret"
 	"github.com/caos/orbos/pkg/tree"
 	"gopkg.in/yaml.v3"
+	macherrs "k8s.io/apimachinery/pkg/api/errors"
 )
 
 func ApplyOrbconfigSecret(
@@ -32,9 +34,44 @@ func ApplyOrbconfigSecret(
 		return nil
 	}
 
+	operatorIdentity, err := CurrentOperatorIdentity(k8sClient)
+	if err != nil {
+		return err
+	}
+
+	return applyOrbconfigSecret(orbConfig, operatorIdentity, k8sClient, monitor)
+}
+
+// ApplyOperatorIdentity writes the orbconfig kubernetes secret with a new identity the operators decrypt recipient encrypted secrets with
+func ApplyOperatorIdentity(
+	orbConfig *orb.Orb,
+	operatorIdentity string,
+	k8sClient kubernetes.ClientInt,
+	monitor mntr.Monitor,
+) error {
+
+	if helper.IsNil(k8sClient) {
+		return errors.New("writing the operators identity requires a kubernetes cluster connection")
+	}
+
+	return applyOrbconfigSecret(orbConfig, operatorIdentity, k8sClient, monitor)
+}
+
+// applyOrbconfigSecret never writes the personal identities of the local orbconfig to the cluster, the operators keep their own identity
+func applyOrbconfigSecret(
+	orbConfig *orb.Orb,
+	operatorIdentity string,
+	k8sClient kubernetes.ClientInt,
+	monitor mntr.Monitor,
+) error {
+
 	monitor.Info("Writing orbconfig kubernetes secret")
 
-	orbConfigBytes, err := yaml.Marshal(orbConfig)
+	inCluster := *orbConfig
+	inCluster.AgeIdentity = operatorIdentity
+	inCluster.SSHIdentity = ""
+
+	orbConfigBytes, err := yaml.Marshal(&inCluster)
 	if err != nil {
 		return err
 	}
@@ -47,6 +84,23 @@ func ApplyOrbconfigSecret(
 	return nil
 }
 
+// CurrentOperatorIdentity reads the identities the operators decrypt recipient encrypted secrets with
+func CurrentOperatorIdentity(k8sClient kubernetes.ClientInt) (string, error) {
+	k8sSecret, err := k8sClient.GetSecret("caos-system", "caos")
+	if macherrs.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading orbconfig kubernetes secret failed: %w", err)
+	}
+
+	inCluster := &orb.Orb{}
+	if err := yaml.Unmarshal(k8sSecret.Data["orbconfig"], inCluster); err != nil {
+		return "", fmt.Errorf("parsing orbconfig kubernetes secret failed: %w", err)
+	}
+	return inCluster.AgeIdentity, nil
+}
+
 func ConfigureOperators(
 	gitClient *git.Client,
 	rewriteKey string,
@@ -0,0 +1,155 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// tokenUser is sent with access tokens if the repository URL contains no user.
+// GitHub, GitLab and Gitea ignore the user when the password is an access token
+const tokenUser = "orbos"
+
+// Remote is a parsed repository URL
+type Remote struct {
+	// HTTP is true for http and https URLs and false for SSH URLs
+	HTTP bool
+	// Scheme is https, http or ssh
+	Scheme string
+	User   string
+	// Host includes the port if the URL contains one
+	Host string
+	// Path is the repositories path without leading slash and .git suffix, for example group/subgroup/orb
+	Path string
+}
+
+// ParseRemote parses https, http and ssh URLs as well as the scp-like syntax user@host:path
+func ParseRemote(repoURL string) (*Remote, error) {
+	if repoURL == "" {
+		return nil, errors.New("repository url is empty")
+	}
+
+	if !strings.Contains(repoURL, "://") {
+		at := strings.Index(repoURL, "@")
+		colon := strings.Index(repoURL, ":")
+		if at < 0 || colon < at {
+			return nil, fmt.Errorf("repository url %s is neither an URL nor of the form user@host:path", repoURL)
+		}
+		return &Remote{
+			Scheme: "ssh",
+			User:   repoURL[:at],
+			Host:   repoURL[at+1 : colon],
+			Path:   trimRepoPath(repoURL[colon+1:]),
+		}, nil
+	}
+
+	parsed, err := url.Parse(repoURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing repository url %s failed", repoURL)
+	}
+
+	remote := &Remote{
+		Scheme: parsed.Scheme,
+		User:   parsed.User.Username(),
+		Host:   parsed.Host,
+		Path:   trimRepoPath(parsed.Path),
+	}
+
+	switch parsed.Scheme {
+	case "https", "http":
+		remote.HTTP = true
+	case "ssh":
+	default:
+		return nil, fmt.Errorf("repository url scheme %s is not supported", parsed.Scheme)
+	}
+
+	if remote.Host == "" || remote.Path == "" {
+		return nil, fmt.Errorf("repository url %s has no host or path", repoURL)
+	}
+	return remote, nil
+}
+
+func trimRepoPath(path string) string {
+	return strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+}
+
+// Hostname returns the host without port
+func (r *Remote) Hostname() string {
+	return strings.Split(r.Host, ":")[0]
+}
+
+// authMethod derives the authentication from the repository URL:
+// For https URLs, the repokey is an access token. Plain http URLs are rejected, as they would expose the token.
+// For ssh URLs, the repokey is a private key. Without a repokey, the keys of the running ssh-agent are used
+// and the host key is verified by the known_hosts files
+func authMethod(remote *Remote, repokey []byte) (transport.AuthMethod, error) {
+
+	key := strings.TrimSpace(string(repokey))
+
+	if remote.HTTP {
+		if remote.Scheme != "https" {
+			return nil, errors.New("access tokens are only sent over https, use an https repository url")
+		}
+		if key == "" {
+			return nil, errors.New("an access token is needed for https repository urls")
+		}
+		user := remote.User
+		if user == "" {
+			user = tokenUser
+		}
+		return &githttp.BasicAuth{Username: user, Password: key}, nil
+	}
+
+	user := remote.User
+	if user == "" {
+		user = "git"
+	}
+
+	if key == "" {
+		if os.Getenv("SSH_AUTH_SOCK") == "" {
+			return nil, errors.New("neither a deployment key is configured nor is an ssh-agent running")
+		}
+		agentAuth, err := gitssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, errors.Wrap(err, "connecting to ssh-agent failed")
+		}
+		// The ssh-agent is only used on workstations, so the host is verified by the users known_hosts files
+		hostKeyCallback, err := gitssh.NewKnownHostsCallback()
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading known_hosts files for verifying %s failed, connect to it by ssh once or set SSH_KNOWN_HOSTS", remote.Hostname())
+		}
+		agentAuth.HostKeyCallback = hostKeyCallback
+		return agentAuth, nil
+	}
+
+	signer, err := ssh.ParsePrivateKey(repokey)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing deployment key failed")
+	}
+
+	keyAuth := &gitssh.PublicKeys{
+		User:   user,
+		Signer: signer,
+	}
+
+	// TODO: Fix
+	keyAuth.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	return keyAuth, nil
+}
+
+// ValidateAuth returns an error if the client can't authenticate against the repository with the repokey
+func ValidateAuth(repoURL string, repokey []byte) error {
+	remote, err := ParseRemote(repoURL)
+	if err != nil {
+		return err
+	}
+	_, err = authMethod(remote, repokey)
+	return err
+}
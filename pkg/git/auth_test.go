@@ -0,0 +1,153 @@
+package git
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Remote
+		wantErr bool
+	}{
+		{url: "git@github.com:caos/orbos.git", want: Remote{Scheme: "ssh", User: "git", Host: "github.com", Path: "caos/orbos"}},
+		{url: "ssh://git@gitlab.example.com:2222/group/subgroup/orb.git", want: Remote{Scheme: "ssh", User: "git", Host: "gitlab.example.com:2222", Path: "group/subgroup/orb"}},
+		{url: "https://gitea.example.com/caos/orb", want: Remote{HTTP: true, Scheme: "https", Host: "gitea.example.com", Path: "caos/orb"}},
+		{url: "http://oauth2@localhost:3000/caos/orb.git/", want: Remote{HTTP: true, Scheme: "http", User: "oauth2", Host: "localhost:3000", Path: "caos/orb"}},
+		{url: "", wantErr: true},
+		{url: "github.com/caos/orbos", wantErr: true},
+		{url: "ftp://example.com/orb.git", wantErr: true},
+		{url: "https://example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ParseRemote(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRemote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("ParseRemote() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMethod(t *testing.T) {
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Unsetenv("SSH_AUTH_SOCK")
+
+	https := &Remote{HTTP: true, Scheme: "https", Host: "gitlab.example.com", Path: "caos/orb"}
+	auth, err := authMethod(https, []byte("token\n"))
+	if err != nil {
+		t.Fatalf("authMethod() error = %v", err)
+	}
+	basic, ok := auth.(*githttp.BasicAuth)
+	if !ok || basic.Username != tokenUser || basic.Password != "token" {
+		t.Errorf("authMethod() = %+v, want basic auth with the access token", auth)
+	}
+
+	if _, err := authMethod(https, nil); err == nil {
+		t.Error("authMethod() for https without access token succeeded")
+	}
+
+	plain := &Remote{HTTP: true, Scheme: "http", Host: "localhost:3000", Path: "caos/orb"}
+	if _, err := authMethod(plain, []byte("token")); err == nil {
+		t.Error("authMethod() for plain http with an access token succeeded")
+	}
+
+	ssh := &Remote{Scheme: "ssh", User: "git", Host: "github.com", Path: "caos/orb"}
+	if _, err := authMethod(ssh, nil); err == nil {
+		t.Error("authMethod() for ssh without deployment key and ssh-agent succeeded")
+	}
+
+	if _, err := authMethod(ssh, []byte("no key")); err == nil {
+		t.Error("authMethod() for ssh with an invalid deployment key succeeded")
+	}
+}
+
+func TestAuthMethod_SSHAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orbos-ssh-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	keyring := agent.NewKeyring()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	defer os.Setenv("SSH_KNOWN_HOSTS", os.Getenv("SSH_KNOWN_HOSTS"))
+	os.Setenv("SSH_AUTH_SOCK", socket)
+
+	remote := &Remote{Scheme: "ssh", User: "git", Host: "github.com", Path: "caos/orb"}
+
+	os.Setenv("SSH_KNOWN_HOSTS", filepath.Join(dir, "missing"))
+	if _, err := authMethod(remote, nil); err == nil {
+		t.Error("authMethod() for ssh-agent without known_hosts file succeeded")
+	}
+
+	knownPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	knownKey, err := ssh.NewPublicKey(knownPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownKey, err := ssh.NewPublicKey(unknownPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	knownHosts := filepath.Join(dir, "known_hosts")
+	if err := ioutil.WriteFile(knownHosts, []byte("github.com "+string(ssh.MarshalAuthorizedKey(knownKey))), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("SSH_KNOWN_HOSTS", knownHosts)
+
+	auth, err := authMethod(remote, nil)
+	if err != nil {
+		t.Fatalf("authMethod() error = %v", err)
+	}
+	agentAuth, ok := auth.(*gitssh.PublicKeysCallback)
+	if !ok {
+		t.Fatalf("authMethod() = %+v, want ssh-agent auth", auth)
+	}
+
+	addr := &net.TCPAddr{IP: net.ParseIP("140.82.121.4"), Port: 22}
+	if err := agentAuth.HostKeyCallback("github.com:22", addr, knownKey); err != nil {
+		t.Errorf("known host key is rejected: %v", err)
+	}
+	if err := agentAuth.HostKeyCallback("github.com:22", addr, unknownKey); err == nil {
+		t.Error("unknown host key is accepted")
+	}
+}
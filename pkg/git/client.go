@@ -25,9 +25,8 @@ import (
 	"github.com/go-git/go-billy/v5/memfs"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/storage/memory"
-	"golang.org/x/crypto/ssh"
 )
 
 type DesiredFile string
@@ -51,7 +50,7 @@ type Client struct {
 	ctx       context.Context
 	committer string
 	email     string
-	auth      *gitssh.PublicKeys
+	auth      transport.AuthMethod
 	repo      *gogit.Repository
 	fs        billy.Filesystem
 	storage   *memory.Storage
@@ -81,10 +80,18 @@ func (g *Client) GetURL() string {
 	return g.repoURL
 }
 
-func (g *Client) Configure(repoURL string, deploykey []byte) error {
-	signer, err := ssh.ParsePrivateKey(deploykey)
+// Configure sets the repository and its authentication.
+// The repokey is an access token for https URLs and a private key for ssh URLs.
+// Without a repokey, ssh URLs are authenticated by the running ssh-agent
+func (g *Client) Configure(repoURL string, repokey []byte) error {
+	remote, err := ParseRemote(repoURL)
 	if err != nil {
-		return errors.Wrap(err, "parsing deployment key failed")
+		return err
+	}
+
+	auth, err := authMethod(remote, repokey)
+	if err != nil {
+		return err
 	}
 
 	if repoURL != g.repoURL {
@@ -92,14 +99,7 @@ func (g *Client) Configure(repoURL string, deploykey []byte) error {
 		g.cloned = false
 	}
 	g.monitor = g.monitor.WithField("repository", repoURL)
-
-	g.auth = &gitssh.PublicKeys{
-		User:   "git",
-		Signer: signer,
-	}
-
-	// TODO: Fix
-	g.auth.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+	g.auth = auth
 
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -47,6 +48,9 @@ import (
 
 const TaintKeyPrefix = "node.orbos.ch/"
 
+// UpdatingLabel holds the kubelet version a node is drained for
+const UpdatingLabel = "orbos.ch/updating"
+
 type NodeWithKubeadm interface {
 	Execute(stdin io.Reader, cmd string) ([]byte, error)
 }
@@ -1005,6 +1009,16 @@ func (c *Client) Tainted(node *core.Node, reason DrainReason) bool {
 	return c.tainted(node.Spec.Taints, reason) != -1
 }
 
+// Cordoned returns true if the node is tainted for updating, rebooting or deleting it
+func Cordoned(node *core.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if strings.HasPrefix(taint.Key, TaintKeyPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Client) tainted(taints []core.Taint, reason DrainReason) int {
 
 	for idx, taint := range taints {
@@ -1023,7 +1037,9 @@ func (c *Client) RemoveFromTaints(taints []core.Taint, reason DrainReason) (resu
 	return append(taints[0:idx], taints[idx+1:]...)
 }
 
-func (c *Client) Drain(machine Machine, node *core.Node, reason DrainReason) (err error) {
+// Drain cordons the node and evicts its pods using the eviction API, so PodDisruptionBudgets are respected.
+// The returned report lists the pods that still ran when the drain timed out
+func (c *Client) Drain(machine Machine, node *core.Node, reason DrainReason, opts DrainOptions) (report *DrainReport, err error) {
 	defer func() {
 		err = errors.Wrapf(err, "draining node %s failed", node.GetName())
 	}()
@@ -1033,18 +1049,53 @@ func (c *Client) Drain(machine Machine, node *core.Node, reason DrainReason) (er
 	})
 	monitor.Info("Draining node")
 
+	report = &DrainReport{Node: node.GetName()}
 	if err = c.cordon(node, reason); err != nil {
-		return err
+		return report, err
 	}
 
-	if err := c.evictPods(node); err != nil {
-		return err
+	report.Blocking, err = c.evictPods(node, opts.timeout())
+	if err != nil {
+		return report, err
+	}
+
+	for _, blocking := range report.Blocking {
+		monitor.WithFields(map[string]interface{}{
+			"pod":       blocking.Name,
+			"namespace": blocking.Namespace,
+			"reason":    blocking.Reason,
+			"policy":    opts.onBlocked(),
+		}).Info("Pod blocks drain")
+	}
+
+	if len(report.Blocking) > 0 {
+		switch opts.onBlocked() {
+		case SkipOnBlockedDrain:
+			node.Spec.Taints = c.RemoveFromTaints(node.Spec.Taints, reason)
+			delete(node.Labels, UpdatingLabel)
+			if err := c.UpdateNode(node); err != nil {
+				return report, err
+			}
+			report.Skipped = true
+			monitor.Info("Skipped draining node")
+			return report, nil
+		case ForceOnBlockedDrain:
+			for _, blocking := range report.Blocking {
+				if err := c.DeletePod(blocking.Namespace, blocking.Name); err != nil && !macherrs.IsNotFound(err) {
+					return report, errors.Wrapf(err, "deleting pod %s/%s failed", blocking.Namespace, blocking.Name)
+				}
+			}
+			report.Forced = true
+		default:
+			return report, errors.Errorf("%d pods block the drain", len(report.Blocking))
+		}
 	}
+
 	if !machine.GetUpdating() {
 		machine.SetUpdating(true)
 		monitor.Changed("Node drained")
 	}
-	return nil
+	return report, nil
 }
 
 func (c *Client) DeleteNode(name string) error {
@@ -1055,7 +1106,7 @@ func (c *Client) DeleteNode(name string) error {
 	return err
 }
 
-func (c *Client) evictPods(node *core.Node) (err error) {
+func (c *Client) evictPods(node *core.Node, timeout time.Duration) (blocking []BlockingPod, err error) {
 
 	defer func() {
 		err = errors.Wrapf(err, "evicting pods from node %s failed", node.GetName())
@@ -1072,7 +1123,7 @@ func (c *Client) evictPods(node *core.Node) (err error) {
 		FieldSelector: selector,
 	})
 	if err != nil {
-		return errors.Wrapf(err, "listing pods with selector %s failed", selector)
+		return nil, errors.Wrapf(err, "listing pods with selector %s failed", selector)
 	}
 
 	// --ignore-daemonsets
@@ -1082,80 +1133,114 @@ func (c *Client) evictPods(node *core.Node) (err error) {
 	}, append([]core.Pod{}, podItems.Items...))
 
 	var wg sync.WaitGroup
+	var mux sync.Mutex
 	synchronizer := helpers.NewSynchronizer(&wg)
+	deadline := time.Now().Add(timeout)
 
 	for _, p := range pods {
 		wg.Add(1)
 		go func(pod core.Pod) {
-
-			var gracePeriodSeconds int64 = 60
-			monitor := c.monitor.WithFields(map[string]interface{}{
-				"pod":       pod.GetName(),
-				"namespace": pod.GetNamespace(),
-			})
-			monitor.Debug("Evicting pod")
-
-			watcher, goErr := c.set.CoreV1().Pods(pod.Namespace).Watch(context.Background(), mach.ListOptions{
-				FieldSelector: fmt.Sprintf("metadata.name=%s", pod.Name),
-			})
-			if goErr != nil {
-				synchronizer.Done(nil)
-				return
-			}
-			defer watcher.Stop()
-
-			if goErr := c.set.PolicyV1beta1().Evictions(pod.Namespace).Evict(context.Background(), &policy.Eviction{
-				TypeMeta: mach.TypeMeta{
-					Kind:       "EvictionKind",
-					APIVersion: c.set.PolicyV1beta1().RESTClient().APIVersion().String(),
-				},
-				ObjectMeta: mach.ObjectMeta{
-					Name:      pod.Name,
-					Namespace: pod.Namespace,
-				},
-				DeleteOptions: &mach.DeleteOptions{
-					GracePeriodSeconds: &gracePeriodSeconds,
-				},
-			}); goErr != nil {
-				synchronizer.Done(errors.Wrapf(goErr, "evicting pod %s failed", pod.Name))
-				return
-			}
-			monitor.Debug("Watching pod")
-
-			timeout := time.After(time.Duration(safeUint64(pod.Spec.TerminationGracePeriodSeconds)) + 30)
-			for {
-				select {
-				case event := <-watcher.ResultChan():
-					wPod, ok := event.Object.(*core.Pod)
-					if !ok {
-						continue
-					}
-					monitor = monitor.WithFields(map[string]interface{}{
-						"event": event.Type,
-					})
-					monitor.Debug("Pod event happened")
-					if event.Type == watch.Deleted {
-						monitor.WithFields(map[string]interface{}{
-							"new_node": wPod.Spec.NodeName,
-						}).Debug("Pod evicted")
-						synchronizer.Done(nil)
-						return
-					}
-				case <-timeout:
-					synchronizer.Done(errors.Wrapf(c.set.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, mach.DeleteOptions{}), "Deleting pod %s after timout exceeded failed", pod.Name))
-					return
-				}
+			reason, goErr := c.evictPod(pod, deadline)
+			if reason != "" {
+				mux.Lock()
+				blocking = append(blocking, BlockingPod{Namespace: pod.Namespace, Name: pod.Name, Reason: reason})
+				mux.Unlock()
 			}
+			synchronizer.Done(goErr)
 		}(p)
 	}
 	wg.Wait()
 
 	if synchronizer.IsError() {
-		return errors.Wrapf(synchronizer, "concurrently evicting pods from node %s failed", node.Name)
+		return nil, errors.Wrapf(synchronizer, "concurrently evicting pods from node %s failed", node.Name)
 	}
 
-	monitor.Info("Pods evicted")
-	return nil
+	sort.Slice(blocking, func(i, j int) bool {
+		return blocking[i].Namespace+"/"+blocking[i].Name < blocking[j].Namespace+"/"+blocking[j].Name
+	})
+
+	if len(blocking) == 0 {
+		monitor.Info("Pods evicted")
+	}
+	return blocking, nil
+}
+
+// evictPod retries the eviction while a PodDisruptionBudget doesn't allow it and returns why the pod is still running at the deadline
+func (c *Client) evictPod(pod core.Pod, deadline time.Time) (blockedReason string, err error) {
+
+	var gracePeriodSeconds int64 = 60
+	monitor := c.monitor.WithFields(map[string]interface{}{
+		"pod":       pod.GetName(),
+		"namespace": pod.GetNamespace(),
+	})
+	monitor.Debug("Evicting pod")
+
+	watcher, err := c.set.CoreV1().Pods(pod.Namespace).Watch(context.Background(), mach.ListOptions{
+		FieldSelector: fmt.Sprintf("metadata.name=%s", pod.Name),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "watching pod %s failed", pod.Name)
+	}
+	defer watcher.Stop()
+
+	for {
+		err := c.set.PolicyV1beta1().Evictions(pod.Namespace).Evict(context.Background(), &policy.Eviction{
+			TypeMeta: mach.TypeMeta{
+				Kind:       "EvictionKind",
+				APIVersion: c.set.PolicyV1beta1().RESTClient().APIVersion().String(),
+			},
+			ObjectMeta: mach.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+			DeleteOptions: &mach.DeleteOptions{
+				GracePeriodSeconds: &gracePeriodSeconds,
+			},
+		})
+		if err == nil {
+			break
+		}
+		if macherrs.IsNotFound(err) {
+			return "", nil
+		}
+		// The API server answers with 429 as long as a PodDisruptionBudget doesn't allow the eviction
+		if !macherrs.IsTooManyRequests(err) {
+			return "", errors.Wrapf(err, "evicting pod %s failed", pod.Name)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Sprintf("eviction is not allowed: %s", err.Error()), nil
+		}
+		monitor.WithField("reason", err.Error()).Debug("Eviction is not allowed yet, retrying")
+		time.Sleep(5 * time.Second)
+	}
+	monitor.Debug("Watching pod")
+
+	terminated := deadline
+	if graceful := time.Now().Add(time.Duration(safeUint64(pod.Spec.TerminationGracePeriodSeconds)+30) * time.Second); graceful.After(terminated) {
+		terminated = graceful
+	}
+	timeout := time.After(time.Until(terminated))
+	for {
+		select {
+		case event := <-watcher.ResultChan():
+			wPod, ok := event.Object.(*core.Pod)
+			if !ok {
+				continue
+			}
+			monitor = monitor.WithFields(map[string]interface{}{
+				"event": event.Type,
+			})
+			monitor.Debug("Pod event happened")
+			if event.Type == watch.Deleted {
+				monitor.WithFields(map[string]interface{}{
+					"new_node": wPod.Spec.NodeName,
+				}).Debug("Pod evicted")
+				return "", nil
+			}
+		case <-timeout:
+			return "pod is not terminated in time", nil
+		}
+	}
 }
 
 func safeUint64(ptr *int64) int64 {
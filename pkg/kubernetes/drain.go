@@ -0,0 +1,56 @@
+package kubernetes
+
+import "time"
+
+// BlockedDrainPolicy decides what happens with the pods that still run when a drain times out
+type BlockedDrainPolicy string
+
+const (
+	// WaitOnBlockedDrain fails the drain, so it is retried later
+	WaitOnBlockedDrain BlockedDrainPolicy = "wait"
+	// SkipOnBlockedDrain uncordons the node, so other nodes can be handled first
+	SkipOnBlockedDrain BlockedDrainPolicy = "skip"
+	// ForceOnBlockedDrain deletes the blocking pods regardless of their PodDisruptionBudgets
+	ForceOnBlockedDrain BlockedDrainPolicy = "force"
+)
+
+type DrainOptions struct {
+	// Timeout for evicting all pods of a node, defaults to five minutes
+	Timeout time.Duration
+	// OnBlocked defaults to ForceOnBlockedDrain
+	OnBlocked BlockedDrainPolicy
+}
+
+func (d DrainOptions) timeout() time.Duration {
+	if d.Timeout > 0 {
+		return d.Timeout
+	}
+	return 5 * time.Minute
+}
+
+func (d DrainOptions) onBlocked() BlockedDrainPolicy {
+	if d.OnBlocked == "" {
+		return ForceOnBlockedDrain
+	}
+	return d.OnBlocked
+}
+
+// BlockingPod still ran when a drain timed out
+type BlockingPod struct {
+	Namespace string
+	Name      string
+	Reason    string
+}
+
+func (b BlockingPod) String() string {
+	return b.Namespace + "/" + b.Name + ": " + b.Reason
+}
+
+type DrainReport struct {
+	Node     string
+	Blocking []BlockingPod
+	// Skipped is true if the node was uncordoned because pods blocked the drain
+	Skipped bool
+	// Forced is true if blocking pods were deleted
+	Forced bool
+}
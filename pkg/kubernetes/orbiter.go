@@ -66,6 +66,9 @@ func EnsureOrbiterArtifacts(
 						Ports: []core.ContainerPort{{
 							Name:          "metrics",
 							ContainerPort: 9000,
+						}, {
+							Name:          "nodeagents",
+							ContainerPort: 9001,
 						}},
 						Resources: core.ResourceRequirements{
 							Limits: core.ResourceList{
@@ -134,6 +137,11 @@ func EnsureOrbiterArtifacts(
 				Protocol:   "TCP",
 				Port:       9000,
 				TargetPort: intstr.FromInt(9000),
+			}, {
+				Name:       "nodeagents",
+				Protocol:   "TCP",
+				Port:       9001,
+				TargetPort: intstr.FromInt(9001),
 			}},
 			Selector: k8sPodSelector,
 			Type:     core.ServiceTypeClusterIP,
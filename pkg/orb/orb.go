@@ -6,11 +6,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
 
 	"github.com/caos/orbos/internal/ssh"
-	"github.com/caos/orbos/internal/stores/github"
+	"github.com/caos/orbos/internal/stores"
 	"github.com/caos/orbos/mntr"
 	"github.com/caos/orbos/pkg/git"
+	"github.com/caos/orbos/pkg/kubernetes"
 
 	"github.com/caos/orbos/pkg/secret"
 
@@ -21,10 +26,49 @@ import (
 )
 
 type Orb struct {
-	Path      string `yaml:"-"`
-	URL       string
-	Repokey   string
-	Masterkey string
+	Path        string `yaml:"-"`
+	URL         string
+	Repokey     string
+	Masterkey   string
+	Vault       *secret.Vault `yaml:",omitempty"`
+	AgeIdentity string        `yaml:",omitempty"`
+	SSHIdentity string        `yaml:",omitempty"`
+}
+
+// Identities returns the age identities and the private key at the SSH identity path
+func (o *Orb) Identities() ([]age.Identity, error) {
+	var identities []age.Identity
+	if o.AgeIdentity != "" {
+		ageIdentities, err := age.ParseIdentities(strings.NewReader(o.AgeIdentity))
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, ageIdentities...)
+	}
+
+	if o.SSHIdentity != "" {
+		key, err := ioutil.ReadFile(helpers.PruneHome(o.SSHIdentity))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read ssh identity")
+		}
+		sshIdentity, err := agessh.ParseIdentity(key)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, sshIdentity)
+	}
+	return identities, nil
+}
+
+// ConfigureSecretBackends makes external secrets resolvable and recipient encrypted secrets decryptable.
+// Age encrypted files and the recipients file are read from the repository the git client is configured for.
+// Kubernetes secrets are only resolvable if a client is passed
+func (o *Orb) ConfigureSecretBackends(gitClient *git.Client, k8sClient kubernetes.ClientInt) error {
+	identities, err := o.Identities()
+	if err != nil {
+		return err
+	}
+	return secret.ConfigureBackends(o.Vault, identities, gitClient.Read, k8sClient)
 }
 
 func (o *Orb) IsConnectable() (err error) {
@@ -34,11 +78,12 @@ func (o *Orb) IsConnectable() (err error) {
 		}
 	}()
 	if o.URL == "" {
-		err = helpers.Concat(err, errors.New("repository url is missing"))
+		return helpers.Concat(err, errors.New("repository url is missing"))
 	}
 
-	if o.Repokey == "" {
-		err = helpers.Concat(err, errors.New("repository key is missing"))
+	// ssh urls don't need a repository key if an ssh-agent is running
+	if authErr := git.ValidateAuth(o.URL, []byte(o.Repokey)); authErr != nil {
+		err = helpers.Concat(err, authErr)
 	}
 	return err
 }
@@ -55,8 +100,8 @@ func IsComplete(o *Orb) (err error) {
 		return errors.New("path not provided")
 	}
 
-	if o.Masterkey == "" {
-		err = helpers.Concat(err, errors.New("master key is missing"))
+	if o.Masterkey == "" && o.AgeIdentity == "" && o.SSHIdentity == "" {
+		err = helpers.Concat(err, errors.New("master key or identity is missing"))
 	}
 
 	if o.Path == "" {
@@ -93,7 +138,7 @@ func (o *Orb) writeBackOrbConfig() error {
 	return ioutil.WriteFile(o.Path, data, os.ModePerm)
 }
 
-func Reconfigure(ctx context.Context, monitor mntr.Monitor, orbConfig *Orb, newRepoURL, newMasterKey string, gitClient *git.Client, clientID, clientSecret string) error {
+func Reconfigure(ctx context.Context, monitor mntr.Monitor, orbConfig *Orb, newRepoURL, newMasterKey string, gitClient *git.Client, clientID, clientSecret, storeToken string) error {
 	if orbConfig.URL == "" && newRepoURL == "" {
 		return errors.New("repository url is neighter passed by flag repourl nor written in orbconfig")
 	}
@@ -132,33 +177,44 @@ func Reconfigure(ctx context.Context, monitor mntr.Monitor, orbConfig *Orb, newR
 		return gitClient.Check()
 	}
 
+	remote, err := git.ParseRemote(orbConfig.URL)
+	if err != nil {
+		return err
+	}
+
 	// If the repokey already has read/write permissions, don't generate a new one.
-	// This ensures git providers other than github keep being supported
-	if err := configureGit(); err != nil {
+	// This ensures git providers other than github, gitlab and gitea keep being supported.
+	// An ssh-agent only authenticates orbctl, so the operators still need a deploy key
+	if err := configureGit(); err != nil || (orbConfig.Repokey == "" && !remote.HTTP) {
 
-		monitor.Info("Starting connection with git-repository")
+		// Deploy keys are ssh keys, so an access token without write permissions can't be replaced
+		if remote.HTTP {
+			return fmt.Errorf("access token has no read/write permissions for repository %s: %w", orbConfig.URL, err)
+		}
 
-		dir := filepath.Dir(orbConfig.Path)
+		monitor.Info("Starting connection with git-repository")
 
-		deployKeyPrivLocal, deployKeyPub, err := ssh.Generate()
+		store, err := stores.New(ctx, monitor, remote, &stores.Config{
+			Token:              storeToken,
+			GitHubClientID:     clientID,
+			GitHubClientSecret: clientSecret,
+			CacheDir:           filepath.Dir(orbConfig.Path),
+		})
 		if err != nil {
-			panic(errors.New("failed to generate ssh key for deploy key"))
-		}
-		g := github.New(monitor).LoginOAuth(ctx, dir, clientID, clientSecret)
-		if err := g.GetStatus(); err != nil {
-			return fmt.Errorf("github oauth login failed: %w", err)
+			return err
 		}
-		repo, err := g.GetRepositorySSH(orbConfig.URL)
-		if err != nil {
-			return errors.New("failed to get github repository")
+
+		if err := store.EnsureRepository(remote); err != nil {
+			return fmt.Errorf("failed to ensure repository %s: %w", remote.Path, err)
 		}
 
-		if err := g.EnsureNoDeployKey(repo).GetStatus(); err != nil {
-			monitor.Error(errors.New("failed to clear deploy keys in repository"))
+		deployKeyPrivLocal, deployKeyPub, err := ssh.Generate()
+		if err != nil {
+			return fmt.Errorf("failed to generate ssh key for deploy key: %w", err)
 		}
 
-		if err := g.CreateDeployKey(repo, deployKeyPub).GetStatus(); err != nil {
-			return errors.New("failed to create deploy keys in repository")
+		if err := store.EnsureDeployKey(remote, deployKeyPub); err != nil {
+			return fmt.Errorf("failed to create deploy keys in repository: %w", err)
 		}
 		orbConfig.Repokey = deployKeyPrivLocal
 
@@ -0,0 +1,190 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// LegacyEncryption is AES-256 in CFB mode keyed with the zero padded master key, without any integrity protection
+	LegacyEncryption = "AES256"
+	// AEADEncryption is AES-256 in GCM mode keyed with a scrypt derivation of the master key.
+	// The encrypted value consists of the scrypt salt, the GCM nonce and the sealed data
+	AEADEncryption = "AES256-GCM-SCRYPT"
+	// Base64Encoding is the URL safe base64 encoding of the encrypted value
+	Base64Encoding = "Base64"
+
+	saltSize = 16
+	keySize  = 32
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+)
+
+// aeadMagic prefixes data encrypted by Encrypt, so Decrypt can distinguish it from legacy data
+var aeadMagic = []byte("ORBOSv2\x00")
+
+// derivedKeys caches scrypt derivations, as ORBITER decrypts the same secrets in every iteration.
+// sealingSalts holds one salt per master key, so marshalling many secrets costs a single derivation
+var (
+	derivedKeys  = make(map[string][]byte)
+	sealingSalts = make(map[string][]byte)
+	kdfMux       sync.Mutex
+)
+
+func deriveKey(masterkey string, salt []byte) ([]byte, error) {
+	kdfMux.Lock()
+	defer kdfMux.Unlock()
+
+	cacheKey := masterkey + "\x00" + string(salt)
+	if key, ok := derivedKeys[cacheKey]; ok {
+		return key, nil
+	}
+
+	key, err := scrypt.Key([]byte(masterkey), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	derivedKeys[cacheKey] = key
+	return key, nil
+}
+
+func sealingSalt(masterkey string) ([]byte, error) {
+	kdfMux.Lock()
+	defer kdfMux.Unlock()
+
+	if salt, ok := sealingSalts[masterkey]; ok {
+		return salt, nil
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	sealingSalts[masterkey] = salt
+	return salt, nil
+}
+
+func aead(masterkey string, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(masterkey, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns the salt, the nonce and the encrypted and authenticated plain data
+func seal(masterkey string, plain []byte) ([]byte, error) {
+	masterkey = strings.Trim(masterkey, "\n")
+	if masterkey == "" {
+		return nil, errors.New("Master key must not be empty")
+	}
+
+	salt, err := sealingSalt(masterkey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := aead(masterkey, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, 0, saltSize+len(nonce)+len(plain)+gcm.Overhead())
+	sealed = append(sealed, salt...)
+	sealed = append(sealed, nonce...)
+	return gcm.Seal(sealed, nonce, plain, nil), nil
+}
+
+// open decrypts data sealed by seal and fails if the master key is wrong or the data was tampered with
+func open(masterkey string, sealed []byte) ([]byte, error) {
+	masterkey = strings.Trim(masterkey, "\n")
+	if len(sealed) < saltSize {
+		return nil, errors.New("Ciphertext is too short")
+	}
+
+	gcm, err := aead(masterkey, sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	sealed = sealed[saltSize:]
+	if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("Ciphertext is too short")
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("Decryption failed, the master key is wrong or the secret was modified")
+	}
+	return plain, nil
+}
+
+func legacyKey(masterkey string) ([]byte, error) {
+	if len(masterkey) < 1 || len(masterkey) > 32 {
+		return nil, errors.New("Master key size must be between 1 and 32 characters")
+	}
+
+	key := make([]byte, keySize)
+	for idx, char := range []byte(strings.Trim(masterkey, "\n")) {
+		key[idx] = char
+	}
+	return key, nil
+}
+
+// legacyOpen decrypts data encrypted with the LegacyEncryption.
+// As the data is not authenticated, a wrong master key is only detected by chance
+func legacyOpen(masterkey string, cipherText []byte) ([]byte, error) {
+	key, err := legacyKey(masterkey)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cipherText) < aes.BlockSize {
+		return nil, errors.New("Ciphertext block size is too short")
+	}
+
+	plain := make([]byte, len(cipherText)-aes.BlockSize)
+	cipher.NewCFBDecrypter(block, cipherText[:aes.BlockSize]).XORKeyStream(plain, cipherText[aes.BlockSize:])
+	return plain, nil
+}
+
+// Encrypt encrypts and authenticates arbitrary data using the Masterkey
+func Encrypt(plain []byte) ([]byte, error) {
+	sealed, err := seal(Masterkey, plain)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, aeadMagic...), sealed...), nil
+}
+
+// Decrypt decrypts data which was encrypted using Encrypt, including data encrypted by former versions without authentication
+func Decrypt(cipherText []byte) ([]byte, error) {
+	if bytes.HasPrefix(cipherText, aeadMagic) {
+		return open(Masterkey, cipherText[len(aeadMagic):])
+	}
+	return legacyOpen(Masterkey, cipherText)
+}
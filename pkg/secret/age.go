@@ -0,0 +1,77 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/ssh"
+)
+
+// errNoIdentity is returned if data is not encrypted to any of the identities passed for decryption
+var errNoIdentity = errors.New("no identity matches any of the recipients")
+
+// parseRecipient parses an age1... X25519 recipient or an ssh-ed25519 or ssh-rsa public key in the authorized keys format.
+// The returned id is the canonical encoding of the public key, so equal keys with different comments have equal ids
+func parseRecipient(s string) (recipient age.Recipient, id string, err error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "age1"):
+		x25519, err := age.ParseX25519Recipient(s)
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing recipient %s failed: %w", s, err)
+		}
+		return x25519, x25519.String(), nil
+	case strings.HasPrefix(s, "ssh-"):
+		recipient, err := agessh.ParseRecipient(s)
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing recipient %s failed: %w", s, err)
+		}
+		pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(s))
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing recipient %s failed: %w", s, err)
+		}
+		return recipient, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pk))), nil
+	}
+	return nil, "", fmt.Errorf("parsing recipient %s failed: unknown format", s)
+}
+
+// sealAge encrypts the plain data to all recipients in the binary age format
+func sealAge(plain []byte, recipients ...age.Recipient) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w, err := age.Encrypt(buf, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// openAge decrypts binary or armored age data with whichever of the identities it is encrypted to
+func openAge(sealed []byte, identities ...age.Identity) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(sealed)
+	if trimmed := bytes.TrimSpace(sealed); bytes.HasPrefix(trimmed, []byte(armor.Header)) {
+		src = armor.NewReader(bytes.NewReader(trimmed))
+	}
+
+	r, err := age.Decrypt(src, identities...)
+	var noMatch *age.NoIdentityMatchError
+	if errors.As(err, &noMatch) {
+		return nil, errNoIdentity
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,78 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v3"
+)
+
+type ageFileBackend struct {
+	identities []age.Identity
+	read       func(path string) []byte
+}
+
+func newAgeFileBackend(identities []age.Identity, read func(path string) []byte) (*ageFileBackend, error) {
+	if read == nil {
+		return nil, errors.New("age backend needs a function to read encrypted files")
+	}
+	return &ageFileBackend{identities: identities, read: read}, nil
+}
+
+func (a *ageFileBackend) Resolve(ref *External) (string, error) {
+	encrypted := a.read(ref.Path)
+	if len(encrypted) == 0 {
+		return "", fmt.Errorf("file %s not found", ref.Path)
+	}
+
+	if isSOPS(encrypted) {
+		return a.resolveSOPS(ref, encrypted)
+	}
+
+	plain, err := openAge(encrypted, a.identities...)
+	if err != nil {
+		return "", err
+	}
+
+	if ref.Key == "" {
+		return string(plain), nil
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(plain, &values); err != nil {
+		return "", fmt.Errorf("decrypted file %s is no YAML document: %w", ref.Path, err)
+	}
+	return lookupKey(values, ref.Key)
+}
+
+// resolveSOPS returns the whole decrypted document as YAML if no key is referenced
+func (a *ageFileBackend) resolveSOPS(ref *External, encrypted []byte) (string, error) {
+	document, err := decryptSOPS(encrypted, a.identities...)
+	if err != nil {
+		return "", fmt.Errorf("decrypting sops file %s failed: %w", ref.Path, err)
+	}
+
+	if ref.Key == "" {
+		plain, err := yaml.Marshal(document)
+		return string(plain), err
+	}
+
+	values := make(map[string]interface{})
+	if err := document.Decode(&values); err != nil {
+		return "", fmt.Errorf("decrypted sops file %s is no mapping: %w", ref.Path, err)
+	}
+	return lookupKey(values, ref.Key)
+}
+
+func lookupKey(values map[string]interface{}, key string) (string, error) {
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found", key)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("value at key %s is no string", key)
+	}
+	return str, nil
+}
@@ -0,0 +1,151 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"filippo.io/age"
+
+	"github.com/caos/orbos/pkg/helper"
+	"github.com/caos/orbos/pkg/kubernetes"
+)
+
+const (
+	// VaultBackend resolves secrets from a HashiCorp Vault KV secrets engine of version 1 or 2
+	VaultBackend = "vault"
+	// AgeBackend resolves secrets from files in the orb repository encrypted by age or by SOPS to age recipients
+	AgeBackend = "age"
+	// KubernetesBackend resolves secrets from kubernetes secrets in the caos-system namespace
+	KubernetesBackend = "kubernetes"
+)
+
+// External: Secret which value is stored outside of the desired state
+type External struct {
+	//Backend which stores the value, one of vault, age and kubernetes
+	Backend string `json:"backend" yaml:"backend"`
+	//Path of the value in the backend. Vault: secret path including the mount, age: file path in the orb repository, kubernetes: secret name
+	Path string `json:"path" yaml:"path"`
+	//Key in the secret from where the value should be used. For age and SOPS files, the key selects a top level property and is optional
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+func (e *External) String() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s:%s", e.Backend, e.Path)
+	}
+	return fmt.Sprintf("%s:%s#%s", e.Backend, e.Path, e.Key)
+}
+
+// Backend resolves the values of external secrets
+type Backend interface {
+	Resolve(ref *External) (string, error)
+}
+
+// ErrBackendNotConfigured is returned when an external secret references a supported backend that is not configured
+var ErrBackendNotConfigured = errors.New("secret backend is not configured")
+
+var (
+	backendsMux sync.RWMutex
+	backends    = make(map[string]Backend)
+)
+
+// RegisterBackend makes a backend available for external secrets referencing it by name.
+// A nil backend unregisters the name
+func RegisterBackend(name string, backend Backend) {
+	backendsMux.Lock()
+	defer backendsMux.Unlock()
+	if backend == nil {
+		delete(backends, name)
+		return
+	}
+	backends[name] = backend
+}
+
+// ConfigureBackends registers the vault, age and kubernetes backends and makes recipient encrypted secrets decryptable by the identities.
+// Vault is configured by the passed properties and falls back to the VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE environment variables.
+// The age backend is only registered if identities are passed and the kubernetes backend only if a client is passed.
+// Age encrypted files and the recipients file are read from the orb repository using the read function
+func ConfigureBackends(vault *Vault, identities []age.Identity, read func(path string) []byte, k8sClient kubernetes.ClientInt) error {
+	vaultBackend := newVaultBackend(vault)
+	if vaultBackend == nil {
+		RegisterBackend(VaultBackend, nil)
+	} else {
+		RegisterBackend(VaultBackend, vaultBackend)
+	}
+
+	if helper.IsNil(k8sClient) {
+		RegisterBackend(KubernetesBackend, nil)
+	} else {
+		RegisterBackend(KubernetesBackend, &kubernetesBackend{client: k8sClient})
+	}
+
+	configureIdentities(identities, read)
+
+	if len(identities) == 0 {
+		RegisterBackend(AgeBackend, nil)
+		return nil
+	}
+
+	ageBackend, err := newAgeFileBackend(identities, read)
+	if err != nil {
+		return err
+	}
+	RegisterBackend(AgeBackend, ageBackend)
+	return nil
+}
+
+// validateExternal checks the reference without resolving it
+func validateExternal(ref *External) error {
+	switch ref.Backend {
+	case VaultBackend, AgeBackend, KubernetesBackend:
+	default:
+		return fmt.Errorf("secret backend %s is not supported", ref.Backend)
+	}
+
+	if ref.Path == "" {
+		return fmt.Errorf("external secret %s has no path", ref)
+	}
+	return nil
+}
+
+// resolveExternal returns an error wrapping ErrBackendNotConfigured if the referenced backend is not configured
+func resolveExternal(ref *External) (string, error) {
+	if err := validateExternal(ref); err != nil {
+		return "", err
+	}
+
+	backendsMux.RLock()
+	backend, ok := backends[ref.Backend]
+	backendsMux.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("resolving external secret %s failed: %w. Configure the %s backend in the orbconfig or the environment", ref, ErrBackendNotConfigured, ref.Backend)
+	}
+
+	value, err := backend.Resolve(ref)
+	if err != nil {
+		return "", fmt.Errorf("resolving external secret %s failed: %w", ref, err)
+	}
+	return value, nil
+}
+
+type kubernetesBackend struct {
+	client kubernetes.ClientInt
+}
+
+func (k *kubernetesBackend) Resolve(ref *External) (string, error) {
+	if ref.Key == "" {
+		return "", errors.New("kubernetes secrets need a key")
+	}
+
+	k8sSecret, err := k.client.GetSecret(existingSecretsNamespace, ref.Path)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := k8sSecret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found", ref.Key)
+	}
+	return string(value), nil
+}
@@ -0,0 +1,362 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/golang/mock/gomock"
+	"gopkg.in/yaml.v3"
+	v1 "k8s.io/api/core/v1"
+
+	kubernetesmock "github.com/caos/orbos/pkg/kubernetes/mock"
+)
+
+// fakeVault serves a version 1 KV secrets engine at kv/ and a version 2 KV secrets engine at secret/ like a vault dev server
+func fakeVault(t *testing.T, token string) (*httptest.Server, *int) {
+	requests := 0
+	respond := func(w http.ResponseWriter, status int, body interface{}) {
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != token {
+			respond(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/secret/"):
+			respond(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+				"path": "secret/", "type": "kv", "options": map[string]string{"version": "2"},
+			}})
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/kv/"):
+			respond(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+				"path": "kv/", "type": "kv", "options": nil,
+			}})
+		case path == "secret/data/orbos/hetzner":
+			respond(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+				"data":     map[string]interface{}{"token": "v2 token"},
+				"metadata": map[string]interface{}{"version": 3},
+			}})
+		case path == "kv/orbos/hetzner":
+			respond(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"token": "v1 token"}})
+		default:
+			respond(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestVaultBackend(t *testing.T) {
+	server, requests := fakeVault(t, "root")
+
+	tests := []struct {
+		name    string
+		token   string
+		ref     *External
+		want    string
+		wantErr bool
+	}{{
+		name:  "kv version 2 secrets are resolved",
+		token: "root",
+		ref:   &External{Backend: VaultBackend, Path: "secret/orbos/hetzner", Key: "token"},
+		want:  "v2 token",
+	}, {
+		name:  "kv version 1 secrets are resolved",
+		token: "root",
+		ref:   &External{Backend: VaultBackend, Path: "/kv/orbos/hetzner", Key: "token"},
+		want:  "v1 token",
+	}, {
+		name:    "missing keys are reported",
+		token:   "root",
+		ref:     &External{Backend: VaultBackend, Path: "secret/orbos/hetzner", Key: "password"},
+		wantErr: true,
+	}, {
+		name:    "missing secrets are reported",
+		token:   "root",
+		ref:     &External{Backend: VaultBackend, Path: "secret/orbos/gce", Key: "token"},
+		wantErr: true,
+	}, {
+		name:    "wrong tokens are reported",
+		token:   "wrong",
+		ref:     &External{Backend: VaultBackend, Path: "secret/orbos/hetzner", Key: "token"},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newVaultBackend(&Vault{Address: server.URL, Token: tt.token}).Resolve(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	backend := newVaultBackend(&Vault{Address: server.URL, Token: "root"})
+	ref := &External{Backend: VaultBackend, Path: "secret/orbos/hetzner", Key: "token"}
+	before := *requests
+	for i := 0; i < 3; i++ {
+		if _, err := backend.Resolve(ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *requests-before != 2 {
+		t.Errorf("resolving a secret three times took %d requests, want 2", *requests-before)
+	}
+}
+
+func TestAgeFileBackend(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypt := func(plain string) []byte {
+		buf := new(bytes.Buffer)
+		armored := armor.NewWriter(buf)
+		w, err := age.Encrypt(armored, identity.Recipient())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(plain)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := armored.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	files := map[string][]byte{
+		"secrets/token.age":  encrypt("file token"),
+		"secrets/tokens.age": encrypt("hetzner: yaml token\ngce: other token\n"),
+	}
+	backend, err := newAgeFileBackend([]age.Identity{identity}, func(path string) []byte { return files[path] })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ref     *External
+		want    string
+		wantErr bool
+	}{{
+		name: "whole files are resolved",
+		ref:  &External{Backend: AgeBackend, Path: "secrets/token.age"},
+		want: "file token",
+	}, {
+		name: "yaml keys are resolved",
+		ref:  &External{Backend: AgeBackend, Path: "secrets/tokens.age", Key: "hetzner"},
+		want: "yaml token",
+	}, {
+		name:    "missing keys are reported",
+		ref:     &External{Backend: AgeBackend, Path: "secrets/tokens.age", Key: "vultr"},
+		wantErr: true,
+	}, {
+		name:    "missing files are reported",
+		ref:     &External{Backend: AgeBackend, Path: "secrets/missing.age"},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := backend.Resolve(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgeFileBackend_SOPS(t *testing.T) {
+	// The testdata files are encrypted by sops 3.13.3 to the recipient of this identity
+	identity, err := age.ParseX25519Identity("AGE-SECRET-KEY-1ZE3T4DRUNV8JDKF4EQC9UHSHKQUEHPQNT3GVXQ8P7T94JVDS5JJSSS5HGU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string][]byte)
+	for _, file := range []string{"tokens.sops.yaml", "tokens.sops.json"} {
+		content, err := ioutil.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files["secrets/"+file] = content
+	}
+	files["secrets/tampered.sops.yaml"] = bytes.Replace(files["secrets/tokens.sops.yaml"], []byte("not secret"), []byte("changed"), 1)
+
+	tests := []struct {
+		name         string
+		identities   []age.Identity
+		ref          *External
+		want         string
+		wantContains []string
+		wantErr      bool
+	}{{
+		name:       "yaml keys are resolved",
+		identities: []age.Identity{identity},
+		ref:        &External{Backend: AgeBackend, Path: "secrets/tokens.sops.yaml", Key: "hetzner"},
+		want:       "yaml token",
+	}, {
+		name:       "multi line values are resolved",
+		identities: []age.Identity{identity},
+		ref:        &External{Backend: AgeBackend, Path: "secrets/tokens.sops.yaml", Key: "gce"},
+		want:       "multi\nline\n",
+	}, {
+		name:       "json keys are resolved",
+		identities: []age.Identity{other, identity},
+		ref:        &External{Backend: AgeBackend, Path: "secrets/tokens.sops.json", Key: "token"},
+		want:       "json token",
+	}, {
+		name:         "whole files are resolved without sops metadata",
+		identities:   []age.Identity{identity},
+		ref:          &External{Backend: AgeBackend, Path: "secrets/tokens.sops.yaml"},
+		wantContains: []string{"retries: 3", "enabled: true", "ratio: 1.5", "- second", "description_unencrypted: not secret"},
+	}, {
+		name:       "values which are no strings are reported",
+		identities: []age.Identity{identity},
+		ref:        &External{Backend: AgeBackend, Path: "secrets/tokens.sops.yaml", Key: "retries"},
+		wantErr:    true,
+	}, {
+		name:       "changed unencrypted values are detected",
+		identities: []age.Identity{identity},
+		ref:        &External{Backend: AgeBackend, Path: "secrets/tampered.sops.yaml", Key: "hetzner"},
+		wantErr:    true,
+	}, {
+		name:       "files not encrypted to the identities are reported",
+		identities: []age.Identity{other},
+		ref:        &External{Backend: AgeBackend, Path: "secrets/tokens.sops.yaml", Key: "hetzner"},
+		wantErr:    true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, err := newAgeFileBackend(tt.identities, func(path string) []byte { return files[path] })
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := backend.Resolve(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantContains == nil && got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Resolve() = %q, want it to contain %q", got, want)
+				}
+			}
+			if strings.Contains(got, "ENC[") || strings.Contains(got, sopsMetadataKey+":") {
+				t.Errorf("Resolve() = %q, want no encrypted values and no sops metadata", got)
+			}
+		})
+	}
+}
+
+func TestSecret_External(t *testing.T) {
+	withMasterkey(t, "masterkey")
+	server, requests := fakeVault(t, "root")
+
+	document := []byte(`secret:
+  external:
+    backend: vault
+    path: secret/orbos/hetzner
+    key: token
+`)
+
+	RegisterBackend(VaultBackend, nil)
+	unresolved := &secretHolder{}
+	if err := yaml.Unmarshal(document, unresolved); err != nil {
+		t.Fatalf("unmarshalling without a configured backend should not fail: %v", err)
+	}
+	if unresolved.Secret.Value != "" || unresolved.Secret.IsZero() {
+		t.Errorf("unresolved secret has value %s", unresolved.Secret.Value)
+	}
+	if _, err := unresolved.Secret.Resolve(); !errors.Is(err, ErrBackendNotConfigured) {
+		t.Errorf("resolving a secret without a configured backend returned %v, want %v", err, ErrBackendNotConfigured)
+	}
+
+	if err := ConfigureBackends(&Vault{Address: server.URL, Token: "root"}, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { RegisterBackend(VaultBackend, nil) })
+
+	resolved := &secretHolder{}
+	if err := yaml.Unmarshal(document, resolved); err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Secret.Value != "" || *requests != 0 {
+		t.Errorf("unmarshalling resolved the external secret to %s using %d requests", resolved.Secret.Value, *requests)
+	}
+	if value, err := resolved.Secret.Resolve(); err != nil || value != "v2 token" {
+		t.Errorf("resolved secret has value %s and error %v", value, err)
+	}
+
+	marshalled, err := yaml.Marshal(resolved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(marshalled), "v2 token") {
+		t.Error("external secret is marshalled with its value")
+	}
+	alias := &struct{ Secret secretAlias }{}
+	if err := yaml.Unmarshal(marshalled, alias); err != nil {
+		t.Fatal(err)
+	}
+	if alias.Secret.External == nil || *alias.Secret.External != *resolved.Secret.External || alias.Secret.Encryption != "" {
+		t.Errorf("external secret is marshalled to\n%s", marshalled)
+	}
+
+	if err := yaml.Unmarshal([]byte("secret:\n  external:\n    backend: ssm\n    path: orbos\n"), &secretHolder{}); err == nil {
+		t.Error("unmarshalling secrets of unknown backends should fail")
+	}
+}
+
+func TestKubernetesBackend(t *testing.T) {
+	ref := &External{Backend: KubernetesBackend, Path: "cloudflare", Key: "apikey"}
+
+	if err := ConfigureBackends(nil, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolveExternal(ref); !errors.Is(err, ErrBackendNotConfigured) {
+		t.Errorf("resolving a kubernetes secret without a client returned %v, want %v", err, ErrBackendNotConfigured)
+	}
+
+	client := kubernetesmock.NewMockClientInt(gomock.NewController(t))
+	client.EXPECT().GetSecret(existingSecretsNamespace, "cloudflare").Return(&v1.Secret{
+		Data: map[string][]byte{"apikey": []byte("cloudflare key")},
+	}, nil)
+	if err := ConfigureBackends(nil, nil, nil, client); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { RegisterBackend(KubernetesBackend, nil) })
+
+	if value, err := resolveExternal(ref); err != nil || value != "cloudflare key" {
+		t.Errorf("resolved kubernetes secret to %s and error %v", value, err)
+	}
+}
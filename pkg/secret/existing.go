@@ -0,0 +1,195 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	macherrs "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/caos/orbos/mntr"
+	"github.com/caos/orbos/pkg/helper"
+	"github.com/caos/orbos/pkg/kubernetes"
+)
+
+const (
+	// ExistingOK means the referenced kubernetes secret contains the referenced key
+	ExistingOK = "ok"
+	// ExistingMissingSecret means the referenced kubernetes secret doesn't exist
+	ExistingMissingSecret = "missing secret"
+	// ExistingMissingKey means the referenced kubernetes secret has no value at the referenced key
+	ExistingMissingKey = "missing key"
+	// ExistingIncomplete means the reference has either no name or no key
+	ExistingIncomplete = "incomplete"
+	// ExistingUnreadable means reading the referenced kubernetes secret failed for another reason
+	ExistingUnreadable = "unreadable"
+)
+
+// ExistingStatus is the result of validating a reference to an existing kubernetes secret
+type ExistingStatus struct {
+	Secret string `json:"secret" yaml:"secret"`
+	Key    string `json:"key" yaml:"key"`
+	Status string `json:"status" yaml:"status"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+	// LastChanged is the time the kubernetes secret was last written
+	LastChanged *time.Time `json:"lastChanged,omitempty" yaml:"lastChanged,omitempty"`
+}
+
+func (e *ExistingStatus) OK() bool {
+	return e.Status == ExistingOK
+}
+
+var (
+	existingValid = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "existing_secret_reference_valid",
+			Help: "Whether a referenced existing kubernetes secret in the caos-system namespace contains the referenced key.",
+		},
+		[]string{"operator", "path", "secret", "key"},
+	)
+	existingLastChanged = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "existing_secret_last_changed_timestamp_seconds",
+			Help: "Time a referenced existing kubernetes secret in the caos-system namespace was last written.",
+		},
+		[]string{"operator", "path", "secret", "key"},
+	)
+	reportedMux sync.Mutex
+	reported    = make(map[string][]prometheus.Labels)
+)
+
+func init() {
+	prometheus.MustRegister(existingValid, existingLastChanged)
+}
+
+// ValidateExisting checks all non-empty references to existing kubernetes secrets, including external secrets of the kubernetes backend.
+// If no client is passed, all complete references are reported unreadable
+func ValidateExisting(k8sClient kubernetes.ClientInt, secrets map[string]*Secret, existing map[string]*Existing) map[string]*ExistingStatus {
+
+	getSecret := func(string) (*v1.Secret, error) {
+		return nil, errors.New("no kubernetes client available")
+	}
+	if !helper.IsNil(k8sClient) {
+		getSecret = func(name string) (*v1.Secret, error) {
+			return k8sClient.GetSecret(existingSecretsNamespace, name)
+		}
+	}
+
+	references := make(map[string]*Existing)
+	for path, ref := range existing {
+		if ref != nil && !ref.IsZero() {
+			references[path] = ref
+		}
+	}
+	for path, sec := range secrets {
+		if sec != nil && sec.External != nil && sec.External.Backend == KubernetesBackend {
+			references[path] = &Existing{Name: sec.External.Path, Key: sec.External.Key}
+		}
+	}
+
+	// Secrets referenced multiple times are read only once
+	read := make(map[string]*v1.Secret)
+	readErrs := make(map[string]error)
+	statuses := make(map[string]*ExistingStatus, len(references))
+	for path, ref := range references {
+		status := &ExistingStatus{Secret: ref.Name, Key: ref.Key}
+		statuses[path] = status
+
+		if ref.Name == "" || ref.Key == "" {
+			status.Status = ExistingIncomplete
+			continue
+		}
+
+		k8sSecret, ok := read[ref.Name]
+		err := readErrs[ref.Name]
+		if !ok && err == nil {
+			k8sSecret, err = getSecret(ref.Name)
+			read[ref.Name] = k8sSecret
+			readErrs[ref.Name] = err
+		}
+
+		switch {
+		case macherrs.IsNotFound(err):
+			status.Status = ExistingMissingSecret
+			continue
+		case err != nil:
+			status.Status = ExistingUnreadable
+			status.Error = err.Error()
+			continue
+		}
+
+		changed := lastChanged(k8sSecret)
+		status.LastChanged = &changed
+		status.Status = ExistingOK
+		if value, ok := k8sSecret.Data[ref.Key]; !ok || len(value) == 0 {
+			status.Status = ExistingMissingKey
+		}
+	}
+	return statuses
+}
+
+// lastChanged returns the latest time a field manager wrote the secret or its creation time
+func lastChanged(k8sSecret *v1.Secret) time.Time {
+	changed := k8sSecret.CreationTimestamp.Time
+	for _, field := range k8sSecret.ManagedFields {
+		if field.Time != nil && field.Time.After(changed) {
+			changed = field.Time.Time
+		}
+	}
+	return changed.UTC()
+}
+
+// ReportExisting logs invalid references and exposes all validated references of an operator as metrics.
+// Metrics of references which are not reported anymore are removed
+func ReportExisting(monitor mntr.Monitor, operator string, statuses map[string]*ExistingStatus) {
+
+	reportedMux.Lock()
+	defer reportedMux.Unlock()
+
+	for _, labels := range reported[operator] {
+		existingValid.Delete(labels)
+		existingLastChanged.Delete(labels)
+	}
+
+	paths := make([]string, 0, len(statuses))
+	for path := range statuses {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	current := make([]prometheus.Labels, 0, len(paths))
+	for _, path := range paths {
+		status := statuses[path]
+		labels := prometheus.Labels{
+			"operator": operator,
+			"path":     path,
+			"secret":   status.Secret,
+			"key":      status.Key,
+		}
+		current = append(current, labels)
+
+		valid := 0.0
+		if status.OK() {
+			valid = 1
+		} else {
+			reason := status.Status
+			if status.Error != "" {
+				reason = fmt.Sprintf("%s: %s", reason, status.Error)
+			}
+			monitor.WithFields(map[string]interface{}{
+				"path":   path,
+				"secret": status.Secret,
+				"key":    status.Key,
+			}).Error(fmt.Errorf("existing secret reference is invalid: %s", reason))
+		}
+		existingValid.With(labels).Set(valid)
+		if status.LastChanged != nil {
+			existingLastChanged.With(labels).Set(float64(status.LastChanged.Unix()))
+		}
+	}
+	reported[operator] = current
+}
@@ -0,0 +1,129 @@
+package secret
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	macherrs "k8s.io/apimachinery/pkg/api/errors"
+	mach "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	kubernetesmock "github.com/caos/orbos/pkg/kubernetes/mock"
+)
+
+func TestValidateExisting(t *testing.T) {
+	created := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	updated := created.Add(48 * time.Hour)
+
+	client := kubernetesmock.NewMockClientInt(gomock.NewController(t))
+	client.EXPECT().GetSecret(existingSecretsNamespace, "grafana").Return(&v1.Secret{
+		ObjectMeta: mach.ObjectMeta{
+			CreationTimestamp: mach.NewTime(created),
+			ManagedFields: []mach.ManagedFieldsEntry{
+				{Time: &mach.Time{Time: created}},
+				{Time: &mach.Time{Time: updated}},
+			},
+		},
+		Data: map[string][]byte{"username": []byte("admin"), "password": {}},
+	}, nil).Times(1)
+	client.EXPECT().GetSecret(existingSecretsNamespace, "argocd").Return(nil, macherrs.NewNotFound(schema.GroupResource{Resource: "secrets"}, "argocd"))
+	client.EXPECT().GetSecret(existingSecretsNamespace, "cloudflare").Return(nil, errors.New("forbidden"))
+
+	statuses := ValidateExisting(client, map[string]*Secret{
+		"cloudflare.apikey": {External: &External{Backend: KubernetesBackend, Path: "cloudflare", Key: "apikey"}},
+		"vault.token":       {External: &External{Backend: VaultBackend, Path: "kv/token"}},
+		"encrypted":         {Value: "value"},
+	}, map[string]*Existing{
+		"grafana.username": {Name: "grafana", Key: "username"},
+		"grafana.password": {Name: "grafana", Key: "password"},
+		"grafana.token":    {Name: "grafana", Key: "token"},
+		"argocd.password":  {Name: "argocd", Key: "password"},
+		"argocd.username":  {Name: "argocd"},
+		"unused":           {},
+	})
+
+	tests := []struct {
+		path        string
+		want        string
+		lastChanged *time.Time
+	}{
+		{path: "grafana.username", want: ExistingOK, lastChanged: &updated},
+		{path: "grafana.password", want: ExistingMissingKey, lastChanged: &updated},
+		{path: "grafana.token", want: ExistingMissingKey, lastChanged: &updated},
+		{path: "argocd.password", want: ExistingMissingSecret},
+		{path: "argocd.username", want: ExistingIncomplete},
+		{path: "cloudflare.apikey", want: ExistingUnreadable},
+	}
+	if len(statuses) != len(tests) {
+		t.Errorf("ValidateExisting() validated %d references, want %d", len(statuses), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			status, ok := statuses[tt.path]
+			if !ok {
+				t.Fatal("reference is not validated")
+			}
+			if status.Status != tt.want {
+				t.Errorf("status = %s, want %s", status.Status, tt.want)
+			}
+			if (status.LastChanged == nil) != (tt.lastChanged == nil) ||
+				status.LastChanged != nil && !status.LastChanged.Equal(*tt.lastChanged) {
+				t.Errorf("last changed = %v, want %v", status.LastChanged, tt.lastChanged)
+			}
+		})
+	}
+}
+
+func TestValidateExisting_NoClient(t *testing.T) {
+	statuses := ValidateExisting(nil, nil, map[string]*Existing{
+		"grafana.username": {Name: "grafana", Key: "username"},
+		"argocd.username":  {Name: "argocd"},
+	})
+	if status := statuses["grafana.username"]; status == nil || status.Status != ExistingUnreadable {
+		t.Errorf("complete reference status = %v, want %s", status, ExistingUnreadable)
+	}
+	if status := statuses["argocd.username"]; status == nil || status.Status != ExistingIncomplete {
+		t.Errorf("incomplete reference status = %v, want %s", status, ExistingIncomplete)
+	}
+}
+
+func TestSources(t *testing.T) {
+	sources := Sources(map[string]*Secret{
+		"orbiter.gce.jsonkey.encrypted":             {Value: "key", Groups: []string{"infra", "operators"}},
+		"orbiter.k8s.kubeconfig.encrypted":          {Value: "kubeconfig"},
+		"boom.grafana.admin.password.encrypted":     {External: &External{Backend: VaultBackend, Path: "secret/grafana", Key: "password"}},
+		"networking.cloudflare.apikey.encrypted":    {},
+		"boom.argocd.sso.github.clientid.encrypted": nil,
+	}, map[string]*Existing{
+		"boom.grafana.admin.password.existing":  {Name: "grafana", Key: "password"},
+		"networking.cloudflare.apikey.existing": {},
+	}, map[string]*ExistingStatus{
+		"boom.grafana.admin.password.existing": {Secret: "grafana", Key: "password", Status: ExistingMissingKey},
+	})
+
+	want := []Source{
+		{Path: "boom.argocd.sso.github.clientid.encrypted", Source: UnsetSource},
+		{Path: "boom.grafana.admin.password.encrypted", Source: ExternalSource, From: "vault:secret/grafana#password"},
+		{Path: "boom.grafana.admin.password.existing", Source: ExistingSource, From: "caos-system/grafana#password"},
+		{Path: "networking.cloudflare.apikey.encrypted", Source: UnsetSource},
+		{Path: "networking.cloudflare.apikey.existing", Source: UnsetSource},
+		{Path: "orbiter.gce.jsonkey.encrypted", Source: RecipientsSource, From: "infra,operators"},
+		{Path: "orbiter.k8s.kubeconfig.encrypted", Source: MasterkeySource},
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("Sources() returned %d sources, want %d", len(sources), len(want))
+	}
+	for i, source := range sources {
+		got := *source
+		got.Status = nil
+		if got != want[i] {
+			t.Errorf("Sources()[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if status := sources[2].Status; status == nil || status.Status != ExistingMissingKey {
+		t.Errorf("Sources() didn't attach the status of the existing reference")
+	}
+}
@@ -7,20 +7,28 @@ import (
 )
 
 func IsExistentSecret(secret *secret.Secret, existent *secret.Existing) bool {
-	if (secret == nil || secret.Value == "") && existent != nil && (existent.Name != "" && existent.Key != "") {
+	if (secret == nil || secret.Value == "" && secret.External == nil) && existent != nil && (existent.Name != "" && existent.Key != "") {
 		return true
 	}
 	return false
 }
 
 func IsCrdSecret(secret *secret.Secret, existent *secret.Existing) bool {
-	if (secret != nil && secret.Value != "") && (existent == nil || (existent.Name == "" || existent.Key == "")) {
+	if secret.HasValue() && (existent == nil || (existent.Name == "" || existent.Key == "")) {
 		return true
 	}
 	return false
 }
 
+func IsExternalSecret(secret *secret.Secret) bool {
+	return secret != nil && secret.External != nil
+}
+
 func GetSecretValueOnlyIncluster(secret *secret.Secret, existing *secret.Existing) (string, error) {
+	if IsExternalSecret(secret) {
+		return secret.Resolve()
+	}
+
 	if IsExistentSecret(secret, existing) {
 		secret, err := clientgo.GetSecret(existing.Name, "caos-system")
 		if err != nil {
@@ -29,13 +37,17 @@ func GetSecretValueOnlyIncluster(secret *secret.Secret, existing *secret.Existin
 
 		return string(secret.Data[existing.Key]), nil
 	} else if IsCrdSecret(secret, existing) {
-		return secret.Value, nil
+		return secret.Resolve()
 	}
 
 	return "", nil
 }
 
 func GetSecretValue(k8sClient kubernetes.ClientInt, secret *secret.Secret, existing *secret.Existing) (string, error) {
+	if IsExternalSecret(secret) {
+		return secret.Resolve()
+	}
+
 	if IsExistentSecret(secret, existing) {
 		secret, err := k8sClient.GetSecret("caos-system", existing.Name)
 		if err != nil {
@@ -44,7 +56,7 @@ func GetSecretValue(k8sClient kubernetes.ClientInt, secret *secret.Secret, exist
 
 		return string(secret.Data[existing.Key]), nil
 	} else if IsCrdSecret(secret, existing) {
-		return secret.Value, nil
+		return secret.Resolve()
 	}
 
 	return "", nil
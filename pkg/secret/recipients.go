@@ -0,0 +1,262 @@
+package secret
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/pkg/git"
+)
+
+const (
+	// AgeEncryption is the age format encrypted to all recipients of the secrets groups
+	AgeEncryption = "AGE"
+	// RecipientsFile declares the recipient groups in the orb repository
+	RecipientsFile git.DesiredFile = "recipients.yml"
+)
+
+var (
+	recipientsMux  sync.RWMutex
+	identities     []age.Identity
+	readRepository func(path string) []byte
+)
+
+// Recipients: Groups of public keys secrets are encrypted to instead of the master key
+type Recipients struct {
+	//Groups of age1... X25519 recipients and ssh-ed25519 or ssh-rsa public keys in the authorized keys format
+	Groups map[string][]string `json:"groups" yaml:"groups"`
+	//Groups every recipient encrypted secret is additionally encrypted to, for example the operators
+	Always []string `json:"always,omitempty" yaml:"always,omitempty"`
+}
+
+// ParseRecipients parses the content of a recipients file
+func ParseRecipients(content []byte) (*Recipients, error) {
+	r := &Recipients{}
+	if err := yaml.Unmarshal(content, r); err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %w", RecipientsFile, err)
+	}
+	if r.Groups == nil {
+		r.Groups = make(map[string][]string)
+	}
+
+	for group, keys := range r.Groups {
+		for _, key := range keys {
+			if _, _, err := parseRecipient(key); err != nil {
+				return nil, fmt.Errorf("recipient group %s is invalid: %w", group, err)
+			}
+		}
+	}
+	for _, group := range r.Always {
+		if _, ok := r.Groups[group]; !ok {
+			return nil, fmt.Errorf("recipient group %s is not declared", group)
+		}
+	}
+	return r, nil
+}
+
+// ReadRecipients reads the recipients file from the repository the git client is configured for.
+// If the file doesn't exist, no recipients are declared
+func ReadRecipients(gitClient *git.Client) (*Recipients, error) {
+	return ParseRecipients(gitClient.Read(string(RecipientsFile)))
+}
+
+func currentRecipients() (*Recipients, error) {
+	recipientsMux.RLock()
+	read := readRepository
+	recipientsMux.RUnlock()
+
+	if read == nil {
+		return nil, errors.New("recipients are not configured")
+	}
+	return ParseRecipients(read(string(RecipientsFile)))
+}
+
+func configureIdentities(ids []age.Identity, read func(path string) []byte) {
+	recipientsMux.Lock()
+	defer recipientsMux.Unlock()
+	identities = ids
+	readRepository = read
+}
+
+func (r *Recipients) Marshal() ([]byte, error) {
+	return yaml.Marshal(r)
+}
+
+// Add adds the public keys to a group, creating the group if it doesn't exist yet.
+// It returns the number of keys that were not already members of the group
+func (r *Recipients) Add(group string, keys ...string) (int, error) {
+	members, err := r.members(group)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, key := range keys {
+		_, id, err := parseRecipient(key)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := members[id]; ok {
+			continue
+		}
+		members[id] = struct{}{}
+		r.Groups[group] = append(r.Groups[group], key)
+		added++
+	}
+	return added, nil
+}
+
+// Remove removes the public keys from a group and deletes the group if it has no members anymore.
+// It returns the number of removed keys
+func (r *Recipients) Remove(group string, keys ...string) (int, error) {
+	if _, ok := r.Groups[group]; !ok {
+		return 0, fmt.Errorf("recipient group %s is not declared", group)
+	}
+
+	remove := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		_, id, err := parseRecipient(key)
+		if err != nil {
+			return 0, err
+		}
+		remove[id] = struct{}{}
+	}
+
+	var kept []string
+	for _, member := range r.Groups[group] {
+		_, id, err := parseRecipient(member)
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := remove[id]; !ok {
+			kept = append(kept, member)
+		}
+	}
+
+	removed := len(r.Groups[group]) - len(kept)
+	r.Groups[group] = kept
+	if len(kept) == 0 {
+		delete(r.Groups, group)
+		for idx, always := range r.Always {
+			if always == group {
+				r.Always = append(r.Always[:idx], r.Always[idx+1:]...)
+				break
+			}
+		}
+	}
+	return removed, nil
+}
+
+// Includes returns true if the secret groups include the group directly or by the always encrypted to groups
+func (r *Recipients) Includes(secretGroups []string, group string) bool {
+	for _, g := range append(append([]string{}, r.Always...), secretGroups...) {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recipients) members(group string) (map[string]struct{}, error) {
+	if r.Groups == nil {
+		r.Groups = make(map[string][]string)
+	}
+	members := make(map[string]struct{})
+	for _, member := range r.Groups[group] {
+		_, id, err := parseRecipient(member)
+		if err != nil {
+			return nil, err
+		}
+		members[id] = struct{}{}
+	}
+	return members, nil
+}
+
+// resolve returns the distinct public keys of the groups and the always encrypted to groups
+func (r *Recipients) resolve(groups []string) ([]age.Recipient, error) {
+	var (
+		recipients []age.Recipient
+		seen       = make(map[string]struct{})
+	)
+	for _, group := range append(append([]string{}, r.Always...), groups...) {
+		members, ok := r.Groups[group]
+		if !ok {
+			return nil, fmt.Errorf("recipient group %s is not declared in %s", group, RecipientsFile)
+		}
+		for _, member := range members {
+			recipient, id, err := parseRecipient(member)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			recipients = append(recipients, recipient)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("recipient groups %v have no members", groups)
+	}
+	return recipients, nil
+}
+
+// List returns all group names sorted alphabetically
+func (r *Recipients) List() []string {
+	groups := make([]string, 0, len(r.Groups))
+	for group := range r.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+func sealForRecipients(recipients *Recipients, groups []string, plain string) (*secretAlias, error) {
+	ageRecipients, err := recipients.resolve(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := sealAge([]byte(plain), ageRecipients...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretAlias{
+		Encryption: AgeEncryption,
+		Encoding:   Base64Encoding,
+		Value:      base64.URLEncoding.EncodeToString(sealed),
+		Groups:     groups,
+	}, nil
+}
+
+// openForRecipient returns an empty value without an error if no identity is configured or
+// if the value is not encrypted to any of the configured identities
+func openForRecipient(value string) (string, error) {
+	recipientsMux.RLock()
+	ids := identities
+	recipientsMux.RUnlock()
+
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	sealed, err := base64.URLEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := openAge(sealed, ids...)
+	if errors.Is(err, errNoIdentity) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
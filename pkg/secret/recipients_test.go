@@ -0,0 +1,208 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v3"
+)
+
+type team struct {
+	identity  *age.X25519Identity
+	recipient string
+}
+
+func newTeam(t *testing.T) team {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return team{identity: identity, recipient: identity.Recipient().String()}
+}
+
+// withRecipients configures the identities and a repository that only contains the recipients file
+func withRecipients(t *testing.T, recipients *Recipients, identities ...age.Identity) {
+	content, err := recipients.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ConfigureBackends(nil, identities, func(path string) []byte {
+		if path == string(RecipientsFile) {
+			return content
+		}
+		return nil
+	}, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { configureIdentities(nil, nil) })
+}
+
+func TestRecipients_AddRemove(t *testing.T) {
+	alice, bob := newTeam(t), newTeam(t)
+	recipients := &Recipients{}
+
+	added, err := recipients.Add("monitoring", alice.recipient, bob.recipient, alice.recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Errorf("Add() added %d recipients, want 2", added)
+	}
+	if _, err := recipients.Add("monitoring", "not a key"); err == nil {
+		t.Error("Add() should reject invalid keys")
+	}
+	recipients.Always = []string{"monitoring"}
+
+	parsed, err := recipients.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipients, err = ParseRecipients(parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := recipients.Remove("monitoring", alice.recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || len(recipients.Groups["monitoring"]) != 1 {
+		t.Errorf("Remove() removed %d recipients, %d are left", removed, len(recipients.Groups["monitoring"]))
+	}
+
+	if _, err := recipients.Remove("monitoring", bob.recipient); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := recipients.Groups["monitoring"]; ok || len(recipients.Always) != 0 {
+		t.Error("Remove() should delete empty groups")
+	}
+
+	if _, err := ParseRecipients([]byte("groups:\n  infra: []\nalways: [operators]\n")); err == nil {
+		t.Error("ParseRecipients() should reject undeclared always groups")
+	}
+}
+
+func TestSecret_Groups(t *testing.T) {
+	withMasterkey(t, "masterkey")
+	infra, monitoring, operators := newTeam(t), newTeam(t), newTeam(t)
+	recipients := &Recipients{
+		Groups: map[string][]string{
+			"infra":      {infra.recipient},
+			"monitoring": {monitoring.recipient},
+			"operators":  {operators.recipient},
+		},
+		Always: []string{"operators"},
+	}
+
+	withRecipients(t, recipients, infra.identity)
+	marshalled, err := yaml.Marshal(&struct{ GCE, Grafana *Secret }{
+		GCE:     &Secret{Value: "gce key", Groups: []string{"infra"}},
+		Grafana: &Secret{Value: "grafana secret", Groups: []string{"monitoring"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(marshalled), "gce key") || strings.Contains(string(marshalled), "grafana secret") {
+		t.Fatal("secrets are marshalled in plain text")
+	}
+
+	tests := []struct {
+		name        string
+		identities  []age.Identity
+		wantGCE     string
+		wantGrafana string
+	}{{
+		name:       "groups members decrypt their secrets only",
+		identities: []age.Identity{infra.identity},
+		wantGCE:    "gce key",
+	}, {
+		name:        "other groups members decrypt their secrets only",
+		identities:  []age.Identity{monitoring.identity},
+		wantGrafana: "grafana secret",
+	}, {
+		name:        "always encrypted to groups members decrypt all secrets",
+		identities:  []age.Identity{operators.identity},
+		wantGCE:     "gce key",
+		wantGrafana: "grafana secret",
+	}, {
+		name:        "all configured identities are tried",
+		identities:  []age.Identity{monitoring.identity, infra.identity},
+		wantGCE:     "gce key",
+		wantGrafana: "grafana secret",
+	}, {
+		name: "secrets stay encrypted without identity",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRecipients(t, recipients, tt.identities...)
+
+			parsed := &struct{ GCE, Grafana *Secret }{}
+			if err := yaml.Unmarshal(marshalled, parsed); err != nil {
+				t.Fatal(err)
+			}
+			if parsed.GCE.Value != tt.wantGCE || parsed.Grafana.Value != tt.wantGrafana {
+				t.Errorf("decrypted %q and %q, want %q and %q", parsed.GCE.Value, parsed.Grafana.Value, tt.wantGCE, tt.wantGrafana)
+			}
+
+			// Undecryptable secrets are written back unchanged
+			remarshalled, err := yaml.Marshal(parsed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			withRecipients(t, recipients, operators.identity)
+			reparsed := &struct{ GCE, Grafana *Secret }{}
+			if err := yaml.Unmarshal(remarshalled, reparsed); err != nil {
+				t.Fatal(err)
+			}
+			if reparsed.GCE.Value != "gce key" || reparsed.Grafana.Value != "grafana secret" {
+				t.Errorf("secrets changed to %q and %q after writing them back", reparsed.GCE.Value, reparsed.Grafana.Value)
+			}
+		})
+	}
+}
+
+func TestReencryptRecipients(t *testing.T) {
+	withMasterkey(t, "masterkey")
+	infra, monitoring, newcomer := newTeam(t), newTeam(t), newTeam(t)
+	recipients := &Recipients{Groups: map[string][]string{
+		"infra":      {infra.recipient},
+		"monitoring": {monitoring.recipient},
+	}}
+
+	withRecipients(t, recipients, infra.identity)
+	document, err := yaml.Marshal(&struct{ GCE, Grafana, Legacy *Secret }{
+		GCE:     &Secret{Value: "gce key", Groups: []string{"infra"}},
+		Grafana: &Secret{Value: "grafana secret", Groups: []string{"monitoring"}},
+		Legacy:  &Secret{Value: "masterkey secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := recipients.Add("infra", newcomer.recipient); err != nil {
+		t.Fatal(err)
+	}
+	affectsInfra := func(groups []string) bool { return recipients.Includes(groups, "infra") }
+
+	reencrypted, count, err := ReencryptRecipients(document, recipients, affectsInfra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("ReencryptRecipients() reencrypted %d secrets, want 1", count)
+	}
+
+	withRecipients(t, recipients, newcomer.identity)
+	parsed := &struct{ GCE, Grafana, Legacy *Secret }{}
+	if err := yaml.Unmarshal(reencrypted, parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.GCE.Value != "gce key" || parsed.Grafana.Value != "" || parsed.Legacy.Value != "masterkey secret" {
+		t.Errorf("newcomer decrypted %q, %q and %q", parsed.GCE.Value, parsed.Grafana.Value, parsed.Legacy.Value)
+	}
+
+	if _, _, err := ReencryptRecipients(document, recipients, func([]string) bool { return true }); err == nil {
+		t.Error("ReencryptRecipients() should fail for secrets that are not encrypted to the identities")
+	}
+}
@@ -0,0 +1,254 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/caos/orbos/pkg/git"
+)
+
+// RotateMasterkey reencrypts all secrets in the given desired state files with the new master key.
+// The files are returned instead of pushed, so the new master key can be distributed first
+func RotateMasterkey(gitClient *git.Client, oldMasterkey, newMasterkey string, files ...git.DesiredFile) ([]git.File, int, error) {
+
+	var (
+		gitFiles []git.File
+		rotated  int
+	)
+	for _, file := range files {
+		if !gitClient.Exists(file) {
+			continue
+		}
+
+		content, count, err := Reencrypt(gitClient.Read(string(file)), oldMasterkey, newMasterkey)
+		if err != nil {
+			return nil, 0, fmt.Errorf("reencrypting secrets in %s failed: %w", file, err)
+		}
+		rotated += count
+		gitFiles = append(gitFiles, git.File{
+			Path:    string(file),
+			Content: content,
+		})
+	}
+
+	return gitFiles, rotated, nil
+}
+
+// Reencrypt decrypts all secrets in a YAML document with the old master key and encrypts them with the new master key.
+// Secrets are recognized by their encryption, encoding and value fields, so the document doesn't need to be parsed by its operator
+func Reencrypt(document []byte, oldMasterkey, newMasterkey string) ([]byte, int, error) {
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(document, root); err != nil {
+		return nil, 0, err
+	}
+
+	count, err := reencryptNode(root, oldMasterkey, newMasterkey)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	encoded, err := encode(root)
+	return encoded, count, err
+}
+
+func encode(root *yaml.Node) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func reencryptNode(node *yaml.Node, oldMasterkey, newMasterkey string) (int, error) {
+
+	if fields := secretFields(node); fields != nil {
+		decrypted, err := decrypt(oldMasterkey, &Secret{
+			Encryption: fields["encryption"].Value,
+			Encoding:   fields["encoding"].Value,
+			Value:      fields["value"].Value,
+		})
+		if err != nil {
+			return 0, err
+		}
+		encrypted, err := encrypt(newMasterkey, decrypted)
+		if err != nil {
+			return 0, err
+		}
+		fields["encryption"].Value = encrypted.Encryption
+		fields["encoding"].Value = encrypted.Encoding
+		fields["value"].Value = encrypted.Value
+		return 1, nil
+	}
+
+	count := 0
+	for _, child := range node.Content {
+		childCount, err := reencryptNode(child, oldMasterkey, newMasterkey)
+		if err != nil {
+			return 0, err
+		}
+		count += childCount
+	}
+	return count, nil
+}
+
+// secretFields returns the value nodes of the encryption, encoding and value fields if the node is an encrypted secret
+func secretFields(node *yaml.Node) map[string]*yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	fields := make(map[string]*yaml.Node)
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key, value := node.Content[idx], node.Content[idx+1]
+		switch key.Value {
+		case "encryption", "encoding", "value":
+			if value.Kind != yaml.ScalarNode {
+				return nil
+			}
+			fields[key.Value] = value
+		}
+	}
+
+	if len(fields) != 3 || fields["value"].Value == "" {
+		return nil
+	}
+
+	switch fields["encryption"].Value {
+	case LegacyEncryption, AEADEncryption:
+		return fields
+	}
+	return nil
+}
+
+// UpdateRecipients reencrypts all recipient encrypted secrets in the given desired state files which groups are affected to the members of their groups.
+// The secrets are pushed together with the recipients file in a single commit
+func UpdateRecipients(gitClient *git.Client, recipients *Recipients, msg string, affected func(groups []string) bool, files ...git.DesiredFile) (int, error) {
+
+	content, err := recipients.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		gitFiles = []git.File{{Path: string(RecipientsFile), Content: content}}
+		updated  int
+	)
+	for _, file := range files {
+		if !gitClient.Exists(file) {
+			continue
+		}
+
+		content, count, err := ReencryptRecipients(gitClient.Read(string(file)), recipients, affected)
+		if err != nil {
+			return 0, fmt.Errorf("reencrypting secrets in %s failed: %w", file, err)
+		}
+		updated += count
+		gitFiles = append(gitFiles, git.File{
+			Path:    string(file),
+			Content: content,
+		})
+	}
+
+	return updated, gitClient.UpdateRemote(msg, gitFiles...)
+}
+
+// ReencryptRecipients decrypts all recipient encrypted secrets in a YAML document which groups are affected with the configured identities
+// and encrypts them to the current members of their groups
+func ReencryptRecipients(document []byte, recipients *Recipients, affected func(groups []string) bool) ([]byte, int, error) {
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(document, root); err != nil {
+		return nil, 0, err
+	}
+
+	count, err := reencryptRecipientsNode(root, recipients, affected)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	encoded, err := encode(root)
+	return encoded, count, err
+}
+
+func reencryptRecipientsNode(node *yaml.Node, recipients *Recipients, affected func(groups []string) bool) (int, error) {
+
+	if fields, groups := recipientSecretFields(node); fields != nil {
+		if !affected(groups) {
+			return 0, nil
+		}
+
+		recipientsMux.RLock()
+		ids := identities
+		recipientsMux.RUnlock()
+
+		sealed, err := base64.URLEncoding.DecodeString(fields["value"].Value)
+		if err != nil {
+			return 0, err
+		}
+		decrypted, err := openAge(sealed, ids...)
+		if errors.Is(err, errNoIdentity) {
+			return 0, fmt.Errorf("the secret at line %d is not encrypted to any of your identities", node.Line)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		encrypted, err := sealForRecipients(recipients, groups, string(decrypted))
+		if err != nil {
+			return 0, fmt.Errorf("encrypting the secret at line %d failed: %w", node.Line, err)
+		}
+		fields["value"].Value = encrypted.Value
+		return 1, nil
+	}
+
+	count := 0
+	for _, child := range node.Content {
+		childCount, err := reencryptRecipientsNode(child, recipients, affected)
+		if err != nil {
+			return 0, err
+		}
+		count += childCount
+	}
+	return count, nil
+}
+
+// recipientSecretFields returns the value nodes of the encryption, encoding and value fields and the groups if the node is a recipient encrypted secret
+func recipientSecretFields(node *yaml.Node) (map[string]*yaml.Node, []string) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var (
+		fields = make(map[string]*yaml.Node)
+		groups []string
+	)
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		key, value := node.Content[idx], node.Content[idx+1]
+		switch key.Value {
+		case "encryption", "encoding", "value":
+			if value.Kind != yaml.ScalarNode {
+				return nil, nil
+			}
+			fields[key.Value] = value
+		case "groups":
+			if err := value.Decode(&groups); err != nil {
+				return nil, nil
+			}
+		}
+	}
+
+	if len(fields) != 3 || fields["value"].Value == "" || fields["encryption"].Value != AgeEncryption {
+		return nil, nil
+	}
+	return fields, groups
+}
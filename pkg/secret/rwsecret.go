@@ -62,6 +62,12 @@ func Read(
 
 	switch secretType := secret.(type) {
 	case *Secret:
+		if secretType.Value == "" && secretType.External != nil {
+			return "", fmt.Errorf("secret %s references %s, but the %s backend is not configured", path, secretType.External, secretType.External.Backend)
+		}
+		if secretType.Value == "" && secretType.undecrypted != nil {
+			return "", fmt.Errorf("secret %s is not encrypted to any of your identities, it is readable by the recipient groups %s", path, strings.Join(secretType.Groups, ", "))
+		}
 		if secretType.Value == "" {
 			return "", fmt.Errorf("secret %s is empty", path)
 		}
@@ -99,11 +105,13 @@ func Rewrite(
 	return pushFunc()
 }
 
+// Write encrypts the value with the master key or, if groups are passed or the secret already has groups, to the members of the recipient groups
 func Write(
 	monitor mntr.Monitor,
 	k8sClient kubernetes.ClientInt,
 	path,
-	value,
+	value string,
+	groups []string,
 	writtenByCLI,
 	writtenByVersion string,
 	getFunc GetFuncs,
@@ -125,12 +133,21 @@ func Write(
 
 	switch secretType := secret.(type) {
 	case *Secret:
-		if secretType.Value == value {
+		if secretType.External != nil {
+			return fmt.Errorf("secret %s references %s, please change the value in the %s backend", path, secretType.External, secretType.External.Backend)
+		}
+		if secretType.Value == value && (len(groups) == 0 || equalGroups(secretType.Groups, groups)) {
 			monitor.Info("Value is unchanged")
 			return nil
 		}
 		secretType.Value = value
+		if len(groups) > 0 {
+			secretType.Groups = groups
+		}
 	case *Existing:
+		if len(groups) > 0 {
+			return fmt.Errorf("secret %s is a kubernetes secret reference, recipient groups are only supported for encrypted secrets", path)
+		}
 		var refChanged bool
 		if secretType.Name == "" {
 			secretType.Name = strings.ReplaceAll(path, ".", "-")
@@ -247,7 +264,7 @@ func secretsListToSlice(
 ) []string {
 	items := make([]string, 0, len(secrets)+len(existing))
 	for key, value := range secrets {
-		if includeEmpty || (value != nil && value.Value != "") {
+		if includeEmpty || (value != nil && (value.Value != "" || value.External != nil || value.undecrypted != nil)) {
 			items = append(items, key)
 		}
 	}
@@ -313,4 +330,16 @@ func exactSecret(
 	return nil, fmt.Errorf("no secret found at %s", path)
 }
 
+func equalGroups(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
 func boolPtr(b bool) *bool { return &b }
@@ -1,12 +1,9 @@
 package secret
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"encoding/base64"
 	"errors"
-	"io"
+	"fmt"
 	"strings"
 	"unicode/utf8"
 
@@ -24,6 +21,12 @@ type Secret struct {
 	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
 	//Encrypted and encoded Value
 	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	//Reference to a value stored in an external backend instead of the encrypted value
+	External *External `json:"external,omitempty" yaml:"external,omitempty"`
+	//Recipient groups the value is encrypted to instead of the master key
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+	// undecrypted keeps secrets that can't be decrypted with the configured master key or identities, so they are written back unchanged
+	undecrypted *secretAlias
 }
 type secretAlias Secret
 
@@ -59,7 +62,7 @@ type ExistingIDSecret struct {
 }
 
 func (s *Secret) IsZero() bool {
-	if s.Value == "" {
+	if s.Value == "" && s.External == nil && s.undecrypted == nil {
 		return true
 	}
 	return false
@@ -88,49 +91,88 @@ func (s *Secret) UnmarshalYAMLWithExisting(node *yaml.Node, existing *Existing)
 	return nil
 }
 
+// masterkeyUsable returns false if secrets encrypted with the given encryption can't be decrypted with the current Masterkey at all
+func masterkeyUsable(encryption string) bool {
+	if encryption == AEADEncryption {
+		return strings.Trim(Masterkey, "\n") != ""
+	}
+	return len(Masterkey) >= 1 && len(Masterkey) <= 32
+}
+
 func unmarshal(s *Secret) (string, error) {
+	if s.External != nil {
+		return s.Resolve()
+	}
+
 	if s.Value == "" {
 		return "", nil
 	}
 
-	cipherText, err := base64.URLEncoding.DecodeString(s.Value)
-	if err != nil {
-		return "", err
+	if s.Encryption == AgeEncryption {
+		return openForRecipient(s.Value)
 	}
 
-	if len(Masterkey) < 1 || len(Masterkey) > 32 {
+	if !masterkeyUsable(s.Encryption) {
 		return "", nil
-		//return errors.New("Master key size must be between 1 and 32 characters")
 	}
 
-	masterKeyLocal := make([]byte, 32)
-	for idx, char := range []byte(strings.Trim(Masterkey, "\n")) {
-		masterKeyLocal[idx] = char
+	return decrypt(Masterkey, s)
+}
+
+func decrypt(masterkey string, s *Secret) (string, error) {
+	if s.Encoding != "" && s.Encoding != Base64Encoding {
+		return "", fmt.Errorf("Encoding %s is not supported", s.Encoding)
 	}
 
-	block, err := aes.NewCipher(masterKeyLocal)
+	cipherText, err := base64.URLEncoding.DecodeString(s.Value)
 	if err != nil {
 		return "", err
 	}
 
-	if len(cipherText) < aes.BlockSize {
-		return "", errors.New("Ciphertext block size is too short")
+	switch s.Encryption {
+	case AEADEncryption:
+		plain, err := open(masterkey, cipherText)
+		if err != nil {
+			return "", err
+		}
+		return string(plain), nil
+	case LegacyEncryption, "":
+		plain, err := legacyOpen(masterkey, cipherText)
+		if err != nil {
+			return "", err
+		}
+		if !utf8.Valid(plain) {
+			return "", errors.New("Decryption failed")
+		}
+		return string(plain), nil
+	default:
+		return "", fmt.Errorf("Encryption %s is not supported", s.Encryption)
 	}
+}
 
-	//IV needs to be unique, but doesn't have to be secure.
-	//It's common to put it at the beginning of the ciphertext.
-	iv := cipherText[:aes.BlockSize]
-	cipherText = cipherText[aes.BlockSize:]
-
-	stream := cipher.NewCFBDecrypter(block, iv)
-	// XORKeyStream can work in-place if the two arguments are the same.
-	stream.XORKeyStream(cipherText, cipherText)
+func encrypt(masterkey string, plain string) (*secretAlias, error) {
+	sealed, err := seal(masterkey, []byte(plain))
+	if err != nil {
+		return nil, err
+	}
+	return &secretAlias{Encryption: AEADEncryption, Encoding: Base64Encoding, Value: base64.URLEncoding.EncodeToString(sealed)}, nil
+}
 
-	if !utf8.Valid(cipherText) {
-		return "", errors.New("Decryption failed")
+// Resolve returns the secrets value. The values of external secrets are fetched from their backends,
+// so Resolve should be called where the value is used
+func (s *Secret) Resolve() (string, error) {
+	if s == nil {
+		return "", nil
 	}
-	//	s.monitor.Info("Decoded and decrypted secret")
-	return string(cipherText), nil
+	if s.External == nil {
+		return s.Value, nil
+	}
+	return resolveExternal(s.External)
+}
+
+// HasValue returns true if the secret has a decrypted value or references an external value
+func (s *Secret) HasValue() bool {
+	return s != nil && (s.Value != "" || s.External != nil)
 }
 
 func (s *Secret) Unmarshal(masterkey string) error {
@@ -152,12 +194,27 @@ func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
 	s.Encoding = alias.Encoding
 	s.Encryption = alias.Encryption
 	s.Value = alias.Value
+	s.External = alias.External
+	s.Groups = alias.Groups
+
+	if alias.External != nil {
+		// The value is only fetched from the backend when it is used, so parsing never depends on the backends availability
+		return validateExternal(alias.External)
+	}
 
 	if alias.Value == "" {
 		return nil
 	}
 
-	if len(Masterkey) < 1 || len(Masterkey) > 32 {
+	if alias.Encryption == AgeEncryption {
+		if s.Value, err = openForRecipient(alias.Value); s.Value == "" {
+			s.undecrypted = alias
+		}
+		return err
+	}
+
+	if !masterkeyUsable(alias.Encryption) {
+		s.undecrypted = alias
 		return nil
 		//return errors.New("Master key size must be between 1 and 32 characters")
 	}
@@ -174,36 +231,42 @@ func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
+// MarshalYAML always encrypts with the AEADEncryption, so legacy secrets are upgraded as soon as they are written again.
+// Secrets with recipient groups are encrypted to the groups members instead.
+// External secrets are marshalled as reference only
 func (s *Secret) MarshalYAML() (interface{}, error) {
 
-	if s.Value == "" {
-		return nil, nil
+	if s.External != nil {
+		return &secretAlias{External: s.External}, nil
 	}
 
-	if len(Masterkey) < 1 || len(Masterkey) > 32 {
-		return nil, errors.New("Master key size must be between 1 and 32 characters")
+	if len(s.Groups) > 0 {
+		if s.Value == "" && s.undecrypted != nil {
+			return s.undecrypted, nil
+		}
+		if s.Value == "" {
+			return nil, nil
+		}
+		recipients, err := currentRecipients()
+		if err != nil {
+			return nil, err
+		}
+		return sealForRecipients(recipients, s.Groups, s.Value)
 	}
 
-	masterKey := make([]byte, 32)
-	for idx, char := range []byte(strings.Trim(Masterkey, "\n")) {
-		masterKey[idx] = char
+	if s.undecrypted != nil && s.Value == s.undecrypted.Value {
+		return s.undecrypted, nil
 	}
 
-	c, err := aes.NewCipher(masterKey)
-	if err != nil {
-		return nil, err
+	if s.Value == "" {
+		return nil, nil
 	}
 
-	cipherText := make([]byte, aes.BlockSize+len(s.Value))
-	iv := cipherText[:aes.BlockSize]
-	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
-		return nil, err
+	if !masterkeyUsable(AEADEncryption) {
+		return nil, errors.New("encrypting secret failed: no master key configured, use recipient groups instead")
 	}
 
-	stream := cipher.NewCFBEncrypter(c, iv)
-	stream.XORKeyStream(cipherText[aes.BlockSize:], []byte(s.Value))
-
-	return &secretAlias{Encryption: "AES256", Encoding: "Base64", Value: base64.URLEncoding.EncodeToString(cipherText)}, nil
+	return encrypt(Masterkey, s.Value)
 }
 
 func InitIfNil(sec *Secret) *Secret {
@@ -235,7 +298,7 @@ func ValidateSecret(secret *Secret, existing *Existing) error {
 		return errors.New("secret not specified")
 	}
 
-	if secret.Value == "" && (existing.Name == "" || existing.Key == "") {
+	if secret.Value == "" && secret.External == nil && (existing.Name == "" || existing.Key == "") {
 		return errors.New("secret has no encrypted value or no valid reference")
 	}
 	return nil
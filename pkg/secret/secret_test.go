@@ -0,0 +1,244 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// legacyEncrypt encrypts like orbctl versions before the AEADEncryption did
+func legacyEncrypt(t *testing.T, masterkey, plain string) string {
+	key, err := legacyKey(masterkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipherText := make([]byte, aes.BlockSize+len(plain))
+	if _, err := io.ReadFull(rand.Reader, cipherText[:aes.BlockSize]); err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCFBEncrypter(block, cipherText[:aes.BlockSize]).XORKeyStream(cipherText[aes.BlockSize:], []byte(plain))
+	return base64.URLEncoding.EncodeToString(cipherText)
+}
+
+type secretHolder struct {
+	Secret *Secret
+}
+
+func withMasterkey(t *testing.T, masterkey string) {
+	previous := Masterkey
+	Masterkey = masterkey
+	t.Cleanup(func() { Masterkey = previous })
+}
+
+func TestSecret_MarshalYAML(t *testing.T) {
+	withMasterkey(t, "a very secret master key that is longer than 32 characters")
+
+	marshalled, err := yaml.Marshal(&secretHolder{Secret: &Secret{Value: "plain value"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alias := &struct{ Secret secretAlias }{}
+	if err := yaml.Unmarshal(marshalled, alias); err != nil {
+		t.Fatal(err)
+	}
+	if alias.Secret.Encryption != AEADEncryption || alias.Secret.Encoding != Base64Encoding {
+		t.Errorf("secret is marshalled with encryption %s and encoding %s", alias.Secret.Encryption, alias.Secret.Encoding)
+	}
+	if strings.Contains(string(marshalled), "plain value") {
+		t.Error("secret is marshalled in plain text")
+	}
+
+	unmarshalled := &secretHolder{}
+	if err := yaml.Unmarshal(marshalled, unmarshalled); err != nil {
+		t.Fatal(err)
+	}
+	if unmarshalled.Secret.Value != "plain value" {
+		t.Errorf("unmarshalled value %s, want plain value", unmarshalled.Secret.Value)
+	}
+}
+
+func TestSecret_UnmarshalYAML(t *testing.T) {
+	const masterkey = "masterkey"
+
+	withMasterkey(t, masterkey)
+	aeadAlias, err := encrypt(masterkey, "aead value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := []byte(aeadAlias.Value)
+	tampered[len(tampered)-3] ^= 1
+
+	tests := []struct {
+		name       string
+		masterkey  string
+		encryption string
+		value      string
+		want       string
+		wantErr    bool
+	}{{
+		name:       "legacy secrets are decrypted transparently",
+		masterkey:  masterkey,
+		encryption: LegacyEncryption,
+		value:      legacyEncrypt(t, masterkey, "legacy value"),
+		want:       "legacy value",
+	}, {
+		name:       "aead secrets are decrypted",
+		masterkey:  masterkey,
+		encryption: AEADEncryption,
+		value:      aeadAlias.Value,
+		want:       "aead value",
+	}, {
+		name:       "aead secrets detect wrong master keys",
+		masterkey:  "wrong masterkey",
+		encryption: AEADEncryption,
+		value:      aeadAlias.Value,
+		wantErr:    true,
+	}, {
+		name:       "aead secrets detect modifications",
+		masterkey:  masterkey,
+		encryption: AEADEncryption,
+		value:      string(tampered),
+		wantErr:    true,
+	}, {
+		name:       "unknown encryptions are rejected",
+		masterkey:  masterkey,
+		encryption: "ROT13",
+		value:      aeadAlias.Value,
+		wantErr:    true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withMasterkey(t, tt.masterkey)
+			document, err := yaml.Marshal(&struct{ Secret secretAlias }{Secret: secretAlias{
+				Encryption: tt.encryption,
+				Encoding:   Base64Encoding,
+				Value:      tt.value,
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			holder := &secretHolder{}
+			err = yaml.Unmarshal(document, holder)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalYAML() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil && holder.Secret.Value != tt.want {
+				t.Errorf("UnmarshalYAML() value = %s, want %s", holder.Secret.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	withMasterkey(t, "masterkey")
+
+	encrypted, err := Encrypt([]byte("snapshot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := Decrypt(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != "snapshot" {
+		t.Errorf("Decrypt() = %s, want snapshot", decrypted)
+	}
+
+	legacy, err := base64.URLEncoding.DecodeString(legacyEncrypt(t, "masterkey", "legacy snapshot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, err = Decrypt(legacy); err != nil || string(decrypted) != "legacy snapshot" {
+		t.Errorf("Decrypt() = %s, %v, want legacy snapshot", decrypted, err)
+	}
+
+	Masterkey = "wrong masterkey"
+	if _, err := Decrypt(encrypted); err == nil {
+		t.Error("Decrypt() with a wrong master key should fail")
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	const (
+		oldMasterkey = "old masterkey"
+		newMasterkey = "new masterkey"
+	)
+
+	aeadAlias, err := encrypt(oldMasterkey, "aead value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document := `kind: orbiter.caos.ch/Orb
+providers:
+  # the legacy secret
+  kvm:
+    spec:
+      sshkeyprivate:
+        encryption: AES256
+        encoding: Base64
+        value: ` + legacyEncrypt(t, oldMasterkey, "legacy value") + `
+      apitoken:
+        encryption: ` + aeadAlias.Encryption + `
+        encoding: ` + aeadAlias.Encoding + `
+        value: ` + aeadAlias.Value + `
+      notasecret:
+        value: plain
+      emptysecret:
+        encryption: AES256
+        encoding: Base64
+`
+
+	rotated, count, err := Reencrypt([]byte(document), oldMasterkey, newMasterkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Reencrypt() rotated %d secrets, want 2", count)
+	}
+	if !strings.Contains(string(rotated), "# the legacy secret") {
+		t.Error("Reencrypt() should keep comments")
+	}
+
+	withMasterkey(t, newMasterkey)
+	parsed := &struct {
+		Providers struct {
+			KVM struct {
+				Spec struct {
+					SSHKeyPrivate *Secret
+					APIToken      *Secret
+					NotASecret    struct{ Value string }
+				}
+			}
+		}
+	}{}
+	if err := yaml.Unmarshal(rotated, parsed); err != nil {
+		t.Fatal(err)
+	}
+	spec := parsed.Providers.KVM.Spec
+	if spec.SSHKeyPrivate.Value != "legacy value" || spec.SSHKeyPrivate.Encryption != AEADEncryption {
+		t.Errorf("legacy secret is reencrypted to %s with %s", spec.SSHKeyPrivate.Value, spec.SSHKeyPrivate.Encryption)
+	}
+	if spec.APIToken.Value != "aead value" {
+		t.Errorf("aead secret is reencrypted to %s", spec.APIToken.Value)
+	}
+	if spec.NotASecret.Value != "plain" {
+		t.Errorf("plain value changed to %s", spec.NotASecret.Value)
+	}
+
+	if _, _, err := Reencrypt([]byte(document), "wrong masterkey", newMasterkey); err == nil {
+		t.Error("Reencrypt() with a wrong old master key should fail")
+	}
+}
@@ -0,0 +1,236 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v3"
+)
+
+// sopsMetadataKey is the top level key SOPS stores its metadata in
+const sopsMetadataKey = "sops"
+
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+type sopsAgeKey struct {
+	Recipient string `yaml:"recipient"`
+	Enc       string `yaml:"enc"`
+}
+
+type sopsMetadata struct {
+	Age              []sopsAgeKey `yaml:"age"`
+	KeyGroups        []yaml.Node  `yaml:"key_groups"`
+	LastModified     string       `yaml:"lastmodified"`
+	MAC              string       `yaml:"mac"`
+	MACOnlyEncrypted bool         `yaml:"mac_only_encrypted"`
+}
+
+// isSOPS returns true if the YAML or JSON document has SOPS metadata
+func isSOPS(document []byte) bool {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(document, root); err != nil || len(root.Content) == 0 {
+		return false
+	}
+	_, metadata := splitSOPSMetadata(root.Content[0])
+	return metadata != nil
+}
+
+// decryptSOPS decrypts a YAML or JSON document encrypted by SOPS to age recipients.
+// The returned document has no SOPS metadata anymore
+func decryptSOPS(document []byte, identities ...age.Identity) (*yaml.Node, error) {
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(document, root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, errors.New("document is empty")
+	}
+
+	stripSOPSComments(root)
+	content, metadataNode := splitSOPSMetadata(root.Content[0])
+	if metadataNode == nil {
+		return nil, errors.New("document has no sops metadata")
+	}
+
+	metadata := &sopsMetadata{}
+	if err := metadataNode.Decode(metadata); err != nil {
+		return nil, fmt.Errorf("parsing sops metadata failed: %w", err)
+	}
+	if len(metadata.KeyGroups) > 0 {
+		return nil, errors.New("sops key groups are not supported")
+	}
+
+	dataKey, err := sopsDataKey(metadata.Age, identities)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha512.New()
+	if err := decryptSOPSNode(content, nil, dataKey, metadata.MACOnlyEncrypted, func(plain []byte) { hash.Write(plain) }); err != nil {
+		return nil, err
+	}
+
+	lastModified, err := time.Parse(time.RFC3339, metadata.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("parsing sops lastmodified failed: %w", err)
+	}
+	mac, _, err := decryptSOPSValue(metadata.MAC, dataKey, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting sops mac failed: %w", err)
+	}
+	if string(mac) != fmt.Sprintf("%X", hash.Sum(nil)) {
+		return nil, errors.New("sops mac mismatch, the file was changed without sops")
+	}
+
+	return content, nil
+}
+
+// splitSOPSMetadata returns a copy of the mapping without the SOPS metadata and the metadata
+func splitSOPSMetadata(node *yaml.Node) (*yaml.Node, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return node, nil
+	}
+
+	var metadata *yaml.Node
+	content := *node
+	content.Content = nil
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == sopsMetadataKey {
+			metadata = node.Content[idx+1]
+			continue
+		}
+		content.Content = append(content.Content, node.Content[idx], node.Content[idx+1])
+	}
+	return &content, metadata
+}
+
+func sopsDataKey(keys []sopsAgeKey, identities []age.Identity) ([]byte, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("file is not encrypted to any age recipient by sops")
+	}
+	for _, key := range keys {
+		dataKey, err := openAge([]byte(key.Enc), identities...)
+		if errors.Is(err, errNoIdentity) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decrypting sops data key of recipient %s failed: %w", key.Recipient, err)
+		}
+		return dataKey, nil
+	}
+	return nil, errNoIdentity
+}
+
+// decryptSOPSNode decrypts all values in place. Like SOPS, the additional data of a value is the path of mapping keys leading to it.
+// All values are passed to the hash in document order, unless only encrypted values are authenticated
+func decryptSOPSNode(node *yaml.Node, path []string, dataKey []byte, macOnlyEncrypted bool, hash func([]byte)) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			if err := decryptSOPSNode(node.Content[idx+1], append(path, node.Content[idx].Value), dataKey, macOnlyEncrypted, hash); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if err := decryptSOPSNode(item, path, dataKey, macOnlyEncrypted, hash); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !sopsValue.MatchString(node.Value) {
+			if !macOnlyEncrypted {
+				hash(sopsPlainBytes(node))
+			}
+			return nil
+		}
+		plain, typ, err := decryptSOPSValue(node.Value, dataKey, strings.Join(path, ":")+":")
+		if err != nil {
+			return fmt.Errorf("decrypting sops value at %s failed: %w", strings.Join(path, "."), err)
+		}
+		hash(plain)
+		node.Value = string(plain)
+		node.Style = 0
+		switch typ {
+		case "bool":
+			node.Tag = "!!bool"
+			node.Value = strings.ToLower(node.Value)
+		case "int", "float":
+			node.Tag = "!!" + typ
+		default:
+			node.Tag = "!!str"
+		}
+	}
+	return nil
+}
+
+// stripSOPSComments removes the comments SOPS encrypted, as they are no values
+func stripSOPSComments(node *yaml.Node) {
+	for _, comment := range []*string{&node.HeadComment, &node.LineComment, &node.FootComment} {
+		if strings.Contains(*comment, "ENC[AES256_GCM") {
+			*comment = ""
+		}
+	}
+	for _, child := range node.Content {
+		stripSOPSComments(child)
+	}
+}
+
+func decryptSOPSValue(value string, dataKey []byte, additionalData string) ([]byte, string, error) {
+	matches := sopsValue.FindStringSubmatch(value)
+	if matches == nil {
+		return nil, "", errors.New("value is not encrypted by sops")
+	}
+
+	var decoded [3][]byte
+	for idx := range decoded {
+		var err error
+		if decoded[idx], err = base64.StdEncoding.DecodeString(matches[idx+1]); err != nil {
+			return nil, "", err
+		}
+	}
+	data, iv, tag := decoded[0], decoded[1], decoded[2]
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, "", err
+	}
+	plain, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, "", err
+	}
+	return plain, matches[4], nil
+}
+
+// sopsPlainBytes formats unencrypted values the way SOPS does when it calculates the mac
+func sopsPlainBytes(node *yaml.Node) []byte {
+	switch node.ShortTag() {
+	case "!!bool":
+		if b, err := strconv.ParseBool(node.Value); err == nil {
+			return []byte(strings.Title(strconv.FormatBool(b)))
+		}
+	case "!!int":
+		if i, err := strconv.ParseInt(node.Value, 0, 64); err == nil {
+			return []byte(strconv.FormatInt(i, 10))
+		}
+	case "!!float":
+		if f, err := strconv.ParseFloat(node.Value, 64); err == nil {
+			return []byte(strconv.FormatFloat(f, 'f', -1, 64))
+		}
+	}
+	return []byte(node.Value)
+}
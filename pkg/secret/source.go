@@ -0,0 +1,73 @@
+package secret
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// MasterkeySource: the value is encrypted with the master key
+	MasterkeySource = "masterkey"
+	// RecipientsSource: the value is encrypted to recipient groups
+	RecipientsSource = "recipients"
+	// ExternalSource: the value is resolved from an external backend
+	ExternalSource = "external"
+	// ExistingSource: the value is read from an existing kubernetes secret
+	ExistingSource = "existing"
+	// UnsetSource: the secret has no value
+	UnsetSource = "unset"
+)
+
+// Source describes where the value of a secret path is resolved from
+type Source struct {
+	Path   string          `json:"path" yaml:"path"`
+	Source string          `json:"source" yaml:"source"`
+	From   string          `json:"from,omitempty" yaml:"from,omitempty"`
+	Status *ExistingStatus `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// Sources lists the sources of all secrets and existing references sorted by path.
+// Statuses of validated existing references are attached to their paths
+func Sources(secrets map[string]*Secret, existing map[string]*Existing, statuses map[string]*ExistingStatus) []*Source {
+
+	sources := make([]*Source, 0, len(secrets)+len(existing))
+	for path, sec := range secrets {
+		source := secretSource(sec)
+		source.Path = path
+		sources = append(sources, source)
+	}
+
+	for path, ref := range existing {
+		source := &Source{Path: path, Source: UnsetSource}
+		if ref != nil && !ref.IsZero() {
+			source.Source = ExistingSource
+			source.From = fmt.Sprintf("%s/%s#%s", existingSecretsNamespace, ref.Name, ref.Key)
+		}
+		sources = append(sources, source)
+	}
+
+	for _, source := range sources {
+		source.Status = statuses[source.Path]
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Path < sources[j].Path
+	})
+	return sources
+}
+
+func secretSource(sec *Secret) *Source {
+	switch {
+	case sec == nil:
+		return &Source{Source: UnsetSource}
+	case sec.External != nil:
+		return &Source{Source: ExternalSource, From: sec.External.String()}
+	case sec.Value == "" && sec.undecrypted == nil:
+		return &Source{Source: UnsetSource}
+	case len(sec.Groups) > 0:
+		return &Source{Source: RecipientsSource, From: strings.Join(sec.Groups, ",")}
+	default:
+		return &Source{Source: MasterkeySource}
+	}
+}
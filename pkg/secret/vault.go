@@ -0,0 +1,182 @@
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const vaultCacheTTL = time.Minute
+
+// Vault: Connection to a HashiCorp Vault server which KV secrets engines hold external secrets
+type Vault struct {
+	//Address of the Vault server, defaults to the VAULT_ADDR environment variable
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	//Token used for authentication, defaults to the VAULT_TOKEN environment variable
+	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+	//Namespace of Vault Enterprise, defaults to the VAULT_NAMESPACE environment variable
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+}
+
+type vaultSecret struct {
+	data    map[string]interface{}
+	fetched time.Time
+}
+
+type vaultBackend struct {
+	config Vault
+	client *http.Client
+	mux    sync.Mutex
+	cache  map[string]vaultSecret
+}
+
+// newVaultBackend returns nil if no vault address is configured
+func newVaultBackend(config *Vault) *vaultBackend {
+	cfg := Vault{}
+	if config != nil {
+		cfg = *config
+	}
+
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if cfg.Namespace == "" {
+		cfg.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+
+	if cfg.Address == "" {
+		return nil
+	}
+	cfg.Address = strings.TrimSuffix(cfg.Address, "/")
+
+	return &vaultBackend{
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  make(map[string]vaultSecret),
+	}
+}
+
+func (v *vaultBackend) Resolve(ref *External) (string, error) {
+	if ref.Key == "" {
+		return "", errors.New("vault secrets need a key")
+	}
+
+	data, err := v.read(strings.Trim(ref.Path, "/"))
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found", ref.Key)
+	}
+
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	marshalled, err := json.Marshal(value)
+	return string(marshalled), err
+}
+
+func (v *vaultBackend) read(path string) (map[string]interface{}, error) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	if cached, ok := v.cache[path]; ok && time.Since(cached.fetched) < vaultCacheTTL {
+		return cached.data, nil
+	}
+
+	apiPath, kvVersion, err := v.apiPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := v.get(apiPath, &resp); err != nil {
+		return nil, err
+	}
+
+	data := resp.Data
+	if kvVersion == "2" {
+		kv2Data, ok := resp.Data["data"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("secret %s is deleted", path)
+		}
+		data = kv2Data
+	}
+
+	v.cache[path] = vaultSecret{data: data, fetched: time.Now()}
+	return data, nil
+}
+
+// apiPath looks up the mount of the secret path and inserts the data segment for version 2 KV secrets engines
+func (v *vaultBackend) apiPath(path string) (string, string, error) {
+	mount := struct {
+		Data struct {
+			Path    string            `json:"path"`
+			Type    string            `json:"type"`
+			Options map[string]string `json:"options"`
+		} `json:"data"`
+	}{}
+	if err := v.get("sys/internal/ui/mounts/"+path, &mount); err != nil {
+		return "", "", fmt.Errorf("looking up mount of %s failed: %w", path, err)
+	}
+
+	mountPath := mount.Data.Path
+	if mountPath == "" || !strings.HasPrefix(path, mountPath) {
+		return path, "1", nil
+	}
+
+	kvVersion := mount.Data.Options["version"]
+	if kvVersion != "2" {
+		return path, "1", nil
+	}
+	return mountPath + "data/" + strings.TrimPrefix(path, mountPath), kvVersion, nil
+}
+
+func (v *vaultBackend) get(apiPath string, into interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, v.config.Address+"/v1/"+apiPath, nil)
+	if err != nil {
+		return err
+	}
+	if v.config.Token != "" {
+		req.Header.Set("X-Vault-Token", v.config.Token)
+	}
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		vaultErr := struct {
+			Errors []string `json:"errors"`
+		}{}
+		if err := json.Unmarshal(body, &vaultErr); err == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("vault responded with status %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, ", "))
+		}
+		return fmt.Errorf("vault responded with status %d", resp.StatusCode)
+	}
+
+	return json.Unmarshal(body, into)
+}